@@ -1,22 +1,185 @@
 package main
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+	_ "time/tzdata"
+
 	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
 	"github.com/quanluong166/friends_management/internal/config"
+	"github.com/quanluong166/friends_management/internal/constant"
 	"github.com/quanluong166/friends_management/internal/controller"
 	"github.com/quanluong166/friends_management/internal/db"
+	"github.com/quanluong166/friends_management/internal/graph"
+	"github.com/quanluong166/friends_management/internal/grpcserver"
 	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/mail"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/notify"
+	"github.com/quanluong166/friends_management/internal/openapi"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
 	"github.com/quanluong166/friends_management/internal/repository"
 	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/quanluong166/friends_management/internal/stream"
+	"github.com/quanluong166/friends_management/internal/webhook"
 )
 
 func main() {
 	config := config.LoadConfig()
 	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	spec, err := openapi.NewSpec()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	validator, err := middleware.OpenAPIValidator(spec, config.AppEnv == constant.APP_ENV_TEST)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	e.Use(validator)
+	authenticators, err := auth.LoadAuthenticators(config.JWTAlgorithm, config.JWTKeyFile, config.APIKeys)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
 	db := db.InitDB(config)
 	repo := repository.NewRepositoy(db)
-	controller := controller.NewController(db, repo.UserRelationshipRepo)
-	handler := handler.NewHandler(controller.UserRelationshipController)
-	routes.RegisterUserRelationshipRoutes(e, handler.UserRelationshipHandler)
+	budgets, err := rateLimitBudgets(config)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	store, err := rateLimitStore(config.RateLimitStore, repo.RateLimitBucketRepo, budgets, e.Logger)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	//Every authenticated route is rate limited per ip before the credentials are checked and per requestor after the tenant is resolved
+	budgetOf := routes.RateLimitBudget(budgets)
+	ipLimit, requestorLimit := middleware.RateLimit(store, middleware.ByIP, budgetOf), middleware.RateLimit(store, middleware.ByRequestor, budgetOf)
+	authenticate, resolveTenant := middleware.Authenticate(authenticators...), middleware.Tenant()
+	authentication := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return ipLimit(authenticate(resolveTenant(requestorLimit(next))))
+	}
+	quotas := controller.Quota{
+		MaxFriends:       config.QuotaMaxFriends,
+		MaxSubscriptions: config.QuotaMaxSubscriptions,
+		MaxBlocks:        config.QuotaMaxBlocks,
+		MaxNewPerDay:     config.QuotaMaxNewPerDay,
+	}
+	policy, err := mentionPolicy(config.MentionPolicy)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	feed := controller.Feed{
+		FanOutOnReadThreshold: config.FeedFanOutOnReadThreshold,
+		EmailRecipients:       config.SMTPAddr != "",
+		MentionPolicy:         policy,
+	}
+	controller := controller.NewController(db, repo.UserRelationshipRepo, repo.AdminAuditLogRepo, repo.RelationshipEventRepo, repo.OutboxEventRepo, repo.UserQuotaRepo, repo.WebhookRepo, repo.WebhookDeliveryRepo, repo.StatusUpdateRepo, repo.EmailDeliveryRepo, repo.NotificationPreferenceRepo, quotas, feed)
+	hub := stream.NewHub(int(config.StreamBufferSize))
+	handler := handler.NewHandler(controller.UserRelationshipController, controller.AdminController, controller.WebhookController, controller.StatusUpdateController, controller.NotificationPreferenceController, hub)
+	idempotency := middleware.Idempotency(repo.IdempotencyKeyRepo, config.IdempotencyTTL)
+	go middleware.PurgeExpiredIdempotencyKeys(repo.IdempotencyKeyRepo, time.Hour, e.Logger)
+	sink, err := outboxSink(config.OutboxSink, config.OutboxHTTPURL, e.Logger)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	//Every domain event is also queued for the webhooks subscribed to it
+	dispatcher := webhook.NewDispatcher(repo.WebhookRepo, repo.WebhookDeliveryRepo)
+	relay := outbox.NewRelay(db, repo.OutboxEventRepo, outbox.Sinks{sink, dispatcher}, int(config.OutboxBatchSize), int(config.OutboxMaxAttempts))
+	go relay.Run(config.OutboxPollInterval, e.Logger)
+	//Every instance listen to the changes made by all of them and push them to its stream clients
+	listener := notify.NewListener(notify.PostgresDialer(db, constant.NOTIFY_CHANNEL_RELATIONSHIP_CHANGES), repo.OutboxEventRepo, hub, int(config.OutboxBatchSize))
+	go listener.Run(config.NotifyRetryInterval, e.Logger)
+	worker := webhook.NewWorker(db, repo.WebhookDeliveryRepo, &http.Client{Timeout: config.WebhookTimeout}, int(config.WebhookBatchSize), int(config.WebhookMaxAttempts))
+	go worker.Run(config.WebhookPollInterval, e.Logger)
+	if feed.EmailRecipients {
+		templates, err := mail.LoadTemplates(config.EmailTemplateDir)
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+		sender := mail.SMTPSender{Addr: config.SMTPAddr, Username: config.SMTPUsername, Password: config.SMTPPassword, Timeout: config.SMTPTimeout}
+		mailer := mail.NewWorker(db, repo.EmailDeliveryRepo, sender, templates, config.SMTPFrom, int(config.EmailBatchSize), int(config.EmailMaxAttempts))
+		go mailer.Run(config.EmailPollInterval, e.Logger)
+		scheduler := mail.NewScheduler(db, repo.EmailDeliveryRepo, int(config.DigestBatchSize))
+		go scheduler.Run(config.DigestPollInterval, e.Logger)
+	}
+	routes.RegisterUserRelationshipRoutes(e, handler.UserRelationshipHandler, authentication, idempotency)
+	routes.RegisterUserRelationshipV2Routes(e, handler.UserRelationshipV2Handler, authentication)
+	routes.RegisterAdminRoutes(e, handler.AdminHandler, authentication)
+	routes.RegisterWebhookRoutes(e, handler.WebhookHandler, authentication)
+	routes.RegisterStreamRoutes(e, handler.StreamHandler, authentication)
+	routes.RegisterStatusUpdateRoutes(e, handler.StatusUpdateHandler, authentication, idempotency)
+	routes.RegisterNotificationPreferenceRoutes(e, handler.NotificationPreferenceHandler, authentication)
+	routes.RegisterOpenAPIRoutes(e, spec)
+	schema := graph.NewSchema(controller.UserRelationshipController, e.Logger)
+	//GraphQL queries are charged their cost on the read budget of the requestor
+	routes.RegisterGraphQLRoutes(e, graph.NewHandler(schema, controller.UserRelationshipController, middleware.ChargeRateLimit(store, budgets.Read, e.Logger)), authentication)
+	limiter := &grpcserver.RateLimiter{Store: store, Budget: grpcserver.MethodBudget(budgets.Write, budgets.Recipients, budgets.Read), Logger: e.Logger}
+	go serveGRPC(config.GRPCPort, controller.UserRelationshipController, authenticators, limiter, e.Logger)
 	e.Logger.Fatal(e.Start(config.PORT))
 }
+
+// serveGRPC run the gRPC transport, it shares the controller and the rate limit buckets with the REST api
+func serveGRPC(address string, userRelationshipController controller.UserRelationshipController, authenticators []auth.Authenticator, limiter *grpcserver.RateLimiter, logger echo.Logger) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Fatal(grpcserver.NewServer(userRelationshipController, authenticators, limiter, logger).Serve(listener))
+}
+
+// rateLimitBudgets parse the budget of every kind of route
+func rateLimitBudgets(c config.AppConfig) (routes.RateLimitBudgets, error) {
+	write, err := ratelimit.ParseBudget("write", c.RateLimitWrite)
+	if err != nil {
+		return routes.RateLimitBudgets{}, err
+	}
+	recipients, err := ratelimit.ParseBudget("recipients", c.RateLimitRecipients)
+	if err != nil {
+		return routes.RateLimitBudgets{}, err
+	}
+	read, err := ratelimit.ParseBudget("read", c.RateLimitRead)
+	if err != nil {
+		return routes.RateLimitBudgets{}, err
+	}
+	return routes.RateLimitBudgets{Write: write, Recipients: recipients, Read: read}, nil
+}
+
+// rateLimitStore select where the buckets are kept, the postgres buckets are purged once every budget had time to refill them
+func rateLimitStore(name string, repo repository.RateLimitBucketRepository, budgets routes.RateLimitBudgets, logger echo.Logger) (ratelimit.Store, error) {
+	switch name {
+	case constant.RATE_LIMIT_STORE_MEMORY:
+		return ratelimit.NewMemoryStore(), nil
+	case constant.RATE_LIMIT_STORE_POSTGRES:
+		idleAfter := max(budgets.Write.Period, budgets.Recipients.Period, budgets.Read.Period)
+		go middleware.PurgeIdleRateLimitBuckets(repo, time.Hour, idleAfter, logger)
+		return repo, nil
+	}
+	return nil, fmt.Errorf("UNKNOWN_RATE_LIMIT_STORE: %s", name)
+}
+
+// mentionPolicy check the mention policy is one of the known ones
+func mentionPolicy(name string) (string, error) {
+	switch name {
+	case constant.MENTION_POLICY_ANYONE, constant.MENTION_POLICY_KNOWN_USERS, constant.MENTION_POLICY_NOT_BLOCKED, constant.MENTION_POLICY_FRIENDS_OF_FRIENDS:
+		return name, nil
+	}
+	return "", fmt.Errorf("UNKNOWN_MENTION_POLICY: %s", name)
+}
+
+// outboxSink select where the relay publish the domain events
+func outboxSink(name, url string, logger echo.Logger) (outbox.Sink, error) {
+	switch name {
+	case constant.OUTBOX_SINK_LOG:
+		return outbox.LogSink{Logger: logger}, nil
+	case constant.OUTBOX_SINK_HTTP:
+		if url == "" {
+			return nil, fmt.Errorf("OUTBOX_HTTP_URL_REQUIRED")
+		}
+		return outbox.HTTPSink{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+	}
+	return nil, fmt.Errorf("UNKNOWN_OUTBOX_SINK: %s", name)
+}
@@ -0,0 +1,130 @@
+package apperror
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	//Stable error codes returned to API clients
+	CODE_BAD_REQUEST        = "BAD_REQUEST"
+	CODE_INVALID_INPUT      = "INVALID_INPUT"
+	CODE_NOT_FOUND          = "NOT_FOUND"
+	CODE_ALREADY_FRIENDS    = "ALREADY_FRIENDS"
+	CODE_ALREADY_SUBSCRIBED = "ALREADY_SUBSCRIBED"
+	CODE_ALREADY_BLOCKED    = "ALREADY_BLOCKED"
+	CODE_BLOCKED            = "BLOCKED"
+	CODE_IDEMPOTENCY_IN_USE = "IDEMPOTENCY_KEY_IN_USE"
+	CODE_IDEMPOTENCY_REUSED = "IDEMPOTENCY_KEY_REUSED"
+	CODE_INTERNAL           = "INTERNAL_ERROR"
+	CODE_UNAUTHENTICATED    = "UNAUTHENTICATED"
+	CODE_FORBIDDEN          = "FORBIDDEN"
+	CODE_RATE_LIMITED       = "RATE_LIMITED"
+	CODE_QUOTA_EXCEEDED     = "QUOTA_EXCEEDED"
+)
+
+const (
+	//Field level validation codes, every transport reports an invalid field with the same code
+	FIELD_REQUIRED        = "REQUIRED"
+	FIELD_INVALID_EMAIL   = "INVALID_EMAIL"
+	FIELD_DUPLICATE       = "DUPLICATE_EMAIL"
+	FIELD_TOO_FEW_ITEMS   = "TOO_FEW_ITEMS"
+	FIELD_OUT_OF_RANGE    = "OUT_OF_RANGE"
+	FIELD_INVALID_VALUE   = "INVALID_VALUE"
+	FIELD_SCHEMA_MISMATCH = "SCHEMA_MISMATCH"
+	FIELD_TOO_COMPLEX     = "TOO_COMPLEX"
+)
+
+// FieldError describe one invalid field of a request
+type FieldError struct {
+	Field  string
+	Code   string
+	Detail string
+}
+
+// QuotaViolation describe the quota a request would exceed
+type QuotaViolation struct {
+	Name  string
+	Limit int64
+	Usage int64
+}
+
+// Error is a domain error with a stable code, the message is safe to show to API clients
+type Error struct {
+	Code    string
+	Message string
+	Fields  []FieldError
+	Quota   *QuotaViolation
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is match any domain error with the same code so errors.Is works with new instances
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrNotFound          = &Error{Code: CODE_NOT_FOUND, Message: "NOT_FOUND"}
+	ErrAlreadyFriends    = &Error{Code: CODE_ALREADY_FRIENDS, Message: "YOU_ALREADY_FRIENDS"}
+	ErrAlreadySubscribed = &Error{Code: CODE_ALREADY_SUBSCRIBED, Message: "YOU_ALREADY_SUBSCRIBED"}
+	ErrAlreadyBlocked    = &Error{Code: CODE_ALREADY_BLOCKED, Message: "ALREADY_BEEN_BLOCKED"}
+	ErrBlocked           = &Error{Code: CODE_BLOCKED, Message: "ONE_OF_YOU_BLOCK_EACH_OTHER"}
+	ErrIdempotencyInUse  = &Error{Code: CODE_IDEMPOTENCY_IN_USE, Message: "REQUEST_WITH_THIS_IDEMPOTENCY_KEY_IS_IN_PROGRESS"}
+	ErrIdempotencyReused = &Error{Code: CODE_IDEMPOTENCY_REUSED, Message: "IDEMPOTENCY_KEY_USED_FOR_DIFFERENT_REQUEST"}
+	ErrInternal          = &Error{Code: CODE_INTERNAL, Message: "INTERNAL_SERVER_ERROR"}
+	ErrUnauthenticated   = &Error{Code: CODE_UNAUTHENTICATED, Message: "AUTHENTICATION_REQUIRED"}
+	ErrForbidden         = &Error{Code: CODE_FORBIDDEN, Message: "REQUESTOR_IS_NOT_THE_AUTHENTICATED_USER"}
+	ErrRateLimited       = &Error{Code: CODE_RATE_LIMITED, Message: "TOO_MANY_REQUESTS"}
+)
+
+// BadRequest create error for request body that can not be parsed
+func BadRequest(message string) *Error {
+	return &Error{Code: CODE_BAD_REQUEST, Message: message}
+}
+
+// InvalidInput create error for request field that failed validation
+func InvalidInput(message string) *Error {
+	return &Error{Code: CODE_INVALID_INPUT, Message: message}
+}
+
+// Validation create invalid input error that carry every invalid field of the request
+func Validation(message string, fields []FieldError) *Error {
+	return &Error{Code: CODE_INVALID_INPUT, Message: message, Fields: fields}
+}
+
+// NotFound create not found error with custom message
+func NotFound(message string) *Error {
+	return &Error{Code: CODE_NOT_FOUND, Message: message}
+}
+
+// Unauthenticated create error for credentials that can not be verified
+func Unauthenticated(message string) *Error {
+	return &Error{Code: CODE_UNAUTHENTICATED, Message: message}
+}
+
+// Forbidden create error for authenticated caller that is not allowed to do the action
+func Forbidden(message string) *Error {
+	return &Error{Code: CODE_FORBIDDEN, Message: message}
+}
+
+// QuotaExceeded create error for a relationship the requestor can not create without exceeding the quota
+func QuotaExceeded(name string, limit, usage int64) *Error {
+	return &Error{
+		Code:    CODE_QUOTA_EXCEEDED,
+		Message: strings.ToUpper(name) + "_QUOTA_EXCEEDED",
+		Quota:   &QuotaViolation{Name: name, Limit: limit, Usage: usage},
+	}
+}
+
+// As get the domain error from the error chain, nil if the chain has no domain error
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+// APIKeyAuthenticator verify the static keys of service to service calls
+type APIKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	hash      [sha256.Size]byte
+	principal Principal
+}
+
+// NewAPIKeyAuthenticator create authenticator for the static keys, each key is mapped to the principal of the calling service
+func NewAPIKeyAuthenticator(keys map[string]Principal) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{}
+	for key, principal := range keys {
+		principal.Method = METHOD_API_KEY
+		a.keys = append(a.keys, apiKey{hash: sha256.Sum256([]byte(key)), principal: principal})
+	}
+	return a
+}
+
+// ParseAPIKeys parse keys written as "key:subject:scope1|scope2:tenant" separated by comma, scopes and tenant are optional
+func ParseAPIKeys(value string) (map[string]Principal, error) {
+	keys := make(map[string]Principal)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 4)
+		if len(parts) < 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("INVALID_API_KEY_ENTRY: an entry must be key:subject[:scopes[:tenant]]")
+		}
+
+		principal := Principal{Subject: parts[1]}
+		if len(parts) >= 3 && len(parts[2]) > 0 {
+			principal.Scopes = strings.Split(parts[2], "|")
+		}
+		if len(parts) == 4 {
+			principal.Tenant = parts[3]
+		}
+		keys[parts[0]] = principal
+	}
+	return keys, nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(credentials Credentials) (*Principal, error) {
+	if len(credentials.APIKey) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	//Compare the hashes of every key in constant time so the response time does not leak the keys
+	hash := sha256.Sum256([]byte(credentials.APIKey))
+	var found *Principal
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], a.keys[i].hash[:]) == 1 {
+			principal := a.keys[i].principal
+			found = &principal
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("UNKNOWN_API_KEY")
+	}
+	return found, nil
+}
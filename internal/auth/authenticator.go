@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+)
+
+// ErrNoCredentials is returned by an authenticator when the request does not carry its kind of credentials
+var ErrNoCredentials = errors.New("NO_CREDENTIALS")
+
+// Credentials are the secrets sent by the caller, each transport read them from its own headers
+type Credentials struct {
+	BearerToken string
+	APIKey      string
+}
+
+// Authenticator verify one kind of credentials
+type Authenticator interface {
+	Authenticate(credentials Credentials) (*Principal, error)
+}
+
+// Authenticate try every authenticator in order and return the first principal.
+// Credentials that are present but invalid are rejected, they never fall through to the next authenticator.
+func Authenticate(credentials Credentials, authenticators ...Authenticator) (*Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(credentials)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		if err != nil {
+			return nil, apperror.Unauthenticated("INVALID_CREDENTIALS")
+		}
+		return principal, nil
+	}
+	return nil, apperror.ErrUnauthenticated
+}
+
+// LoadAuthenticators create the configured authenticators, JWT when a key file is set and API keys when keys are set
+func LoadAuthenticators(jwtAlgorithm, jwtKeyFile, apiKeys string) ([]Authenticator, error) {
+	var authenticators []Authenticator
+	if len(jwtKeyFile) > 0 {
+		jwtAuthenticator, err := LoadJWTAuthenticator(jwtAlgorithm, jwtKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	if len(apiKeys) > 0 {
+		keys, err := ParseAPIKeys(apiKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, NewAPIKeyAuthenticator(keys))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("AUTHENTICATION_IS_NOT_CONFIGURED: set AUTH_JWT_KEY_FILE or API_KEYS")
+	}
+	return authenticators, nil
+}
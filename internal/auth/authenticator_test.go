@@ -0,0 +1,167 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-secret")
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	require.NoError(t, err)
+	return token
+}
+
+func TestJWTAuthenticator_HS256(t *testing.T) {
+	valid := jwt.MapClaims{"sub": "andy@example.com", "scope": "read admin", "exp": time.Now().Add(time.Hour).Unix()}
+
+	tcs := map[string]struct {
+		credentials auth.Credentials
+		principal   *auth.Principal
+		err         bool
+		noToken     bool
+	}{
+		"Valid token": {
+			credentials: auth.Credentials{BearerToken: signHS256(t, valid)},
+			principal:   &auth.Principal{Subject: "andy@example.com", Scopes: []string{"read", "admin"}, Method: auth.METHOD_JWT},
+		},
+		"Token bound to a tenant": {
+			credentials: auth.Credentials{BearerToken: signHS256(t, jwt.MapClaims{"sub": "andy@example.com", "tenant": "acme", "exp": time.Now().Add(time.Hour).Unix()})},
+			principal:   &auth.Principal{Subject: "andy@example.com", Scopes: []string{}, Method: auth.METHOD_JWT, Tenant: "acme"},
+		},
+		"Expired token": {
+			credentials: auth.Credentials{BearerToken: signHS256(t, jwt.MapClaims{"sub": "andy@example.com", "exp": time.Now().Add(-time.Minute).Unix()})},
+			err:         true,
+		},
+		"Token without expiry": {
+			credentials: auth.Credentials{BearerToken: signHS256(t, jwt.MapClaims{"sub": "andy@example.com"})},
+			err:         true,
+		},
+		"Token without subject": {
+			credentials: auth.Credentials{BearerToken: signHS256(t, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})},
+			err:         true,
+		},
+		"Unsigned token": {
+			credentials: auth.Credentials{BearerToken: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return token
+			}()},
+			err: true,
+		},
+		"No token": {
+			credentials: auth.Credentials{APIKey: "key"},
+			noToken:     true,
+		},
+	}
+
+	authenticator := auth.NewHMACAuthenticator(secret)
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(tc.credentials)
+			switch {
+			case tc.noToken:
+				assert.ErrorIs(t, err, auth.ErrNoCredentials)
+			case tc.err:
+				assert.Error(t, err)
+				assert.Nil(t, principal)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tc.principal, principal)
+				assert.Equal(t, principal.HasScope(auth.SCOPE_ADMIN), principal.CanActAs("someone@example.com"))
+			}
+		})
+	}
+}
+
+func TestLoadJWTAuthenticator_RS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "jwt.pub")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}), 0o600))
+
+	authenticator, err := auth.LoadJWTAuthenticator(auth.ALGORITHM_RS256, keyFile)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{"sub": "andy@example.com", "exp": time.Now().Add(time.Hour).Unix()}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+	require.NoError(t, err)
+
+	principal, err := authenticator.Authenticate(auth.Credentials{BearerToken: token})
+	require.NoError(t, err)
+	assert.Equal(t, "andy@example.com", principal.Subject)
+	assert.False(t, principal.CanActAs("john@example.com"))
+
+	//A HS256 token signed with the public key must not be accepted by the RS256 authenticator
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	require.NoError(t, err)
+	_, err = authenticator.Authenticate(auth.Credentials{BearerToken: forged})
+	assert.Error(t, err)
+
+	_, err = auth.LoadJWTAuthenticator("ES256", keyFile)
+	assert.EqualError(t, err, "UNSUPPORTED_JWT_ALGORITHM: ES256")
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	keys, err := auth.ParseAPIKeys("svc-key:billing-service:admin|read, user-key:andy@example.com, acme-key:acme-app::acme")
+	require.NoError(t, err)
+	authenticator := auth.NewAPIKeyAuthenticator(keys)
+
+	principal, err := authenticator.Authenticate(auth.Credentials{APIKey: "svc-key"})
+	require.NoError(t, err)
+	assert.Equal(t, &auth.Principal{Subject: "billing-service", Scopes: []string{"admin", "read"}, Method: auth.METHOD_API_KEY}, principal)
+
+	principal, err = authenticator.Authenticate(auth.Credentials{APIKey: "user-key"})
+	require.NoError(t, err)
+	assert.True(t, principal.CanActAs("ANDY@example.com"))
+	assert.False(t, principal.CanActAs("john@example.com"))
+
+	principal, err = authenticator.Authenticate(auth.Credentials{APIKey: "acme-key"})
+	require.NoError(t, err)
+	assert.Equal(t, &auth.Principal{Subject: "acme-app", Method: auth.METHOD_API_KEY, Tenant: "acme"}, principal)
+
+	_, err = authenticator.Authenticate(auth.Credentials{APIKey: "unknown"})
+	assert.Error(t, err)
+
+	_, err = authenticator.Authenticate(auth.Credentials{})
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+
+	_, err = auth.ParseAPIKeys("only-key")
+	assert.Error(t, err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := auth.NewAPIKeyAuthenticator(map[string]auth.Principal{"svc-key": {Subject: "billing-service"}})
+	jwtAuthenticator := auth.NewHMACAuthenticator(secret)
+
+	principal, err := auth.Authenticate(auth.Credentials{APIKey: "svc-key"}, jwtAuthenticator, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "billing-service", principal.Subject)
+
+	//An invalid token is rejected even when a valid API key is also sent
+	_, err = auth.Authenticate(auth.Credentials{BearerToken: "invalid", APIKey: "svc-key"}, jwtAuthenticator, keys)
+	assert.True(t, errors.Is(err, apperror.ErrUnauthenticated))
+	assert.EqualError(t, err, "INVALID_CREDENTIALS")
+
+	_, err = auth.Authenticate(auth.Credentials{}, jwtAuthenticator, keys)
+	assert.Equal(t, apperror.ErrUnauthenticated, err)
+
+	_, err = auth.LoadAuthenticators(auth.ALGORITHM_HS256, "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	//Supported JWT signing algorithms
+	ALGORITHM_HS256 = "HS256"
+	ALGORITHM_RS256 = "RS256"
+)
+
+// claims of the JWT, sub is the email of the user, scope is a space separated list like OAuth 2 and tenant bind the token to one tenant
+type claims struct {
+	jwt.RegisteredClaims
+	Scope  string `json:"scope,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// JWTAuthenticator verify bearer tokens signed with one algorithm and key, tokens must have an expiry
+type JWTAuthenticator struct {
+	algorithm string
+	key       interface{}
+}
+
+// NewHMACAuthenticator create authenticator for HS256 tokens signed with the shared secret
+func NewHMACAuthenticator(secret []byte) *JWTAuthenticator {
+	return &JWTAuthenticator{algorithm: ALGORITHM_HS256, key: secret}
+}
+
+// NewRSAAuthenticator create authenticator for RS256 tokens signed with the private key of the public key
+func NewRSAAuthenticator(publicKey *rsa.PublicKey) *JWTAuthenticator {
+	return &JWTAuthenticator{algorithm: ALGORITHM_RS256, key: publicKey}
+}
+
+// LoadJWTAuthenticator create authenticator from a local key file, the file hold the HS256 secret or the RS256 PEM public key
+func LoadJWTAuthenticator(algorithm, keyFile string) (*JWTAuthenticator, error) {
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("READ_JWT_KEY_FILE_FAIL: %w", err)
+	}
+
+	switch algorithm {
+	case ALGORITHM_HS256:
+		secret := []byte(strings.TrimSpace(string(key)))
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("JWT_SECRET_IS_EMPTY")
+		}
+		return NewHMACAuthenticator(secret), nil
+	case ALGORITHM_RS256:
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(key)
+		if err != nil {
+			return nil, fmt.Errorf("PARSE_JWT_PUBLIC_KEY_FAIL: %w", err)
+		}
+		return NewRSAAuthenticator(publicKey), nil
+	}
+	return nil, fmt.Errorf("UNSUPPORTED_JWT_ALGORITHM: %s", algorithm)
+}
+
+func (a *JWTAuthenticator) Authenticate(credentials Credentials) (*Principal, error) {
+	if len(credentials.BearerToken) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	var c claims
+	_, err := jwt.ParseWithClaims(credentials.BearerToken, &c, func(*jwt.Token) (interface{}, error) {
+		return a.key, nil
+	}, jwt.WithValidMethods([]string{a.algorithm}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Subject) == 0 {
+		return nil, fmt.Errorf("JWT_SUBJECT_IS_REQUIRED")
+	}
+
+	return &Principal{Subject: c.Subject, Scopes: strings.Fields(c.Scope), Method: METHOD_JWT, Tenant: c.Tenant}, nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	//Scope that allow a caller to act on behalf of any user
+	SCOPE_ADMIN = "admin"
+	//Scope of the services that are not bound to a tenant and select it in every request
+	SCOPE_SERVICE = "service"
+
+	//How the principal was authenticated
+	METHOD_JWT     = "JWT"
+	METHOD_API_KEY = "API_KEY"
+)
+
+// Principal is the authenticated caller, Subject is the email of the user or the name of the service.
+// Tenant is set when the credentials are bound to one tenant.
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Method  string
+	Tenant  string
+}
+
+// HasScope check if the principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanActAs check if the principal is allowed to act as the user with the email
+func (p *Principal) CanActAs(email string) bool {
+	return p.HasScope(SCOPE_ADMIN) || strings.EqualFold(p.Subject, email)
+}
+
+type principalKey struct{}
+
+// NewContext store the principal in the context, every transport use it so handlers read the caller the same way
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext get the principal of the request, nil when the request is not authenticated
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
@@ -2,6 +2,10 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
 )
 
 type AppConfig struct {
@@ -13,6 +17,60 @@ type AppConfig struct {
 	TimeZone   string
 	SSLMode    string
 	PORT       string
+	//Address of the gRPC server
+	GRPCPort string
+	//Environment of the application, responses are validated against the OpenAPI spec in test
+	AppEnv string
+	//Authentication, JWT signed with the key in JWTKeyFile and static API keys "key:subject:scope1|scope2" separated by comma
+	JWTAlgorithm string
+	JWTKeyFile   string
+	APIKeys      string
+	//How long the first response of an Idempotency-Key is kept for replay
+	IdempotencyTTL time.Duration
+	//Rate limit budgets written as "limit/period", every route kind has its own bucket per requestor and per ip
+	RateLimitWrite      string
+	RateLimitRecipients string
+	RateLimitRead       string
+	//Where the buckets are kept, "memory" for a single instance or "postgres" to share them between instances
+	RateLimitStore string
+	//Default quotas of every email, admins can override them per email, zero means no cap
+	QuotaMaxFriends       int64
+	QuotaMaxSubscriptions int64
+	QuotaMaxBlocks        int64
+	QuotaMaxNewPerDay     int64
+	//Where the outbox relay publish the domain events, "log" or "http" to post them to OutboxHTTPURL, an event is dead lettered
+	//after OutboxMaxAttempts failures
+	OutboxSink         string
+	OutboxHTTPURL      string
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int64
+	OutboxMaxAttempts  int64
+	//Webhook deliveries, a delivery is moved to the dead letters after WebhookMaxAttempts failures
+	WebhookPollInterval time.Duration
+	WebhookBatchSize    int64
+	WebhookMaxAttempts  int64
+	WebhookTimeout      time.Duration
+	//Events buffered per stream client, a client that falls further behind is disconnected and resumes when it reconnects
+	StreamBufferSize int64
+	//Delay before listening again to the change notifications of the other instances, doubled after every failure up to a minute
+	NotifyRetryInterval time.Duration
+	//Authors with at least this many friends and subscribers have their status updates read from them instead of written to every feed, 0 disables it
+	FeedFanOutOnReadThreshold int64
+	//Who can be reached by mentioning their email in a status update: ANYONE, KNOWN_USERS, NOT_BLOCKED or FRIENDS_OF_FRIENDS
+	MentionPolicy string
+	//Emails to the recipients of the status updates, disabled when SMTPAddr is empty. The templates shipped with the service are used when EmailTemplateDir is empty
+	SMTPAddr          string
+	SMTPUsername      string
+	SMTPPassword      string
+	SMTPFrom          string
+	SMTPTimeout       time.Duration
+	EmailTemplateDir  string
+	EmailPollInterval time.Duration
+	EmailBatchSize    int64
+	EmailMaxAttempts  int64
+	//Digests of the status updates for the recipients that prefer an hourly, daily or weekly email, windows end on UTC boundaries
+	DigestPollInterval time.Duration
+	DigestBatchSize    int64
 }
 
 type TestConfig struct {
@@ -33,6 +91,32 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
+
+func getInt64Env(key string, defaultValue int64) int64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return number
+}
+
 // LoadConfig support to get application config
 func LoadConfig() AppConfig {
 	return AppConfig{
@@ -44,6 +128,53 @@ func LoadConfig() AppConfig {
 		TimeZone:   getEnv("DB_TIMEZONE", "UTC"),
 		SSLMode:    getEnv("DB_SSLMODE", "disable"),
 		PORT:       getEnv("PORT", ":8080"),
+		GRPCPort:   getEnv("GRPC_PORT", ":9090"),
+		AppEnv:     getEnv("APP_ENV", constant.APP_ENV_DEVELOPMENT),
+
+		JWTAlgorithm: getEnv("AUTH_JWT_ALGORITHM", "HS256"),
+		JWTKeyFile:   getEnv("AUTH_JWT_KEY_FILE", ""),
+		APIKeys:      getEnv("API_KEYS", ""),
+
+		IdempotencyTTL: getDurationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		RateLimitWrite:      getEnv("RATE_LIMIT_WRITE", "30/1m"),
+		RateLimitRecipients: getEnv("RATE_LIMIT_RECIPIENTS", "60/1m"),
+		RateLimitRead:       getEnv("RATE_LIMIT_READ", "300/1m"),
+		RateLimitStore:      getEnv("RATE_LIMIT_STORE", constant.RATE_LIMIT_STORE_MEMORY),
+
+		QuotaMaxFriends:       getInt64Env("QUOTA_MAX_FRIENDS", 5000),
+		QuotaMaxSubscriptions: getInt64Env("QUOTA_MAX_SUBSCRIPTIONS", 5000),
+		QuotaMaxBlocks:        getInt64Env("QUOTA_MAX_BLOCKS", 1000),
+		QuotaMaxNewPerDay:     getInt64Env("QUOTA_MAX_NEW_PER_DAY", 200),
+
+		OutboxSink:         getEnv("OUTBOX_SINK", constant.OUTBOX_SINK_LOG),
+		OutboxHTTPURL:      getEnv("OUTBOX_HTTP_URL", ""),
+		OutboxPollInterval: getDurationEnv("OUTBOX_POLL_INTERVAL", time.Second),
+		OutboxBatchSize:    getInt64Env("OUTBOX_BATCH_SIZE", 100),
+		OutboxMaxAttempts:  getInt64Env("OUTBOX_MAX_ATTEMPTS", 20),
+
+		WebhookPollInterval: getDurationEnv("WEBHOOK_POLL_INTERVAL", time.Second),
+		WebhookBatchSize:    getInt64Env("WEBHOOK_BATCH_SIZE", 100),
+		WebhookMaxAttempts:  getInt64Env("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeout:      getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		StreamBufferSize:    getInt64Env("STREAM_BUFFER_SIZE", 64),
+		NotifyRetryInterval: getDurationEnv("NOTIFY_RETRY_INTERVAL", time.Second),
+
+		FeedFanOutOnReadThreshold: getInt64Env("FEED_FAN_OUT_ON_READ_THRESHOLD", 10000),
+		MentionPolicy:             getEnv("MENTION_POLICY", constant.MENTION_POLICY_ANYONE),
+
+		SMTPAddr:           getEnv("SMTP_ADDR", ""),
+		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:           getEnv("SMTP_FROM", "noreply@friends-management.local"),
+		SMTPTimeout:        getDurationEnv("SMTP_TIMEOUT", 10*time.Second),
+		EmailTemplateDir:   getEnv("EMAIL_TEMPLATE_DIR", ""),
+		EmailPollInterval:  getDurationEnv("EMAIL_POLL_INTERVAL", time.Second),
+		EmailBatchSize:     getInt64Env("EMAIL_BATCH_SIZE", 100),
+		EmailMaxAttempts:   getInt64Env("EMAIL_MAX_ATTEMPTS", 6),
+		DigestPollInterval: getDurationEnv("DIGEST_POLL_INTERVAL", time.Minute),
+		DigestBatchSize:    getInt64Env("DIGEST_BATCH_SIZE", 100),
 	}
 }
 
@@ -9,4 +9,92 @@ const (
 	//database config
 	DATABASE_MAX_OPEN_CONNECTION = 10
 	DATABASE_MAX_IDLE_CONNECTION = 5
+
+	//Idempotency key status
+	IDEMPOTENCY_STATUS_IN_PROGRESS = "IN_PROGRESS"
+	IDEMPOTENCY_STATUS_COMPLETED   = "COMPLETED"
+
+	//pagination config
+	DEFAULT_PAGE_LIMIT = 20
+	MAX_PAGE_LIMIT     = 100
+
+	//Audited admin actions
+	ADMIN_ACTION_LIST_RELATIONSHIPS         = "LIST_RELATIONSHIPS"
+	ADMIN_ACTION_FORCE_REMOVE_RELATIONSHIP  = "FORCE_REMOVE_RELATIONSHIP"
+	ADMIN_ACTION_FORCE_UNBLOCK              = "FORCE_UNBLOCK"
+	ADMIN_ACTION_GET_QUOTA                  = "GET_QUOTA"
+	ADMIN_ACTION_SET_QUOTA                  = "SET_QUOTA"
+	ADMIN_ACTION_RESET_QUOTA                = "RESET_QUOTA"
+	ADMIN_ACTION_LIST_RELATIONSHIP_EVENTS   = "LIST_RELATIONSHIP_EVENTS"
+	ADMIN_ACTION_CREATE_WEBHOOK             = "CREATE_WEBHOOK"
+	ADMIN_ACTION_LIST_WEBHOOKS              = "LIST_WEBHOOKS"
+	ADMIN_ACTION_DELETE_WEBHOOK             = "DELETE_WEBHOOK"
+	ADMIN_ACTION_LIST_WEBHOOK_DEAD_LETTERS  = "LIST_WEBHOOK_DEAD_LETTERS"
+	ADMIN_ACTION_REPLAY_WEBHOOK_DEAD_LETTER = "REPLAY_WEBHOOK_DEAD_LETTER"
+
+	//Actions recorded in the relationship events
+	RELATIONSHIP_EVENT_CREATE = "CREATE"
+	RELATIONSHIP_EVENT_DELETE = "DELETE"
+	RELATIONSHIP_EVENT_BLOCK  = "BLOCK"
+
+	//Domain events published to downstream services through the outbox
+	DOMAIN_EVENT_FRIENDSHIP_CREATED = "FriendshipCreated"
+	DOMAIN_EVENT_FRIENDSHIP_REMOVED = "FriendshipRemoved"
+	DOMAIN_EVENT_SUBSCRIBED         = "Subscribed"
+	DOMAIN_EVENT_UNSUBSCRIBED       = "Unsubscribed"
+	DOMAIN_EVENT_BLOCKED            = "Blocked"
+	DOMAIN_EVENT_UNBLOCKED          = "Unblocked"
+
+	//Outbox sinks
+	OUTBOX_SINK_LOG  = "log"
+	OUTBOX_SINK_HTTP = "http"
+
+	//Postgres channel notified of every relationship change, the payload is the json outbox message
+	NOTIFY_CHANNEL_RELATIONSHIP_CHANGES = "relationship_changes"
+
+	//How a status update reaches the feeds, written to every recipient when posted or read from the author when the feed is listed
+	FAN_OUT_ON_WRITE = "WRITE"
+	FAN_OUT_ON_READ  = "READ"
+
+	//Status of the email sent to one recipient, FAILED once every attempt failed
+	EMAIL_STATUS_PENDING = "PENDING"
+	EMAIL_STATUS_SENT    = "SENT"
+	EMAIL_STATUS_FAILED  = "FAILED"
+
+	//Email templates
+	EMAIL_TEMPLATE_STATUS_UPDATE = "status_update"
+	EMAIL_TEMPLATE_DIGEST        = "digest"
+
+	//How often an email is sent the status updates it receives, every other frequency than immediate is a digest
+	NOTIFICATION_FREQUENCY_IMMEDIATE = "IMMEDIATE"
+	NOTIFICATION_FREQUENCY_HOURLY    = "HOURLY"
+	NOTIFICATION_FREQUENCY_DAILY     = "DAILY"
+	NOTIFICATION_FREQUENCY_WEEKLY    = "WEEKLY"
+
+	//Who the emails mentioned in a status update can be, the friends and subscribers of the author can always be mentioned
+	MENTION_POLICY_ANYONE             = "ANYONE"
+	MENTION_POLICY_KNOWN_USERS        = "KNOWN_USERS"
+	MENTION_POLICY_NOT_BLOCKED        = "NOT_BLOCKED"
+	MENTION_POLICY_FRIENDS_OF_FRIENDS = "FRIENDS_OF_FRIENDS"
+
+	//Webhook deliveries
+	WEBHOOK_SIGNATURE_HEADER = "X-Webhook-Signature"
+	WEBHOOK_SECRET_PREFIX    = "whsec_"
+
+	//Quotas of one email, also the names reported in QUOTA_EXCEEDED errors
+	QUOTA_MAX_FRIENDS       = "max_friends"
+	QUOTA_MAX_SUBSCRIPTIONS = "max_subscriptions"
+	QUOTA_MAX_BLOCKS        = "max_blocks"
+	QUOTA_MAX_NEW_PER_DAY   = "max_new_per_day"
+
+	//Tenant of the rows created before multi tenancy and of requests that do not select one
+	DEFAULT_TENANT_ID = "default"
+
+	//Rate limit stores
+	RATE_LIMIT_STORE_MEMORY   = "memory"
+	RATE_LIMIT_STORE_POSTGRES = "postgres"
+
+	//application environment
+	APP_ENV_DEVELOPMENT = "development"
+	APP_ENV_TEST        = "test"
 )
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Actor is the authenticated caller of an audited action
+type Actor struct {
+	Subject   string
+	IP        string
+	UserAgent string
+}
+
+// AdminController defines the moderation actions of admins, every action is audited
+type AdminController interface {
+	ListRelationships(actor Actor, filter repository.RelationshipFilter) ([]model.UserRelationship, int64, error)
+	ForceRemoveRelationship(actor Actor, id uint) error
+	ForceUnblock(actor Actor, email1, email2 string) error
+	GetQuota(actor Actor, email string) (*QuotaReport, error)
+	SetQuota(actor Actor, override *model.UserQuota) (*QuotaReport, error)
+	ResetQuota(actor Actor, email string) error
+	ListRelationshipEvents(actor Actor, filter repository.RelationshipEventFilter) ([]model.RelationshipEvent, int64, error)
+	WithTenant(tenantID string) AdminController
+}
+
+type adminController struct {
+	db                    *gorm.DB
+	userRelationshipRepo  repository.UserRelationshipRepository
+	adminAuditLogRepo     repository.AdminAuditLogRepository
+	relationshipEventRepo repository.RelationshipEventRepository
+	outboxEventRepo       repository.OutboxEventRepository
+	userQuotaRepo         repository.UserQuotaRepository
+	quota                 quotaChecker
+}
+
+func NewAdminController(db *gorm.DB, userRelationshipRepo repository.UserRelationshipRepository, adminAuditLogRepo repository.AdminAuditLogRepository, relationshipEventRepo repository.RelationshipEventRepository, outboxEventRepo repository.OutboxEventRepository, userQuotaRepo repository.UserQuotaRepository, quotas Quota) AdminController {
+	return &adminController{
+		db:                    db,
+		userRelationshipRepo:  userRelationshipRepo,
+		adminAuditLogRepo:     adminAuditLogRepo,
+		relationshipEventRepo: relationshipEventRepo,
+		outboxEventRepo:       outboxEventRepo,
+		userQuotaRepo:         userQuotaRepo,
+		quota:                 quotaChecker{defaults: quotas, userRelationshipRepo: userRelationshipRepo, userQuotaRepo: userQuotaRepo, relationshipEventRepo: relationshipEventRepo},
+	}
+}
+
+// ListRelationships support list relationships matching the filter
+func (ac *adminController) ListRelationships(actor Actor, filter repository.RelationshipFilter) ([]model.UserRelationship, int64, error) {
+	relationships, total, err := ac.userRelationshipRepo.ListRelationships(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("LIST_RELATIONSHIPS_FAIL: %w", err)
+	}
+
+	if err := audit(ac.adminAuditLogRepo, actor, constant.ADMIN_ACTION_LIST_RELATIONSHIPS, filter); err != nil {
+		return nil, 0, err
+	}
+	return relationships, total, nil
+}
+
+// ForceRemoveRelationship support delete a relationship by id, the reverse row of a friendship is removed too
+func (ac *adminController) ForceRemoveRelationship(actor Actor, id uint) error {
+	return ac.db.Transaction(func(tx *gorm.DB) error {
+		userRelationshipRepo := ac.userRelationshipRepo.WithTx(tx)
+		relationship, err := userRelationshipRepo.GetRelationshipByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.NotFound("RELATIONSHIP_NOT_FOUND")
+		}
+		if err != nil {
+			return fmt.Errorf("GET_RELATIONSHIP_FAIL: %w", err)
+		}
+
+		if _, err := userRelationshipRepo.DeleteRelationshipByID(id); err != nil {
+			return fmt.Errorf("DELETE_RELATIONSHIP_FAIL: %w", err)
+		}
+
+		before := relationshipStates([]model.UserRelationship{*relationship})
+		var reverseDeleted int64
+		if relationship.Type == constant.FRIEND_RELATIONSHIP_TYPE {
+			reverseDeleted, err = userRelationshipRepo.DeleteRelationshipByType(relationship.TargetEmail, relationship.RequestorEmail, constant.FRIEND_RELATIONSHIP_TYPE)
+			if err != nil {
+				return fmt.Errorf("DELETE_REVERSE_FRIENDSHIP_RELATION_FAIL: %w", err)
+			}
+			if reverseDeleted > 0 {
+				before = append(before, model.RelationshipState{Requestor: relationship.TargetEmail, Target: relationship.RequestorEmail, Type: constant.FRIEND_RELATIONSHIP_TYPE})
+			}
+		}
+
+		err = recordEvent(ac.relationshipEventRepo.WithTx(tx), actor, constant.RELATIONSHIP_EVENT_DELETE, relationship.RequestorEmail, relationship.TargetEmail, before, nil)
+		if err != nil {
+			return err
+		}
+
+		err = publishEvent(ac.outboxEventRepo.WithTx(tx), removedEvents[relationship.Type], relationship.RequestorEmail, relationship.TargetEmail)
+		if err != nil {
+			return err
+		}
+
+		details := map[string]interface{}{"relationship": relationship, "reverse_deleted": reverseDeleted}
+		return audit(ac.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_FORCE_REMOVE_RELATIONSHIP, details)
+	})
+}
+
+// ForceUnblock support delete the blocks between two emails whoever created them
+func (ac *adminController) ForceUnblock(actor Actor, email1, email2 string) error {
+	return ac.db.Transaction(func(tx *gorm.DB) error {
+		userRelationshipRepo := ac.userRelationshipRepo.WithTx(tx)
+		firstDeleted, err := userRelationshipRepo.DeleteRelationshipByType(email1, email2, constant.BLOCK_RELATIONSHIP_TYPE)
+		if err != nil {
+			return fmt.Errorf("DELETE_FIRST_BLOCK_RELATION_FAIL: %w", err)
+		}
+
+		secondDeleted, err := userRelationshipRepo.DeleteRelationshipByType(email2, email1, constant.BLOCK_RELATIONSHIP_TYPE)
+		if err != nil {
+			return fmt.Errorf("DELETE_SECOND_BLOCK_RELATION_FAIL: %w", err)
+		}
+
+		if firstDeleted+secondDeleted == 0 {
+			return apperror.NotFound("BLOCK_NOT_FOUND")
+		}
+
+		var before []model.RelationshipState
+		if firstDeleted > 0 {
+			before = append(before, model.RelationshipState{Requestor: email1, Target: email2, Type: constant.BLOCK_RELATIONSHIP_TYPE})
+		}
+		if secondDeleted > 0 {
+			before = append(before, model.RelationshipState{Requestor: email2, Target: email1, Type: constant.BLOCK_RELATIONSHIP_TYPE})
+		}
+		if err := recordEvent(ac.relationshipEventRepo.WithTx(tx), actor, constant.RELATIONSHIP_EVENT_DELETE, email1, email2, before, nil); err != nil {
+			return err
+		}
+
+		//Every removed block is published as unblocked by the user who created it
+		for _, state := range before {
+			if err := publishEvent(ac.outboxEventRepo.WithTx(tx), constant.DOMAIN_EVENT_UNBLOCKED, state.Requestor, state.Target); err != nil {
+				return err
+			}
+		}
+
+		details := map[string]interface{}{"email1": email1, "email2": email2, "deleted": firstDeleted + secondDeleted}
+		return audit(ac.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_FORCE_UNBLOCK, details)
+	})
+}
+
+// GetQuota support get the quota of an email with its usage
+func (ac *adminController) GetQuota(actor Actor, email string) (*QuotaReport, error) {
+	report, err := ac.quota.report(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := audit(ac.adminAuditLogRepo, actor, constant.ADMIN_ACTION_GET_QUOTA, map[string]string{"email": email}); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// SetQuota support override the caps of an email, a nil cap keep the default
+func (ac *adminController) SetQuota(actor Actor, override *model.UserQuota) (*QuotaReport, error) {
+	override.UpdatedBy = actor.Subject
+	err := ac.db.Transaction(func(tx *gorm.DB) error {
+		if err := ac.userQuotaRepo.WithTx(tx).Upsert(override); err != nil {
+			return fmt.Errorf("UPSERT_USER_QUOTA_FAIL: %w", err)
+		}
+		return audit(ac.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_SET_QUOTA, override)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ac.quota.report(override.Email)
+}
+
+// ResetQuota support delete the override of an email so the defaults apply again
+func (ac *adminController) ResetQuota(actor Actor, email string) error {
+	return ac.db.Transaction(func(tx *gorm.DB) error {
+		deleted, err := ac.userQuotaRepo.WithTx(tx).DeleteByEmail(email)
+		if err != nil {
+			return fmt.Errorf("DELETE_USER_QUOTA_FAIL: %w", err)
+		}
+
+		if deleted == 0 {
+			return apperror.NotFound("QUOTA_OVERRIDE_NOT_FOUND")
+		}
+		return audit(ac.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_RESET_QUOTA, map[string]string{"email": email})
+	})
+}
+
+// ListRelationshipEvents support list the history of the relationship graph matching the filter
+func (ac *adminController) ListRelationshipEvents(actor Actor, filter repository.RelationshipEventFilter) ([]model.RelationshipEvent, int64, error) {
+	events, total, err := ac.relationshipEventRepo.List(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("LIST_RELATIONSHIP_EVENTS_FAIL: %w", err)
+	}
+
+	if err := audit(ac.adminAuditLogRepo, actor, constant.ADMIN_ACTION_LIST_RELATIONSHIP_EVENTS, filter); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// WithTenant return a controller that moderate the relationships of the tenant
+func (ac *adminController) WithTenant(tenantID string) AdminController {
+	return &adminController{
+		db:                    ac.db,
+		userRelationshipRepo:  ac.userRelationshipRepo.WithTenant(tenantID),
+		adminAuditLogRepo:     ac.adminAuditLogRepo.WithTenant(tenantID),
+		relationshipEventRepo: ac.relationshipEventRepo.WithTenant(tenantID),
+		outboxEventRepo:       ac.outboxEventRepo.WithTenant(tenantID),
+		userQuotaRepo:         ac.userQuotaRepo.WithTenant(tenantID),
+		quota:                 ac.quota.withTenant(tenantID),
+	}
+}
+
+// audit support record an admin action with its details as json
+func audit(repo repository.AdminAuditLogRepository, actor Actor, action string, details interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("ENCODE_AUDIT_DETAILS_FAIL: %w", err)
+	}
+
+	err = repo.Create(&model.AdminAuditLog{
+		Actor:     actor.Subject,
+		Action:    action,
+		Details:   string(data),
+		IP:        actor.IP,
+		UserAgent: actor.UserAgent,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("CREATE_AUDIT_LOG_FAIL: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockAdminAuditLogRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockAdminAuditLogRepository) Create(log *model.AdminAuditLog) error {
+	args := m.Called(log)
+	return args.Error(0)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockAdminAuditLogRepository) WithTx(tx *gorm.DB) repository.AdminAuditLogRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockAdminAuditLogRepository) WithTenant(tenantID string) repository.AdminAuditLogRepository {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,446 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+var admin = controller.Actor{Subject: "ops@example.com", IP: "10.0.0.1", UserAgent: "curl/8.0"}
+
+// auditLogFor match the audit record of an action by the admin
+func auditLogFor(action string) interface{} {
+	return mock.MatchedBy(func(log *model.AdminAuditLog) bool {
+		return log.Actor == admin.Subject && log.Action == action && log.IP == admin.IP && log.UserAgent == admin.UserAgent
+	})
+}
+
+func TestAdminController_ListRelationships(t *testing.T) {
+	filter := repository.RelationshipFilter{Email: "alice@example.com", Limit: 20}
+	relationships := []model.UserRelationship{{ID: 1, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE}}
+
+	tcs := map[string]struct {
+		repoErr  error
+		auditErr error
+		err      error
+	}{
+		"Success": {},
+		"Error_DatabaseError": {
+			repoErr: errors.New("DATABASE_ERROR"),
+			err:     errors.New("LIST_RELATIONSHIPS_FAIL: DATABASE_ERROR"),
+		},
+		"Error_AuditFailed": {
+			auditErr: errors.New("DATABASE_ERROR"),
+			err:      errors.New("CREATE_AUDIT_LOG_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			mockRepo.On("ListRelationships", filter).Return(relationships, int64(1), tc.repoErr)
+			if tc.repoErr == nil {
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_LIST_RELATIONSHIPS)).Return(tc.auditErr)
+			}
+
+			ctrl := controller.NewAdminController(nil, mockRepo, mockAuditRepo, recordEvents(), publishEvents(), new(controller.MockUserQuotaRepository), controller.Quota{})
+			actual, total, err := ctrl.ListRelationships(admin, filter)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				assert.Nil(t, actual)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, relationships, actual)
+				assert.Equal(t, int64(1), total)
+			}
+			mockRepo.AssertExpectations(t)
+			mockAuditRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminController_ForceRemoveRelationship(t *testing.T) {
+	friendship := &model.UserRelationship{ID: 7, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE}
+	subscription := &model.UserRelationship{ID: 7, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}
+
+	tcs := map[string]struct {
+		err            error
+		commit         bool
+		audit          bool
+		auditErr       error
+		before         []model.RelationshipState
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"Error_NotFound": {
+			err:            apperror.NotFound("RELATIONSHIP_NOT_FOUND"),
+			mockOn:         []string{"GetRelationshipByID"},
+			callArgument:   [][]interface{}{{uint(7)}},
+			returnArgument: [][]interface{}{{nil, gorm.ErrRecordNotFound}},
+		},
+		"Error_GetRelationshipFailed": {
+			err:            errors.New("GET_RELATIONSHIP_FAIL: DATABASE_ERROR"),
+			mockOn:         []string{"GetRelationshipByID"},
+			callArgument:   [][]interface{}{{uint(7)}},
+			returnArgument: [][]interface{}{{nil, errors.New("DATABASE_ERROR")}},
+		},
+		"Error_DeleteFailed": {
+			err:            errors.New("DELETE_RELATIONSHIP_FAIL: DATABASE_ERROR"),
+			mockOn:         []string{"GetRelationshipByID", "DeleteRelationshipByID"},
+			callArgument:   [][]interface{}{{uint(7)}, {uint(7)}},
+			returnArgument: [][]interface{}{{subscription, nil}, {int64(0), errors.New("DATABASE_ERROR")}},
+		},
+		"Error_AuditFailedRollsBack": {
+			err:            errors.New("CREATE_AUDIT_LOG_FAIL: DATABASE_ERROR"),
+			audit:          true,
+			auditErr:       errors.New("DATABASE_ERROR"),
+			mockOn:         []string{"GetRelationshipByID", "DeleteRelationshipByID"},
+			callArgument:   [][]interface{}{{uint(7)}, {uint(7)}},
+			returnArgument: [][]interface{}{{subscription, nil}, {int64(1), nil}},
+		},
+		"Success_Subscription": {
+			commit:         true,
+			audit:          true,
+			before:         []model.RelationshipState{{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}},
+			mockOn:         []string{"GetRelationshipByID", "DeleteRelationshipByID"},
+			callArgument:   [][]interface{}{{uint(7)}, {uint(7)}},
+			returnArgument: [][]interface{}{{subscription, nil}, {int64(1), nil}},
+		},
+		"Success_FriendshipRemovesReverseRow": {
+			commit: true,
+			audit:  true,
+			before: []model.RelationshipState{
+				{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE},
+				{Requestor: "bob@example.com", Target: "alice@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE},
+			},
+			mockOn: []string{"GetRelationshipByID", "DeleteRelationshipByID", "DeleteRelationshipByType"},
+			callArgument: [][]interface{}{
+				{uint(7)},
+				{uint(7)},
+				{"bob@example.com", "alice@example.com", constant.FRIEND_RELATIONSHIP_TYPE},
+			},
+			returnArgument: [][]interface{}{{friendship, nil}, {int64(1), nil}, {int64(1), nil}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.commit {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			for idx, mockName := range tc.mockOn {
+				mockRepo.On(mockName, tc.callArgument[idx]...).Return(tc.returnArgument[idx]...).Once()
+			}
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.audit {
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_FORCE_REMOVE_RELATIONSHIP)).Return(tc.auditErr)
+			}
+			mockEventRepo, mockOutboxRepo := recordEvents(), publishEvents()
+			if tc.before != nil {
+				mockEventRepo = expectEvent(constant.RELATIONSHIP_EVENT_DELETE, "alice@example.com", "bob@example.com", tc.before, nil, admin)
+				mockOutboxRepo = expectOutboxEvents([3]string{removedEvents[tc.before[0].Type], "alice@example.com", "bob@example.com"})
+			}
+
+			ctrl := controller.NewAdminController(db, mockRepo, mockAuditRepo, mockEventRepo, mockOutboxRepo, new(controller.MockUserQuotaRepository), controller.Quota{})
+			err := ctrl.ForceRemoveRelationship(admin, 7)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			mockAuditRepo.AssertExpectations(t)
+			mockEventRepo.AssertExpectations(t)
+			mockOutboxRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAdminController_ForceUnblock(t *testing.T) {
+	email1 := "alice@example.com"
+	email2 := "bob@example.com"
+
+	tcs := map[string]struct {
+		err            error
+		commit         bool
+		audit          bool
+		before         []model.RelationshipState
+		returnArgument [][]interface{}
+	}{
+		"Error_NotBlocked": {
+			err:            apperror.NotFound("BLOCK_NOT_FOUND"),
+			returnArgument: [][]interface{}{{int64(0), nil}, {int64(0), nil}},
+		},
+		"Error_DeleteSecondBlockFailed": {
+			err:            errors.New("DELETE_SECOND_BLOCK_RELATION_FAIL: DATABASE_ERROR"),
+			returnArgument: [][]interface{}{{int64(1), nil}, {int64(0), errors.New("DATABASE_ERROR")}},
+		},
+		"Success_OneDirection": {
+			commit:         true,
+			audit:          true,
+			before:         []model.RelationshipState{{Requestor: email2, Target: email1, Type: constant.BLOCK_RELATIONSHIP_TYPE}},
+			returnArgument: [][]interface{}{{int64(0), nil}, {int64(1), nil}},
+		},
+		"Success_BothDirections": {
+			commit: true,
+			audit:  true,
+			before: []model.RelationshipState{
+				{Requestor: email1, Target: email2, Type: constant.BLOCK_RELATIONSHIP_TYPE},
+				{Requestor: email2, Target: email1, Type: constant.BLOCK_RELATIONSHIP_TYPE},
+			},
+			returnArgument: [][]interface{}{{int64(1), nil}, {int64(1), nil}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.commit {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("DeleteRelationshipByType", email1, email2, constant.BLOCK_RELATIONSHIP_TYPE).Return(tc.returnArgument[0]...).Once()
+			mockRepo.On("DeleteRelationshipByType", email2, email1, constant.BLOCK_RELATIONSHIP_TYPE).Return(tc.returnArgument[1]...).Once()
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.audit {
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_FORCE_UNBLOCK)).Return(nil)
+			}
+			mockEventRepo, mockOutboxRepo := recordEvents(), publishEvents()
+			if tc.before != nil {
+				mockEventRepo = expectEvent(constant.RELATIONSHIP_EVENT_DELETE, email1, email2, tc.before, nil, admin)
+				//Each removed block is unblocked by the user who created it
+				var published [][3]string
+				for _, state := range tc.before {
+					published = append(published, [3]string{constant.DOMAIN_EVENT_UNBLOCKED, state.Requestor, state.Target})
+				}
+				mockOutboxRepo = expectOutboxEvents(published...)
+			}
+
+			ctrl := controller.NewAdminController(db, mockRepo, mockAuditRepo, mockEventRepo, mockOutboxRepo, new(controller.MockUserQuotaRepository), controller.Quota{})
+			err := ctrl.ForceUnblock(admin, email1, email2)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			mockAuditRepo.AssertExpectations(t)
+			mockEventRepo.AssertExpectations(t)
+			mockOutboxRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAdminController_WithTenant(t *testing.T) {
+	mockRepo := new(controller.MockUserRelationshipRepository)
+	mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+	mockQuotaRepo := new(controller.MockUserQuotaRepository)
+	mockEventRepo := recordEvents()
+	mockOutboxRepo := publishEvents()
+	mockRepo.On("ListRelationships", repository.RelationshipFilter{Limit: 20}).Return(nil, int64(0), nil)
+	mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_LIST_RELATIONSHIPS)).Return(nil)
+
+	ctrl := controller.NewAdminController(nil, mockRepo, mockAuditRepo, mockEventRepo, mockOutboxRepo, mockQuotaRepo, controller.Quota{}).WithTenant("acme")
+	_, _, err := ctrl.ListRelationships(admin, repository.RelationshipFilter{Limit: 20})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", mockRepo.Tenant)
+	assert.Equal(t, "acme", mockAuditRepo.Tenant)
+	assert.Equal(t, "acme", mockQuotaRepo.Tenant)
+	assert.Equal(t, "acme", mockEventRepo.Tenant)
+	assert.Equal(t, "acme", mockOutboxRepo.Tenant)
+}
+
+func TestAdminController_GetQuota(t *testing.T) {
+	email := "alice@example.com"
+	override := &model.UserQuota{Email: email, MaxFriends: int64Ptr(1000)}
+
+	mockRepo := new(controller.MockUserRelationshipRepository)
+	mockRepo.On("CountRelationshipsByType", email, constant.FRIEND_RELATIONSHIP_TYPE).Return(int64(600), nil)
+	mockRepo.On("CountRelationshipsByType", email, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).Return(int64(20), nil)
+	mockRepo.On("CountRelationshipsByType", email, constant.BLOCK_RELATIONSHIP_TYPE).Return(int64(3), nil)
+	mockQuotaRepo := new(controller.MockUserQuotaRepository)
+	mockQuotaRepo.On("GetByEmail", email).Return(override, nil)
+	mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+	mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_GET_QUOTA)).Return(nil)
+
+	defaults := controller.Quota{MaxFriends: 500, MaxSubscriptions: 1000, MaxBlocks: 200, MaxNewPerDay: 100}
+	ctrl := controller.NewAdminController(nil, mockRepo, mockAuditRepo, createdSince(email, 7), publishEvents(), mockQuotaRepo, defaults)
+	report, err := ctrl.GetQuota(admin, email)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &controller.QuotaReport{
+		Email:    email,
+		Quota:    controller.Quota{MaxFriends: 1000, MaxSubscriptions: 1000, MaxBlocks: 200, MaxNewPerDay: 100},
+		Override: override,
+		Usage:    controller.QuotaUsage{Friends: 600, Subscriptions: 20, Blocks: 3, NewPerDay: 7},
+	}, report)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestAdminController_SetQuota(t *testing.T) {
+	email := "alice@example.com"
+
+	tcs := map[string]struct {
+		upsertErr error
+		auditErr  error
+		err       error
+	}{
+		"Success": {},
+		"Error_UpsertFailed": {
+			upsertErr: errors.New("DATABASE_ERROR"),
+			err:       errors.New("UPSERT_USER_QUOTA_FAIL: DATABASE_ERROR"),
+		},
+		"Error_AuditFailedRollsBack": {
+			auditErr: errors.New("DATABASE_ERROR"),
+			err:      errors.New("CREATE_AUDIT_LOG_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			override := &model.UserQuota{Email: email, MaxNewPerDay: int64Ptr(0)}
+			mockQuotaRepo := new(controller.MockUserQuotaRepository)
+			mockQuotaRepo.On("Upsert", mock.MatchedBy(func(quota *model.UserQuota) bool {
+				return quota.Email == email && quota.UpdatedBy == admin.Subject
+			})).Return(tc.upsertErr)
+			mockQuotaRepo.On("GetByEmail", email).Return(override, nil)
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_SET_QUOTA)).Return(tc.auditErr)
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("CountRelationshipsByType", email, mock.Anything).Return(int64(0), nil)
+
+			ctrl := controller.NewAdminController(db, mockRepo, mockAuditRepo, createdSince(email, 0), publishEvents(), mockQuotaRepo, controller.Quota{MaxNewPerDay: 100})
+			report, err := ctrl.SetQuota(admin, override)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				assert.Nil(t, report)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, int64(0), report.Quota.MaxNewPerDay)
+				assert.Equal(t, override, report.Override)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAdminController_ResetQuota(t *testing.T) {
+	email := "alice@example.com"
+
+	tcs := map[string]struct {
+		deleted int64
+		err     error
+	}{
+		"Success": {
+			deleted: 1,
+		},
+		"Error_NoOverride": {
+			err: apperror.NotFound("QUOTA_OVERRIDE_NOT_FOUND"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockQuotaRepo := new(controller.MockUserQuotaRepository)
+			mockQuotaRepo.On("DeleteByEmail", email).Return(tc.deleted, nil)
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.err == nil {
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_RESET_QUOTA)).Return(nil)
+			}
+
+			ctrl := controller.NewAdminController(db, new(controller.MockUserRelationshipRepository), mockAuditRepo, recordEvents(), publishEvents(), mockQuotaRepo, controller.Quota{})
+			err := ctrl.ResetQuota(admin, email)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockAuditRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAdminController_ListRelationshipEvents(t *testing.T) {
+	filter := repository.RelationshipEventFilter{Email: "alice@example.com", Limit: 20}
+	events := []model.RelationshipEvent{{ID: 1, Action: constant.RELATIONSHIP_EVENT_CREATE, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com"}}
+
+	tcs := map[string]struct {
+		listErr error
+		audit   bool
+		err     error
+	}{
+		"Success": {
+			audit: true,
+		},
+		"Error_ListFailed": {
+			listErr: errors.New("DATABASE_ERROR"),
+			err:     errors.New("LIST_RELATIONSHIP_EVENTS_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockEventRepo := new(controller.MockRelationshipEventRepository)
+			if tc.listErr != nil {
+				mockEventRepo.On("List", filter).Return(nil, int64(0), tc.listErr)
+			} else {
+				mockEventRepo.On("List", filter).Return(events, int64(1), nil)
+			}
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.audit {
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_LIST_RELATIONSHIP_EVENTS)).Return(nil)
+			}
+
+			ctrl := controller.NewAdminController(nil, new(controller.MockUserRelationshipRepository), mockAuditRepo, mockEventRepo, publishEvents(), new(controller.MockUserQuotaRepository), controller.Quota{})
+			result, total, err := ctrl.ListRelationshipEvents(admin, filter)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, events, result)
+				assert.Equal(t, int64(1), total)
+			}
+			mockAuditRepo.AssertExpectations(t)
+		})
+	}
+}
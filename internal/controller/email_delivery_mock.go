@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockEmailDeliveryRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockEmailDeliveryRepository) Enqueue(statusUpdateID uint, template string, recipients []string, at, sendAt time.Time) error {
+	args := m.Called(statusUpdateID, template, recipients, at, sendAt)
+	return args.Error(0)
+}
+
+func (m *MockEmailDeliveryRepository) EnqueueDigest(statusUpdateID uint, recipients []string, at time.Time) error {
+	args := m.Called(statusUpdateID, recipients, at)
+	return args.Error(0)
+}
+
+func (m *MockEmailDeliveryRepository) TryLockScheduler() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEmailDeliveryRepository) ListDueDigests(cutoffs repository.DigestCutoffs, limit int) ([]repository.PendingDigest, error) {
+	args := m.Called(cutoffs, limit)
+	var digests []repository.PendingDigest
+	if args.Get(0) != nil {
+		digests = args.Get(0).([]repository.PendingDigest)
+	}
+	return digests, args.Error(1)
+}
+
+func (m *MockEmailDeliveryRepository) DropBlockedDigestEntries(recipient string) error {
+	args := m.Called(recipient)
+	return args.Error(0)
+}
+
+func (m *MockEmailDeliveryRepository) ListDigestEntries(recipient string, before time.Time) ([]model.DigestEntry, error) {
+	args := m.Called(recipient, before)
+	var entries []model.DigestEntry
+	if args.Get(0) != nil {
+		entries = args.Get(0).([]model.DigestEntry)
+	}
+	return entries, args.Error(1)
+}
+
+func (m *MockEmailDeliveryRepository) CreateDigest(recipient string, entryIDs []uint, at time.Time) (*model.EmailDelivery, error) {
+	args := m.Called(recipient, entryIDs, at)
+	var delivery *model.EmailDelivery
+	if args.Get(0) != nil {
+		delivery = args.Get(0).(*model.EmailDelivery)
+	}
+	return delivery, args.Error(1)
+}
+
+func (m *MockEmailDeliveryRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.EmailDelivery, error) {
+	args := m.Called(now, lease, limit)
+	var deliveries []model.EmailDelivery
+	if args.Get(0) != nil {
+		deliveries = args.Get(0).([]model.EmailDelivery)
+	}
+	return deliveries, args.Error(1)
+}
+
+func (m *MockEmailDeliveryRepository) MarkSent(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockEmailDeliveryRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(id, attempts, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockEmailDeliveryRepository) MarkUndeliverable(id uint, attempts int, lastError string) error {
+	args := m.Called(id, attempts, lastError)
+	return args.Error(0)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockEmailDeliveryRepository) WithTx(tx *gorm.DB) repository.EmailDeliveryRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockEmailDeliveryRepository) WithTenant(tenantID string) repository.EmailDeliveryRepository {
+	m.Tenant = tenantID
+	return m
+}
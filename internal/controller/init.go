@@ -6,11 +6,19 @@ import (
 )
 
 type Controller struct {
-	UserRelationshipController UserRelationshipController
+	UserRelationshipController       UserRelationshipController
+	AdminController                  AdminController
+	WebhookController                WebhookController
+	StatusUpdateController           StatusUpdateController
+	NotificationPreferenceController NotificationPreferenceController
 }
 
-func NewController(db *gorm.DB, userRelationshipRepo repository.UserRelationshipRepository) Controller {
+func NewController(db *gorm.DB, userRelationshipRepo repository.UserRelationshipRepository, adminAuditLogRepo repository.AdminAuditLogRepository, relationshipEventRepo repository.RelationshipEventRepository, outboxEventRepo repository.OutboxEventRepository, userQuotaRepo repository.UserQuotaRepository, webhookRepo repository.WebhookRepository, webhookDeliveryRepo repository.WebhookDeliveryRepository, statusUpdateRepo repository.StatusUpdateRepository, emailDeliveryRepo repository.EmailDeliveryRepository, notificationPreferenceRepo repository.NotificationPreferenceRepository, quotas Quota, feed Feed) Controller {
 	return Controller{
-		UserRelationshipController: NewUserRelationshipController(db, userRelationshipRepo),
+		UserRelationshipController:       NewUserRelationshipController(db, userRelationshipRepo, relationshipEventRepo, outboxEventRepo, userQuotaRepo, notificationPreferenceRepo, quotas, feed.MentionPolicy),
+		AdminController:                  NewAdminController(db, userRelationshipRepo, adminAuditLogRepo, relationshipEventRepo, outboxEventRepo, userQuotaRepo, quotas),
+		WebhookController:                NewWebhookController(db, webhookRepo, webhookDeliveryRepo, adminAuditLogRepo),
+		StatusUpdateController:           NewStatusUpdateController(db, userRelationshipRepo, statusUpdateRepo, emailDeliveryRepo, notificationPreferenceRepo, feed),
+		NotificationPreferenceController: NewNotificationPreferenceController(notificationPreferenceRepo),
 	}
 }
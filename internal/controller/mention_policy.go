@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/mention"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/pkg/utils"
+)
+
+// mentionedRecipients get the emails mentioned in the text that the update reaches. The friends of the author and the emails
+// subscribed to it are always reached, the other emails are strangers: the policy decides which of them can be mentioned
+// and a stranger that ignores the mentions of strangers is left out. The mentions are lower cased while relationships keep
+// the emails as they were entered, so emails are compared case-insensitively.
+func mentionedRecipients(relationshipRepo repository.UserRelationshipRepository, preferenceRepo repository.NotificationPreferenceRepository, policy, author, text string) ([]string, error) {
+	mentioned := mention.Extract(text)
+	if len(mentioned) == 0 {
+		return nil, nil
+	}
+
+	contacts, err := relationshipRepo.GetContactEmails(author, mentioned)
+	if err != nil {
+		return nil, fmt.Errorf("GET_CONTACT_EMAILS_FAIL: %w", err)
+	}
+	strangers := utils.RemoveSameEmailsFromSecond(utils.Combine(contacts, []string{author}), mentioned)
+	if len(strangers) == 0 {
+		return mentioned, nil
+	}
+
+	allowed, err := allowedStrangers(relationshipRepo, policy, author, strangers)
+	if err != nil {
+		return nil, err
+	}
+	leftOut := make(map[string]bool, len(strangers))
+	for _, email := range utils.RemoveSameElementsFromSecond(allowed, strangers) {
+		leftOut[email] = true
+	}
+	if len(allowed) > 0 {
+		preferences, err := preferenceRepo.ListByEmails(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("LIST_NOTIFICATION_PREFERENCES_FAIL: %w", err)
+		}
+		for email, preference := range preferences {
+			if preference.IgnoreStrangerMentions {
+				leftOut[email] = true
+			}
+		}
+	}
+
+	//The mentions keep the order of the text
+	var recipients []string
+	for _, email := range mentioned {
+		if !leftOut[email] {
+			recipients = append(recipients, email)
+		}
+	}
+	return recipients, nil
+}
+
+// allowedStrangers get the strangers the author can mention under the policy
+func allowedStrangers(repo repository.UserRelationshipRepository, policy, author string, strangers []string) ([]string, error) {
+	switch policy {
+	case constant.MENTION_POLICY_ANYONE:
+		return strangers, nil
+	case constant.MENTION_POLICY_KNOWN_USERS:
+		known, err := repo.GetKnownEmails(strangers)
+		if err != nil {
+			return nil, fmt.Errorf("GET_KNOWN_EMAILS_FAIL: %w", err)
+		}
+		return known, nil
+	case constant.MENTION_POLICY_NOT_BLOCKED:
+		return notBlocked(repo, author, strangers)
+	case constant.MENTION_POLICY_FRIENDS_OF_FRIENDS:
+		strangers, err := notBlocked(repo, author, strangers)
+		if err != nil || len(strangers) == 0 {
+			return nil, err
+		}
+		return friendsOfFriends(repo, author, strangers)
+	}
+	return nil, fmt.Errorf("UNKNOWN_MENTION_POLICY: %s", policy)
+}
+
+// notBlocked get the emails that did not block the author and are not blocked by it
+func notBlocked(repo repository.UserRelationshipRepository, author string, emails []string) ([]string, error) {
+	blocks, err := repo.GetBlockConnectionEmailsByEmails([]string{author})
+	if err != nil {
+		return nil, fmt.Errorf("GET_BLOCK_CONNECTION_EMAILS_FAIL: %w", err)
+	}
+	return utils.RemoveSameEmailsFromSecond(blocks[author], emails), nil
+}
+
+// friendsOfFriends get the emails that have a friend in common with the author. Friendships are stored in both directions,
+// so the friends of the friends of the author are looked up with the emails of the friends as they were entered.
+func friendsOfFriends(repo repository.UserRelationshipRepository, author string, emails []string) ([]string, error) {
+	friends, err := repo.GetListFriendshipEmail(author)
+	if err != nil {
+		return nil, fmt.Errorf("GET_LIST_FRIENDSHIP_EMAIL_FAIL: %w", err)
+	}
+	if len(friends) == 0 {
+		return nil, nil
+	}
+	friendsOf, err := repo.GetTargetEmailsByRequestors(friends, constant.FRIEND_RELATIONSHIP_TYPE)
+	if err != nil {
+		return nil, fmt.Errorf("GET_FRIENDS_OF_FRIENDS_FAIL: %w", err)
+	}
+
+	isFriendOfFriend := make(map[string]bool)
+	for _, friendsOfFriend := range friendsOf {
+		for _, email := range friendsOfFriend {
+			isFriendOfFriend[strings.ToLower(email)] = true
+		}
+	}
+
+	var result []string
+	for _, email := range emails {
+		if isFriendOfFriend[strings.ToLower(email)] {
+			result = append(result, email)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRelationshipController_GetListEmailCanReceiveUpdateMentionPolicy(t *testing.T) {
+	updaterEmail := "alice@example.com"
+	//dave subscribed to alice, carol and erin are strangers
+	text := "hi @carol@example.com, <erin@example.com> and dave@example.com"
+	mentioned := []string{"carol@example.com", "erin@example.com", "dave@example.com"}
+	strangers := []string{"carol@example.com", "erin@example.com"}
+
+	tcs := map[string]struct {
+		policy     string
+		contactErr error
+		mockOn     []string
+		callArgs   [][]interface{}
+		returnArgs [][]interface{}
+		allowed    []string
+		prefs      map[string]model.NotificationPreference
+		now        []string
+		err        string
+	}{
+		"Anyone": {
+			policy:  constant.MENTION_POLICY_ANYONE,
+			allowed: strangers,
+			now:     []string{"bob@example.com", "dave@example.com", "carol@example.com", "erin@example.com"},
+		},
+		"AnyoneButIgnoredStrangerMentions": {
+			policy:  constant.MENTION_POLICY_ANYONE,
+			allowed: strangers,
+			prefs:   map[string]model.NotificationPreference{"erin@example.com": {IgnoreStrangerMentions: true}},
+			now:     []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+		},
+		"KnownUsers": {
+			policy:     constant.MENTION_POLICY_KNOWN_USERS,
+			mockOn:     []string{"GetKnownEmails"},
+			callArgs:   [][]interface{}{{strangers}},
+			returnArgs: [][]interface{}{{[]string{"erin@example.com"}, nil}},
+			allowed:    []string{"erin@example.com"},
+			now:        []string{"bob@example.com", "dave@example.com", "erin@example.com"},
+		},
+		"NotBlocked": {
+			policy:     constant.MENTION_POLICY_NOT_BLOCKED,
+			mockOn:     []string{"GetBlockConnectionEmailsByEmails"},
+			callArgs:   [][]interface{}{{[]string{updaterEmail}}},
+			returnArgs: [][]interface{}{{map[string][]string{updaterEmail: {"carol@example.com"}}, nil}},
+			allowed:    []string{"erin@example.com"},
+			now:        []string{"bob@example.com", "dave@example.com", "erin@example.com"},
+		},
+		"FriendsOfFriends": {
+			policy:   constant.MENTION_POLICY_FRIENDS_OF_FRIENDS,
+			mockOn:   []string{"GetBlockConnectionEmailsByEmails", "GetTargetEmailsByRequestors"},
+			callArgs: [][]interface{}{{[]string{updaterEmail}}, {[]string{"bob@example.com"}, constant.FRIEND_RELATIONSHIP_TYPE}},
+			returnArgs: [][]interface{}{
+				{map[string][]string{}, nil},
+				//Friendships keep the emails as they were entered
+				{map[string][]string{"bob@example.com": {updaterEmail, "Carol@Example.com"}}, nil},
+			},
+			allowed: []string{"carol@example.com"},
+			now:     []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+		},
+		"FriendsOfFriendsNotBlocked": {
+			policy:     constant.MENTION_POLICY_FRIENDS_OF_FRIENDS,
+			mockOn:     []string{"GetBlockConnectionEmailsByEmails"},
+			callArgs:   [][]interface{}{{[]string{updaterEmail}}},
+			returnArgs: [][]interface{}{{map[string][]string{updaterEmail: strangers}, nil}},
+			now:        []string{"bob@example.com", "dave@example.com"},
+		},
+		"Error_GetContactEmailsFailed": {
+			policy:     constant.MENTION_POLICY_ANYONE,
+			contactErr: errors.New("DATABASE_ERROR"),
+			err:        "GET_CONTACT_EMAILS_FAIL: DATABASE_ERROR",
+		},
+		"Error_GetKnownEmailsFailed": {
+			policy:     constant.MENTION_POLICY_KNOWN_USERS,
+			mockOn:     []string{"GetKnownEmails"},
+			callArgs:   [][]interface{}{{strangers}},
+			returnArgs: [][]interface{}{{nil, errors.New("DATABASE_ERROR")}},
+			err:        "GET_KNOWN_EMAILS_FAIL: DATABASE_ERROR",
+		},
+		"Error_UnknownPolicy": {
+			policy: "SOMEONE",
+			err:    "UNKNOWN_MENTION_POLICY: SOMEONE",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("GetContactEmails", updaterEmail, mentioned).Return([]string{"dave@example.com"}, tc.contactErr)
+			mockRepo.On("GetListFriendshipEmail", updaterEmail).Return([]string{"bob@example.com"}, nil).Maybe()
+			mockRepo.On("GetListSubscriberEmail", updaterEmail).Return([]string{"dave@example.com"}, nil).Maybe()
+			for idx, mockName := range tc.mockOn {
+				mockRepo.On(mockName, tc.callArgs[idx]...).Return(tc.returnArgs[idx]...)
+			}
+			mockPreferenceRepo := new(controller.MockNotificationPreferenceRepository)
+			if tc.allowed != nil {
+				mockPreferenceRepo.On("ListByEmails", tc.allowed).Return(tc.prefs, nil)
+			}
+			if tc.now != nil {
+				mockPreferenceRepo.On("ListByEmails", tc.now).Return(map[string]model.NotificationPreference{}, nil)
+			}
+
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), mockPreferenceRepo, controller.Quota{}, tc.policy)
+			recipients, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, recipients)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.now, recipients.Now)
+			mockRepo.AssertExpectations(t)
+			mockPreferenceRepo.AssertExpectations(t)
+		})
+	}
+}
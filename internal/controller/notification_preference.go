@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+// defaultTimezone is the timezone of the quiet hours of an email that did not choose one
+const defaultTimezone = "UTC"
+
+// NotificationPreferenceController defines how users choose between an email per status update and a digest,
+// their quiet hours and whether they are notified of the updates that do not mention them
+type NotificationPreferenceController interface {
+	GetPreference(email string) (*model.NotificationPreference, error)
+	SetPreference(email string, preference model.NotificationPreference) (*model.NotificationPreference, error)
+	WithTenant(tenantID string) NotificationPreferenceController
+}
+
+type notificationPreferenceController struct {
+	preferenceRepo repository.NotificationPreferenceRepository
+}
+
+func NewNotificationPreferenceController(preferenceRepo repository.NotificationPreferenceRepository) NotificationPreferenceController {
+	return &notificationPreferenceController{preferenceRepo: preferenceRepo}
+}
+
+// GetPreference support get the preference of the email, an email that never set one is notified immediately of every update
+func (nc *notificationPreferenceController) GetPreference(email string) (*model.NotificationPreference, error) {
+	preference, err := nc.preferenceRepo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_IMMEDIATE, Timezone: defaultTimezone}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GET_NOTIFICATION_PREFERENCE_FAIL: %w", err)
+	}
+	return preference, nil
+}
+
+// SetPreference support replace the preference of the email, the updates already waiting for a digest are sent
+// with the next digest of the new frequency, or right away when it is immediate. The quiet hours are in UTC when there is no timezone
+func (nc *notificationPreferenceController) SetPreference(email string, preference model.NotificationPreference) (*model.NotificationPreference, error) {
+	preference.Email = email
+	if len(preference.Timezone) == 0 {
+		preference.Timezone = defaultTimezone
+	}
+	if err := nc.preferenceRepo.Upsert(&preference); err != nil {
+		return nil, fmt.Errorf("SET_NOTIFICATION_PREFERENCE_FAIL: %w", err)
+	}
+	return &preference, nil
+}
+
+// WithTenant return a controller that manage the preferences of the tenant
+func (nc *notificationPreferenceController) WithTenant(tenantID string) NotificationPreferenceController {
+	return &notificationPreferenceController{preferenceRepo: nc.preferenceRepo.WithTenant(tenantID)}
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockNotificationPreferenceRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockNotificationPreferenceRepository) GetByEmail(email string) (*model.NotificationPreference, error) {
+	args := m.Called(email)
+	var preference *model.NotificationPreference
+	if args.Get(0) != nil {
+		preference = args.Get(0).(*model.NotificationPreference)
+	}
+	return preference, args.Error(1)
+}
+
+func (m *MockNotificationPreferenceRepository) Upsert(preference *model.NotificationPreference) error {
+	args := m.Called(preference)
+	return args.Error(0)
+}
+
+func (m *MockNotificationPreferenceRepository) ListByEmails(emails []string) (map[string]model.NotificationPreference, error) {
+	args := m.Called(emails)
+	var preferences map[string]model.NotificationPreference
+	if args.Get(0) != nil {
+		preferences = args.Get(0).(map[string]model.NotificationPreference)
+	}
+	return preferences, args.Error(1)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockNotificationPreferenceRepository) WithTx(tx *gorm.DB) repository.NotificationPreferenceRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockNotificationPreferenceRepository) WithTenant(tenantID string) repository.NotificationPreferenceRepository {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,104 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// noPreferences return a preference repository where no email has a preference
+func noPreferences() *controller.MockNotificationPreferenceRepository {
+	mockPreferenceRepo := new(controller.MockNotificationPreferenceRepository)
+	mockPreferenceRepo.On("ListByEmails", mock.Anything).Return(map[string]model.NotificationPreference{}, nil).Maybe()
+	return mockPreferenceRepo
+}
+
+func TestNotificationPreferenceController_GetPreference(t *testing.T) {
+	email := "alice@example.com"
+
+	tcs := map[string]struct {
+		returnArgument []interface{}
+		expected       *model.NotificationPreference
+		err            string
+	}{
+		"Success": {
+			returnArgument: []interface{}{&model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_DAILY}, nil},
+			expected:       &model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_DAILY},
+		},
+		"Success_DefaultImmediate": {
+			returnArgument: []interface{}{nil, gorm.ErrRecordNotFound},
+			expected:       &model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_IMMEDIATE, Timezone: "UTC"},
+		},
+		"Error": {
+			returnArgument: []interface{}{nil, errors.New("DATABASE_ERROR")},
+			err:            "GET_NOTIFICATION_PREFERENCE_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockNotificationPreferenceRepository)
+			mockRepo.On("GetByEmail", email).Return(tc.returnArgument...)
+
+			ctrl := controller.NewNotificationPreferenceController(mockRepo).WithTenant("tenant-b")
+			preference, err := ctrl.GetPreference(email)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, preference)
+			assert.Equal(t, "tenant-b", mockRepo.Tenant)
+		})
+	}
+}
+
+func TestNotificationPreferenceController_SetPreference(t *testing.T) {
+	email := "alice@example.com"
+
+	tcs := map[string]struct {
+		preference model.NotificationPreference
+		expected   model.NotificationPreference
+		upsertErr  error
+		err        string
+	}{
+		"Success": {
+			preference: model.NotificationPreference{Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY, Timezone: "Asia/Ho_Chi_Minh", QuietHoursStart: "22:00", QuietHoursEnd: "07:00", MentionsOnly: true},
+			expected:   model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY, Timezone: "Asia/Ho_Chi_Minh", QuietHoursStart: "22:00", QuietHoursEnd: "07:00", MentionsOnly: true},
+		},
+		"Success_DefaultTimezone": {
+			preference: model.NotificationPreference{Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY, QuietHoursStart: "22:00", QuietHoursEnd: "07:00"},
+			expected:   model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY, Timezone: "UTC", QuietHoursStart: "22:00", QuietHoursEnd: "07:00"},
+		},
+		"Error": {
+			preference: model.NotificationPreference{Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY},
+			expected:   model.NotificationPreference{Email: email, Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY, Timezone: "UTC"},
+			upsertErr:  errors.New("DATABASE_ERROR"),
+			err:        "SET_NOTIFICATION_PREFERENCE_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockNotificationPreferenceRepository)
+			mockRepo.On("Upsert", &tc.expected).Return(tc.upsertErr)
+
+			ctrl := controller.NewNotificationPreferenceController(mockRepo)
+			preference, err := ctrl.SetPreference(email, tc.preference)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, preference)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, &tc.expected, preference)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
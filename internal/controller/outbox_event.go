@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/repository"
+)
+
+// removedEvents is the domain event published when a relationship of the type is removed
+var removedEvents = map[string]string{
+	constant.FRIEND_RELATIONSHIP_TYPE:      constant.DOMAIN_EVENT_FRIENDSHIP_REMOVED,
+	constant.SUBSCRIBER_RELATIONSHIOP_TYPE: constant.DOMAIN_EVENT_UNSUBSCRIBED,
+	constant.BLOCK_RELATIONSHIP_TYPE:       constant.DOMAIN_EVENT_UNBLOCKED,
+}
+
+// publishEvent support write one domain event to the outbox and notify every instance of the change,
+// it must run in the transaction of the change so the notification is only sent when the change is committed
+func publishEvent(repo repository.OutboxEventRepository, eventType, requestor, target string) error {
+	event := &model.OutboxEvent{
+		Type:           eventType,
+		RequestorEmail: requestor,
+		TargetEmail:    target,
+		CreatedAt:      time.Now(),
+	}
+	if err := repo.Create(event); err != nil {
+		return fmt.Errorf("CREATE_OUTBOX_EVENT_FAIL: %w", err)
+	}
+
+	payload, err := json.Marshal(outbox.NewMessage(*event))
+	if err != nil {
+		return fmt.Errorf("ENCODE_CHANGE_NOTIFICATION_FAIL: %w", err)
+	}
+	if err := repo.Notify(constant.NOTIFY_CHANNEL_RELATIONSHIP_CHANGES, string(payload)); err != nil {
+		return fmt.Errorf("NOTIFY_CHANGE_FAIL: %w", err)
+	}
+	return nil
+}
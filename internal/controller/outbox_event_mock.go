@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockOutboxEventRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockOutboxEventRepository) Create(event *model.OutboxEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockOutboxEventRepository) ClaimPending(now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error) {
+	args := m.Called(now, lease, limit)
+	var events []model.OutboxEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]model.OutboxEvent)
+	}
+	return events, args.Error(1)
+}
+
+func (m *MockOutboxEventRepository) MarkPublished(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockOutboxEventRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(id, attempts, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockOutboxEventRepository) ReleaseClaim(ids []uint) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
+
+func (m *MockOutboxEventRepository) MarkDeadLettered(id uint, attempts int, lastError string, at time.Time) error {
+	args := m.Called(id, attempts, lastError, at)
+	return args.Error(0)
+}
+
+func (m *MockOutboxEventRepository) ListForEmail(email string, afterID uint, limit int) ([]model.OutboxEvent, error) {
+	args := m.Called(email, afterID, limit)
+	var events []model.OutboxEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]model.OutboxEvent)
+	}
+	return events, args.Error(1)
+}
+
+func (m *MockOutboxEventRepository) ResumeAfterID(email string, lastID uint, window time.Duration) (uint, error) {
+	args := m.Called(email, lastID, window)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *MockOutboxEventRepository) Notify(channel, payload string) error {
+	args := m.Called(channel, payload)
+	return args.Error(0)
+}
+
+func (m *MockOutboxEventRepository) LastID() (uint, error) {
+	args := m.Called()
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *MockOutboxEventRepository) ListAfter(afterID uint, limit int) ([]model.OutboxEvent, error) {
+	args := m.Called(afterID, limit)
+	var events []model.OutboxEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]model.OutboxEvent)
+	}
+	return events, args.Error(1)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockOutboxEventRepository) WithTx(tx *gorm.DB) repository.OutboxEventRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockOutboxEventRepository) WithTenant(tenantID string) repository.OutboxEventRepository {
+	m.Tenant = tenantID
+	return m
+}
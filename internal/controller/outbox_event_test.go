@@ -0,0 +1,41 @@
+package controller_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// removedEvents is the domain event expected when a relationship of the type is removed
+var removedEvents = map[string]string{
+	constant.FRIEND_RELATIONSHIP_TYPE:      constant.DOMAIN_EVENT_FRIENDSHIP_REMOVED,
+	constant.SUBSCRIBER_RELATIONSHIOP_TYPE: constant.DOMAIN_EVENT_UNSUBSCRIBED,
+	constant.BLOCK_RELATIONSHIP_TYPE:       constant.DOMAIN_EVENT_UNBLOCKED,
+}
+
+// publishEvents return an outbox repository that accept every domain event
+func publishEvents() *controller.MockOutboxEventRepository {
+	mockOutboxRepo := new(controller.MockOutboxEventRepository)
+	mockOutboxRepo.On("Create", mock.Anything).Return(nil).Maybe()
+	mockOutboxRepo.On("Notify", constant.NOTIFY_CHANNEL_RELATIONSHIP_CHANGES, mock.Anything).Return(nil).Maybe()
+	return mockOutboxRepo
+}
+
+// expectOutboxEvents return an outbox repository that only accept the domain events and their change notifications, given as type, requestor and target
+func expectOutboxEvents(events ...[3]string) *controller.MockOutboxEventRepository {
+	mockOutboxRepo := new(controller.MockOutboxEventRepository)
+	for _, expected := range events {
+		mockOutboxRepo.On("Create", mock.MatchedBy(func(event *model.OutboxEvent) bool {
+			return event.Type == expected[0] && event.RequestorEmail == expected[1] && event.TargetEmail == expected[2] && !event.CreatedAt.IsZero()
+		})).Return(nil).Once()
+		notification := fmt.Sprintf(`"type":%q,"requestor":%q,"target":%q`, expected[0], expected[1], expected[2])
+		mockOutboxRepo.On("Notify", constant.NOTIFY_CHANNEL_RELATIONSHIP_CHANGES, mock.MatchedBy(func(payload string) bool {
+			return strings.Contains(payload, notification)
+		})).Return(nil).Once()
+	}
+	return mockOutboxRepo
+}
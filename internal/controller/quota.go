@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+// quotaWindow is the period of the max new relationships per day quota, it is rolling so there is no burst at midnight
+const quotaWindow = 24 * time.Hour
+
+// Quota caps the relationships one email can create, zero means no cap
+type Quota struct {
+	MaxFriends       int64
+	MaxSubscriptions int64
+	MaxBlocks        int64
+	MaxNewPerDay     int64
+}
+
+// Override return the quota with the caps set by an admin for the email
+func (q Quota) Override(override *model.UserQuota) Quota {
+	if override == nil {
+		return q
+	}
+	if override.MaxFriends != nil {
+		q.MaxFriends = *override.MaxFriends
+	}
+	if override.MaxSubscriptions != nil {
+		q.MaxSubscriptions = *override.MaxSubscriptions
+	}
+	if override.MaxBlocks != nil {
+		q.MaxBlocks = *override.MaxBlocks
+	}
+	if override.MaxNewPerDay != nil {
+		q.MaxNewPerDay = *override.MaxNewPerDay
+	}
+	return q
+}
+
+// capOf return the name and the cap of the quota of one relationship type
+func (q Quota) capOf(relationshipType string) (string, int64) {
+	switch relationshipType {
+	case constant.FRIEND_RELATIONSHIP_TYPE:
+		return constant.QUOTA_MAX_FRIENDS, q.MaxFriends
+	case constant.SUBSCRIBER_RELATIONSHIOP_TYPE:
+		return constant.QUOTA_MAX_SUBSCRIPTIONS, q.MaxSubscriptions
+	case constant.BLOCK_RELATIONSHIP_TYPE:
+		return constant.QUOTA_MAX_BLOCKS, q.MaxBlocks
+	}
+	return "", 0
+}
+
+// QuotaUsage is what one email already created
+type QuotaUsage struct {
+	Friends       int64
+	Subscriptions int64
+	Blocks        int64
+	//NewPerDay count the relationships created in the last 24 hours
+	NewPerDay int64
+}
+
+// QuotaReport is the quota of one email as seen by admins
+type QuotaReport struct {
+	Email    string
+	Quota    Quota
+	Override *model.UserQuota
+	Usage    QuotaUsage
+}
+
+// quotaChecker enforce the quotas of the requestor before a relationship is created, inside the transaction that creates it
+type quotaChecker struct {
+	defaults             Quota
+	userRelationshipRepo repository.UserRelationshipRepository
+	userQuotaRepo        repository.UserQuotaRepository
+	//relationshipEventRepo count the relationships created in the window, removing a relationship does not give the quota back
+	relationshipEventRepo repository.RelationshipEventRepository
+}
+
+// quotaOf return the quota of the email and the override of an admin, nil when the defaults apply
+func (qc quotaChecker) quotaOf(email string) (Quota, *model.UserQuota, error) {
+	override, err := qc.userQuotaRepo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return qc.defaults, nil, nil
+	}
+	if err != nil {
+		return Quota{}, nil, fmt.Errorf("GET_USER_QUOTA_FAIL: %w", err)
+	}
+	return qc.defaults.Override(override), override, nil
+}
+
+// lock hold the quotas of the emails until the end of the transaction, so concurrent requests can not go over a cap.
+// It must be called on a checker returned by withTx, before the quotas are checked.
+func (qc quotaChecker) lock(emails ...string) error {
+	if err := qc.userQuotaRepo.Lock(emails...); err != nil {
+		return fmt.Errorf("LOCK_QUOTA_FAIL: %w", err)
+	}
+	return nil
+}
+
+// check return QUOTA_EXCEEDED when the requestor can not create one more relationship of the type
+func (qc quotaChecker) check(requestor, relationshipType string) error {
+	quota, _, err := qc.quotaOf(requestor)
+	if err != nil {
+		return err
+	}
+
+	if err := qc.checkCap(quota, requestor, relationshipType); err != nil {
+		return err
+	}
+
+	if quota.MaxNewPerDay > 0 {
+		usage, err := qc.relationshipEventRepo.CountCreatedSince(requestor, time.Now().Add(-quotaWindow))
+		if err != nil {
+			return fmt.Errorf("COUNT_NEW_RELATIONSHIPS_FAIL: %w", err)
+		}
+		if usage >= quota.MaxNewPerDay {
+			return apperror.QuotaExceeded(constant.QUOTA_MAX_NEW_PER_DAY, quota.MaxNewPerDay, usage)
+		}
+	}
+	return nil
+}
+
+// checkFriend return QUOTA_EXCEEDED when the target of a friendship can not have one more friend
+func (qc quotaChecker) checkFriend(target string) error {
+	quota, _, err := qc.quotaOf(target)
+	if err != nil {
+		return err
+	}
+	return qc.checkCap(quota, target, constant.FRIEND_RELATIONSHIP_TYPE)
+}
+
+// checkCap return QUOTA_EXCEEDED when the email already has as many relationships of the type as its cap
+func (qc quotaChecker) checkCap(quota Quota, email, relationshipType string) error {
+	name, limit := quota.capOf(relationshipType)
+	if limit <= 0 {
+		return nil
+	}
+	usage, err := qc.userRelationshipRepo.CountRelationshipsByType(email, relationshipType)
+	if err != nil {
+		return fmt.Errorf("COUNT_RELATIONSHIPS_FAIL: %w", err)
+	}
+	if usage >= limit {
+		return apperror.QuotaExceeded(name, limit, usage)
+	}
+	return nil
+}
+
+// report return the quota of the email with its usage
+func (qc quotaChecker) report(email string) (*QuotaReport, error) {
+	quota, override, err := qc.quotaOf(email)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &QuotaReport{Email: email, Quota: quota, Override: override}
+	counts := []struct {
+		relationshipType string
+		usage            *int64
+	}{
+		{constant.FRIEND_RELATIONSHIP_TYPE, &report.Usage.Friends},
+		{constant.SUBSCRIBER_RELATIONSHIOP_TYPE, &report.Usage.Subscriptions},
+		{constant.BLOCK_RELATIONSHIP_TYPE, &report.Usage.Blocks},
+	}
+	for _, count := range counts {
+		*count.usage, err = qc.userRelationshipRepo.CountRelationshipsByType(email, count.relationshipType)
+		if err != nil {
+			return nil, fmt.Errorf("COUNT_RELATIONSHIPS_FAIL: %w", err)
+		}
+	}
+
+	report.Usage.NewPerDay, err = qc.relationshipEventRepo.CountCreatedSince(email, time.Now().Add(-quotaWindow))
+	if err != nil {
+		return nil, fmt.Errorf("COUNT_NEW_RELATIONSHIPS_FAIL: %w", err)
+	}
+	return report, nil
+}
+
+// withTx return a checker that read the quotas and relationships in the transaction
+func (qc quotaChecker) withTx(tx *gorm.DB) quotaChecker {
+	return quotaChecker{
+		defaults:              qc.defaults,
+		userRelationshipRepo:  qc.userRelationshipRepo.WithTx(tx),
+		userQuotaRepo:         qc.userQuotaRepo.WithTx(tx),
+		relationshipEventRepo: qc.relationshipEventRepo.WithTx(tx),
+	}
+}
+
+// withTenant return a checker that read the quotas and relationships of the tenant
+func (qc quotaChecker) withTenant(tenantID string) quotaChecker {
+	return quotaChecker{
+		defaults:              qc.defaults,
+		userRelationshipRepo:  qc.userRelationshipRepo.WithTenant(tenantID),
+		userQuotaRepo:         qc.userQuotaRepo.WithTenant(tenantID),
+		relationshipEventRepo: qc.relationshipEventRepo.WithTenant(tenantID),
+	}
+}
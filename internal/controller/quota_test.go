@@ -0,0 +1,276 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// noQuotaOverride return a quota repository where no email has an override
+func noQuotaOverride() *controller.MockUserQuotaRepository {
+	mockQuotaRepo := new(controller.MockUserQuotaRepository)
+	mockQuotaRepo.On("Lock", mock.Anything).Return(nil).Maybe()
+	mockQuotaRepo.On("GetByEmail", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	return mockQuotaRepo
+}
+
+// createdSince return an event repository that record the events and count the relationships created by the email in the window
+func createdSince(email string, count int64) *controller.MockRelationshipEventRepository {
+	mockEventRepo := recordEvents()
+	mockEventRepo.On("CountCreatedSince", email, mock.Anything).Return(count, nil)
+	return mockEventRepo
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func TestQuota_Override(t *testing.T) {
+	defaults := controller.Quota{MaxFriends: 500, MaxSubscriptions: 1000, MaxBlocks: 200, MaxNewPerDay: 100}
+
+	assert.Equal(t, defaults, defaults.Override(nil))
+	assert.Equal(t, controller.Quota{MaxFriends: 5000, MaxSubscriptions: 1000, MaxBlocks: 0, MaxNewPerDay: 100},
+		defaults.Override(&model.UserQuota{MaxFriends: int64Ptr(5000), MaxBlocks: int64Ptr(0)}))
+}
+
+func TestUserRelationshipController_AddSubscriberQuota(t *testing.T) {
+	requestor := "alice@example.com"
+	target := "bob@example.com"
+	defaults := controller.Quota{MaxSubscriptions: 2, MaxNewPerDay: 10}
+
+	tcs := map[string]struct {
+		override      *model.UserQuota
+		overrideErr   error
+		subscriptions int64
+		newPerDay     int64
+		err           error
+	}{
+		"Success_UnderQuota": {
+			subscriptions: 1,
+			newPerDay:     1,
+		},
+		"Error_MaxSubscriptions": {
+			subscriptions: 2,
+			err:           apperror.QuotaExceeded(constant.QUOTA_MAX_SUBSCRIPTIONS, 2, 2),
+		},
+		"Error_MaxNewPerDay": {
+			subscriptions: 1,
+			newPerDay:     10,
+			err:           apperror.QuotaExceeded(constant.QUOTA_MAX_NEW_PER_DAY, 10, 10),
+		},
+		"Success_OverrideRaiseCap": {
+			override:      &model.UserQuota{Email: requestor, MaxSubscriptions: int64Ptr(5)},
+			subscriptions: 2,
+			newPerDay:     2,
+		},
+		"Success_OverrideRemoveCaps": {
+			override: &model.UserQuota{Email: requestor, MaxSubscriptions: int64Ptr(0), MaxNewPerDay: int64Ptr(0)},
+		},
+		"Error_OverrideLowerCap": {
+			override:      &model.UserQuota{Email: requestor, MaxSubscriptions: int64Ptr(1)},
+			subscriptions: 1,
+			err:           apperror.QuotaExceeded(constant.QUOTA_MAX_SUBSCRIPTIONS, 1, 1),
+		},
+		"Error_GetOverrideFailed": {
+			overrideErr: errors.New("DATABASE_ERROR"),
+			err:         errors.New("GET_USER_QUOTA_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockQuotaRepo := new(controller.MockUserQuotaRepository)
+			mockQuotaRepo.On("Lock", []string{requestor}).Return(nil)
+			switch {
+			case tc.overrideErr != nil:
+				mockQuotaRepo.On("GetByEmail", requestor).Return(nil, tc.overrideErr)
+			case tc.override != nil:
+				mockQuotaRepo.On("GetByEmail", requestor).Return(tc.override, nil)
+			default:
+				mockQuotaRepo.On("GetByEmail", requestor).Return(nil, gorm.ErrRecordNotFound)
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("CheckIfTheRequestorAlreadySubscribe", requestor, target).Return(false, nil)
+			mockRepo.On("CheckTwoUsersBlockedEachOther", requestor, target).Return(false, nil)
+			mockRepo.On("CountRelationshipsByType", requestor, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).Return(tc.subscriptions, nil)
+			mockRepo.On("AddSubscriber", requestor, target).Return(nil)
+
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, createdSince(requestor, tc.newPerDay), publishEvents(), mockQuotaRepo, noPreferences(), defaults, constant.MENTION_POLICY_ANYONE)
+			err := ctrl.AddSubscriber(requestor, target)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				if expected := apperror.As(tc.err); expected != nil {
+					assert.Equal(t, expected.Quota, apperror.As(err).Quota)
+					mockRepo.AssertNotCalled(t, "AddSubscriber", requestor, target)
+				}
+			} else {
+				assert.NoError(t, err)
+				mockRepo.AssertCalled(t, "AddSubscriber", requestor, target)
+			}
+			mockQuotaRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRelationshipController_AddFriendshipQuota(t *testing.T) {
+	email1 := "alice@example.com"
+	email2 := "bob@example.com"
+
+	tcs := map[string]struct {
+		friends1 int64
+		friends2 int64
+		err      error
+	}{
+		"Success_UnderQuota": {
+			friends1: 2,
+			friends2: 2,
+		},
+		"Error_RequestorMaxFriends": {
+			friends1: 3,
+			err:      apperror.QuotaExceeded(constant.QUOTA_MAX_FRIENDS, 3, 3),
+		},
+		"Error_TargetMaxFriends": {
+			friends1: 2,
+			friends2: 3,
+			err:      apperror.QuotaExceeded(constant.QUOTA_MAX_FRIENDS, 3, 3),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("CheckTwoUsersBlockedEachOther", email1, email2).Return(false, nil)
+			mockRepo.On("CheckTwoUsersAreFriends", email1, email2).Return(false, nil)
+			mockRepo.On("CountRelationshipsByType", email1, constant.FRIEND_RELATIONSHIP_TYPE).Return(tc.friends1, nil)
+			mockRepo.On("CountRelationshipsByType", email2, constant.FRIEND_RELATIONSHIP_TYPE).Return(tc.friends2, nil)
+			mockRepo.On("CreateFriendRelationship", mock.Anything, mock.Anything).Return(nil)
+			//Both users are locked so a concurrent friendship of the target can not go over its cap
+			mockQuotaRepo := noQuotaOverride()
+			mockQuotaRepo.On("Lock", []string{email1, email2}).Return(nil)
+
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), mockQuotaRepo, noPreferences(), controller.Quota{MaxFriends: 3}, constant.MENTION_POLICY_ANYONE)
+			err := ctrl.AddFriendship(email1, email2)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.Equal(t, &apperror.QuotaViolation{Name: constant.QUOTA_MAX_FRIENDS, Limit: 3, Usage: 3}, apperror.As(err).Quota)
+				mockRepo.AssertNotCalled(t, "CreateFriendRelationship", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				mockRepo.AssertNumberOfCalls(t, "CreateFriendRelationship", 2)
+			}
+			mockQuotaRepo.AssertCalled(t, "Lock", []string{email1, email2})
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRelationshipController_AddSubscriberQuotaLockFailed(t *testing.T) {
+	requestor := "alice@example.com"
+	target := "bob@example.com"
+
+	db, sqlMock := setupMockTxDB(t)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	mockRepo := new(controller.MockUserRelationshipRepository)
+	mockRepo.On("CheckIfTheRequestorAlreadySubscribe", requestor, target).Return(false, nil)
+	mockRepo.On("CheckTwoUsersBlockedEachOther", requestor, target).Return(false, nil)
+	mockQuotaRepo := new(controller.MockUserQuotaRepository)
+	mockQuotaRepo.On("Lock", []string{requestor}).Return(errors.New("DATABASE_ERROR"))
+
+	ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), mockQuotaRepo, noPreferences(), controller.Quota{MaxSubscriptions: 2}, constant.MENTION_POLICY_ANYONE)
+	err := ctrl.AddSubscriber(requestor, target)
+
+	assert.EqualError(t, err, "LOCK_QUOTA_FAIL: DATABASE_ERROR")
+	mockRepo.AssertNotCalled(t, "AddSubscriber", requestor, target)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserRelationshipController_AddBlockQuota(t *testing.T) {
+	requestor := "alice@example.com"
+	target := "bob@example.com"
+
+	tcs := map[string]struct {
+		blocks int64
+		err    error
+	}{
+		"Success_UnderQuota": {
+			blocks: 1,
+		},
+		"Error_MaxBlocks": {
+			blocks: 2,
+			err:    apperror.QuotaExceeded(constant.QUOTA_MAX_BLOCKS, 2, 2),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("CheckTwoUsersBlockedEachOther", requestor, target).Return(false, nil)
+			mockRepo.On("CountRelationshipsByType", requestor, constant.BLOCK_RELATIONSHIP_TYPE).Return(tc.blocks, nil)
+			mockRepo.On("GetRelationshipsBetween", requestor, target).Return(nil, nil)
+			mockRepo.On("DeleteRelationship", requestor, target).Return(nil)
+			mockRepo.On("DeleteRelationship", target, requestor).Return(nil)
+			mockRepo.On("CreateBlockRelationship", requestor, target).Return(nil)
+
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{MaxBlocks: 2}, constant.MENTION_POLICY_ANYONE)
+			err := ctrl.AddBlock(requestor, target)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				mockRepo.AssertNotCalled(t, "CreateBlockRelationship", requestor, target)
+			} else {
+				assert.NoError(t, err)
+				mockRepo.AssertCalled(t, "CreateBlockRelationship", requestor, target)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRelationshipController_WithTenantQuota(t *testing.T) {
+	mockRepo := new(controller.MockUserRelationshipRepository)
+	mockRepo.On("CheckIfTheRequestorAlreadySubscribe", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("CheckTwoUsersBlockedEachOther", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("AddSubscriber", mock.Anything, mock.Anything).Return(nil)
+	mockQuotaRepo := noQuotaOverride()
+
+	db, sqlMock := setupMockTxDB(t)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), mockQuotaRepo, noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE).WithTenant("acme")
+
+	assert.NoError(t, ctrl.AddSubscriber("alice@example.com", "bob@example.com"))
+	assert.Equal(t, "acme", mockQuotaRepo.Tenant)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/mention"
+	"github.com/quanluong166/friends_management/internal/model"
+)
+
+// Recipients is who receives an update now and who is deferred until the end of their quiet hours
+type Recipients struct {
+	Now      []string
+	Deferred []DeferredRecipient
+}
+
+// DeferredRecipient is a recipient in its quiet hours, it receives the update at Until
+type DeferredRecipient struct {
+	Email string
+	Until time.Time
+}
+
+// planDelivery split the recipients of an update by their preferences at now: the recipients that only want mentions
+// are left out unless the text mentions them, and the recipients in their quiet hours are deferred until they end.
+// The mentions are lower cased so recipients are matched with them case-insensitively.
+func planDelivery(preferences map[string]model.NotificationPreference, recipients []string, text string, now time.Time) Recipients {
+	mentioned := make(map[string]bool)
+	for _, email := range mention.Extract(text) {
+		mentioned[email] = true
+	}
+
+	plan := Recipients{Now: make([]string, 0, len(recipients))}
+	for _, recipient := range recipients {
+		preference, ok := preferences[recipient]
+		if !ok {
+			plan.Now = append(plan.Now, recipient)
+			continue
+		}
+		if preference.MentionsOnly && !mentioned[strings.ToLower(recipient)] {
+			continue
+		}
+		if until, quiet := preference.QuietUntil(now); quiet {
+			plan.Deferred = append(plan.Deferred, DeferredRecipient{Email: recipient, Until: until})
+			continue
+		}
+		plan.Now = append(plan.Now, recipient)
+	}
+	return plan
+}
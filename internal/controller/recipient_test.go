@@ -0,0 +1,111 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRelationshipController_GetListEmailCanReceiveUpdatePreferences(t *testing.T) {
+	updaterEmail := "alice@example.com"
+	text := "hello carol@example.com"
+	//Quiet hours around the time the test runs so the recipient is always in them
+	now := time.Now().UTC()
+	quiet := model.NotificationPreference{
+		Timezone:        "UTC",
+		QuietHoursStart: now.Add(-time.Hour).Format("15:04"),
+		QuietHoursEnd:   now.Add(time.Hour).Format("15:04"),
+	}
+
+	tcs := map[string]struct {
+		preferences map[string]model.NotificationPreference
+		prefErr     error
+		now         []string
+		deferred    []string
+		err         string
+	}{
+		"NoPreference": {
+			now: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+		},
+		"MentionsOnlyLeftOutUnlessMentioned": {
+			preferences: map[string]model.NotificationPreference{
+				"bob@example.com":   {Email: "bob@example.com", MentionsOnly: true},
+				"carol@example.com": {Email: "carol@example.com", MentionsOnly: true},
+			},
+			now: []string{"dave@example.com", "carol@example.com"},
+		},
+		"QuietHoursDeferred": {
+			preferences: map[string]model.NotificationPreference{
+				"dave@example.com": quiet,
+			},
+			now:      []string{"bob@example.com", "carol@example.com"},
+			deferred: []string{"dave@example.com"},
+		},
+		"Error_ListPreferencesFailed": {
+			prefErr: errors.New("DATABASE_ERROR"),
+			err:     "LIST_NOTIFICATION_PREFERENCES_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("GetListFriendshipEmail", updaterEmail).Return([]string{"bob@example.com"}, nil)
+			mockRepo.On("GetListSubscriberEmail", updaterEmail).Return([]string{"dave@example.com"}, nil)
+			mockRepo.On("GetContactEmails", updaterEmail, []string{"carol@example.com"}).Return([]string{}, nil)
+			mockPreferenceRepo := new(controller.MockNotificationPreferenceRepository)
+			mockPreferenceRepo.On("ListByEmails", []string{"carol@example.com"}).Return(tc.preferences, tc.prefErr)
+			mockPreferenceRepo.On("ListByEmails", []string{"bob@example.com", "dave@example.com", "carol@example.com"}).Return(tc.preferences, tc.prefErr)
+
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), mockPreferenceRepo, controller.Quota{}, constant.MENTION_POLICY_ANYONE).WithTenant("tenant-b")
+			recipients, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, recipients)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.now, recipients.Now)
+			assert.Len(t, recipients.Deferred, len(tc.deferred))
+			for i, email := range tc.deferred {
+				assert.Equal(t, email, recipients.Deferred[i].Email)
+				assert.WithinDuration(t, now.Add(time.Hour), recipients.Deferred[i].Until, time.Minute)
+			}
+			assert.Equal(t, "tenant-b", mockPreferenceRepo.Tenant)
+		})
+	}
+}
+
+func TestUserRelationshipController_GetListEmailCanReceiveUpdateMixedCase(t *testing.T) {
+	updaterEmail := "alice@example.com"
+	//The mentions are lower cased while the relationships keep the emails as they were entered
+	text := "hi Bob@example.com, carol@example.com and DAVE@EXAMPLE.COM"
+	mentioned := []string{"bob@example.com", "carol@example.com", "dave@example.com"}
+	recipients := []string{"Bob@Example.com", "Dave@Example.com", "carol@example.com"}
+
+	mockRepo := new(controller.MockUserRelationshipRepository)
+	mockRepo.On("GetListFriendshipEmail", updaterEmail).Return([]string{"Bob@Example.com"}, nil)
+	mockRepo.On("GetListSubscriberEmail", updaterEmail).Return([]string{"Dave@Example.com"}, nil)
+	mockRepo.On("GetContactEmails", updaterEmail, mentioned).Return([]string{"bob@example.com", "dave@example.com"}, nil)
+	mockRepo.On("GetKnownEmails", []string{"carol@example.com"}).Return([]string{"carol@example.com"}, nil)
+	mockPreferenceRepo := new(controller.MockNotificationPreferenceRepository)
+	mockPreferenceRepo.On("ListByEmails", []string{"carol@example.com"}).Return(map[string]model.NotificationPreference{}, nil)
+	mockPreferenceRepo.On("ListByEmails", recipients).Return(map[string]model.NotificationPreference{
+		"Bob@Example.com": {Email: "bob@example.com", MentionsOnly: true},
+	}, nil)
+
+	ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), mockPreferenceRepo, controller.Quota{}, constant.MENTION_POLICY_KNOWN_USERS)
+	result, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
+
+	//A mentioned friend is listed once and a mentions only friend is reached by a mention in another case
+	assert.NoError(t, err)
+	assert.Equal(t, recipients, result.Now)
+	mockRepo.AssertExpectations(t)
+	mockPreferenceRepo.AssertExpectations(t)
+}
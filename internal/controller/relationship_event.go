@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+)
+
+// recordEvent support append one change of the relationship graph, it must run in the transaction of the change
+func recordEvent(repo repository.RelationshipEventRepository, actor Actor, action, requestor, target string, before, after []model.RelationshipState) error {
+	err := repo.Create(&model.RelationshipEvent{
+		Action:         action,
+		RequestorEmail: requestor,
+		TargetEmail:    target,
+		Before:         before,
+		After:          after,
+		Actor:          actor.Subject,
+		IP:             actor.IP,
+		UserAgent:      actor.UserAgent,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("CREATE_RELATIONSHIP_EVENT_FAIL: %w", err)
+	}
+	return nil
+}
+
+// relationshipStates support convert relationship rows to the states recorded in the events
+func relationshipStates(relationships []model.UserRelationship) []model.RelationshipState {
+	states := make([]model.RelationshipState, 0, len(relationships))
+	for _, relationship := range relationships {
+		states = append(states, model.RelationshipState{Requestor: relationship.RequestorEmail, Target: relationship.TargetEmail, Type: relationship.Type})
+	}
+	return states
+}
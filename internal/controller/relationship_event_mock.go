@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockRelationshipEventRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockRelationshipEventRepository) Create(event *model.RelationshipEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockRelationshipEventRepository) List(filter repository.RelationshipEventFilter) ([]model.RelationshipEvent, int64, error) {
+	args := m.Called(filter)
+	var events []model.RelationshipEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]model.RelationshipEvent)
+	}
+	return events, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRelationshipEventRepository) CountCreatedSince(requestor string, since time.Time) (int64, error) {
+	args := m.Called(requestor, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockRelationshipEventRepository) WithTx(tx *gorm.DB) repository.RelationshipEventRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockRelationshipEventRepository) WithTenant(tenantID string) repository.RelationshipEventRepository {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,191 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// recordEvents return an event repository that accept every relationship event
+func recordEvents() *controller.MockRelationshipEventRepository {
+	mockEventRepo := new(controller.MockRelationshipEventRepository)
+	mockEventRepo.On("Create", mock.Anything).Return(nil).Maybe()
+	return mockEventRepo
+}
+
+// expectEvent return an event repository that only accept the event with the action, emails and states
+func expectEvent(action, requestor, target string, before, after []model.RelationshipState, actor controller.Actor) *controller.MockRelationshipEventRepository {
+	mockEventRepo := new(controller.MockRelationshipEventRepository)
+	mockEventRepo.On("Create", mock.MatchedBy(func(event *model.RelationshipEvent) bool {
+		return event.Action == action && event.RequestorEmail == requestor && event.TargetEmail == target &&
+			assert.ObjectsAreEqual(before, event.Before) && assert.ObjectsAreEqual(after, event.After) &&
+			event.Actor == actor.Subject && event.IP == actor.IP && event.UserAgent == actor.UserAgent && !event.CreatedAt.IsZero()
+	})).Return(nil).Once()
+	return mockEventRepo
+}
+
+func TestUserRelationshipController_RecordEvents(t *testing.T) {
+	requestor := "user1@example.com"
+	target := "user2@example.com"
+	actor := controller.Actor{Subject: requestor, IP: "192.0.2.1", UserAgent: "curl/8.0"}
+	friend := func(from, to string) model.RelationshipState {
+		return model.RelationshipState{Requestor: from, Target: to, Type: constant.FRIEND_RELATIONSHIP_TYPE}
+	}
+
+	tcs := map[string]struct {
+		mockRepo  func(mockRepo *controller.MockUserRelationshipRepository)
+		call      func(ctrl controller.UserRelationshipController) error
+		action    string
+		before    []model.RelationshipState
+		after     []model.RelationshipState
+		published [3]string
+		eventFail bool
+		//outboxFail make the domain event fail after the relationship event was recorded
+		outboxFail bool
+	}{
+		"AddFriendship": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("CheckTwoUsersBlockedEachOther", requestor, target).Return(false, nil)
+				mockRepo.On("CheckTwoUsersAreFriends", requestor, target).Return(false, nil)
+				mockRepo.On("CreateFriendRelationship", requestor, target).Return(nil)
+				mockRepo.On("CreateFriendRelationship", target, requestor).Return(nil)
+			},
+			call:      func(ctrl controller.UserRelationshipController) error { return ctrl.AddFriendship(requestor, target) },
+			published: [3]string{constant.DOMAIN_EVENT_FRIENDSHIP_CREATED, requestor, target},
+			action:    constant.RELATIONSHIP_EVENT_CREATE,
+			after:     []model.RelationshipState{friend(requestor, target), friend(target, requestor)},
+		},
+		"AddSubscriber": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("CheckIfTheRequestorAlreadySubscribe", requestor, target).Return(false, nil)
+				mockRepo.On("CheckTwoUsersBlockedEachOther", requestor, target).Return(false, nil)
+				mockRepo.On("AddSubscriber", requestor, target).Return(nil)
+			},
+			call:      func(ctrl controller.UserRelationshipController) error { return ctrl.AddSubscriber(requestor, target) },
+			published: [3]string{constant.DOMAIN_EVENT_SUBSCRIBED, requestor, target},
+			action:    constant.RELATIONSHIP_EVENT_CREATE,
+			after:     []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}},
+		},
+		"AddBlock_ReplaceFriendship": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("CheckTwoUsersBlockedEachOther", requestor, target).Return(false, nil)
+				mockRepo.On("GetRelationshipsBetween", requestor, target).Return([]model.UserRelationship{
+					{RequestorEmail: requestor, TargetEmail: target, Type: constant.FRIEND_RELATIONSHIP_TYPE},
+					{RequestorEmail: target, TargetEmail: requestor, Type: constant.FRIEND_RELATIONSHIP_TYPE},
+				}, nil)
+				mockRepo.On("DeleteRelationship", requestor, target).Return(nil)
+				mockRepo.On("DeleteRelationship", target, requestor).Return(nil)
+				mockRepo.On("CreateBlockRelationship", requestor, target).Return(nil)
+			},
+			call:      func(ctrl controller.UserRelationshipController) error { return ctrl.AddBlock(requestor, target) },
+			published: [3]string{constant.DOMAIN_EVENT_BLOCKED, requestor, target},
+			action:    constant.RELATIONSHIP_EVENT_BLOCK,
+			before:    []model.RelationshipState{friend(requestor, target), friend(target, requestor)},
+			after:     []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.BLOCK_RELATIONSHIP_TYPE}},
+		},
+		"RemoveFriendship": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("DeleteRelationshipByType", requestor, target, constant.FRIEND_RELATIONSHIP_TYPE).Return(int64(1), nil)
+				mockRepo.On("DeleteRelationshipByType", target, requestor, constant.FRIEND_RELATIONSHIP_TYPE).Return(int64(1), nil)
+			},
+			call: func(ctrl controller.UserRelationshipController) error {
+				return ctrl.RemoveFriendship(requestor, target)
+			},
+			published: [3]string{constant.DOMAIN_EVENT_FRIENDSHIP_REMOVED, requestor, target},
+			action:    constant.RELATIONSHIP_EVENT_DELETE,
+			before:    []model.RelationshipState{friend(requestor, target), friend(target, requestor)},
+		},
+		"RemoveSubscriber": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("DeleteRelationshipByType", requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).Return(int64(1), nil)
+			},
+			call: func(ctrl controller.UserRelationshipController) error {
+				return ctrl.RemoveSubscriber(requestor, target)
+			},
+			published: [3]string{constant.DOMAIN_EVENT_UNSUBSCRIBED, requestor, target},
+			action:    constant.RELATIONSHIP_EVENT_DELETE,
+			before:    []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}},
+		},
+		"RemoveBlock": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("DeleteRelationshipByType", requestor, target, constant.BLOCK_RELATIONSHIP_TYPE).Return(int64(1), nil)
+			},
+			call:      func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveBlock(requestor, target) },
+			published: [3]string{constant.DOMAIN_EVENT_UNBLOCKED, requestor, target},
+			action:    constant.RELATIONSHIP_EVENT_DELETE,
+			before:    []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.BLOCK_RELATIONSHIP_TYPE}},
+		},
+		"OutboxFailRollback": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("DeleteRelationshipByType", requestor, target, constant.BLOCK_RELATIONSHIP_TYPE).Return(int64(1), nil)
+			},
+			call:       func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveBlock(requestor, target) },
+			action:     constant.RELATIONSHIP_EVENT_DELETE,
+			before:     []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.BLOCK_RELATIONSHIP_TYPE}},
+			outboxFail: true,
+		},
+		"EventFailRollback": {
+			mockRepo: func(mockRepo *controller.MockUserRelationshipRepository) {
+				mockRepo.On("DeleteRelationshipByType", requestor, target, constant.BLOCK_RELATIONSHIP_TYPE).Return(int64(1), nil)
+			},
+			call:      func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveBlock(requestor, target) },
+			eventFail: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+
+			mockEventRepo := expectEvent(tc.action, requestor, target, tc.before, tc.after, actor)
+			mockOutboxRepo := expectOutboxEvents(tc.published)
+			switch {
+			case tc.eventFail:
+				mockEventRepo = new(controller.MockRelationshipEventRepository)
+				mockEventRepo.On("Create", mock.Anything).Return(errors.New("DATABASE_ERROR"))
+				mockOutboxRepo = new(controller.MockOutboxEventRepository)
+				sqlMock.ExpectRollback()
+			case tc.outboxFail:
+				mockOutboxRepo = new(controller.MockOutboxEventRepository)
+				mockOutboxRepo.On("Create", mock.Anything).Return(errors.New("DATABASE_ERROR"))
+				sqlMock.ExpectRollback()
+			default:
+				sqlMock.ExpectCommit()
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			tc.mockRepo(mockRepo)
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, mockEventRepo, mockOutboxRepo, noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE).WithActor(actor)
+			err := tc.call(ctrl)
+			switch {
+			case tc.eventFail:
+				assert.EqualError(t, err, "CREATE_RELATIONSHIP_EVENT_FAIL: DATABASE_ERROR")
+			case tc.outboxFail:
+				assert.EqualError(t, err, "CREATE_OUTBOX_EVENT_FAIL: DATABASE_ERROR")
+			default:
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			mockEventRepo.AssertExpectations(t)
+			mockOutboxRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRelationshipController_WithActorKeepsTenant(t *testing.T) {
+	mockRepo := new(controller.MockUserRelationshipRepository)
+	mockEventRepo := recordEvents()
+	mockOutboxRepo := publishEvents()
+	controller.NewUserRelationshipController(nil, mockRepo, mockEventRepo, mockOutboxRepo, noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE).
+		WithTenant("acme").WithActor(controller.Actor{Subject: "user1@example.com"})
+	assert.Equal(t, "acme", mockRepo.Tenant)
+	assert.Equal(t, "acme", mockEventRepo.Tenant)
+	assert.Equal(t, "acme", mockOutboxRepo.Tenant)
+}
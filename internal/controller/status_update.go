@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// StatusUpdateController defines how users post status updates and read their feed
+type StatusUpdateController interface {
+	PostUpdate(author, text string) (*model.StatusUpdate, error)
+	ListFeed(email string, before uint, limit int) ([]model.StatusUpdate, error)
+	WithTenant(tenantID string) StatusUpdateController
+}
+
+// Feed configures how a status update reaches its recipients
+type Feed struct {
+	//Authors with at least this many friends and subscribers are fanned out on read, 0 always fan out on write
+	FanOutOnReadThreshold int64
+	//Queue an email for every recipient, the friends and subscribers of an author fanned out on read included
+	EmailRecipients bool
+	//Who can be reached by mentioning their email in the text, one of the MENTION_POLICY constants
+	MentionPolicy string
+}
+
+type statusUpdateController struct {
+	db                   *gorm.DB
+	userRelationshipRepo repository.UserRelationshipRepository
+	statusUpdateRepo     repository.StatusUpdateRepository
+	emailDeliveryRepo    repository.EmailDeliveryRepository
+	preferenceRepo       repository.NotificationPreferenceRepository
+	feed                 Feed
+}
+
+func NewStatusUpdateController(db *gorm.DB, userRelationshipRepo repository.UserRelationshipRepository, statusUpdateRepo repository.StatusUpdateRepository, emailDeliveryRepo repository.EmailDeliveryRepository, preferenceRepo repository.NotificationPreferenceRepository, feed Feed) StatusUpdateController {
+	return &statusUpdateController{
+		db:                   db,
+		userRelationshipRepo: userRelationshipRepo,
+		statusUpdateRepo:     statusUpdateRepo,
+		emailDeliveryRepo:    emailDeliveryRepo,
+		preferenceRepo:       preferenceRepo,
+		feed:                 feed,
+	}
+}
+
+// PostUpdate support store a status update, write it to the feed of its recipients and queue their emails,
+// the recipients that prefer a digest get it in their next digest instead. The emails follow the preferences
+// of the recipients, the feeds get every update.
+// The update of an author with a huge audience is only written to the feeds of the mentioned emails,
+// the friends and subscribers read it from the author when they list their feed.
+func (sc *statusUpdateController) PostUpdate(author, text string) (*model.StatusUpdate, error) {
+	audience, err := sc.userRelationshipRepo.CountFriendsAndSubscribers(author)
+	if err != nil {
+		return nil, fmt.Errorf("COUNT_FRIENDS_AND_SUBSCRIBERS_FAIL: %w", err)
+	}
+
+	update := &model.StatusUpdate{AuthorEmail: author, Text: text, FanOut: constant.FAN_OUT_ON_WRITE, CreatedAt: time.Now()}
+	if sc.feed.FanOutOnReadThreshold > 0 && audience >= sc.feed.FanOutOnReadThreshold {
+		update.FanOut = constant.FAN_OUT_ON_READ
+	}
+
+	mentioned, err := mentionedRecipients(sc.userRelationshipRepo, sc.preferenceRepo, sc.feed.MentionPolicy, author, text)
+	if err != nil {
+		return nil, err
+	}
+
+	//The whole audience is only resolved when the update is written to it or emailed to it
+	var recipients []string
+	if update.FanOut == constant.FAN_OUT_ON_WRITE || sc.feed.EmailRecipients {
+		recipients, err = updateRecipients(sc.userRelationshipRepo, author, mentioned)
+		if err != nil {
+			return nil, err
+		}
+		recipients = utils.RemoveSameElementsFromSecond([]string{author}, recipients)
+	}
+	owners := recipients
+	if update.FanOut == constant.FAN_OUT_ON_READ {
+		owners = utils.RemoveSameElementsFromSecond([]string{author}, mentioned)
+	}
+
+	//A recipient in its quiet hours is emailed when they end, the scheduler defers the digests by itself
+	var immediate, digest []string
+	var deferred []DeferredRecipient
+	if sc.feed.EmailRecipients {
+		preferences, err := sc.preferenceRepo.ListByEmails(recipients)
+		if err != nil {
+			return nil, fmt.Errorf("LIST_NOTIFICATION_PREFERENCES_FAIL: %w", err)
+		}
+		plan := planDelivery(preferences, recipients, text, update.CreatedAt)
+		for _, recipient := range plan.Now {
+			if prefersDigest(preferences, recipient) {
+				digest = append(digest, recipient)
+			} else {
+				immediate = append(immediate, recipient)
+			}
+		}
+		for _, recipient := range plan.Deferred {
+			if prefersDigest(preferences, recipient.Email) {
+				digest = append(digest, recipient.Email)
+			} else {
+				deferred = append(deferred, recipient)
+			}
+		}
+	}
+
+	err = sc.db.Transaction(func(tx *gorm.DB) error {
+		repo := sc.statusUpdateRepo.WithTx(tx)
+		if err := repo.Create(update); err != nil {
+			return fmt.Errorf("CREATE_STATUS_UPDATE_FAIL: %w", err)
+		}
+		if err := repo.CreateFeedEntries(update.ID, owners, update.CreatedAt); err != nil {
+			return fmt.Errorf("CREATE_FEED_ENTRIES_FAIL: %w", err)
+		}
+		if !sc.feed.EmailRecipients {
+			return nil
+		}
+		emailRepo := sc.emailDeliveryRepo.WithTx(tx)
+		if err := emailRepo.Enqueue(update.ID, constant.EMAIL_TEMPLATE_STATUS_UPDATE, immediate, update.CreatedAt, update.CreatedAt); err != nil {
+			return fmt.Errorf("ENQUEUE_EMAIL_DELIVERIES_FAIL: %w", err)
+		}
+		sendTimes, recipientsAt := groupBySendTime(deferred)
+		for _, sendAt := range sendTimes {
+			if err := emailRepo.Enqueue(update.ID, constant.EMAIL_TEMPLATE_STATUS_UPDATE, recipientsAt[sendAt], update.CreatedAt, sendAt); err != nil {
+				return fmt.Errorf("ENQUEUE_EMAIL_DELIVERIES_FAIL: %w", err)
+			}
+		}
+		if err := emailRepo.EnqueueDigest(update.ID, digest, update.CreatedAt); err != nil {
+			return fmt.Errorf("ENQUEUE_DIGEST_ENTRIES_FAIL: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// prefersDigest report whether the recipient is sent digests instead of an email per update
+func prefersDigest(preferences map[string]model.NotificationPreference, recipient string) bool {
+	preference, ok := preferences[recipient]
+	return ok && preference.Frequency != constant.NOTIFICATION_FREQUENCY_IMMEDIATE
+}
+
+// groupBySendTime group the deferred recipients by the end of their quiet hours, the times are in the order they first appear
+func groupBySendTime(deferred []DeferredRecipient) ([]time.Time, map[time.Time][]string) {
+	var sendTimes []time.Time
+	recipientsAt := make(map[time.Time][]string)
+	for _, recipient := range deferred {
+		if _, ok := recipientsAt[recipient.Until]; !ok {
+			sendTimes = append(sendTimes, recipient.Until)
+		}
+		recipientsAt[recipient.Until] = append(recipientsAt[recipient.Until], recipient.Email)
+	}
+	return sendTimes, recipientsAt
+}
+
+// ListFeed support get one page of the feed of the email, newest first and before an id when it is not 0
+func (sc *statusUpdateController) ListFeed(email string, before uint, limit int) ([]model.StatusUpdate, error) {
+	updates, err := sc.statusUpdateRepo.ListFeed(email, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("LIST_FEED_FAIL: %w", err)
+	}
+	return updates, nil
+}
+
+// WithTenant return a controller that post and read the status updates of the tenant
+func (sc *statusUpdateController) WithTenant(tenantID string) StatusUpdateController {
+	return &statusUpdateController{
+		db:                   sc.db,
+		userRelationshipRepo: sc.userRelationshipRepo.WithTenant(tenantID),
+		statusUpdateRepo:     sc.statusUpdateRepo.WithTenant(tenantID),
+		emailDeliveryRepo:    sc.emailDeliveryRepo.WithTenant(tenantID),
+		preferenceRepo:       sc.preferenceRepo.WithTenant(tenantID),
+		feed:                 sc.feed,
+	}
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockStatusUpdateRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockStatusUpdateRepository) Create(update *model.StatusUpdate) error {
+	args := m.Called(update)
+	return args.Error(0)
+}
+
+func (m *MockStatusUpdateRepository) CreateFeedEntries(statusUpdateID uint, owners []string, at time.Time) error {
+	args := m.Called(statusUpdateID, owners, at)
+	return args.Error(0)
+}
+
+func (m *MockStatusUpdateRepository) ListFeed(email string, before uint, limit int) ([]model.StatusUpdate, error) {
+	args := m.Called(email, before, limit)
+	var updates []model.StatusUpdate
+	if args.Get(0) != nil {
+		updates = args.Get(0).([]model.StatusUpdate)
+	}
+	return updates, args.Error(1)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockStatusUpdateRepository) WithTx(tx *gorm.DB) repository.StatusUpdateRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockStatusUpdateRepository) WithTenant(tenantID string) repository.StatusUpdateRepository {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,247 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStatusUpdateController_PostUpdate(t *testing.T) {
+	author := "alice@example.com"
+	text := "hello carol@example.com and alice@example.com"
+	//Quiet hours around the time the test runs so the recipient is always in them
+	now := time.Now().UTC()
+	quiet := model.NotificationPreference{
+		Frequency:       constant.NOTIFICATION_FREQUENCY_IMMEDIATE,
+		Timezone:        "UTC",
+		QuietHoursStart: now.Add(-time.Hour).Format("15:04"),
+		QuietHoursEnd:   now.Add(time.Hour).Format("15:04"),
+	}
+
+	tcs := map[string]struct {
+		audience   int64
+		countErr   error
+		fanOut     string
+		policy     string
+		blocks     map[string][]string
+		recipients []string
+		email      bool
+		prefs      map[string]model.NotificationPreference
+		emailed    []string
+		deferred   []string
+		digested   []string
+		prefErr    error
+		createErr  error
+		feedErr    error
+		emailErr   error
+		digestErr  error
+		noTx       bool
+		err        string
+	}{
+		"FanOutOnWrite": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+		},
+		"FanOutOnReadOnlyWriteMentions": {
+			audience:   3,
+			fanOut:     constant.FAN_OUT_ON_READ,
+			recipients: []string{"carol@example.com"},
+		},
+		"MentionPolicyLeavesBlockedMentionsOut": {
+			audience: 3,
+			fanOut:   constant.FAN_OUT_ON_READ,
+			policy:   constant.MENTION_POLICY_NOT_BLOCKED,
+			blocks:   map[string][]string{author: {"carol@example.com"}},
+		},
+		"FanOutOnWriteEmailed": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			email:      true,
+			emailed:    []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+		},
+		"FanOutOnReadEmailsWholeAudience": {
+			audience:   3,
+			fanOut:     constant.FAN_OUT_ON_READ,
+			recipients: []string{"carol@example.com"},
+			email:      true,
+			emailed:    []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+		},
+		"DigestRecipientsWaitForDigest": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			email:      true,
+			prefs:      map[string]model.NotificationPreference{"dave@example.com": {Frequency: constant.NOTIFICATION_FREQUENCY_DAILY}},
+			emailed:    []string{"bob@example.com", "carol@example.com"},
+			digested:   []string{"dave@example.com"},
+		},
+		"MentionsOnlyLeftOutAndQuietHoursDeferred": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			email:      true,
+			prefs: map[string]model.NotificationPreference{
+				"bob@example.com":  {Frequency: constant.NOTIFICATION_FREQUENCY_IMMEDIATE, MentionsOnly: true},
+				"dave@example.com": quiet,
+			},
+			emailed:  []string{"carol@example.com"},
+			deferred: []string{"dave@example.com"},
+		},
+		"Error_ListNotificationPreferencesFailed": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			email:      true,
+			prefErr:    errors.New("DATABASE_ERROR"),
+			noTx:       true,
+			err:        "LIST_NOTIFICATION_PREFERENCES_FAIL: DATABASE_ERROR",
+		},
+		"Error_EnqueueDigestEntriesFailed": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			email:      true,
+			prefs:      map[string]model.NotificationPreference{"dave@example.com": {Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY}},
+			emailed:    []string{"bob@example.com", "carol@example.com"},
+			digested:   []string{"dave@example.com"},
+			digestErr:  errors.New("DATABASE_ERROR"),
+			err:        "ENQUEUE_DIGEST_ENTRIES_FAIL: DATABASE_ERROR",
+		},
+		"Error_EnqueueEmailsFailed": {
+			audience:   2,
+			fanOut:     constant.FAN_OUT_ON_WRITE,
+			recipients: []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			email:      true,
+			emailed:    []string{"bob@example.com", "dave@example.com", "carol@example.com"},
+			emailErr:   errors.New("DATABASE_ERROR"),
+			err:        "ENQUEUE_EMAIL_DELIVERIES_FAIL: DATABASE_ERROR",
+		},
+		"Error_CountFailed": {
+			countErr: errors.New("DATABASE_ERROR"),
+			noTx:     true,
+			err:      "COUNT_FRIENDS_AND_SUBSCRIBERS_FAIL: DATABASE_ERROR",
+		},
+		"Error_CreateFailed": {
+			audience:  3,
+			fanOut:    constant.FAN_OUT_ON_READ,
+			createErr: errors.New("DATABASE_ERROR"),
+			err:       "CREATE_STATUS_UPDATE_FAIL: DATABASE_ERROR",
+		},
+		"Error_CreateFeedEntriesFailed": {
+			audience:   3,
+			fanOut:     constant.FAN_OUT_ON_READ,
+			recipients: []string{"carol@example.com"},
+			feedErr:    errors.New("DATABASE_ERROR"),
+			err:        "CREATE_FEED_ENTRIES_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			if !tc.noTx {
+				sqlMock.ExpectBegin()
+				if tc.err == "" {
+					sqlMock.ExpectCommit()
+				} else {
+					sqlMock.ExpectRollback()
+				}
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("CountFriendsAndSubscribers", author).Return(tc.audience, tc.countErr)
+			mockRepo.On("GetListFriendshipEmail", author).Return([]string{"bob@example.com"}, nil).Maybe()
+			mockRepo.On("GetListSubscriberEmail", author).Return([]string{"dave@example.com"}, nil).Maybe()
+			mockRepo.On("GetBlockConnectionEmailsByEmails", []string{author}).Return(tc.blocks, nil).Maybe()
+			mockRepo.On("GetContactEmails", author, []string{"carol@example.com", "alice@example.com"}).Return([]string{}, nil).Maybe()
+			mockStatusRepo := new(controller.MockStatusUpdateRepository)
+			mockStatusRepo.On("Create", mock.MatchedBy(func(update *model.StatusUpdate) bool {
+				return update.AuthorEmail == author && update.Text == text && update.FanOut == tc.fanOut && !update.CreatedAt.IsZero()
+			})).Return(tc.createErr).Run(func(args mock.Arguments) {
+				args.Get(0).(*model.StatusUpdate).ID = 7
+			}).Maybe()
+			mockStatusRepo.On("CreateFeedEntries", uint(7), tc.recipients, mock.AnythingOfType("time.Time")).Return(tc.feedErr).Maybe()
+			mockEmailRepo := new(controller.MockEmailDeliveryRepository)
+			if tc.emailed != nil {
+				mockEmailRepo.On("Enqueue", uint(7), constant.EMAIL_TEMPLATE_STATUS_UPDATE, tc.emailed, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(tc.emailErr).Once()
+			}
+			if tc.deferred != nil {
+				mockEmailRepo.On("Enqueue", uint(7), constant.EMAIL_TEMPLATE_STATUS_UPDATE, tc.deferred, mock.AnythingOfType("time.Time"), mock.MatchedBy(func(sendAt time.Time) bool {
+					return sendAt.Sub(now.Add(time.Hour)).Abs() < time.Minute
+				})).Return(nil).Once()
+			}
+			if tc.email && tc.emailErr == nil && tc.prefErr == nil {
+				mockEmailRepo.On("EnqueueDigest", uint(7), tc.digested, mock.AnythingOfType("time.Time")).Return(tc.digestErr).Once()
+			}
+			mockPreferenceRepo := new(controller.MockNotificationPreferenceRepository)
+			mockPreferenceRepo.On("ListByEmails", []string{"carol@example.com"}).Return(map[string]model.NotificationPreference{}, nil).Maybe()
+			if tc.email {
+				mockPreferenceRepo.On("ListByEmails", []string{"bob@example.com", "dave@example.com", "carol@example.com"}).Return(tc.prefs, tc.prefErr).Once()
+			}
+
+			policy := constant.MENTION_POLICY_ANYONE
+			if tc.policy != "" {
+				policy = tc.policy
+			}
+			ctrl := controller.NewStatusUpdateController(db, mockRepo, mockStatusRepo, mockEmailRepo, mockPreferenceRepo, controller.Feed{FanOutOnReadThreshold: 3, EmailRecipients: tc.email, MentionPolicy: policy})
+			update, err := ctrl.PostUpdate(author, text)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, update)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(7), update.ID)
+				mockStatusRepo.AssertExpectations(t)
+			}
+			mockEmailRepo.AssertExpectations(t)
+			mockPreferenceRepo.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStatusUpdateController_ListFeed(t *testing.T) {
+	tcs := map[string]struct {
+		returnArgument []interface{}
+		expected       []model.StatusUpdate
+		err            string
+	}{
+		"Success": {
+			returnArgument: []interface{}{[]model.StatusUpdate{{ID: 49, AuthorEmail: "alice@example.com", Text: "hello"}}, nil},
+			expected:       []model.StatusUpdate{{ID: 49, AuthorEmail: "alice@example.com", Text: "hello"}},
+		},
+		"Error": {
+			returnArgument: []interface{}{nil, errors.New("DATABASE_ERROR")},
+			err:            "LIST_FEED_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockStatusRepo := new(controller.MockStatusUpdateRepository)
+			mockStatusRepo.On("ListFeed", "bob@example.com", uint(50), 20).Return(tc.returnArgument...)
+			mockRepo := new(controller.MockUserRelationshipRepository)
+
+			ctrl := controller.NewStatusUpdateController(mockDB, mockRepo, mockStatusRepo, new(controller.MockEmailDeliveryRepository), new(controller.MockNotificationPreferenceRepository), controller.Feed{}).WithTenant("tenant-b")
+			updates, err := ctrl.ListFeed("bob@example.com", 50, 20)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, updates)
+			assert.Equal(t, "tenant-b", mockStatusRepo.Tenant)
+			assert.Equal(t, "tenant-b", mockRepo.Tenant)
+			mockStatusRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockUserQuotaRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockUserQuotaRepository) GetByEmail(email string) (*model.UserQuota, error) {
+	args := m.Called(email)
+	var quota *model.UserQuota
+	if args.Get(0) != nil {
+		quota = args.Get(0).(*model.UserQuota)
+	}
+	return quota, args.Error(1)
+}
+
+func (m *MockUserQuotaRepository) Upsert(quota *model.UserQuota) error {
+	args := m.Called(quota)
+	return args.Error(0)
+}
+
+func (m *MockUserQuotaRepository) DeleteByEmail(email string) (int64, error) {
+	args := m.Called(email)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserQuotaRepository) Lock(emails ...string) error {
+	args := m.Called(emails)
+	return args.Error(0)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockUserQuotaRepository) WithTx(tx *gorm.DB) repository.UserQuotaRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockUserQuotaRepository) WithTenant(tenantID string) repository.UserQuotaRepository {
+	m.Tenant = tenantID
+	return m
+}
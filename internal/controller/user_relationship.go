@@ -2,12 +2,21 @@ package controller
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
 	"github.com/quanluong166/friends_management/internal/repository"
 	"github.com/quanluong166/friends_management/pkg/utils"
 	"gorm.io/gorm"
 )
 
+// eventResumeWindow is how long before the last event of a resuming client its events are listed again
+const eventResumeWindow = time.Minute
+
 // UserRelationshipController defines the business logic for managing user relationships
 type UserRelationshipController interface {
 	AddFriendship(requestor, target string) error
@@ -15,18 +24,48 @@ type UserRelationshipController interface {
 	ListCommonFriends(email1, email2 string) ([]string, int64, error)
 	AddSubscriber(requestor, target string) error
 	AddBlock(requestor, target string) error
-	GetListEmailCanReceiveUpdate(updaterEmail, text string) ([]string, error)
+	IsBlocking(requestor, target string) (bool, error)
+	GetListEmailCanReceiveUpdate(updaterEmail, text string) (*Recipients, error)
+	ListSubscribers(email string, limit, offset int) ([]string, int64, error)
+	ListBlocks(requestor string, limit, offset int) ([]string, int64, error)
+	ListFriendshipsAsOf(email string, asOf time.Time) ([]string, int64, error)
+	ListSubscribersAsOf(email string, asOf time.Time, limit, offset int) ([]string, int64, error)
+	ListBlocksAsOf(requestor string, asOf time.Time, limit, offset int) ([]string, int64, error)
+	ListEventsSince(email string, afterID uint, limit int) ([]model.OutboxEvent, error)
+	ResumeEventsAfter(email string, lastID uint) (uint, error)
+	RemoveFriendship(email1, email2 string) error
+	RemoveSubscriber(requestor, target string) error
+	RemoveBlock(requestor, target string) error
+	ListFriendshipsByEmails(emails []string) (map[string][]string, error)
+	ListSubscribersByEmails(emails []string) (map[string][]string, error)
+	ListSubscriptionsByEmails(emails []string) (map[string][]string, error)
+	ListBlockConnectionsByEmails(emails []string) (map[string][]string, error)
+	WithTenant(tenantID string) UserRelationshipController
+	WithActor(actor Actor) UserRelationshipController
 }
 
 type userRelationshipController struct {
-	db                   *gorm.DB
-	userRelationshipRepo repository.UserRelationshipRepository
+	db                    *gorm.DB
+	userRelationshipRepo  repository.UserRelationshipRepository
+	relationshipEventRepo repository.RelationshipEventRepository
+	outboxEventRepo       repository.OutboxEventRepository
+	preferenceRepo        repository.NotificationPreferenceRepository
+	quota                 quotaChecker
+	actor                 Actor
+	mentionPolicy         string
 }
 
-func NewUserRelationshipController(db *gorm.DB, repo repository.UserRelationshipRepository) UserRelationshipController {
+// NewUserRelationshipController create the controller, quotas are the caps of every email unless an admin override them
+// and the mention policy decides who can be reached by mentioning their email in an update
+func NewUserRelationshipController(db *gorm.DB, repo repository.UserRelationshipRepository, relationshipEventRepo repository.RelationshipEventRepository, outboxEventRepo repository.OutboxEventRepository, userQuotaRepo repository.UserQuotaRepository, preferenceRepo repository.NotificationPreferenceRepository, quotas Quota, mentionPolicy string) UserRelationshipController {
 	return &userRelationshipController{
-		userRelationshipRepo: repo,
-		db:                   db,
+		userRelationshipRepo:  repo,
+		relationshipEventRepo: relationshipEventRepo,
+		outboxEventRepo:       outboxEventRepo,
+		preferenceRepo:        preferenceRepo,
+		db:                    db,
+		quota:                 quotaChecker{defaults: quotas, userRelationshipRepo: repo, userQuotaRepo: userQuotaRepo, relationshipEventRepo: relationshipEventRepo},
+		mentionPolicy:         mentionPolicy,
 	}
 }
 
@@ -38,7 +77,7 @@ func (uc *userRelationshipController) AddFriendship(email1, email2 string) error
 	}
 
 	if isBlock {
-		return errors.New("ONE_OF_YOU_BLOCK_EACH_OTHER")
+		return apperror.ErrBlocked
 	}
 
 	isFriend, err := uc.userRelationshipRepo.CheckTwoUsersAreFriends(email1, email2)
@@ -47,20 +86,41 @@ func (uc *userRelationshipController) AddFriendship(email1, email2 string) error
 	}
 
 	if isFriend {
-		return errors.New("YOU_ALREADY_FRIENDS")
+		return apperror.ErrAlreadyFriends
 	}
 
 	return uc.db.Transaction(func(tx *gorm.DB) error {
-		err := uc.userRelationshipRepo.CreateFriendRelationship(email1, email2)
+		//A friendship counts toward the friends of both users
+		quota := uc.quota.withTx(tx)
+		if err := quota.lock(email1, email2); err != nil {
+			return err
+		}
+		if err := quota.check(email1, constant.FRIEND_RELATIONSHIP_TYPE); err != nil {
+			return err
+		}
+		if err := quota.checkFriend(email2); err != nil {
+			return err
+		}
+
+		userRelationshipRepo := uc.userRelationshipRepo.WithTx(tx)
+		err := userRelationshipRepo.CreateFriendRelationship(email1, email2)
 		if err != nil {
-			return errors.New("CREATE_FRIST_FRIENDSHIP_RELATION_FAILED: " + err.Error())
+			return fmt.Errorf("CREATE_FRIST_FRIENDSHIP_RELATION_FAILED: %w", err)
 		}
 
-		err = uc.userRelationshipRepo.CreateFriendRelationship(email2, email1)
+		err = userRelationshipRepo.CreateFriendRelationship(email2, email1)
 		if err != nil {
-			return errors.New("CREATE_SECOND_FRIENDSHIP_RELATION_FAILED: " + err.Error())
+			return fmt.Errorf("CREATE_SECOND_FRIENDSHIP_RELATION_FAILED: %w", err)
+		}
+
+		after := []model.RelationshipState{
+			{Requestor: email1, Target: email2, Type: constant.FRIEND_RELATIONSHIP_TYPE},
+			{Requestor: email2, Target: email1, Type: constant.FRIEND_RELATIONSHIP_TYPE},
+		}
+		if err := recordEvent(uc.relationshipEventRepo.WithTx(tx), uc.actor, constant.RELATIONSHIP_EVENT_CREATE, email1, email2, nil, after); err != nil {
+			return err
 		}
-		return nil
+		return publishEvent(uc.outboxEventRepo.WithTx(tx), constant.DOMAIN_EVENT_FRIENDSHIP_CREATED, email1, email2)
 	})
 }
 
@@ -68,7 +128,7 @@ func (uc *userRelationshipController) AddFriendship(email1, email2 string) error
 func (uc *userRelationshipController) ListFriendships(email string) ([]string, int64, error) {
 	friendships, err := uc.userRelationshipRepo.GetListFriendshipEmail(email)
 	if err != nil {
-		return nil, 0, errors.New("GET_LIST_FRIENDSHIP_FAIL: " + err.Error())
+		return nil, 0, fmt.Errorf("GET_LIST_FRIENDSHIP_FAIL: %w", err)
 	}
 	return friendships, int64(len(friendships)), nil
 }
@@ -77,21 +137,21 @@ func (uc *userRelationshipController) ListFriendships(email string) ([]string, i
 func (uc *userRelationshipController) ListCommonFriends(email1, email2 string) ([]string, int64, error) {
 	isBlock, err := uc.userRelationshipRepo.CheckTwoUsersBlockedEachOther(email1, email2)
 	if err != nil {
-		return nil, 0, errors.New("CHECK_TWO_USERS_BLOCK_EACH_OTHER_FAIL: " + err.Error())
+		return nil, 0, fmt.Errorf("CHECK_TWO_USERS_BLOCK_EACH_OTHER_FAIL: %w", err)
 	}
 
 	if isBlock {
-		return nil, 0, errors.New("ONE_OF_YOU_BLOCK_EACH_OTHER")
+		return nil, 0, apperror.ErrBlocked
 	}
 
 	friendships1, err := uc.userRelationshipRepo.GetListFriendshipEmail(email1)
 	if err != nil {
-		return nil, 0, errors.New("GET_LIST_FRIENDSHIP_FOR_FIRST_EMAIL_FAIL: " + err.Error())
+		return nil, 0, fmt.Errorf("GET_LIST_FRIENDSHIP_FOR_FIRST_EMAIL_FAIL: %w", err)
 	}
 
 	friendships2, err := uc.userRelationshipRepo.GetListFriendshipEmail(email2)
 	if err != nil {
-		return nil, 0, errors.New("GET_LIST_FRIENDSHIP_FOR_SECOND_EMAIL_FAIL: " + err.Error())
+		return nil, 0, fmt.Errorf("GET_LIST_FRIENDSHIP_FOR_SECOND_EMAIL_FAIL: %w", err)
 	}
 
 	commonFriends := utils.FindCommon(friendships1, friendships2)
@@ -102,25 +162,43 @@ func (uc *userRelationshipController) ListCommonFriends(email1, email2 string) (
 func (uc *userRelationshipController) AddSubscriber(requestor, target string) error {
 	//Check if user already subcribe
 	isSubscribe, err := uc.userRelationshipRepo.CheckIfTheRequestorAlreadySubscribe(requestor, target)
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return errors.New("CHECK_IF_THE_REQUESTOR_ALREADY_SUBSCRIBE_FAIL: " + err.Error())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("CHECK_IF_THE_REQUESTOR_ALREADY_SUBSCRIBE_FAIL: %w", err)
 	}
 
 	if isSubscribe {
-		return errors.New("YOU_ALREADY_SUBSCRIBED")
+		return apperror.ErrAlreadySubscribed
 	}
 
 	//Check if target is blocked by requestor or vice versa
 	isBlock, err := uc.userRelationshipRepo.CheckTwoUsersBlockedEachOther(requestor, target)
 	if err != nil {
-		return errors.New("CHECK_TWO_USERS_BLOCK_EACH_OTHER_FAIL: " + err.Error())
+		return fmt.Errorf("CHECK_TWO_USERS_BLOCK_EACH_OTHER_FAIL: %w", err)
 	}
 
 	if isBlock {
-		return errors.New("ONE_OF_YOU_BLOCK_EACH_OTHER")
+		return apperror.ErrBlocked
 	}
 
-	return uc.userRelationshipRepo.AddSubscriber(requestor, target)
+	return uc.db.Transaction(func(tx *gorm.DB) error {
+		quota := uc.quota.withTx(tx)
+		if err := quota.lock(requestor); err != nil {
+			return err
+		}
+		if err := quota.check(requestor, constant.SUBSCRIBER_RELATIONSHIOP_TYPE); err != nil {
+			return err
+		}
+
+		if err := uc.userRelationshipRepo.WithTx(tx).AddSubscriber(requestor, target); err != nil {
+			return err
+		}
+
+		after := []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}}
+		if err := recordEvent(uc.relationshipEventRepo.WithTx(tx), uc.actor, constant.RELATIONSHIP_EVENT_CREATE, requestor, target, nil, after); err != nil {
+			return err
+		}
+		return publishEvent(uc.outboxEventRepo.WithTx(tx), constant.DOMAIN_EVENT_SUBSCRIBED, requestor, target)
+	})
 }
 
 // AddBlock support create block and delete the other connection between two emails
@@ -128,50 +206,282 @@ func (uc *userRelationshipController) AddBlock(requestor, target string) error {
 	//Check if target is blocked by requestor or vice versa
 	isBlock, err := uc.userRelationshipRepo.CheckTwoUsersBlockedEachOther(requestor, target)
 	if err != nil {
-		return errors.New("CHECK_TWO_USERS_BLOCK_EACH_OTHER_FAIL: " + err.Error())
+		return fmt.Errorf("CHECK_TWO_USERS_BLOCK_EACH_OTHER_FAIL: %w", err)
 	}
 
 	if isBlock {
-		return errors.New("ALREADY_BEEN_BLOCKED")
+		return apperror.ErrAlreadyBlocked
 	}
 
 	//Delete all relationship of the requestor and target and then create new block connection
 	return uc.db.Transaction(func(tx *gorm.DB) error {
-		err := uc.userRelationshipRepo.DeleteRelationship(requestor, target)
+		quota := uc.quota.withTx(tx)
+		if err := quota.lock(requestor); err != nil {
+			return err
+		}
+		if err := quota.check(requestor, constant.BLOCK_RELATIONSHIP_TYPE); err != nil {
+			return err
+		}
+
+		userRelationshipRepo := uc.userRelationshipRepo.WithTx(tx)
+		//The connections replaced by the block are kept as the state before it
+		before, err := userRelationshipRepo.GetRelationshipsBetween(requestor, target)
+		if err != nil {
+			return fmt.Errorf("GET_RELATIONSHIPS_BETWEEN_FAIL: %w", err)
+		}
+
+		err = userRelationshipRepo.DeleteRelationship(requestor, target)
+		if err != nil {
+			return fmt.Errorf("DELETE_REQUESTOR_RELATIONSHIP_FAIL: %w", err)
+		}
+
+		err = userRelationshipRepo.DeleteRelationship(target, requestor)
+		if err != nil {
+			return fmt.Errorf("DELETE_TARGET_RELATIONSHIP_FAIL: %w", err)
+		}
+
+		err = userRelationshipRepo.CreateBlockRelationship(requestor, target)
+		if err != nil {
+			return fmt.Errorf("CREATE_BLOCK_RELATIONSHIP_FAILED: %w", err)
+		}
+
+		after := []model.RelationshipState{{Requestor: requestor, Target: target, Type: constant.BLOCK_RELATIONSHIP_TYPE}}
+		if err := recordEvent(uc.relationshipEventRepo.WithTx(tx), uc.actor, constant.RELATIONSHIP_EVENT_BLOCK, requestor, target, relationshipStates(before), after); err != nil {
+			return err
+		}
+		return publishEvent(uc.outboxEventRepo.WithTx(tx), constant.DOMAIN_EVENT_BLOCKED, requestor, target)
+	})
+}
+
+// IsBlocking support check whether the requestor blocks the target, a block of the target on the requestor does not count
+func (uc *userRelationshipController) IsBlocking(requestor, target string) (bool, error) {
+	relationships, err := uc.userRelationshipRepo.GetRelationshipsBetween(requestor, target)
+	if err != nil {
+		return false, fmt.Errorf("GET_RELATIONSHIPS_BETWEEN_FAIL: %w", err)
+	}
+
+	for _, relationship := range relationships {
+		if relationship.Type == constant.BLOCK_RELATIONSHIP_TYPE && strings.EqualFold(relationship.RequestorEmail, requestor) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetListEmailCanReceiveUpdate function to support get list of email can receive update from the updater,
+// split between the emails that receive it now and the emails in their quiet hours
+func (uc *userRelationshipController) GetListEmailCanReceiveUpdate(updaterEmail, text string) (*Recipients, error) {
+	mentioned, err := mentionedRecipients(uc.userRelationshipRepo, uc.preferenceRepo, uc.mentionPolicy, updaterEmail, text)
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := updateRecipients(uc.userRelationshipRepo, updaterEmail, mentioned)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := uc.preferenceRepo.ListByEmails(recipients)
+	if err != nil {
+		return nil, fmt.Errorf("LIST_NOTIFICATION_PREFERENCES_FAIL: %w", err)
+	}
+
+	plan := planDelivery(preferences, recipients, text, time.Now())
+	return &plan, nil
+}
+
+// updateRecipients get the friends and subscribers of the updater and the mentioned emails the update reaches
+func updateRecipients(repo repository.UserRelationshipRepository, updaterEmail string, mentioned []string) ([]string, error) {
+	friendships, err := repo.GetListFriendshipEmail(updaterEmail)
+	if err != nil {
+		return nil, fmt.Errorf("GET_LIST_FRIENDSHIP_EMAIL_FAIL: %w", err)
+	}
+
+	subscribers, err := repo.GetListSubscriberEmail(updaterEmail)
+	if err != nil {
+		return nil, fmt.Errorf("GET_LIST_SUBSCRIBER_EMAIL_FAIL: %w", err)
+	}
+
+	//A mentioned friend or subscriber is only listed once, whatever the case of the mention
+	audience := utils.Combine(friendships, subscribers)
+	return utils.Combine(audience, utils.RemoveSameEmailsFromSecond(audience, mentioned)), nil
+}
+
+// ListSubscribers support get one page of subscriber emails of the email
+func (uc *userRelationshipController) ListSubscribers(email string, limit, offset int) ([]string, int64, error) {
+	subscribers, total, err := uc.userRelationshipRepo.GetListSubscriberEmailWithPagination(email, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GET_LIST_SUBSCRIBER_FAIL: %w", err)
+	}
+	return subscribers, total, nil
+}
+
+// ListBlocks support get one page of emails blocked by the requestor
+func (uc *userRelationshipController) ListBlocks(requestor string, limit, offset int) ([]string, int64, error) {
+	blocks, total, err := uc.userRelationshipRepo.GetListBlockedEmailWithPagination(requestor, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GET_LIST_BLOCK_FAIL: %w", err)
+	}
+	return blocks, total, nil
+}
+
+// ListFriendshipsAsOf support get list friend the email had at the time
+func (uc *userRelationshipController) ListFriendshipsAsOf(email string, asOf time.Time) ([]string, int64, error) {
+	friendships, err := uc.userRelationshipRepo.GetListFriendshipEmailAsOf(email, asOf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GET_LIST_FRIENDSHIP_AS_OF_FAIL: %w", err)
+	}
+	return friendships, int64(len(friendships)), nil
+}
+
+// ListSubscribersAsOf support get one page of subscriber emails the email had at the time
+func (uc *userRelationshipController) ListSubscribersAsOf(email string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	subscribers, total, err := uc.userRelationshipRepo.GetListSubscriberEmailAsOfWithPagination(email, asOf, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GET_LIST_SUBSCRIBER_AS_OF_FAIL: %w", err)
+	}
+	return subscribers, total, nil
+}
+
+// ListBlocksAsOf support get one page of emails blocked by the requestor at the time
+func (uc *userRelationshipController) ListBlocksAsOf(requestor string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	blocks, total, err := uc.userRelationshipRepo.GetListBlockedEmailAsOfWithPagination(requestor, asOf, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GET_LIST_BLOCK_AS_OF_FAIL: %w", err)
+	}
+	return blocks, total, nil
+}
+
+// ListEventsSince support get the domain events affecting the email after an event id, oldest first
+func (uc *userRelationshipController) ListEventsSince(email string, afterID uint, limit int) ([]model.OutboxEvent, error) {
+	events, err := uc.outboxEventRepo.ListForEmail(email, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("LIST_EVENTS_SINCE_FAIL: %w", err)
+	}
+	return events, nil
+}
+
+// ResumeEventsAfter support get the event id to list the events after for a client that received lastID, the events of the email
+// written within eventResumeWindow before lastID are listed again since a lower id can commit later
+func (uc *userRelationshipController) ResumeEventsAfter(email string, lastID uint) (uint, error) {
+	afterID, err := uc.outboxEventRepo.ResumeAfterID(email, lastID, eventResumeWindow)
+	if err != nil {
+		return 0, fmt.Errorf("RESUME_EVENTS_FAIL: %w", err)
+	}
+	return afterID, nil
+}
+
+// RemoveFriendship support delete the friend connection in both directions
+func (uc *userRelationshipController) RemoveFriendship(email1, email2 string) error {
+	return uc.db.Transaction(func(tx *gorm.DB) error {
+		userRelationshipRepo := uc.userRelationshipRepo.WithTx(tx)
+		deleted, err := userRelationshipRepo.DeleteRelationshipByType(email1, email2, constant.FRIEND_RELATIONSHIP_TYPE)
 		if err != nil {
-			return errors.New("DELETE_REQUESTOR_RELATIONSHIP_FAIL: " + err.Error())
+			return fmt.Errorf("DELETE_FIRST_FRIENDSHIP_RELATION_FAIL: %w", err)
+		}
+
+		if deleted == 0 {
+			return apperror.NotFound("FRIENDSHIP_NOT_FOUND")
 		}
 
-		err = uc.userRelationshipRepo.DeleteRelationship(target, requestor)
+		reverseDeleted, err := userRelationshipRepo.DeleteRelationshipByType(email2, email1, constant.FRIEND_RELATIONSHIP_TYPE)
 		if err != nil {
-			return errors.New("DELETE_TARGET_RELATIONSHIP_FAIL: " + err.Error())
+			return fmt.Errorf("DELETE_SECOND_FRIENDSHIP_RELATION_FAIL: %w", err)
+		}
+
+		before := []model.RelationshipState{{Requestor: email1, Target: email2, Type: constant.FRIEND_RELATIONSHIP_TYPE}}
+		if reverseDeleted > 0 {
+			before = append(before, model.RelationshipState{Requestor: email2, Target: email1, Type: constant.FRIEND_RELATIONSHIP_TYPE})
+		}
+		if err := recordEvent(uc.relationshipEventRepo.WithTx(tx), uc.actor, constant.RELATIONSHIP_EVENT_DELETE, email1, email2, before, nil); err != nil {
+			return err
 		}
+		return publishEvent(uc.outboxEventRepo.WithTx(tx), constant.DOMAIN_EVENT_FRIENDSHIP_REMOVED, email1, email2)
+	})
+}
 
-		err = uc.userRelationshipRepo.CreateBlockRelationship(requestor, target)
+// RemoveSubscriber support delete the subscriber connection of the requestor to the target
+func (uc *userRelationshipController) RemoveSubscriber(requestor, target string) error {
+	return uc.removeRelationship(requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, "DELETE_SUBSCRIBER_RELATION_FAIL", "SUBSCRIPTION_NOT_FOUND")
+}
+
+// RemoveBlock support delete the block created by the requestor, a block created by the target is never removed
+func (uc *userRelationshipController) RemoveBlock(requestor, target string) error {
+	return uc.removeRelationship(requestor, target, constant.BLOCK_RELATIONSHIP_TYPE, "DELETE_BLOCK_RELATION_FAIL", "BLOCK_NOT_FOUND")
+}
+
+// removeRelationship support delete one type of connection from the requestor to the target and record it
+func (uc *userRelationshipController) removeRelationship(requestor, target, relationshipType, failMessage, notFoundMessage string) error {
+	return uc.db.Transaction(func(tx *gorm.DB) error {
+		deleted, err := uc.userRelationshipRepo.WithTx(tx).DeleteRelationshipByType(requestor, target, relationshipType)
 		if err != nil {
-			return errors.New("CREATE_BLOCK_RELATIONSHIP_FAILED: " + err.Error())
+			return fmt.Errorf("%s: %w", failMessage, err)
+		}
+
+		if deleted == 0 {
+			return apperror.NotFound(notFoundMessage)
 		}
-		return nil
+
+		before := []model.RelationshipState{{Requestor: requestor, Target: target, Type: relationshipType}}
+		if err := recordEvent(uc.relationshipEventRepo.WithTx(tx), uc.actor, constant.RELATIONSHIP_EVENT_DELETE, requestor, target, before, nil); err != nil {
+			return err
+		}
+		return publishEvent(uc.outboxEventRepo.WithTx(tx), removedEvents[relationshipType], requestor, target)
 	})
 }
 
-// GetListEmailCanReceiveUpdate function to support get list of email can receive update from the updater
-func (uc *userRelationshipController) GetListEmailCanReceiveUpdate(updaterEmail, text string) ([]string, error) {
-	friendships, err := uc.userRelationshipRepo.GetListFriendshipEmail(updaterEmail)
+// ListFriendshipsByEmails support get the friend emails of many emails at once, it is used to batch graph queries
+func (uc *userRelationshipController) ListFriendshipsByEmails(emails []string) (map[string][]string, error) {
+	friendships, err := uc.userRelationshipRepo.GetTargetEmailsByRequestors(emails, constant.FRIEND_RELATIONSHIP_TYPE)
+	if err != nil {
+		return nil, fmt.Errorf("GET_LIST_FRIENDSHIP_BY_EMAILS_FAIL: %w", err)
+	}
+	return friendships, nil
+}
+
+// ListSubscribersByEmails support get the subscriber emails of many emails at once
+func (uc *userRelationshipController) ListSubscribersByEmails(emails []string) (map[string][]string, error) {
+	subscribers, err := uc.userRelationshipRepo.GetRequestorEmailsByTargets(emails, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+	if err != nil {
+		return nil, fmt.Errorf("GET_LIST_SUBSCRIBER_BY_EMAILS_FAIL: %w", err)
+	}
+	return subscribers, nil
+}
+
+// ListSubscriptionsByEmails support get the emails that each of many emails subscribe to
+func (uc *userRelationshipController) ListSubscriptionsByEmails(emails []string) (map[string][]string, error) {
+	subscriptions, err := uc.userRelationshipRepo.GetTargetEmailsByRequestors(emails, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
 	if err != nil {
-		return nil, errors.New("GET_LIST_FRIENDSHIP_EMAIL_FAIL: " + err.Error())
+		return nil, fmt.Errorf("GET_LIST_SUBSCRIPTION_BY_EMAILS_FAIL: %w", err)
 	}
+	return subscriptions, nil
+}
 
-	subscribers, err := uc.userRelationshipRepo.GetListSubscriberEmail(updaterEmail)
+// ListBlockConnectionsByEmails support get, for many emails at once, the emails that block them or are blocked by them
+func (uc *userRelationshipController) ListBlockConnectionsByEmails(emails []string) (map[string][]string, error) {
+	blocks, err := uc.userRelationshipRepo.GetBlockConnectionEmailsByEmails(emails)
 	if err != nil {
-		return nil, errors.New("GET_LIST_SUBSCRIBER_EMAIL_FAIL: " + err.Error())
+		return nil, fmt.Errorf("GET_LIST_BLOCK_CONNECTION_BY_EMAILS_FAIL: %w", err)
 	}
+	return blocks, nil
+}
 
-	if len(text) == 0 {
-		return utils.Combine(friendships, subscribers), nil
+// WithTenant return a controller that only see the relationships of the tenant
+func (uc *userRelationshipController) WithTenant(tenantID string) UserRelationshipController {
+	return &userRelationshipController{
+		userRelationshipRepo:  uc.userRelationshipRepo.WithTenant(tenantID),
+		relationshipEventRepo: uc.relationshipEventRepo.WithTenant(tenantID),
+		outboxEventRepo:       uc.outboxEventRepo.WithTenant(tenantID),
+		preferenceRepo:        uc.preferenceRepo.WithTenant(tenantID),
+		db:                    uc.db,
+		quota:                 uc.quota.withTenant(tenantID),
+		actor:                 uc.actor,
+		mentionPolicy:         uc.mentionPolicy,
 	}
+}
 
-	//Get email from text
-	mentionedEmails := utils.FindEmails(text)
-	return utils.Combine(friendships, subscribers, mentionedEmails), nil
+// WithActor return a controller that record the actor in the relationship events of its changes
+func (uc *userRelationshipController) WithActor(actor Actor) UserRelationshipController {
+	scoped := *uc
+	scoped.actor = actor
+	return &scoped
 }
@@ -1,11 +1,17 @@
 package controller
 
 import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type MockUserRelationshipRepository struct {
 	mock.Mock
+	Tenant string
 }
 
 func (m *MockUserRelationshipRepository) CreateFriendRelationship(email1, email2 string) error {
@@ -13,16 +19,29 @@ func (m *MockUserRelationshipRepository) CreateFriendRelationship(email1, email2
 	return args.Error(0)
 }
 
-func (m *MockUserRelationshipRepository) UpdateToFriendship(email1, email2 string) error {
-	args := m.Called(email1, email2)
-	return args.Error(0)
-}
-
 func (m *MockUserRelationshipRepository) GetListSubscriberEmail(target string) ([]string, error) {
 	args := m.Called(target)
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (m *MockUserRelationshipRepository) GetListSubscriberEmailWithPagination(target string, limit, offset int) ([]string, int64, error) {
+	args := m.Called(target, limit, offset)
+	var subscribers []string
+	if args.Get(0) != nil {
+		subscribers = args.Get(0).([]string)
+	}
+	return subscribers, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRelationshipRepository) GetListBlockedEmailWithPagination(requestor string, limit, offset int) ([]string, int64, error) {
+	args := m.Called(requestor, limit, offset)
+	var blocks []string
+	if args.Get(0) != nil {
+		blocks = args.Get(0).([]string)
+	}
+	return blocks, args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockUserRelationshipRepository) GetListFriendshipEmail(target string) ([]string, error) {
 	args := m.Called(target)
 	var friendships []string
@@ -32,6 +51,33 @@ func (m *MockUserRelationshipRepository) GetListFriendshipEmail(target string) (
 	return friendships, args.Error(1)
 }
 
+func (m *MockUserRelationshipRepository) GetListFriendshipEmailAsOf(requestor string, asOf time.Time) ([]string, error) {
+	args := m.Called(requestor, asOf)
+	var friendships []string
+	if args.Get(0) != nil {
+		friendships = args.Get(0).([]string)
+	}
+	return friendships, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetListSubscriberEmailAsOfWithPagination(target string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	args := m.Called(target, asOf, limit, offset)
+	var subscribers []string
+	if args.Get(0) != nil {
+		subscribers = args.Get(0).([]string)
+	}
+	return subscribers, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRelationshipRepository) GetListBlockedEmailAsOfWithPagination(requestor string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	args := m.Called(requestor, asOf, limit, offset)
+	var blocks []string
+	if args.Get(0) != nil {
+		blocks = args.Get(0).([]string)
+	}
+	return blocks, args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockUserRelationshipRepository) CheckTwoUsersBlockedEachOther(email1, email2 string) (bool, error) {
 	args := m.Called(email1, email2)
 	return args.Bool(0), args.Error(1)
@@ -61,3 +107,106 @@ func (m *MockUserRelationshipRepository) DeleteRelationship(requestorEmail, targ
 	args := m.Called(requestorEmail, targetEmail)
 	return args.Error(0)
 }
+
+func (m *MockUserRelationshipRepository) DeleteRelationshipByType(requestor, target, relationshipType string) (int64, error) {
+	args := m.Called(requestor, target, relationshipType)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetTargetEmailsByRequestors(requestors []string, relationshipType string) (map[string][]string, error) {
+	args := m.Called(requestors, relationshipType)
+	var emails map[string][]string
+	if args.Get(0) != nil {
+		emails = args.Get(0).(map[string][]string)
+	}
+	return emails, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetRequestorEmailsByTargets(targets []string, relationshipType string) (map[string][]string, error) {
+	args := m.Called(targets, relationshipType)
+	var emails map[string][]string
+	if args.Get(0) != nil {
+		emails = args.Get(0).(map[string][]string)
+	}
+	return emails, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetBlockConnectionEmailsByEmails(emails []string) (map[string][]string, error) {
+	args := m.Called(emails)
+	var blockEmails map[string][]string
+	if args.Get(0) != nil {
+		blockEmails = args.Get(0).(map[string][]string)
+	}
+	return blockEmails, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetContactEmails(email string, emails []string) ([]string, error) {
+	args := m.Called(email, emails)
+	var contactEmails []string
+	if args.Get(0) != nil {
+		contactEmails = args.Get(0).([]string)
+	}
+	return contactEmails, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetKnownEmails(emails []string) ([]string, error) {
+	args := m.Called(emails)
+	var knownEmails []string
+	if args.Get(0) != nil {
+		knownEmails = args.Get(0).([]string)
+	}
+	return knownEmails, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) ListRelationships(filter repository.RelationshipFilter) ([]model.UserRelationship, int64, error) {
+	args := m.Called(filter)
+	var relationships []model.UserRelationship
+	if args.Get(0) != nil {
+		relationships = args.Get(0).([]model.UserRelationship)
+	}
+	return relationships, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRelationshipRepository) GetRelationshipByID(id uint) (*model.UserRelationship, error) {
+	args := m.Called(id)
+	var relationship *model.UserRelationship
+	if args.Get(0) != nil {
+		relationship = args.Get(0).(*model.UserRelationship)
+	}
+	return relationship, args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) DeleteRelationshipByID(id uint) (int64, error) {
+	args := m.Called(id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) CountRelationshipsByType(requestor, relationshipType string) (int64, error) {
+	args := m.Called(requestor, relationshipType)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) CountFriendsAndSubscribers(email string) (int64, error) {
+	args := m.Called(email)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRelationshipRepository) GetRelationshipsBetween(email1, email2 string) ([]model.UserRelationship, error) {
+	args := m.Called(email1, email2)
+	var relationships []model.UserRelationship
+	if args.Get(0) != nil {
+		relationships = args.Get(0).([]model.UserRelationship)
+	}
+	return relationships, args.Error(1)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockUserRelationshipRepository) WithTx(tx *gorm.DB) repository.UserRelationshipRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockUserRelationshipRepository) WithTenant(tenantID string) repository.UserRelationshipRepository {
+	m.Tenant = tenantID
+	return m
+}
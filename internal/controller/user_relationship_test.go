@@ -3,12 +3,19 @@ package controller_test
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
 	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
 	"github.com/quanluong166/friends_management/pkg/helper"
 	"github.com/quanluong166/friends_management/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var mockDB *gorm.DB
@@ -219,7 +226,7 @@ func TestUserRealtionshipController_AddFriend(t *testing.T) {
 				callArgument := tc.callArgument[idx]
 				mockRepo.On(mockName, callArgument...).Return(argument...)
 			}
-			ctrl := controller.NewUserRelationshipController(tx, mockRepo)
+			ctrl := controller.NewUserRelationshipController(tx, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
 			err := ctrl.AddFriendship(email1, email2)
 			if tc.err != nil {
 				assert.EqualError(t, err, tc.err.Error())
@@ -285,7 +292,7 @@ func TestUserRealtionshipController_ListFriendships(t *testing.T) {
 				callArgument := tc.callArgument[idx]
 				mockRepo.On(mockName, callArgument...).Return(argument...)
 			}
-			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
 			actualList, actualCount, err := ctrl.ListFriendships(input)
 			if tc.err != nil {
 				assert.EqualError(t, err, "GET_LIST_FRIENDSHIP_FAIL: "+tc.err.Error())
@@ -301,6 +308,150 @@ func TestUserRealtionshipController_ListFriendships(t *testing.T) {
 	}
 }
 
+func TestUserRealtionshipController_ListSubscribers(t *testing.T) {
+	input := "test1@example.com"
+	expectedSubscriberEmails := []string{"subscriber1@example.com", "subscriber2@example.com"}
+
+	tcs := map[string]struct {
+		err            error
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"Error_DatabaseError": {
+			callArgument: [][]interface{}{
+				{
+					input, 20, 0,
+				},
+			},
+			err: errors.New("DATABASE_ERROR"),
+			mockOn: []string{
+				"GetListSubscriberEmailWithPagination",
+			},
+			returnArgument: [][]interface{}{
+				{
+					nil,
+					int64(0),
+					errors.New("DATABASE_ERROR"),
+				},
+			},
+		},
+		"Success": {
+			callArgument: [][]interface{}{
+				{
+					input, 20, 0,
+				},
+			},
+			err: nil,
+			mockOn: []string{
+				"GetListSubscriberEmailWithPagination",
+			},
+			returnArgument: [][]interface{}{
+				{
+					expectedSubscriberEmails,
+					int64(25),
+					nil,
+				},
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			for idx, mockName := range tc.mockOn {
+				argument := tc.returnArgument[idx]
+				callArgument := tc.callArgument[idx]
+				mockRepo.On(mockName, callArgument...).Return(argument...)
+			}
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+			actualList, actualCount, err := ctrl.ListSubscribers(input, 20, 0)
+			if tc.err != nil {
+				assert.EqualError(t, err, "GET_LIST_SUBSCRIBER_FAIL: "+tc.err.Error())
+				assert.Nil(t, actualList)
+				assert.Equal(t, int64(0), actualCount)
+			} else {
+				assert.Equal(t, expectedSubscriberEmails, actualList)
+				assert.Equal(t, int64(25), actualCount)
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRealtionshipController_ListBlocks(t *testing.T) {
+	input := "test1@example.com"
+	expectedBlockedEmails := []string{"blocked1@example.com"}
+
+	tcs := map[string]struct {
+		err            error
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"Error_DatabaseError": {
+			callArgument: [][]interface{}{
+				{
+					input, 10, 0,
+				},
+			},
+			err: errors.New("DATABASE_ERROR"),
+			mockOn: []string{
+				"GetListBlockedEmailWithPagination",
+			},
+			returnArgument: [][]interface{}{
+				{
+					nil,
+					int64(0),
+					errors.New("DATABASE_ERROR"),
+				},
+			},
+		},
+		"Success": {
+			callArgument: [][]interface{}{
+				{
+					input, 10, 0,
+				},
+			},
+			err: nil,
+			mockOn: []string{
+				"GetListBlockedEmailWithPagination",
+			},
+			returnArgument: [][]interface{}{
+				{
+					expectedBlockedEmails,
+					int64(1),
+					nil,
+				},
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			for idx, mockName := range tc.mockOn {
+				argument := tc.returnArgument[idx]
+				callArgument := tc.callArgument[idx]
+				mockRepo.On(mockName, callArgument...).Return(argument...)
+			}
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+			actualList, actualCount, err := ctrl.ListBlocks(input, 10, 0)
+			if tc.err != nil {
+				assert.EqualError(t, err, "GET_LIST_BLOCK_FAIL: "+tc.err.Error())
+				assert.Nil(t, actualList)
+				assert.Equal(t, int64(0), actualCount)
+			} else {
+				assert.Equal(t, expectedBlockedEmails, actualList)
+				assert.Equal(t, int64(1), actualCount)
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
 func TestUserRealtionshipController_ListCommonFriends(t *testing.T) {
 	email1 := "user1@example.com"
 	email2 := "user2@example.com"
@@ -485,7 +636,7 @@ func TestUserRealtionshipController_ListCommonFriends(t *testing.T) {
 				callArgument := tc.callArgument[idx]
 				mockRepo.On(mockName, callArgument...).Return(argument...)
 			}
-			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
 			actualList, actualCount, err := ctrl.ListCommonFriends(email1, email2)
 			if tc.err != nil {
 				assert.EqualError(t, err, tc.err.Error())
@@ -685,7 +836,15 @@ func TestUserRealtionshipController_AddSubscriber(t *testing.T) {
 				callArgument := tc.callArgument[idx]
 				mockRepo.On(mockName, callArgument...).Return(argument...)
 			}
-			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+			//The subscription is written in a transaction with its event, only the last cases reach it
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
 			err := ctrl.AddSubscriber(requestor, target)
 			if tc.err != nil {
 				assert.EqualError(t, err, tc.err.Error())
@@ -894,12 +1053,14 @@ func TestUserRealtionshipController_AddBlock(t *testing.T) {
 			mockRepo := new(controller.MockUserRelationshipRepository)
 			tx := mockDB.Begin()
 			defer tx.Rollback()
+			//The connections replaced by the block are only read for its event
+			mockRepo.On("GetRelationshipsBetween", requestor, target).Return(nil, nil).Maybe()
 			for idx, mockName := range tc.mockOn {
 				argument := tc.returnArgument[idx]
 				callArgument := tc.callArgument[idx]
 				mockRepo.On(mockName, callArgument...).Return(argument...)
 			}
-			ctrl := controller.NewUserRelationshipController(tx, mockRepo)
+			ctrl := controller.NewUserRelationshipController(tx, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
 			err := ctrl.AddBlock(requestor, target)
 			if tc.err != nil {
 				assert.EqualError(t, err, tc.err.Error())
@@ -1000,13 +1161,14 @@ func TestUserRealtionshipController_GetListEmailCanReceiveUpdate(t *testing.T) {
 				callArgument := tc.callArgument[idx]
 				mockRepo.On(mockName, callArgument...).Return(argument...)
 			}
-			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
 			actualList, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
 			if tc.err != nil {
 				assert.EqualError(t, err, tc.err.Error())
 				assert.Nil(t, actualList)
 			} else {
-				assert.Equal(t, utils.Combine(friendEmails, subscriberEmails), actualList)
+				assert.Equal(t, utils.Combine(friendEmails, subscriberEmails), actualList.Now)
+				assert.Empty(t, actualList.Deferred)
 				assert.NoError(t, err)
 			}
 			mockRepo.AssertExpectations(t)
@@ -1014,11 +1176,171 @@ func TestUserRealtionshipController_GetListEmailCanReceiveUpdate(t *testing.T) {
 	}
 }
 
+// setupMockTxDB support open gorm on sqlmock so transactions can run without a database
+func setupMockTxDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return db, mock
+}
+
+func TestUserRealtionshipController_RemoveFriendship(t *testing.T) {
+	email1 := "user1@example.com"
+	email2 := "user2@example.com"
+
+	tcs := map[string]struct {
+		err            error
+		commit         bool
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"Error_DeleteFirstRelationFailed": {
+			err:            errors.New("DELETE_FIRST_FRIENDSHIP_RELATION_FAIL: DATABASE_ERROR"),
+			mockOn:         []string{"DeleteRelationshipByType"},
+			callArgument:   [][]interface{}{{email1, email2, constant.FRIEND_RELATIONSHIP_TYPE}},
+			returnArgument: [][]interface{}{{int64(0), errors.New("DATABASE_ERROR")}},
+		},
+		"Error_NotFriends": {
+			err:            apperror.ErrNotFound,
+			mockOn:         []string{"DeleteRelationshipByType"},
+			callArgument:   [][]interface{}{{email1, email2, constant.FRIEND_RELATIONSHIP_TYPE}},
+			returnArgument: [][]interface{}{{int64(0), nil}},
+		},
+		"Error_DeleteSecondRelationFailed": {
+			err:    errors.New("DELETE_SECOND_FRIENDSHIP_RELATION_FAIL: DATABASE_ERROR"),
+			mockOn: []string{"DeleteRelationshipByType", "DeleteRelationshipByType"},
+			callArgument: [][]interface{}{
+				{email1, email2, constant.FRIEND_RELATIONSHIP_TYPE},
+				{email2, email1, constant.FRIEND_RELATIONSHIP_TYPE},
+			},
+			returnArgument: [][]interface{}{{int64(1), nil}, {int64(0), errors.New("DATABASE_ERROR")}},
+		},
+		"Success": {
+			commit: true,
+			mockOn: []string{"DeleteRelationshipByType", "DeleteRelationshipByType"},
+			callArgument: [][]interface{}{
+				{email1, email2, constant.FRIEND_RELATIONSHIP_TYPE},
+				{email2, email1, constant.FRIEND_RELATIONSHIP_TYPE},
+			},
+			returnArgument: [][]interface{}{{int64(1), nil}, {int64(1), nil}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.commit {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			for idx, mockName := range tc.mockOn {
+				mockRepo.On(mockName, tc.callArgument[idx]...).Return(tc.returnArgument[idx]...).Once()
+			}
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+			err := ctrl.RemoveFriendship(email1, email2)
+			if tc.err != nil {
+				if errors.Is(tc.err, apperror.ErrNotFound) {
+					assert.ErrorIs(t, err, apperror.ErrNotFound)
+				} else {
+					assert.EqualError(t, err, tc.err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRealtionshipController_RemoveSubscriberAndBlock(t *testing.T) {
+	requestor := "user1@example.com"
+	target := "user2@example.com"
+
+	tcs := map[string]struct {
+		relationshipType string
+		remove           func(ctrl controller.UserRelationshipController) error
+		deleted          int64
+		repoErr          error
+		err              error
+	}{
+		"RemoveSubscriber_Success": {
+			relationshipType: constant.SUBSCRIBER_RELATIONSHIOP_TYPE,
+			remove:           func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveSubscriber(requestor, target) },
+			deleted:          1,
+		},
+		"RemoveSubscriber_NotFound": {
+			relationshipType: constant.SUBSCRIBER_RELATIONSHIOP_TYPE,
+			remove:           func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveSubscriber(requestor, target) },
+			err:              apperror.NotFound("SUBSCRIPTION_NOT_FOUND"),
+		},
+		"RemoveSubscriber_DatabaseError": {
+			relationshipType: constant.SUBSCRIBER_RELATIONSHIOP_TYPE,
+			remove:           func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveSubscriber(requestor, target) },
+			repoErr:          errors.New("DATABASE_ERROR"),
+			err:              errors.New("DELETE_SUBSCRIBER_RELATION_FAIL: DATABASE_ERROR"),
+		},
+		"RemoveBlock_Success": {
+			relationshipType: constant.BLOCK_RELATIONSHIP_TYPE,
+			remove:           func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveBlock(requestor, target) },
+			deleted:          1,
+		},
+		"RemoveBlock_NotFound": {
+			relationshipType: constant.BLOCK_RELATIONSHIP_TYPE,
+			remove:           func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveBlock(requestor, target) },
+			err:              apperror.NotFound("BLOCK_NOT_FOUND"),
+		},
+		"RemoveBlock_DatabaseError": {
+			relationshipType: constant.BLOCK_RELATIONSHIP_TYPE,
+			remove:           func(ctrl controller.UserRelationshipController) error { return ctrl.RemoveBlock(requestor, target) },
+			repoErr:          errors.New("DATABASE_ERROR"),
+			err:              errors.New("DELETE_BLOCK_RELATION_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("DeleteRelationshipByType", requestor, target, tc.relationshipType).Return(tc.deleted, tc.repoErr)
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+			ctrl := controller.NewUserRelationshipController(db, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+			err := tc.remove(ctrl)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
 // 	t.Run("Error_DatabaseError", func(t *testing.T) {
 // 		mockRepo := new(controller.MockUserRelationshipRepository)
 // 		mockRepo.On("GetListFriendshipEmail", updaterEmail).Return(nil, errors.New("DATABASE_ERROR"))
 
-// 		ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+// 		ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, noQuotaOverride(), controller.Quota{})
 // 		actualList, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
 // 		assert.Nil(t, actualList)
 // 		assert.EqualError(t, err, "DATABASE_ERROR")
@@ -1034,7 +1356,7 @@ func TestUserRealtionshipController_GetListEmailCanReceiveUpdate(t *testing.T) {
 // 		mockRepo.On("GetListFriendshipEmail", updaterEmail).Return(friendEmails, nil)
 // 		mockRepo.On("GetListSubscriberEmail", updaterEmail).Return(subscriberEmails, nil)
 
-// 		ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+// 		ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, noQuotaOverride(), controller.Quota{})
 // 		actualList, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
 // 		assert.ElementsMatch(t, expectedEmails, actualList)
 // 		assert.NoError(t, err)
@@ -1046,10 +1368,267 @@ func TestUserRealtionshipController_GetListEmailCanReceiveUpdate(t *testing.T) {
 // 		mockRepo.On("GetListFriendshipEmail", updaterEmail).Return([]string{}, nil)
 // 		mockRepo.On("GetListSubscriberEmail", updaterEmail).Return([]string{}, nil)
 
-// 		ctrl := controller.NewUserRelationshipController(mockDB, mockRepo)
+// 		ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, noQuotaOverride(), controller.Quota{})
 // 		actualList, err := ctrl.GetListEmailCanReceiveUpdate(updaterEmail, text)
 // 		assert.Empty(t, actualList)
 // 		assert.NoError(t, err)
 // 		mockRepo.AssertExpectations(t)
 // 	})
 // }
+
+func TestUserRealtionshipController_ListByEmails(t *testing.T) {
+	emails := []string{"test1@example.com", "test2@example.com"}
+	expected := map[string][]string{"test1@example.com": {"test3@example.com"}}
+
+	tcs := map[string]struct {
+		list         func(ctrl controller.UserRelationshipController) (map[string][]string, error)
+		mockOn       string
+		callArgument []interface{}
+		errPrefix    string
+	}{
+		"ListFriendshipsByEmails": {
+			list:         func(ctrl controller.UserRelationshipController) (map[string][]string, error) { return ctrl.ListFriendshipsByEmails(emails) },
+			mockOn:       "GetTargetEmailsByRequestors",
+			callArgument: []interface{}{emails, constant.FRIEND_RELATIONSHIP_TYPE},
+			errPrefix:    "GET_LIST_FRIENDSHIP_BY_EMAILS_FAIL: ",
+		},
+		"ListSubscribersByEmails": {
+			list:         func(ctrl controller.UserRelationshipController) (map[string][]string, error) { return ctrl.ListSubscribersByEmails(emails) },
+			mockOn:       "GetRequestorEmailsByTargets",
+			callArgument: []interface{}{emails, constant.SUBSCRIBER_RELATIONSHIOP_TYPE},
+			errPrefix:    "GET_LIST_SUBSCRIBER_BY_EMAILS_FAIL: ",
+		},
+		"ListSubscriptionsByEmails": {
+			list:         func(ctrl controller.UserRelationshipController) (map[string][]string, error) { return ctrl.ListSubscriptionsByEmails(emails) },
+			mockOn:       "GetTargetEmailsByRequestors",
+			callArgument: []interface{}{emails, constant.SUBSCRIBER_RELATIONSHIOP_TYPE},
+			errPrefix:    "GET_LIST_SUBSCRIPTION_BY_EMAILS_FAIL: ",
+		},
+		"ListBlockConnectionsByEmails": {
+			list:         func(ctrl controller.UserRelationshipController) (map[string][]string, error) { return ctrl.ListBlockConnectionsByEmails(emails) },
+			mockOn:       "GetBlockConnectionEmailsByEmails",
+			callArgument: []interface{}{emails},
+			errPrefix:    "GET_LIST_BLOCK_CONNECTION_BY_EMAILS_FAIL: ",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name+"_Success", func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On(tc.mockOn, tc.callArgument...).Return(expected, nil)
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+
+			actual, err := tc.list(ctrl)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, actual)
+			mockRepo.AssertExpectations(t)
+		})
+
+		t.Run(name+"_DatabaseError", func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On(tc.mockOn, tc.callArgument...).Return(nil, errors.New("DATABASE_ERROR"))
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+
+			actual, err := tc.list(ctrl)
+			assert.EqualError(t, err, tc.errPrefix+"DATABASE_ERROR")
+			assert.Nil(t, actual)
+		})
+	}
+}
+
+func TestUserRealtionshipController_ListAsOf(t *testing.T) {
+	email := "alice@example.com"
+	asOf := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tcs := map[string]struct {
+		mockOn         string
+		callArgument   []interface{}
+		returnArgument []interface{}
+		call           func(ctrl controller.UserRelationshipController) ([]string, int64, error)
+		err            error
+	}{
+		"Friendships": {
+			mockOn:         "GetListFriendshipEmailAsOf",
+			callArgument:   []interface{}{email, asOf},
+			returnArgument: []interface{}{[]string{"bob@example.com"}, nil},
+			call: func(ctrl controller.UserRelationshipController) ([]string, int64, error) {
+				return ctrl.ListFriendshipsAsOf(email, asOf)
+			},
+		},
+		"Subscribers": {
+			mockOn:         "GetListSubscriberEmailAsOfWithPagination",
+			callArgument:   []interface{}{email, asOf, 10, 0},
+			returnArgument: []interface{}{[]string{"bob@example.com"}, int64(1), nil},
+			call: func(ctrl controller.UserRelationshipController) ([]string, int64, error) {
+				return ctrl.ListSubscribersAsOf(email, asOf, 10, 0)
+			},
+		},
+		"Blocks": {
+			mockOn:         "GetListBlockedEmailAsOfWithPagination",
+			callArgument:   []interface{}{email, asOf, 10, 0},
+			returnArgument: []interface{}{[]string{"bob@example.com"}, int64(1), nil},
+			call: func(ctrl controller.UserRelationshipController) ([]string, int64, error) {
+				return ctrl.ListBlocksAsOf(email, asOf, 10, 0)
+			},
+		},
+		"Error_Friendships": {
+			mockOn:         "GetListFriendshipEmailAsOf",
+			callArgument:   []interface{}{email, asOf},
+			returnArgument: []interface{}{nil, errors.New("DATABASE_ERROR")},
+			call: func(ctrl controller.UserRelationshipController) ([]string, int64, error) {
+				return ctrl.ListFriendshipsAsOf(email, asOf)
+			},
+			err: errors.New("GET_LIST_FRIENDSHIP_AS_OF_FAIL: DATABASE_ERROR"),
+		},
+		"Error_Subscribers": {
+			mockOn:         "GetListSubscriberEmailAsOfWithPagination",
+			callArgument:   []interface{}{email, asOf, 10, 0},
+			returnArgument: []interface{}{nil, int64(0), errors.New("DATABASE_ERROR")},
+			call: func(ctrl controller.UserRelationshipController) ([]string, int64, error) {
+				return ctrl.ListSubscribersAsOf(email, asOf, 10, 0)
+			},
+			err: errors.New("GET_LIST_SUBSCRIBER_AS_OF_FAIL: DATABASE_ERROR"),
+		},
+		"Error_Blocks": {
+			mockOn:         "GetListBlockedEmailAsOfWithPagination",
+			callArgument:   []interface{}{email, asOf, 10, 0},
+			returnArgument: []interface{}{nil, int64(0), errors.New("DATABASE_ERROR")},
+			call: func(ctrl controller.UserRelationshipController) ([]string, int64, error) {
+				return ctrl.ListBlocksAsOf(email, asOf, 10, 0)
+			},
+			err: errors.New("GET_LIST_BLOCK_AS_OF_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On(tc.mockOn, tc.callArgument...).Return(tc.returnArgument...)
+
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), publishEvents(), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+			actualList, actualCount, err := tc.call(ctrl)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				assert.Nil(t, actualList)
+				assert.Equal(t, int64(0), actualCount)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"bob@example.com"}, actualList)
+				assert.Equal(t, int64(1), actualCount)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRealtionshipController_ResumeEventsAfter(t *testing.T) {
+	tcs := map[string]struct {
+		returnArgument []interface{}
+		expected       uint
+		err            string
+	}{
+		"Success": {
+			returnArgument: []interface{}{uint(39), nil},
+			expected:       39,
+		},
+		"Error": {
+			returnArgument: []interface{}{uint(0), errors.New("DATABASE_ERROR")},
+			err:            "RESUME_EVENTS_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockOutboxRepo := new(controller.MockOutboxEventRepository)
+			mockOutboxRepo.On("ResumeAfterID", "alice@example.com", uint(41), time.Minute).Return(tc.returnArgument...)
+
+			ctrl := controller.NewUserRelationshipController(mockDB, new(controller.MockUserRelationshipRepository), recordEvents(), mockOutboxRepo, noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE).WithTenant("tenant-b")
+			actual, err := ctrl.ResumeEventsAfter("alice@example.com", 41)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, "tenant-b", mockOutboxRepo.Tenant)
+			mockOutboxRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRealtionshipController_ListEventsSince(t *testing.T) {
+	events := []model.OutboxEvent{{ID: 42, Type: constant.DOMAIN_EVENT_BLOCKED, RequestorEmail: "bob@example.com", TargetEmail: "alice@example.com"}}
+
+	tcs := map[string]struct {
+		returnArgument []interface{}
+		expected       []model.OutboxEvent
+		err            string
+	}{
+		"Success": {
+			returnArgument: []interface{}{events, nil},
+			expected:       events,
+		},
+		"Error": {
+			returnArgument: []interface{}{nil, errors.New("DATABASE_ERROR")},
+			err:            "LIST_EVENTS_SINCE_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockOutboxRepo := new(controller.MockOutboxEventRepository)
+			mockOutboxRepo.On("ListForEmail", "alice@example.com", uint(41), 100).Return(tc.returnArgument...)
+
+			ctrl := controller.NewUserRelationshipController(mockDB, new(controller.MockUserRelationshipRepository), recordEvents(), mockOutboxRepo, noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE).WithTenant("tenant-b")
+			actual, err := ctrl.ListEventsSince("alice@example.com", 41, 100)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, "tenant-b", mockOutboxRepo.Tenant)
+			mockOutboxRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRealtionshipController_IsBlocking(t *testing.T) {
+	tcs := map[string]struct {
+		returnArgument []interface{}
+		expected       bool
+		err            string
+	}{
+		"Blocking": {
+			returnArgument: []interface{}{[]model.UserRelationship{{RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", Type: constant.BLOCK_RELATIONSHIP_TYPE}}, nil},
+			expected:       true,
+		},
+		"BlockedByTheTarget": {
+			returnArgument: []interface{}{[]model.UserRelationship{{RequestorEmail: "bob@example.com", TargetEmail: "alice@example.com", Type: constant.BLOCK_RELATIONSHIP_TYPE}}, nil},
+		},
+		"OtherConnection": {
+			returnArgument: []interface{}{[]model.UserRelationship{{RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}}, nil},
+		},
+		"Error": {
+			returnArgument: []interface{}{nil, errors.New("DATABASE_ERROR")},
+			err:            "GET_RELATIONSHIPS_BETWEEN_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(controller.MockUserRelationshipRepository)
+			mockRepo.On("GetRelationshipsBetween", "alice@example.com", "bob@example.com").Return(tc.returnArgument...)
+
+			ctrl := controller.NewUserRelationshipController(mockDB, mockRepo, recordEvents(), new(controller.MockOutboxEventRepository), noQuotaOverride(), noPreferences(), controller.Quota{}, constant.MENTION_POLICY_ANYONE)
+			actual, err := ctrl.IsBlocking("alice@example.com", "bob@example.com")
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, actual)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// WebhookController defines how admins manage the webhooks of partner apps, every action is audited
+type WebhookController interface {
+	CreateWebhook(actor Actor, webhook *model.Webhook) error
+	ListWebhooks(actor Actor) ([]model.Webhook, error)
+	DeleteWebhook(actor Actor, id uint) error
+	ListDeadLetters(actor Actor, webhookID uint, limit, offset int) ([]model.WebhookDeadLetter, int64, error)
+	ReplayDeadLetter(actor Actor, webhookID, id uint) error
+	WithTenant(tenantID string) WebhookController
+}
+
+type webhookController struct {
+	db                  *gorm.DB
+	webhookRepo         repository.WebhookRepository
+	webhookDeliveryRepo repository.WebhookDeliveryRepository
+	adminAuditLogRepo   repository.AdminAuditLogRepository
+}
+
+func NewWebhookController(db *gorm.DB, webhookRepo repository.WebhookRepository, webhookDeliveryRepo repository.WebhookDeliveryRepository, adminAuditLogRepo repository.AdminAuditLogRepository) WebhookController {
+	return &webhookController{
+		db:                  db,
+		webhookRepo:         webhookRepo,
+		webhookDeliveryRepo: webhookDeliveryRepo,
+		adminAuditLogRepo:   adminAuditLogRepo,
+	}
+}
+
+// CreateWebhook support register a webhook with a new secret, the secret is only returned by this call
+func (wc *webhookController) CreateWebhook(actor Actor, hook *model.Webhook) error {
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return fmt.Errorf("GENERATE_WEBHOOK_SECRET_FAIL: %w", err)
+	}
+	hook.Secret = secret
+	hook.CreatedBy = actor.Subject
+	hook.CreatedAt = time.Now()
+
+	return wc.db.Transaction(func(tx *gorm.DB) error {
+		if err := wc.webhookRepo.WithTx(tx).Create(hook); err != nil {
+			return fmt.Errorf("CREATE_WEBHOOK_FAIL: %w", err)
+		}
+		return audit(wc.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_CREATE_WEBHOOK, hook)
+	})
+}
+
+// ListWebhooks support list every webhook of the tenant
+func (wc *webhookController) ListWebhooks(actor Actor) ([]model.Webhook, error) {
+	webhooks, err := wc.webhookRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("LIST_WEBHOOKS_FAIL: %w", err)
+	}
+
+	if err := audit(wc.adminAuditLogRepo, actor, constant.ADMIN_ACTION_LIST_WEBHOOKS, nil); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook support delete a webhook with its pending deliveries and dead letters
+func (wc *webhookController) DeleteWebhook(actor Actor, id uint) error {
+	return wc.db.Transaction(func(tx *gorm.DB) error {
+		deleted, err := wc.webhookRepo.WithTx(tx).DeleteByID(id)
+		if err != nil {
+			return fmt.Errorf("DELETE_WEBHOOK_FAIL: %w", err)
+		}
+
+		if deleted == 0 {
+			return apperror.NotFound("WEBHOOK_NOT_FOUND")
+		}
+		return audit(wc.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_DELETE_WEBHOOK, map[string]uint{"id": id})
+	})
+}
+
+// ListDeadLetters support list the deliveries of a webhook that failed every attempt
+func (wc *webhookController) ListDeadLetters(actor Actor, webhookID uint, limit, offset int) ([]model.WebhookDeadLetter, int64, error) {
+	deadLetters, total, err := wc.webhookRepo.ListDeadLetters(webhookID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("LIST_WEBHOOK_DEAD_LETTERS_FAIL: %w", err)
+	}
+
+	details := map[string]interface{}{"webhook_id": webhookID, "limit": limit, "offset": offset}
+	if err := audit(wc.adminAuditLogRepo, actor, constant.ADMIN_ACTION_LIST_WEBHOOK_DEAD_LETTERS, details); err != nil {
+		return nil, 0, err
+	}
+	return deadLetters, total, nil
+}
+
+// ReplayDeadLetter support queue a dead letter again, it gets a full set of attempts
+func (wc *webhookController) ReplayDeadLetter(actor Actor, webhookID, id uint) error {
+	return wc.db.Transaction(func(tx *gorm.DB) error {
+		webhookRepo := wc.webhookRepo.WithTx(tx)
+		deadLetter, err := webhookRepo.GetDeadLetter(webhookID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.NotFound("DEAD_LETTER_NOT_FOUND")
+		}
+		if err != nil {
+			return fmt.Errorf("GET_WEBHOOK_DEAD_LETTER_FAIL: %w", err)
+		}
+
+		now := time.Now()
+		err = wc.webhookDeliveryRepo.WithTx(tx).Enqueue(&model.WebhookDelivery{
+			WebhookID:     deadLetter.WebhookID,
+			EventID:       deadLetter.EventID,
+			EventType:     deadLetter.EventType,
+			Payload:       deadLetter.Payload,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("ENQUEUE_WEBHOOK_DELIVERY_FAIL: %w", err)
+		}
+
+		if err := webhookRepo.DeleteDeadLetter(deadLetter.ID); err != nil {
+			return fmt.Errorf("DELETE_WEBHOOK_DEAD_LETTER_FAIL: %w", err)
+		}
+		return audit(wc.adminAuditLogRepo.WithTx(tx), actor, constant.ADMIN_ACTION_REPLAY_WEBHOOK_DEAD_LETTER, deadLetter)
+	})
+}
+
+// WithTenant return a controller that manage the webhooks of the tenant
+func (wc *webhookController) WithTenant(tenantID string) WebhookController {
+	return &webhookController{
+		db:                  wc.db,
+		webhookRepo:         wc.webhookRepo.WithTenant(tenantID),
+		webhookDeliveryRepo: wc.webhookDeliveryRepo.WithTenant(tenantID),
+		adminAuditLogRepo:   wc.adminAuditLogRepo.WithTenant(tenantID),
+	}
+}
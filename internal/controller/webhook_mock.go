@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockWebhookRepository) Create(webhook *model.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) List() ([]model.Webhook, error) {
+	args := m.Called()
+	var webhooks []model.Webhook
+	if args.Get(0) != nil {
+		webhooks = args.Get(0).([]model.Webhook)
+	}
+	return webhooks, args.Error(1)
+}
+
+func (m *MockWebhookRepository) DeleteByID(id uint) (int64, error) {
+	args := m.Called(id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListDeadLetters(webhookID uint, limit, offset int) ([]model.WebhookDeadLetter, int64, error) {
+	args := m.Called(webhookID, limit, offset)
+	var deadLetters []model.WebhookDeadLetter
+	if args.Get(0) != nil {
+		deadLetters = args.Get(0).([]model.WebhookDeadLetter)
+	}
+	return deadLetters, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockWebhookRepository) GetDeadLetter(webhookID, id uint) (*model.WebhookDeadLetter, error) {
+	args := m.Called(webhookID, id)
+	var deadLetter *model.WebhookDeadLetter
+	if args.Get(0) != nil {
+		deadLetter = args.Get(0).(*model.WebhookDeadLetter)
+	}
+	return deadLetter, args.Error(1)
+}
+
+func (m *MockWebhookRepository) DeleteDeadLetter(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockWebhookRepository) WithTx(tx *gorm.DB) repository.WebhookRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockWebhookRepository) WithTenant(tenantID string) repository.WebhookRepository {
+	m.Tenant = tenantID
+	return m
+}
+
+type MockWebhookDeliveryRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockWebhookDeliveryRepository) Enqueue(delivery *model.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookDeliveryRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	args := m.Called(now, lease, limit)
+	var deliveries []model.WebhookDelivery
+	if args.Get(0) != nil {
+		deliveries = args.Get(0).([]model.WebhookDelivery)
+	}
+	return deliveries, args.Error(1)
+}
+
+func (m *MockWebhookDeliveryRepository) MarkDelivered(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockWebhookDeliveryRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(id, attempts, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockWebhookDeliveryRepository) MoveToDeadLetter(delivery *model.WebhookDelivery, lastError string, at time.Time) error {
+	args := m.Called(delivery, lastError, at)
+	return args.Error(0)
+}
+
+// WithTx return the same mock so expectations are shared inside transactions
+func (m *MockWebhookDeliveryRepository) WithTx(tx *gorm.DB) repository.WebhookDeliveryRepository {
+	return m
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockWebhookDeliveryRepository) WithTenant(tenantID string) repository.WebhookDeliveryRepository {
+	m.Tenant = tenantID
+	return m
+}
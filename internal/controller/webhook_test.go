@@ -0,0 +1,185 @@
+package controller_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestWebhookController_CreateWebhook(t *testing.T) {
+	tcs := map[string]struct {
+		createErr error
+		err       error
+	}{
+		"Success": {},
+		"Error_CreateFailed": {
+			createErr: errors.New("DATABASE_ERROR"),
+			err:       errors.New("CREATE_WEBHOOK_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockWebhookRepo := new(controller.MockWebhookRepository)
+			mockWebhookRepo.On("Create", mock.AnythingOfType("*model.Webhook")).Return(tc.createErr)
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.err == nil {
+				mockAuditRepo.On("Create", mock.MatchedBy(func(log *model.AdminAuditLog) bool {
+					//The secret must never be written to the audit log
+					return log.Action == constant.ADMIN_ACTION_CREATE_WEBHOOK && !strings.Contains(log.Details, constant.WEBHOOK_SECRET_PREFIX)
+				})).Return(nil)
+			}
+
+			webhook := &model.Webhook{URL: "https://partner.example.com/hook", EventTypes: []string{constant.DOMAIN_EVENT_BLOCKED}}
+			ctrl := controller.NewWebhookController(db, mockWebhookRepo, new(controller.MockWebhookDeliveryRepository), mockAuditRepo)
+			err := ctrl.CreateWebhook(admin, webhook)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(webhook.Secret, constant.WEBHOOK_SECRET_PREFIX))
+				assert.Len(t, webhook.Secret, len(constant.WEBHOOK_SECRET_PREFIX)+64)
+				assert.Equal(t, admin.Subject, webhook.CreatedBy)
+				assert.False(t, webhook.CreatedAt.IsZero())
+			}
+			mockAuditRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookController_DeleteWebhook(t *testing.T) {
+	tcs := map[string]struct {
+		deleted int64
+		err     error
+	}{
+		"Success": {
+			deleted: 1,
+		},
+		"Error_NotFound": {
+			err: apperror.NotFound("WEBHOOK_NOT_FOUND"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockWebhookRepo := new(controller.MockWebhookRepository)
+			mockWebhookRepo.On("DeleteByID", uint(3)).Return(tc.deleted, nil)
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.err == nil {
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_DELETE_WEBHOOK)).Return(nil)
+			}
+
+			ctrl := controller.NewWebhookController(db, mockWebhookRepo, new(controller.MockWebhookDeliveryRepository), mockAuditRepo)
+			err := ctrl.DeleteWebhook(admin, 3)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockAuditRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookController_ReplayDeadLetter(t *testing.T) {
+	deadLetter := &model.WebhookDeadLetter{ID: 9, WebhookID: 3, EventID: 42, EventType: constant.DOMAIN_EVENT_SUBSCRIBED, Payload: `{"id":42}`, Attempts: 8}
+
+	tcs := map[string]struct {
+		getErr     error
+		enqueueErr error
+		replayed   bool
+		err        error
+	}{
+		"Success": {
+			replayed: true,
+		},
+		"Error_NotFound": {
+			getErr: gorm.ErrRecordNotFound,
+			err:    apperror.NotFound("DEAD_LETTER_NOT_FOUND"),
+		},
+		"Error_EnqueueFailed": {
+			enqueueErr: errors.New("DATABASE_ERROR"),
+			err:        errors.New("ENQUEUE_WEBHOOK_DELIVERY_FAIL: DATABASE_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.err == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			mockWebhookRepo := new(controller.MockWebhookRepository)
+			if tc.getErr != nil {
+				mockWebhookRepo.On("GetDeadLetter", uint(3), uint(9)).Return(nil, tc.getErr)
+			} else {
+				mockWebhookRepo.On("GetDeadLetter", uint(3), uint(9)).Return(deadLetter, nil)
+			}
+			mockDeliveryRepo := new(controller.MockWebhookDeliveryRepository)
+			mockDeliveryRepo.On("Enqueue", mock.MatchedBy(func(delivery *model.WebhookDelivery) bool {
+				return delivery.WebhookID == 3 && delivery.EventID == 42 && delivery.Payload == deadLetter.Payload && delivery.Attempts == 0 && !delivery.NextAttemptAt.IsZero()
+			})).Return(tc.enqueueErr)
+			mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+			if tc.replayed {
+				mockWebhookRepo.On("DeleteDeadLetter", uint(9)).Return(nil).Once()
+				mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_REPLAY_WEBHOOK_DEAD_LETTER)).Return(nil)
+			}
+
+			ctrl := controller.NewWebhookController(db, mockWebhookRepo, mockDeliveryRepo, mockAuditRepo)
+			err := ctrl.ReplayDeadLetter(admin, 3, 9)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockWebhookRepo.AssertExpectations(t)
+			mockAuditRepo.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookController_WithTenant(t *testing.T) {
+	mockWebhookRepo := new(controller.MockWebhookRepository)
+	mockWebhookRepo.On("List").Return([]model.Webhook{}, nil)
+	mockDeliveryRepo := new(controller.MockWebhookDeliveryRepository)
+	mockAuditRepo := new(controller.MockAdminAuditLogRepository)
+	mockAuditRepo.On("Create", auditLogFor(constant.ADMIN_ACTION_LIST_WEBHOOKS)).Return(nil)
+
+	ctrl := controller.NewWebhookController(nil, mockWebhookRepo, mockDeliveryRepo, mockAuditRepo).WithTenant("tenant-b")
+	_, err := ctrl.ListWebhooks(admin)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-b", mockWebhookRepo.Tenant)
+	assert.Equal(t, "tenant-b", mockDeliveryRepo.Tenant)
+	assert.Equal(t, "tenant-b", mockAuditRepo.Tenant)
+}
@@ -19,6 +19,29 @@ import (
 // User for init repository functions
 var DB *gorm.DB
 
+// relationshipEventsAppendOnly make the database refuse to update or delete a relationship event
+const relationshipEventsAppendOnly = `
+CREATE OR REPLACE FUNCTION relationship_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'relationship_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS relationship_events_append_only ON relationship_events;
+CREATE TRIGGER relationship_events_append_only BEFORE UPDATE OR DELETE ON relationship_events
+	FOR EACH ROW EXECUTE FUNCTION relationship_events_append_only();
+`
+
+// relationshipHistoryBackfill open the history of the relationships created before the history was kept, it is safe to run again
+const relationshipHistoryBackfill = `
+INSERT INTO relationship_histories (tenant_id, requestor_email, target_email, type, valid_from)
+SELECT r.tenant_id, r.requestor_email, r.target_email, r.type, r.created_at FROM user_relationships r
+WHERE NOT EXISTS (
+	SELECT 1 FROM relationship_histories h
+	WHERE h.tenant_id = r.tenant_id AND h.requestor_email = r.requestor_email AND h.target_email = r.target_email
+		AND h.type = r.type AND h.valid_to IS NULL
+);
+`
+
 func InitDB(c config.AppConfig) *gorm.DB {
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
@@ -43,7 +66,22 @@ func InitDB(c config.AppConfig) *gorm.DB {
 
 	DB = db
 
-	if err := db.AutoMigrate(&model.UserRelationship{}); err != nil {
+	if err := db.AutoMigrate(&model.UserRelationship{}, &model.IdempotencyKey{}, &model.AdminAuditLog{}, &model.RateLimitBucket{}, &model.UserQuota{}, &model.RelationshipEvent{}, &model.RelationshipHistory{}, &model.OutboxEvent{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.WebhookDeadLetter{}, &model.StatusUpdate{}, &model.FeedEntry{}, &model.EmailDelivery{}, &model.DigestEntry{}, &model.NotificationPreference{}); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	//Idempotency keys were unique per requestor before multi tenancy, the old index would make tenants collide
+	if db.Migrator().HasIndex(&model.IdempotencyKey{}, "idx_idempotency_key_requestor") {
+		if err := db.Migrator().DropIndex(&model.IdempotencyKey{}, "idx_idempotency_key_requestor"); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+
+	if err := db.Exec(relationshipEventsAppendOnly).Error; err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	if err := db.Exec(relationshipHistoryBackfill).Error; err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 	return DB
@@ -60,5 +98,6 @@ func MigrateUp(db *gorm.DB) error {
 	if execErr := db.Exec(string(data)).Error; execErr != nil {
 		return execErr
 	}
-	return nil
+	//The seed writes the relationships directly, their history has to be opened too
+	return db.Exec(relationshipHistoryBackfill).Error
 }
@@ -0,0 +1,506 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+const (
+	//LIST_SIZE is how many items the cost assumes a list field returns, nested lists multiply it
+	LIST_SIZE = 10
+	//costCap keep the cost of absurdly nested queries from overflowing
+	costCap = 1 << 30
+)
+
+var errInvalidQuery = errors.New("INVALID_GRAPHQL_QUERY")
+
+// QueryCost estimate how many fields the operation of the query resolves before it runs. Every field returning a user or a list
+// costs one for each time it may be resolved, the scalar fields of a user are free. A list is assumed to hold LIST_SIZE items.
+// graphql-go does not expose its query parser so the query is read here, a query it cannot read returns an error and is left
+// to the schema to reject.
+func QueryCost(schema *types.Schema, query, operationName string) (int, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return 0, err
+	}
+
+	p := &parser{tokens: tokens}
+	document, err := p.document()
+	if err != nil {
+		return 0, err
+	}
+
+	operation, err := document.operation(operationName)
+	if err != nil {
+		return 0, err
+	}
+
+	c := coster{schema: schema, fragments: document.fragments, visiting: map[string]bool{}}
+	return max(c.cost(operation.selections, schema.EntryPoints[operation.kind]), 1), nil
+}
+
+// coster add up the cost of the selections, fragments are expanded where they are spread
+type coster struct {
+	schema    *types.Schema
+	fragments map[string]fragment
+	//visiting are the fragments being expanded, a fragment spreading itself is invalid and is not expanded again
+	visiting map[string]bool
+}
+
+func (c *coster) cost(selections []selection, parent types.NamedType) int {
+	total := 0
+	for _, sel := range selections {
+		switch {
+		case sel.spread != "":
+			f, ok := c.fragments[sel.spread]
+			if !ok || c.visiting[sel.spread] {
+				continue
+			}
+			c.visiting[sel.spread] = true
+			total += c.cost(f.selections, c.typeOf(f.typeCondition, parent))
+			delete(c.visiting, sel.spread)
+		case sel.field == "":
+			total += c.cost(sel.selections, c.typeOf(sel.typeCondition, parent))
+		default:
+			named, list := unwrap(fieldType(parent, sel.field))
+			if sel.selections == nil && !list {
+				continue
+			}
+			children := c.cost(sel.selections, named)
+			if list {
+				children *= LIST_SIZE
+			}
+			total += 1 + children
+		}
+		total = min(total, costCap)
+	}
+	return total
+}
+
+func (c *coster) typeOf(typeCondition string, parent types.NamedType) types.NamedType {
+	if typeCondition == "" {
+		return parent
+	}
+	return c.schema.Types[typeCondition]
+}
+
+// fieldType return the type of the field of the parent, nil when the parent has no such field
+func fieldType(parent types.NamedType, name string) types.Type {
+	var fields types.FieldsDefinition
+	switch t := parent.(type) {
+	case *types.ObjectTypeDefinition:
+		fields = t.Fields
+	case *types.InterfaceTypeDefinition:
+		fields = t.Fields
+	}
+
+	if field := fields.Get(name); field != nil {
+		return field.Type
+	}
+	return nil
+}
+
+// unwrap return the named type inside the non null and list wrappers and whether it is a list
+func unwrap(t types.Type) (types.NamedType, bool) {
+	list := false
+	for {
+		switch wrapped := t.(type) {
+		case *types.NonNull:
+			t = wrapped.OfType
+		case *types.List:
+			list = true
+			t = wrapped.OfType
+		case types.NamedType:
+			return wrapped, list
+		default:
+			return nil, list
+		}
+	}
+}
+
+type selection struct {
+	//field is the name of a field, empty for a fragment
+	field string
+	//spread is the name of a spread fragment
+	spread string
+	//typeCondition is the type of an inline fragment, empty when it has none
+	typeCondition string
+	selections    []selection
+}
+
+type operation struct {
+	kind       string
+	name       string
+	selections []selection
+}
+
+type fragment struct {
+	typeCondition string
+	selections    []selection
+}
+
+type document struct {
+	operations []operation
+	fragments  map[string]fragment
+}
+
+// operation get the operation to run like the schema does, the only one of the document or the one with the name
+func (d document) operation(name string) (operation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return operation{}, fmt.Errorf("%w: the operation name is required", errInvalidQuery)
+		}
+		return d.operations[0], nil
+	}
+
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return operation{}, fmt.Errorf("%w: no operation %q", errInvalidQuery, name)
+}
+
+type tokenKind int
+
+const (
+	tokenPunctuator tokenKind = iota
+	tokenName
+	//tokenValue is a number or a string, the cost never needs its value
+	tokenValue
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex split the query in tokens, ignoring white spaces, commas and comments
+func lex(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, token{kind: tokenPunctuator, text: "..."})
+			i += 3
+		case strings.IndexByte("!$&()[]{}:=@|", ch) >= 0:
+			tokens = append(tokens, token{kind: tokenPunctuator, text: string(ch)})
+			i++
+		case ch == '_' || isLetter(ch):
+			start := i
+			for i < len(query) && (query[i] == '_' || isLetter(query[i]) || isDigit(query[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: query[start:i]})
+		case ch == '-' || isDigit(ch):
+			start := i
+			for i++; i < len(query) && (isDigit(query[i]) || strings.IndexByte(".eE+-", query[i]) >= 0); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenValue, text: query[start:i]})
+		case strings.HasPrefix(query[i:], `"""`):
+			end := blockStringEnd(query, i+3)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string", errInvalidQuery)
+			}
+			tokens = append(tokens, token{kind: tokenValue, text: query[i:end]})
+			i = end
+		case ch == '"':
+			end := stringEnd(query, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string", errInvalidQuery)
+			}
+			tokens = append(tokens, token{kind: tokenValue, text: query[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", errInvalidQuery, ch)
+		}
+	}
+	return tokens, nil
+}
+
+// stringEnd return the index after the closing quote of the string starting at i, -1 when it is not closed on its line
+func stringEnd(query string, i int) int {
+	for ; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		case '\n', '\r':
+			return -1
+		}
+	}
+	return -1
+}
+
+// blockStringEnd return the index after the closing triple quote of the block string starting at i, -1 when it is not closed
+func blockStringEnd(query string, i int) int {
+	for ; i < len(query); i++ {
+		if strings.HasPrefix(query[i:], `\"""`) {
+			i += 3
+			continue
+		}
+		if strings.HasPrefix(query[i:], `"""`) {
+			return i + 3
+		}
+	}
+	return -1
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// parser read the operations and fragments of a document, the arguments, variables and directives do not change the cost so
+// they are skipped
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// is check the next token is the punctuator or name
+func (p *parser) is(kind tokenKind, text string) bool {
+	tok, ok := p.peek()
+	return ok && tok.kind == kind && tok.text == text
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if !p.is(kind, text) {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	tok, ok := p.peek()
+	if !ok || tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	p.pos++
+	return tok.text, nil
+}
+
+func (p *parser) unexpected() error {
+	tok, ok := p.peek()
+	if !ok {
+		return fmt.Errorf("%w: unexpected end of query", errInvalidQuery)
+	}
+	return fmt.Errorf("%w: unexpected %q", errInvalidQuery, tok.text)
+}
+
+func (p *parser) document() (document, error) {
+	d := document{fragments: map[string]fragment{}}
+	for p.pos < len(p.tokens) {
+		if p.is(tokenName, "fragment") {
+			p.pos++
+			name, f, err := p.fragment()
+			if err != nil {
+				return document{}, err
+			}
+			d.fragments[name] = f
+			continue
+		}
+
+		op, err := p.operation()
+		if err != nil {
+			return document{}, err
+		}
+		d.operations = append(d.operations, op)
+	}
+	return d, nil
+}
+
+func (p *parser) operation() (operation, error) {
+	op := operation{kind: "query"}
+	if !p.is(tokenPunctuator, "{") {
+		kind, err := p.name()
+		if err != nil {
+			return operation{}, err
+		}
+		if kind != "query" && kind != "mutation" && kind != "subscription" {
+			p.pos--
+			return operation{}, p.unexpected()
+		}
+		op.kind = kind
+
+		if tok, ok := p.peek(); ok && tok.kind == tokenName {
+			op.name = tok.text
+			p.pos++
+		}
+		if err := p.skipParentheses(); err != nil {
+			return operation{}, err
+		}
+		if err := p.directives(); err != nil {
+			return operation{}, err
+		}
+	}
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return operation{}, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+func (p *parser) fragment() (string, fragment, error) {
+	name, err := p.name()
+	if err != nil {
+		return "", fragment{}, err
+	}
+	if err := p.expect(tokenName, "on"); err != nil {
+		return "", fragment{}, err
+	}
+	typeCondition, err := p.name()
+	if err != nil {
+		return "", fragment{}, err
+	}
+	if err := p.directives(); err != nil {
+		return "", fragment{}, err
+	}
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return "", fragment{}, err
+	}
+	return name, fragment{typeCondition: typeCondition, selections: selections}, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect(tokenPunctuator, "{"); err != nil {
+		return nil, err
+	}
+
+	selections := []selection{}
+	for !p.is(tokenPunctuator, "}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	p.pos++
+	return selections, nil
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.is(tokenPunctuator, "...") {
+		p.pos++
+		return p.fragmentSelection()
+	}
+
+	field, err := p.name()
+	if err != nil {
+		return selection{}, err
+	}
+	//An alias is followed by the name of the field
+	if p.is(tokenPunctuator, ":") {
+		p.pos++
+		if field, err = p.name(); err != nil {
+			return selection{}, err
+		}
+	}
+	if err := p.skipParentheses(); err != nil {
+		return selection{}, err
+	}
+	if err := p.directives(); err != nil {
+		return selection{}, err
+	}
+
+	sel := selection{field: field}
+	if p.is(tokenPunctuator, "{") {
+		if sel.selections, err = p.selectionSet(); err != nil {
+			return selection{}, err
+		}
+	}
+	return sel, nil
+}
+
+// fragmentSelection read what follows "...", a spread fragment or an inline fragment with an optional type condition
+func (p *parser) fragmentSelection() (selection, error) {
+	var sel selection
+	if tok, ok := p.peek(); ok && tok.kind == tokenName {
+		p.pos++
+		if tok.text != "on" {
+			sel.spread = tok.text
+			return sel, p.directives()
+		}
+
+		typeCondition, err := p.name()
+		if err != nil {
+			return selection{}, err
+		}
+		sel.typeCondition = typeCondition
+	}
+	if err := p.directives(); err != nil {
+		return selection{}, err
+	}
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return selection{}, err
+	}
+	sel.selections = selections
+	return sel, nil
+}
+
+func (p *parser) directives() error {
+	for p.is(tokenPunctuator, "@") {
+		p.pos++
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if err := p.skipParentheses(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipParentheses skip the arguments or variable definitions when the next token opens them
+func (p *parser) skipParentheses() error {
+	if !p.is(tokenPunctuator, "(") {
+		return nil
+	}
+
+	depth := 0
+	for ; p.pos < len(p.tokens); p.pos++ {
+		tok := p.tokens[p.pos]
+		if tok.kind != tokenPunctuator {
+			continue
+		}
+		switch tok.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		}
+		if depth == 0 {
+			p.pos++
+			return nil
+		}
+	}
+	return p.unexpected()
+}
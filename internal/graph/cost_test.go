@@ -0,0 +1,84 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/graph"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCost(t *testing.T) {
+	schema := graph.NewSchema(new(handler.MockUserRelationshipController), echo.New().Logger).ASTSchema()
+	tcs := map[string]struct {
+		query         string
+		operationName string
+		cost          int
+		hasErr        bool
+	}{
+		"Scalar fields are free": {
+			query: `{ user(email: "andy@example.com") { email } }`,
+			cost:  1,
+		},
+		"List of users": {
+			query: `{ user(email: "andy@example.com") { email friends { email } } }`,
+			cost:  2,
+		},
+		"Nested lists multiply": {
+			query: `{ user(email: "andy@example.com") { friends { friends { email } commonFriends(with: "john@example.com") { email } } } }`,
+			cost:  1 + 1 + graph.LIST_SIZE*2,
+		},
+		"List of strings": {
+			query: `{ user(email: "andy@example.com") { recipients(text: "hi, \"kate@example.com\"") } }`,
+			cost:  2,
+		},
+		"Aliases, directives and comments": {
+			query: `query Friends($email: String!, $skip: Boolean = false) {
+				# Every alias is resolved
+				a: user(email: $email) { friends @skip(if: $skip) { email } }
+				b: user(email: $email) { subscribers { email } }
+			}`,
+			cost: 4,
+		},
+		"Fragments": {
+			query: `query { user(email: "andy@example.com") { friends { ...Relationships } } }
+			fragment Relationships on User { friends { email } ... on User { subscribers { email } } }`,
+			cost: 1 + 1 + graph.LIST_SIZE*2,
+		},
+		"Fragment spreading itself": {
+			query: `{ user(email: "andy@example.com") { ...Self } } fragment Self on User { friends { ...Self } }`,
+			cost:  2,
+		},
+		"Named operation": {
+			query:         `query A { user(email: "a@example.com") { email } } query B { user(email: "b@example.com") { friends { email } } }`,
+			operationName: "B",
+			cost:          2,
+		},
+		"Missing operation name": {
+			query:  `query A { user(email: "a@example.com") { email } } query B { user(email: "b@example.com") { email } }`,
+			hasErr: true,
+		},
+		"Unterminated selection": {
+			query:  `{ user(email: "andy@example.com") { email }`,
+			hasErr: true,
+		},
+		"Unterminated string": {
+			query:  `{ user(email: "andy@example.com) { email } }`,
+			hasErr: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			cost, err := graph.QueryCost(schema, tc.query, tc.operationName)
+			if tc.hasErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.cost, cost)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+)
+
+// graphError expose the domain error code and invalid fields in the extensions of a graphql error
+type graphError struct {
+	err *apperror.Error
+}
+
+func (e graphError) Error() string {
+	return e.err.Message
+}
+
+func (e graphError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.err.Code}
+	if len(e.err.Fields) > 0 {
+		fields := make([]map[string]string, 0, len(e.err.Fields))
+		for _, field := range e.err.Fields {
+			fields = append(fields, map[string]string{"field": field.Field, "code": field.Code, "detail": field.Detail})
+		}
+		extensions["errors"] = fields
+	}
+	if e.err.Quota != nil {
+		extensions["quota"] = map[string]interface{}{"name": e.err.Quota.Name, "limit": e.err.Quota.Limit, "usage": e.err.Quota.Usage}
+	}
+	return extensions
+}
+
+// resolverError convert an error to a client safe graphql error, unknown errors are logged and reported as internal
+func resolverError(logger echo.Logger, err error) graphError {
+	appErr := apperror.As(err)
+	if appErr == nil {
+		logger.Errorf("graphql: %v", err)
+		appErr = apperror.ErrInternal
+	}
+	return graphError{appErr}
+}
@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+// Charge take cost tokens of the rate limit of the caller, it only returns an error when the caller has not enough left
+type Charge func(w http.ResponseWriter, r *http.Request, cost int) error
+
+// Handler serve graphql queries over http, every request gets its own loaders
+type Handler struct {
+	Controller controller.UserRelationshipController
+	schema     *graphql.Schema
+	charge     Charge
+}
+
+// NewHandler create the graphql handler, queries are charged by their cost unless charge is nil
+func NewHandler(schema *graphql.Schema, Controller controller.UserRelationshipController, charge Charge) *Handler {
+	return &Handler{Controller: Controller, schema: schema, charge: charge}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cost, err := QueryCost(h.schema.ASTSchema(), params.Query, params.OperationName)
+	if err != nil {
+		//A query the cost cannot be read of is invalid, it costs nothing more and the schema reports why
+		cost = 1
+	}
+
+	if cost > MAX_QUERY_COST {
+		detail := fmt.Sprintf("the query may resolve %d fields, the limit is %d", cost, MAX_QUERY_COST)
+		writeResponse(w, http.StatusOK, errorResponse(apperror.Validation("QUERY_IS_TOO_COMPLEX", []apperror.FieldError{{Field: "query", Code: apperror.FIELD_TOO_COMPLEX, Detail: detail}})))
+		return
+	}
+
+	if h.charge != nil {
+		//The rate limit middleware already took one token for the request
+		if err := h.charge(w, r, cost-1); err != nil {
+			writeResponse(w, http.StatusTooManyRequests, errorResponse(apperror.ErrRateLimited))
+			return
+		}
+	}
+
+	ctx := WithLoaders(r.Context(), NewLoaders(h.Controller.WithTenant(tenant.FromContext(r.Context()))))
+	writeResponse(w, http.StatusOK, h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+}
+
+// errorResponse build a response without data for an error raised before the query runs
+func errorResponse(err *apperror.Error) *graphql.Response {
+	graphErr := graphError{err}
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{{Message: graphErr.Error(), Extensions: graphErr.Extensions()}}}
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *graphql.Response) {
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(responseJSON)
+}
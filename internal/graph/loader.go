@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/quanluong166/friends_management/internal/controller"
+)
+
+// LOADER_WAIT is how long a loader collect keys before it runs one batch query
+const LOADER_WAIT = 2 * time.Millisecond
+
+type loadersKey struct{}
+
+// Loaders batch the relationship lookups of one request so a list of users is resolved with one query per field instead of one per user
+type Loaders struct {
+	Friends          *dataloader.Loader[string, []string]
+	Subscribers      *dataloader.Loader[string, []string]
+	Subscriptions    *dataloader.Loader[string, []string]
+	BlockConnections *dataloader.Loader[string, []string]
+}
+
+// NewLoaders create the loaders of one request, results are cached for the request only
+func NewLoaders(Controller controller.UserRelationshipController) *Loaders {
+	return &Loaders{
+		Friends:          newLoader(Controller.ListFriendshipsByEmails),
+		Subscribers:      newLoader(Controller.ListSubscribersByEmails),
+		Subscriptions:    newLoader(Controller.ListSubscriptionsByEmails),
+		BlockConnections: newLoader(Controller.ListBlockConnectionsByEmails),
+	}
+}
+
+// WithLoaders store the loaders in the request context
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+func loadersFrom(ctx context.Context) *Loaders {
+	return ctx.Value(loadersKey{}).(*Loaders)
+}
+
+// newLoader adapt a controller batch function, an email without connection gets an empty list
+func newLoader(fetch func(emails []string) (map[string][]string, error)) *dataloader.Loader[string, []string] {
+	batch := func(ctx context.Context, emails []string) []*dataloader.Result[[]string] {
+		results := make([]*dataloader.Result[[]string], len(emails))
+		connections, err := fetch(emails)
+		for i, email := range emails {
+			if err != nil {
+				results[i] = &dataloader.Result[[]string]{Error: err}
+				continue
+			}
+			results[i] = &dataloader.Result[[]string]{Data: connections[email]}
+		}
+		return results
+	}
+	return dataloader.NewBatchedLoader(batch, dataloader.WithWait[string, []string](LOADER_WAIT))
+}
@@ -0,0 +1,252 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"github.com/quanluong166/friends_management/pkg/utils"
+)
+
+// Resolver is the root query resolver, unexpected errors of the resolvers are logged with the Logger
+type Resolver struct {
+	Controller controller.UserRelationshipController
+	Logger     echo.Logger
+}
+
+// User resolver for get a user by email
+func (r *Resolver) User(ctx context.Context, args struct{ Email string }) (*UserResolver, error) {
+	if err := validateEmail("email", args.Email); err != nil {
+		return nil, resolverError(r.Logger, err)
+	}
+	return &UserResolver{email: args.Email, controller: r.Controller.WithTenant(tenant.FromContext(ctx)), logger: r.Logger}, nil
+}
+
+// UserResolver resolve the fields of one user, relationships are loaded through the request loaders
+type UserResolver struct {
+	email      string
+	controller controller.UserRelationshipController
+	logger     echo.Logger
+}
+
+func (u *UserResolver) Email() string {
+	return u.email
+}
+
+// Friends resolver for get friends of the user, the friends the user had at asOf are read from the history
+func (u *UserResolver) Friends(ctx context.Context, args struct{ AsOf *string }) ([]*UserResolver, error) {
+	asOf, err := parseAsOf(args.AsOf)
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	if asOf != nil {
+		emails, _, err := u.controller.ListFriendshipsAsOf(u.email, *asOf)
+		if err != nil {
+			return nil, resolverError(u.logger, err)
+		}
+		return u.users(emails), nil
+	}
+
+	emails, err := loadersFrom(ctx).Friends.Load(ctx, u.email)()
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+	return u.users(emails), nil
+}
+
+// Subscribers resolver for get users that subscribe to the user, the subscribers the user had at asOf are read from the history
+func (u *UserResolver) Subscribers(ctx context.Context, args struct{ AsOf *string }) ([]*UserResolver, error) {
+	asOf, err := parseAsOf(args.AsOf)
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	if asOf != nil {
+		emails, err := u.subscribersAsOf(*asOf)
+		if err != nil {
+			return nil, resolverError(u.logger, err)
+		}
+		return u.users(emails), nil
+	}
+
+	emails, err := loadersFrom(ctx).Subscribers.Load(ctx, u.email)()
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+	return u.users(emails), nil
+}
+
+// subscribersAsOf read every page of the subscribers the user had at the time
+func (u *UserResolver) subscribersAsOf(asOf time.Time) ([]string, error) {
+	var emails []string
+	for {
+		page, total, err := u.controller.ListSubscribersAsOf(u.email, asOf, constant.MAX_PAGE_LIMIT, len(emails))
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, page...)
+		if len(page) == 0 || int64(len(emails)) >= total {
+			return emails, nil
+		}
+	}
+}
+
+// Subscriptions resolver for get users the user subscribes to
+func (u *UserResolver) Subscriptions(ctx context.Context) ([]*UserResolver, error) {
+	emails, err := loadersFrom(ctx).Subscriptions.Load(ctx, u.email)()
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+	return u.users(emails), nil
+}
+
+// CommonFriends resolver for get friends shared with another user, it follows the rules of the common friends api.
+// Only one of the two users or an admin can ask.
+func (u *UserResolver) CommonFriends(ctx context.Context, args struct{ With string }) ([]*UserResolver, error) {
+	if err := validateEmail("with", args.With); err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	if err := authorizeActor(ctx, u.email, args.With); err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	loaders := loadersFrom(ctx)
+	blockThunk := loaders.BlockConnections.Load(ctx, u.email)
+	friendsThunk := loaders.Friends.Load(ctx, u.email)
+	otherFriendsThunk := loaders.Friends.Load(ctx, args.With)
+
+	blocks, err := blockThunk()
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	if ok, _ := utils.Contains(blocks, args.With); ok {
+		return nil, resolverError(u.logger, apperror.ErrBlocked)
+	}
+
+	friends, err := friendsThunk()
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	otherFriends, err := otherFriendsThunk()
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	return u.users(utils.FindCommon(friends, otherFriends)), nil
+}
+
+// Recipients resolver for get emails that receive an update of the user now, only the user or an admin can ask
+func (u *UserResolver) Recipients(ctx context.Context, args struct{ Text *string }) ([]string, error) {
+	recipients, err := u.recipients(ctx, args.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	if recipients.Now == nil {
+		return []string{}, nil
+	}
+	return recipients.Now, nil
+}
+
+// DeferredRecipients resolver for get recipients of an update of the user that receive it after their quiet hours,
+// only the user or an admin can ask
+func (u *UserResolver) DeferredRecipients(ctx context.Context, args struct{ Text *string }) ([]*DeferredRecipientResolver, error) {
+	recipients, err := u.recipients(ctx, args.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	deferred := make([]*DeferredRecipientResolver, 0, len(recipients.Deferred))
+	for _, recipient := range recipients.Deferred {
+		deferred = append(deferred, &DeferredRecipientResolver{recipient: recipient})
+	}
+	return deferred, nil
+}
+
+func (u *UserResolver) recipients(ctx context.Context, text *string) (*controller.Recipients, error) {
+	if err := authorizeActor(ctx, u.email); err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+
+	var updateText string
+	if text != nil {
+		updateText = *text
+	}
+
+	recipients, err := u.controller.GetListEmailCanReceiveUpdate(u.email, updateText)
+	if err != nil {
+		return nil, resolverError(u.logger, err)
+	}
+	return recipients, nil
+}
+
+// DeferredRecipientResolver resolve a recipient in its quiet hours
+type DeferredRecipientResolver struct {
+	recipient controller.DeferredRecipient
+}
+
+func (d *DeferredRecipientResolver) Email() string {
+	return d.recipient.Email
+}
+
+func (d *DeferredRecipientResolver) Until() string {
+	return d.recipient.Until.UTC().Format(time.RFC3339)
+}
+
+func (u *UserResolver) users(emails []string) []*UserResolver {
+	users := make([]*UserResolver, 0, len(emails))
+	for _, email := range emails {
+		users = append(users, &UserResolver{email: email, controller: u.controller, logger: u.logger})
+	}
+	return users
+}
+
+// authorizeActor check the authenticated caller can act as one of the emails, admins can act as anyone
+func authorizeActor(ctx context.Context, emails ...string) error {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return apperror.ErrUnauthenticated
+	}
+
+	for _, email := range emails {
+		if principal.CanActAs(email) {
+			return nil
+		}
+	}
+	return apperror.ErrForbidden
+}
+
+// parseAsOf read the optional asOf argument, it returns nil when it is not set
+func parseAsOf(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, apperror.Validation("INVALID_FILTER_INPUT", []apperror.FieldError{{Field: "asOf", Code: apperror.FIELD_INVALID_VALUE, Detail: "asOf must be an RFC 3339 time"}})
+	}
+	return &asOf, nil
+}
+
+func validateEmail(field, value string) error {
+	if len(strings.TrimSpace(value)) == 0 {
+		return apperror.Validation("EMAIL_IS_REQUIRED", []apperror.FieldError{{Field: field, Code: apperror.FIELD_REQUIRED, Detail: fmt.Sprintf("%s is required", field)}})
+	}
+
+	if !utils.IsValidEmail(value) {
+		return apperror.Validation("INVALID_EMAIL_INPUT", []apperror.FieldError{{Field: field, Code: apperror.FIELD_INVALID_EMAIL, Detail: fmt.Sprintf("%q is not a valid email", value)}})
+	}
+	return nil
+}
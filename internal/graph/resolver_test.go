@@ -0,0 +1,301 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/graph"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type graphResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, ctrl *handler.MockUserRelationshipController, query string) graphResponse {
+	t.Helper()
+	return executeAs(t, ctrl, &auth.Principal{Subject: "andy@example.com"}, query)
+}
+
+func executeAs(t *testing.T, ctrl *handler.MockUserRelationshipController, principal *auth.Principal, query string) graphResponse {
+	t.Helper()
+	h := graph.NewHandler(graph.NewSchema(ctrl, echo.New().Logger), ctrl, nil)
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(auth.NewContext(req.Context(), principal))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp graphResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+// emails match the batch keys in any order
+func emails(expected ...string) interface{} {
+	sort.Strings(expected)
+	return mock.MatchedBy(func(actual []string) bool {
+		sorted := append([]string(nil), actual...)
+		sort.Strings(sorted)
+		return strings.Join(sorted, ",") == strings.Join(expected, ",")
+	})
+}
+
+func TestGraph_NestedFriendsAreBatched(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListFriendshipsByEmails", emails("andy@example.com")).
+		Return(map[string][]string{"andy@example.com": {"john@example.com", "kate@example.com"}}, nil).Once()
+	ctrl.On("ListFriendshipsByEmails", emails("john@example.com", "kate@example.com")).
+		Return(map[string][]string{"john@example.com": {"andy@example.com", "kate@example.com"}, "kate@example.com": {"andy@example.com", "john@example.com"}}, nil).Once()
+	ctrl.On("ListSubscriptionsByEmails", emails("andy@example.com")).
+		Return(map[string][]string{"andy@example.com": {"lisa@example.com"}}, nil).Once()
+	ctrl.On("ListBlockConnectionsByEmails", emails("john@example.com", "kate@example.com")).
+		Return(map[string][]string{}, nil).Once()
+
+	resp := execute(t, ctrl, `{
+		user(email: "andy@example.com") {
+			email
+			subscriptions { email }
+			friends {
+				email
+				commonFriends(with: "andy@example.com") { email }
+			}
+		}
+	}`)
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"user": {
+		"email": "andy@example.com",
+		"subscriptions": [{"email": "lisa@example.com"}],
+		"friends": [
+			{"email": "john@example.com", "commonFriends": [{"email": "kate@example.com"}]},
+			{"email": "kate@example.com", "commonFriends": [{"email": "john@example.com"}]}
+		]
+	}}`, string(resp.Data))
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_CommonFriendsBlocked(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListBlockConnectionsByEmails", emails("andy@example.com")).
+		Return(map[string][]string{"andy@example.com": {"john@example.com"}}, nil)
+	ctrl.On("ListFriendshipsByEmails", mock.Anything).Return(map[string][]string{}, nil)
+
+	resp := execute(t, ctrl, `{ user(email: "andy@example.com") { commonFriends(with: "john@example.com") { email } } }`)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "ONE_OF_YOU_BLOCK_EACH_OTHER", resp.Errors[0].Message)
+	assert.Equal(t, apperror.CODE_BLOCKED, resp.Errors[0].Extensions["code"])
+}
+
+func TestGraph_SubscribersAndRecipients(t *testing.T) {
+	text := "hello kate@example.com"
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListSubscribersByEmails", emails("andy@example.com")).Return(map[string][]string{}, nil)
+	ctrl.On("GetListEmailCanReceiveUpdate", "andy@example.com", text).Return(&controller.Recipients{Now: []string{"kate@example.com"}}, nil)
+
+	resp := execute(t, ctrl, `{ user(email: "andy@example.com") { subscribers { email } recipients(text: "`+text+`") } }`)
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"user": {"subscribers": [], "recipients": ["kate@example.com"]}}`, string(resp.Data))
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_AsOf(t *testing.T) {
+	asOf := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListFriendshipsAsOf", "andy@example.com", asOf).Return([]string{"john@example.com"}, int64(1), nil)
+	//The subscribers are read page by page until the total is reached
+	ctrl.On("ListSubscribersAsOf", "andy@example.com", asOf, constant.MAX_PAGE_LIMIT, 0).Return([]string{"kate@example.com"}, int64(2), nil)
+	ctrl.On("ListSubscribersAsOf", "andy@example.com", asOf, constant.MAX_PAGE_LIMIT, 1).Return([]string{"lisa@example.com"}, int64(2), nil)
+
+	resp := execute(t, ctrl, `{ user(email: "andy@example.com") { friends(asOf: "2025-03-01T00:00:00Z") { email } subscribers(asOf: "2025-03-01T00:00:00Z") { email } } }`)
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"user": {"friends": [{"email": "john@example.com"}], "subscribers": [{"email": "kate@example.com"}, {"email": "lisa@example.com"}]}}`, string(resp.Data))
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_InvalidAsOf(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+
+	resp := execute(t, ctrl, `{ user(email: "andy@example.com") { friends(asOf: "2025-03-01") { email } } }`)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, apperror.CODE_INVALID_INPUT, resp.Errors[0].Extensions["code"])
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_DeferredRecipients(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("GetListEmailCanReceiveUpdate", "andy@example.com", "hello").Return(&controller.Recipients{
+		Now:      []string{"kate@example.com"},
+		Deferred: []controller.DeferredRecipient{{Email: "lisa@example.com", Until: time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC)}},
+	}, nil)
+
+	resp := execute(t, ctrl, `{ user(email: "andy@example.com") { recipients(text: "hello") deferredRecipients(text: "hello") { email until } } }`)
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"user": {"recipients": ["kate@example.com"], "deferredRecipients": [{"email": "lisa@example.com", "until": "2025-01-02T06:00:00Z"}]}}`, string(resp.Data))
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_DeferredRecipientsOfAnotherUser(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+
+	resp := executeAs(t, ctrl, &auth.Principal{Subject: "mallory@example.com"}, `{ user(email: "andy@example.com") { deferredRecipients { email } } }`)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, apperror.CODE_FORBIDDEN, resp.Errors[0].Extensions["code"])
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_RecipientsOfAnotherUser(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+
+	resp := executeAs(t, ctrl, &auth.Principal{Subject: "mallory@example.com"}, `{ user(email: "andy@example.com") { recipients } }`)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, apperror.CODE_FORBIDDEN, resp.Errors[0].Extensions["code"])
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_Errors(t *testing.T) {
+	testCases := map[string]struct {
+		query   string
+		mockOn  string
+		message string
+		code    string
+	}{
+		"Invalid email": {
+			query:   `{ user(email: "andy") { email } }`,
+			message: "INVALID_EMAIL_INPUT",
+			code:    apperror.CODE_INVALID_INPUT,
+		},
+		"Invalid with email": {
+			query:   `{ user(email: "andy@example.com") { commonFriends(with: "") { email } } }`,
+			message: "EMAIL_IS_REQUIRED",
+			code:    apperror.CODE_INVALID_INPUT,
+		},
+		"Database error is not exposed": {
+			query:   `{ user(email: "andy@example.com") { friends { email } } }`,
+			mockOn:  "ListFriendshipsByEmails",
+			message: "INTERNAL_SERVER_ERROR",
+			code:    apperror.CODE_INTERNAL,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := new(handler.MockUserRelationshipController)
+			if len(tc.mockOn) > 0 {
+				ctrl.On(tc.mockOn, mock.Anything).Return(nil, errors.New("GET_LIST_FRIENDSHIP_BY_EMAILS_FAIL: DATABASE_ERROR"))
+			}
+
+			resp := execute(t, ctrl, tc.query)
+
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tc.message, resp.Errors[0].Message)
+			assert.Equal(t, tc.code, resp.Errors[0].Extensions["code"])
+		})
+	}
+}
+
+func TestGraph_ChargeByCost(t *testing.T) {
+	query := `{ user(email: "andy@example.com") { friends { email } } }`
+	serve := func(ctrl *handler.MockUserRelationshipController, charge graph.Charge) *httptest.ResponseRecorder {
+		body, err := json.Marshal(map[string]string{"query": query})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{Subject: "andy@example.com"}))
+		rec := httptest.NewRecorder()
+		graph.NewHandler(graph.NewSchema(ctrl, echo.New().Logger), ctrl, charge).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Charged", func(t *testing.T) {
+		ctrl := new(handler.MockUserRelationshipController)
+		ctrl.On("ListFriendshipsByEmails", emails("andy@example.com")).Return(map[string][]string{}, nil)
+		var charged int
+
+		rec := serve(ctrl, func(w http.ResponseWriter, r *http.Request, cost int) error {
+			charged = cost
+			return nil
+		})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		//The rate limit middleware already took the first token
+		assert.Equal(t, 1, charged)
+		ctrl.AssertExpectations(t)
+	})
+
+	t.Run("Rate limited", func(t *testing.T) {
+		ctrl := new(handler.MockUserRelationshipController)
+
+		rec := serve(ctrl, func(w http.ResponseWriter, r *http.Request, cost int) error {
+			return apperror.ErrRateLimited
+		})
+
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		var resp graphResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, apperror.CODE_RATE_LIMITED, resp.Errors[0].Extensions["code"])
+		ctrl.AssertNotCalled(t, "ListFriendshipsByEmails", mock.Anything)
+	})
+}
+
+func TestGraph_CommonFriendsOfOtherUsers(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+
+	resp := executeAs(t, ctrl, &auth.Principal{Subject: "mallory@example.com"}, `{ user(email: "andy@example.com") { commonFriends(with: "john@example.com") { email } } }`)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, apperror.CODE_FORBIDDEN, resp.Errors[0].Extensions["code"])
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_TooComplex(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+
+	resp := execute(t, ctrl, `{ user(email: "andy@example.com") { friends { friends { friends { email } subscribers { email } } } } }`)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "QUERY_IS_TOO_COMPLEX", resp.Errors[0].Message)
+	assert.Equal(t, apperror.CODE_INVALID_INPUT, resp.Errors[0].Extensions["code"])
+	ctrl.AssertExpectations(t)
+}
+
+func TestGraph_TooDeep(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+
+	//Every nested field of a user is a list so the cost limit rejects a query this deep first, the schema rejects it too
+	resp := graph.NewSchema(ctrl, echo.New().Logger).Exec(context.Background(), `{ user(email: "andy@example.com") { friends { friends { friends { friends { email } } } } } }`, "", nil)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "depth")
+	ctrl.AssertExpectations(t)
+}
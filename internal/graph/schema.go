@@ -0,0 +1,55 @@
+package graph
+
+import (
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/controller"
+)
+
+// SCHEMA is the graph of users connected by their relationships
+const SCHEMA = `
+schema {
+	query: Query
+}
+
+type Query {
+	# Get the user with the email, every email is a user so it never returns null for a valid email
+	user(email: String!): User!
+}
+
+type User {
+	email: String!
+	# Friends of this user, at the RFC 3339 time asOf when it is set
+	friends(asOf: String): [User!]!
+	# Users that subscribe to updates of this user, at the RFC 3339 time asOf when it is set
+	subscribers(asOf: String): [User!]!
+	# Users this user subscribes to
+	subscriptions: [User!]!
+	# Friends shared with another user, fails when one of the two users blocks the other. Only one of the two users can ask
+	commonFriends(with: String!): [User!]!
+	# Emails that receive an update posted by this user now
+	recipients(text: String): [String!]!
+	# Recipients of an update posted by this user that are in their quiet hours, they receive it later
+	deferredRecipients(text: String): [DeferredRecipient!]!
+}
+
+type DeferredRecipient {
+	email: String!
+	# RFC 3339 time the quiet hours of the recipient end
+	until: String!
+}
+`
+
+const (
+	// MAX_PARALLELISM is how many fields are resolved concurrently, siblings resolved together are batched by the loaders
+	MAX_PARALLELISM = 50
+	// MAX_DEPTH is how deep the fields of a query can be nested, friends of friends of friends of a user is 5 levels with their email
+	MAX_DEPTH = 5
+	// MAX_QUERY_COST reject the queries that may resolve too many fields before they run, see QueryCost
+	MAX_QUERY_COST = 200
+)
+
+// NewSchema parse the schema with resolvers on top of the user relationship controller
+func NewSchema(Controller controller.UserRelationshipController, logger echo.Logger) *graphql.Schema {
+	return graphql.MustParseSchema(SCHEMA, &Resolver{Controller: Controller, Logger: logger}, graphql.MaxParallelism(MAX_PARALLELISM), graphql.MaxDepth(MAX_DEPTH))
+}
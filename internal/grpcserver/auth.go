@@ -0,0 +1,69 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	//Metadata keys of the credentials, the same headers as the REST api
+	METADATA_AUTHORIZATION = "authorization"
+	METADATA_API_KEY       = "x-api-key"
+	METADATA_TENANT_ID     = "x-tenant-id"
+
+	bearerPrefix = "bearer "
+)
+
+// AuthInterceptor reject calls without valid credentials and store the principal in the context
+func AuthInterceptor(authenticators ...auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		credentials := auth.Credentials{APIKey: first(md.Get(METADATA_API_KEY))}
+		authorization := first(md.Get(METADATA_AUTHORIZATION))
+		if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+			credentials.BearerToken = strings.TrimSpace(authorization[len(bearerPrefix):])
+		}
+
+		principal, err := auth.Authenticate(credentials, authenticators...)
+		if err != nil {
+			return nil, err
+		}
+		return next(auth.NewContext(ctx, principal), req)
+	}
+}
+
+// TenantInterceptor resolve the tenant of the call from the credentials or the x-tenant-id metadata, it must run after AuthInterceptor
+func TenantInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tenantID, err := tenant.Resolve(auth.FromContext(ctx), first(md.Get(METADATA_TENANT_ID)))
+	if err != nil {
+		return nil, err
+	}
+	return next(tenant.NewContext(ctx, tenantID), req)
+}
+
+// authorizeActor check the email acting in the call is the authenticated caller, admins can act as anyone
+func authorizeActor(ctx context.Context, email string) error {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return apperror.ErrUnauthenticated
+	}
+
+	if !principal.CanActAs(email) {
+		return apperror.ErrForbidden
+	}
+	return nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,92 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ERROR_DOMAIN is the domain of the ErrorInfo detail attached to every error status
+const ERROR_DOMAIN = "friends_management"
+
+// codeByErrorCode map domain error code to grpc status code
+var codeByErrorCode = map[string]codes.Code{
+	apperror.CODE_BAD_REQUEST:        codes.InvalidArgument,
+	apperror.CODE_INVALID_INPUT:      codes.InvalidArgument,
+	apperror.CODE_NOT_FOUND:          codes.NotFound,
+	apperror.CODE_ALREADY_FRIENDS:    codes.AlreadyExists,
+	apperror.CODE_ALREADY_SUBSCRIBED: codes.AlreadyExists,
+	apperror.CODE_ALREADY_BLOCKED:    codes.AlreadyExists,
+	apperror.CODE_BLOCKED:            codes.FailedPrecondition,
+	apperror.CODE_IDEMPOTENCY_IN_USE: codes.Aborted,
+	apperror.CODE_IDEMPOTENCY_REUSED: codes.InvalidArgument,
+	apperror.CODE_UNAUTHENTICATED:    codes.Unauthenticated,
+	apperror.CODE_FORBIDDEN:          codes.PermissionDenied,
+	apperror.CODE_RATE_LIMITED:       codes.ResourceExhausted,
+	apperror.CODE_QUOTA_EXCEEDED:     codes.ResourceExhausted,
+}
+
+// ErrorInterceptor convert errors returned by the rpc methods to grpc status, like the http error handler does for REST.
+// Internal failures are logged with the logger.
+func ErrorInterceptor(logger echo.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		resp, err := next(ctx, req)
+		if err != nil {
+			return nil, toStatus(logger, info.FullMethod, err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// toStatus build the status of an error, the domain code is sent as ErrorInfo reason and invalid fields as BadRequest violations
+func toStatus(logger echo.Logger, method string, err error) *status.Status {
+	if _, ok := status.FromError(err); ok {
+		return status.Convert(err)
+	}
+
+	appErr := apperror.As(err)
+	code, ok := codeByErrorCode[codeOf(appErr)]
+	if !ok {
+		//Everything else is an internal failure, the detail is logged and never returned to the client
+		logger.Errorf("%s: %v", method, err)
+		appErr = apperror.ErrInternal
+		code = codes.Internal
+	}
+
+	st := status.New(code, appErr.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: appErr.Code, Domain: ERROR_DOMAIN}}
+	if len(appErr.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(appErr.Fields))
+		for _, field := range appErr.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Code + ": " + field.Detail})
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	if appErr.Quota != nil {
+		details = append(details, &errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     appErr.Quota.Name,
+			Description: fmt.Sprintf("limit %d, usage %d", appErr.Quota.Limit, appErr.Quota.Usage),
+		}}})
+	}
+
+	withDetails, detailErr := st.WithDetails(details...)
+	if detailErr != nil {
+		return st
+	}
+	return withDetails
+}
+
+func codeOf(appErr *apperror.Error) string {
+	if appErr == nil {
+		return ""
+	}
+	return appErr.Code
+}
@@ -0,0 +1,306 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/grpcserver/friendspb"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FriendsServer is the gRPC transport of the user relationship api, it calls the same controller as the REST handlers
+type FriendsServer struct {
+	friendspb.UnimplementedFriendsServiceServer
+	Controller controller.UserRelationshipController
+}
+
+func NewFriendsServer(Controller controller.UserRelationshipController) friendspb.FriendsServiceServer {
+	return &FriendsServer{Controller: Controller}
+}
+
+// NewServer create a grpc server with the FriendsService registered, every call is authenticated and domain errors are converted to grpc status.
+// The calls are rate limited per peer and per requestor unless the limiter is nil, internal failures are logged with the logger.
+func NewServer(Controller controller.UserRelationshipController, authenticators []auth.Authenticator, limiter *RateLimiter, logger echo.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{ErrorInterceptor(logger), AuthInterceptor(authenticators...), TenantInterceptor}
+	if limiter != nil {
+		interceptors = []grpc.UnaryServerInterceptor{ErrorInterceptor(logger), limiter.RateLimitInterceptor(ByPeer), AuthInterceptor(authenticators...), TenantInterceptor, limiter.RateLimitInterceptor(ByRequestor)}
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+	server := grpc.NewServer(opts...)
+	friendspb.RegisterFriendsServiceServer(server, NewFriendsServer(Controller))
+	return server
+}
+
+// tenantController get the controller scoped to the tenant of the call, changes are recorded with the caller as actor
+func (sv *FriendsServer) tenantController(ctx context.Context) controller.UserRelationshipController {
+	return sv.Controller.WithTenant(tenant.FromContext(ctx)).WithActor(actorFrom(ctx))
+}
+
+// actorFrom get the caller of the call from its principal, peer address and user-agent metadata
+func actorFrom(ctx context.Context) controller.Actor {
+	md, _ := metadata.FromIncomingContext(ctx)
+	actor := controller.Actor{UserAgent: first(md.Get("user-agent"))}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		actor.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(actor.IP); err == nil {
+			actor.IP = host
+		}
+	}
+	if principal := auth.FromContext(ctx); principal != nil {
+		actor.Subject = principal.Subject
+	}
+	return actor
+}
+
+// AddFriendship rpc for make friend connection
+func (sv *FriendsServer) AddFriendship(ctx context.Context, req *friendspb.AddFriendshipRequest) (*friendspb.AddFriendshipResponse, error) {
+	var v requestValidator
+	v.emailPair("requestor", req.GetRequestor(), "target", req.GetTarget())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetRequestor()); err != nil {
+		return nil, err
+	}
+
+	if err := sv.tenantController(ctx).AddFriendship(req.GetRequestor(), req.GetTarget()); err != nil {
+		return nil, err
+	}
+	return &friendspb.AddFriendshipResponse{}, nil
+}
+
+// ListFriendships rpc for get list friend email, at the as_of time when it is set
+func (sv *FriendsServer) ListFriendships(ctx context.Context, req *friendspb.ListFriendshipsRequest) (*friendspb.ListFriendshipsResponse, error) {
+	var v requestValidator
+	v.email("email", req.GetEmail())
+	asOf := v.optionalTime("as_of", req.GetAsOf())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	var friends []string
+	var count int64
+	var err error
+	if asOf != nil {
+		friends, count, err = sv.tenantController(ctx).ListFriendshipsAsOf(req.GetEmail(), *asOf)
+	} else {
+		friends, count, err = sv.tenantController(ctx).ListFriendships(req.GetEmail())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &friendspb.ListFriendshipsResponse{Friends: friends, Count: count}, nil
+}
+
+// ListCommonFriends rpc for get list common friend email
+func (sv *FriendsServer) ListCommonFriends(ctx context.Context, req *friendspb.ListCommonFriendsRequest) (*friendspb.ListCommonFriendsResponse, error) {
+	var v requestValidator
+	v.emailPair("email1", req.GetEmail1(), "email2", req.GetEmail2())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetEmail1()); err != nil {
+		return nil, err
+	}
+
+	friends, count, err := sv.tenantController(ctx).ListCommonFriends(req.GetEmail1(), req.GetEmail2())
+	if err != nil {
+		return nil, err
+	}
+	return &friendspb.ListCommonFriendsResponse{Friends: friends, Count: count}, nil
+}
+
+// AddSubscriber rpc for make subscriber connection
+func (sv *FriendsServer) AddSubscriber(ctx context.Context, req *friendspb.AddSubscriberRequest) (*friendspb.AddSubscriberResponse, error) {
+	var v requestValidator
+	v.email("requestor", req.GetRequestor())
+	v.email("target", req.GetTarget())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetRequestor()); err != nil {
+		return nil, err
+	}
+
+	if err := sv.tenantController(ctx).AddSubscriber(req.GetRequestor(), req.GetTarget()); err != nil {
+		return nil, err
+	}
+	return &friendspb.AddSubscriberResponse{}, nil
+}
+
+// AddBlock rpc for make block connection
+func (sv *FriendsServer) AddBlock(ctx context.Context, req *friendspb.AddBlockRequest) (*friendspb.AddBlockResponse, error) {
+	var v requestValidator
+	v.email("requestor", req.GetRequestor())
+	v.email("target", req.GetTarget())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetRequestor()); err != nil {
+		return nil, err
+	}
+
+	if err := sv.tenantController(ctx).AddBlock(req.GetRequestor(), req.GetTarget()); err != nil {
+		return nil, err
+	}
+	return &friendspb.AddBlockResponse{}, nil
+}
+
+// GetListEmailCanReceiveUpdate rpc for get list email can receive update from the sender now and the emails in their quiet hours,
+// deferred until the end of them
+func (sv *FriendsServer) GetListEmailCanReceiveUpdate(ctx context.Context, req *friendspb.GetListEmailCanReceiveUpdateRequest) (*friendspb.GetListEmailCanReceiveUpdateResponse, error) {
+	var v requestValidator
+	v.email("sender", req.GetSender())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetSender()); err != nil {
+		return nil, err
+	}
+
+	recipients, err := sv.tenantController(ctx).GetListEmailCanReceiveUpdate(req.GetSender(), req.GetText())
+	if err != nil {
+		return nil, err
+	}
+	resp := &friendspb.GetListEmailCanReceiveUpdateResponse{Recipients: recipients.Now}
+	for _, deferred := range recipients.Deferred {
+		resp.Deferred = append(resp.Deferred, &friendspb.DeferredRecipient{Email: deferred.Email, Until: timestamppb.New(deferred.Until)})
+	}
+	return resp, nil
+}
+
+// ListSubscribers rpc for get list subscriber email of the email, at the as_of time when it is set
+func (sv *FriendsServer) ListSubscribers(ctx context.Context, req *friendspb.ListSubscribersRequest) (*friendspb.ListSubscribersResponse, error) {
+	var v requestValidator
+	v.email("email", req.GetEmail())
+	v.pagination(req.GetLimit(), req.GetOffset())
+	asOf := v.optionalTime("as_of", req.GetAsOf())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	limit := normalizeLimit(req.GetLimit())
+	var subscribers []string
+	var count int64
+	var err error
+	if asOf != nil {
+		subscribers, count, err = sv.tenantController(ctx).ListSubscribersAsOf(req.GetEmail(), *asOf, int(limit), int(req.GetOffset()))
+	} else {
+		subscribers, count, err = sv.tenantController(ctx).ListSubscribers(req.GetEmail(), int(limit), int(req.GetOffset()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &friendspb.ListSubscribersResponse{Subscribers: subscribers, Count: count, Limit: limit, Offset: req.GetOffset()}, nil
+}
+
+// ListBlocks rpc for get list email blocked by the requestor, at the as_of time when it is set
+func (sv *FriendsServer) ListBlocks(ctx context.Context, req *friendspb.ListBlocksRequest) (*friendspb.ListBlocksResponse, error) {
+	var v requestValidator
+	v.email("requestor", req.GetRequestor())
+	v.pagination(req.GetLimit(), req.GetOffset())
+	asOf := v.optionalTime("as_of", req.GetAsOf())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetRequestor()); err != nil {
+		return nil, err
+	}
+
+	limit := normalizeLimit(req.GetLimit())
+	var blocks []string
+	var count int64
+	var err error
+	if asOf != nil {
+		blocks, count, err = sv.tenantController(ctx).ListBlocksAsOf(req.GetRequestor(), *asOf, int(limit), int(req.GetOffset()))
+	} else {
+		blocks, count, err = sv.tenantController(ctx).ListBlocks(req.GetRequestor(), int(limit), int(req.GetOffset()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &friendspb.ListBlocksResponse{Blocks: blocks, Count: count, Limit: limit, Offset: req.GetOffset()}, nil
+}
+
+// RemoveFriendship rpc for remove friend connection
+func (sv *FriendsServer) RemoveFriendship(ctx context.Context, req *friendspb.RemoveFriendshipRequest) (*friendspb.RemoveFriendshipResponse, error) {
+	var v requestValidator
+	v.emailPair("email1", req.GetEmail1(), "email2", req.GetEmail2())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetEmail1()); err != nil {
+		return nil, err
+	}
+
+	if err := sv.tenantController(ctx).RemoveFriendship(req.GetEmail1(), req.GetEmail2()); err != nil {
+		return nil, err
+	}
+	return &friendspb.RemoveFriendshipResponse{}, nil
+}
+
+// RemoveSubscriber rpc for unsubscribe from the target
+func (sv *FriendsServer) RemoveSubscriber(ctx context.Context, req *friendspb.RemoveSubscriberRequest) (*friendspb.RemoveSubscriberResponse, error) {
+	var v requestValidator
+	v.email("requestor", req.GetRequestor())
+	v.email("target", req.GetTarget())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetRequestor()); err != nil {
+		return nil, err
+	}
+
+	if err := sv.tenantController(ctx).RemoveSubscriber(req.GetRequestor(), req.GetTarget()); err != nil {
+		return nil, err
+	}
+	return &friendspb.RemoveSubscriberResponse{}, nil
+}
+
+// RemoveBlock rpc for remove a block created by the requestor
+func (sv *FriendsServer) RemoveBlock(ctx context.Context, req *friendspb.RemoveBlockRequest) (*friendspb.RemoveBlockResponse, error) {
+	var v requestValidator
+	v.email("requestor", req.GetRequestor())
+	v.email("target", req.GetTarget())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(ctx, req.GetRequestor()); err != nil {
+		return nil, err
+	}
+
+	if err := sv.tenantController(ctx).RemoveBlock(req.GetRequestor(), req.GetTarget()); err != nil {
+		return nil, err
+	}
+	return &friendspb.RemoveBlockResponse{}, nil
+}
+
+// normalizeLimit apply the default and max page size
+func normalizeLimit(limit int32) int32 {
+	if limit == 0 {
+		limit = constant.DEFAULT_PAGE_LIMIT
+	}
+
+	if limit > constant.MAX_PAGE_LIMIT {
+		limit = constant.MAX_PAGE_LIMIT
+	}
+
+	return limit
+}
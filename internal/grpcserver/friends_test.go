@@ -0,0 +1,352 @@
+package grpcserver_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/grpcserver"
+	"github.com/quanluong166/friends_management/internal/grpcserver/friendspb"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	adminKey = "admin-key"
+	andyKey  = "andy-key"
+	acmeKey  = "acme-key"
+)
+
+var authenticators = []auth.Authenticator{auth.NewAPIKeyAuthenticator(map[string]auth.Principal{
+	adminKey: {Subject: "ops@example.com", Scopes: []string{auth.SCOPE_ADMIN}},
+	andyKey:  {Subject: "andy@example.com"},
+	acmeKey:  {Subject: "andy@example.com", Tenant: "acme"},
+})}
+
+// setupClient start the grpc server on an in-process listener and return a client connected to it as an admin
+func setupClient(t *testing.T, ctrl *handler.MockUserRelationshipController) friendspb.FriendsServiceClient {
+	return setupClientWithKey(t, ctrl, adminKey)
+}
+
+func setupClientWithKey(t *testing.T, ctrl *handler.MockUserRelationshipController, apiKey string) friendspb.FriendsServiceClient {
+	return setupClientWithServer(t, grpcserver.NewServer(ctrl, authenticators, nil, echo.New().Logger), apiKey)
+}
+
+func setupClientWithServer(t *testing.T, server *grpc.Server, apiKey string) friendspb.FriendsServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	withAPIKey := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if len(apiKey) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, grpcserver.METADATA_API_KEY, apiKey)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(withAPIKey),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return friendspb.NewFriendsServiceClient(conn)
+}
+
+func TestFriendsServer_AddFriendship(t *testing.T) {
+	testCases := map[string]struct {
+		req            *friendspb.AddFriendshipRequest
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+		code           codes.Code
+		reason         string
+		fields         []string
+	}{
+		"Success": {
+			req:            &friendspb.AddFriendshipRequest{Requestor: "andy@example.com", Target: "john@example.com"},
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"andy@example.com", "john@example.com"}},
+			returnArgument: [][]interface{}{{nil}},
+			code:           codes.OK,
+		},
+		"Invalid emails": {
+			req:    &friendspb.AddFriendshipRequest{Requestor: "andy", Target: ""},
+			code:   codes.InvalidArgument,
+			reason: apperror.CODE_INVALID_INPUT,
+			fields: []string{"requestor", "target"},
+		},
+		"Same email": {
+			req:    &friendspb.AddFriendshipRequest{Requestor: "andy@example.com", Target: "ANDY@example.com"},
+			code:   codes.InvalidArgument,
+			reason: apperror.CODE_INVALID_INPUT,
+			fields: []string{"target"},
+		},
+		"Already friends": {
+			req:            &friendspb.AddFriendshipRequest{Requestor: "andy@example.com", Target: "john@example.com"},
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"andy@example.com", "john@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrAlreadyFriends}},
+			code:           codes.AlreadyExists,
+			reason:         apperror.CODE_ALREADY_FRIENDS,
+		},
+		"Blocked": {
+			req:            &friendspb.AddFriendshipRequest{Requestor: "andy@example.com", Target: "john@example.com"},
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"andy@example.com", "john@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrBlocked}},
+			code:           codes.FailedPrecondition,
+			reason:         apperror.CODE_BLOCKED,
+		},
+		"Database error": {
+			req:            &friendspb.AddFriendshipRequest{Requestor: "andy@example.com", Target: "john@example.com"},
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"andy@example.com", "john@example.com"}},
+			returnArgument: [][]interface{}{{errors.New("CREATE_FRIEND_RELATIONSHIP_FAIL: DATABASE_ERROR")}},
+			code:           codes.Internal,
+			reason:         apperror.CODE_INTERNAL,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := new(handler.MockUserRelationshipController)
+			for i := range tc.mockOn {
+				ctrl.On(tc.mockOn[i], tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			client := setupClient(t, ctrl)
+
+			_, err := client.AddFriendship(context.Background(), tc.req)
+
+			assertStatus(t, err, tc.code, tc.reason, tc.fields)
+			ctrl.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFriendsServer_ListSubscribers(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListSubscribers", "andy@example.com", 20, 5).Return([]string{"john@example.com"}, int64(6), nil)
+	client := setupClient(t, ctrl)
+
+	resp, err := client.ListSubscribers(context.Background(), &friendspb.ListSubscribersRequest{Email: "andy@example.com", Offset: 5})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"john@example.com"}, resp.GetSubscribers())
+	assert.Equal(t, int64(6), resp.GetCount())
+	assert.Equal(t, int32(20), resp.GetLimit())
+	assert.Equal(t, int32(5), resp.GetOffset())
+
+	_, err = client.ListSubscribers(context.Background(), &friendspb.ListSubscribersRequest{Email: "andy@example.com", Limit: -1})
+	assertStatus(t, err, codes.InvalidArgument, apperror.CODE_INVALID_INPUT, []string{"limit"})
+	ctrl.AssertExpectations(t)
+}
+
+func TestFriendsServer_AsOf(t *testing.T) {
+	asOf := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListFriendshipsAsOf", "andy@example.com", asOf).Return([]string{"john@example.com"}, int64(1), nil)
+	ctrl.On("ListSubscribersAsOf", "andy@example.com", asOf, 20, 0).Return([]string{"kate@example.com"}, int64(1), nil)
+	ctrl.On("ListBlocksAsOf", "andy@example.com", asOf, 20, 0).Return([]string{"lisa@example.com"}, int64(1), nil)
+	client := setupClient(t, ctrl)
+	ctx := context.Background()
+
+	friends, err := client.ListFriendships(ctx, &friendspb.ListFriendshipsRequest{Email: "andy@example.com", AsOf: timestamppb.New(asOf)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"john@example.com"}, friends.GetFriends())
+
+	subscribers, err := client.ListSubscribers(ctx, &friendspb.ListSubscribersRequest{Email: "andy@example.com", AsOf: timestamppb.New(asOf)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kate@example.com"}, subscribers.GetSubscribers())
+
+	blocks, err := client.ListBlocks(ctx, &friendspb.ListBlocksRequest{Requestor: "andy@example.com", AsOf: timestamppb.New(asOf)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lisa@example.com"}, blocks.GetBlocks())
+
+	_, err = client.ListFriendships(ctx, &friendspb.ListFriendshipsRequest{Email: "andy@example.com", AsOf: &timestamppb.Timestamp{Nanos: -1}})
+	assertStatus(t, err, codes.InvalidArgument, apperror.CODE_INVALID_INPUT, []string{"as_of"})
+	ctrl.AssertExpectations(t)
+}
+
+func TestFriendsServer_Tenant(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListFriendships", "andy@example.com").Return([]string{}, int64(0), nil)
+	req := &friendspb.ListFriendshipsRequest{Email: "andy@example.com"}
+
+	_, err := setupClient(t, ctrl).ListFriendships(metadata.AppendToOutgoingContext(context.Background(), grpcserver.METADATA_TENANT_ID, "globex"), req)
+	require.NoError(t, err)
+	assert.Equal(t, "globex", ctrl.Tenant)
+
+	_, err = setupClientWithKey(t, ctrl, andyKey).ListFriendships(metadata.AppendToOutgoingContext(context.Background(), grpcserver.METADATA_TENANT_ID, "globex"), req)
+	assertStatus(t, err, codes.PermissionDenied, apperror.CODE_FORBIDDEN, nil)
+
+	acme := setupClientWithKey(t, ctrl, acmeKey)
+	_, err = acme.ListFriendships(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", ctrl.Tenant)
+
+	_, err = acme.ListFriendships(metadata.AppendToOutgoingContext(context.Background(), grpcserver.METADATA_TENANT_ID, "globex"), req)
+	assertStatus(t, err, codes.PermissionDenied, apperror.CODE_FORBIDDEN, nil)
+	ctrl.AssertExpectations(t)
+}
+
+func TestFriendsServer_RemoveFriendship(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("RemoveFriendship", "andy@example.com", "john@example.com").Return(nil).Once()
+	ctrl.On("RemoveFriendship", "andy@example.com", "john@example.com").Return(apperror.NotFound("FRIENDSHIP_NOT_FOUND")).Once()
+	client := setupClient(t, ctrl)
+	req := &friendspb.RemoveFriendshipRequest{Email1: "andy@example.com", Email2: "john@example.com"}
+
+	_, err := client.RemoveFriendship(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "ops@example.com", ctrl.Actor.Subject)
+	assert.Contains(t, ctrl.Actor.UserAgent, "grpc-go")
+
+	_, err = client.RemoveFriendship(context.Background(), req)
+	assertStatus(t, err, codes.NotFound, apperror.CODE_NOT_FOUND, nil)
+	assert.Equal(t, "FRIENDSHIP_NOT_FOUND", status.Convert(err).Message())
+	ctrl.AssertExpectations(t)
+}
+
+func TestFriendsServer_Auth(t *testing.T) {
+	testCases := map[string]struct {
+		apiKey    string
+		requestor string
+		code      codes.Code
+		reason    string
+	}{
+		"Missing credentials": {
+			requestor: "andy@example.com",
+			code:      codes.Unauthenticated,
+			reason:    apperror.CODE_UNAUTHENTICATED,
+		},
+		"Unknown key": {
+			apiKey:    "wrong-key",
+			requestor: "andy@example.com",
+			code:      codes.Unauthenticated,
+			reason:    apperror.CODE_UNAUTHENTICATED,
+		},
+		"Requestor is another user": {
+			apiKey:    andyKey,
+			requestor: "john@example.com",
+			code:      codes.PermissionDenied,
+			reason:    apperror.CODE_FORBIDDEN,
+		},
+		"Requestor is the caller": {
+			apiKey:    andyKey,
+			requestor: "andy@example.com",
+			code:      codes.OK,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := new(handler.MockUserRelationshipController)
+			if tc.code == codes.OK {
+				ctrl.On("AddBlock", tc.requestor, "kate@example.com").Return(nil)
+			}
+			client := setupClientWithKey(t, ctrl, tc.apiKey)
+
+			_, err := client.AddBlock(context.Background(), &friendspb.AddBlockRequest{Requestor: tc.requestor, Target: "kate@example.com"})
+
+			assertStatus(t, err, tc.code, tc.reason, nil)
+			ctrl.AssertExpectations(t)
+		})
+	}
+}
+
+// TestFriendsServer_AllMethods check every rpc reach the controller with the request fields
+func TestFriendsServer_AllMethods(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("ListFriendships", "andy@example.com").Return([]string{"john@example.com"}, int64(1), nil)
+	ctrl.On("ListCommonFriends", "andy@example.com", "john@example.com").Return([]string{"kate@example.com"}, int64(1), nil)
+	ctrl.On("AddSubscriber", "andy@example.com", "john@example.com").Return(nil)
+	ctrl.On("AddBlock", "andy@example.com", "john@example.com").Return(nil)
+	until := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+	ctrl.On("GetListEmailCanReceiveUpdate", "andy@example.com", "hello").Return(&controller.Recipients{
+		Now:      []string{"kate@example.com"},
+		Deferred: []controller.DeferredRecipient{{Email: "lisa@example.com", Until: until}},
+	}, nil)
+	ctrl.On("ListBlocks", "andy@example.com", 100, 0).Return([]string{"john@example.com"}, int64(1), nil)
+	ctrl.On("RemoveSubscriber", "andy@example.com", "john@example.com").Return(nil)
+	ctrl.On("RemoveBlock", "andy@example.com", "john@example.com").Return(apperror.NotFound("BLOCK_NOT_FOUND"))
+	client := setupClient(t, ctrl)
+	ctx := context.Background()
+
+	friends, err := client.ListFriendships(ctx, &friendspb.ListFriendshipsRequest{Email: "andy@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"john@example.com"}, friends.GetFriends())
+
+	common, err := client.ListCommonFriends(ctx, &friendspb.ListCommonFriendsRequest{Email1: "andy@example.com", Email2: "john@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), common.GetCount())
+
+	_, err = client.AddSubscriber(ctx, &friendspb.AddSubscriberRequest{Requestor: "andy@example.com", Target: "john@example.com"})
+	require.NoError(t, err)
+
+	_, err = client.AddBlock(ctx, &friendspb.AddBlockRequest{Requestor: "andy@example.com", Target: "john@example.com"})
+	require.NoError(t, err)
+
+	recipients, err := client.GetListEmailCanReceiveUpdate(ctx, &friendspb.GetListEmailCanReceiveUpdateRequest{Sender: "andy@example.com", Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kate@example.com"}, recipients.GetRecipients())
+	require.Len(t, recipients.GetDeferred(), 1)
+	assert.Equal(t, "lisa@example.com", recipients.GetDeferred()[0].GetEmail())
+	assert.Equal(t, until, recipients.GetDeferred()[0].GetUntil().AsTime())
+
+	blocks, err := client.ListBlocks(ctx, &friendspb.ListBlocksRequest{Requestor: "andy@example.com", Limit: 500})
+	require.NoError(t, err)
+	assert.Equal(t, int32(100), blocks.GetLimit())
+
+	_, err = client.RemoveSubscriber(ctx, &friendspb.RemoveSubscriberRequest{Requestor: "andy@example.com", Target: "john@example.com"})
+	require.NoError(t, err)
+
+	_, err = client.RemoveBlock(ctx, &friendspb.RemoveBlockRequest{Requestor: "andy@example.com", Target: "john@example.com"})
+	assertStatus(t, err, codes.NotFound, apperror.CODE_NOT_FOUND, nil)
+
+	ctrl.AssertExpectations(t)
+}
+
+// assertStatus check the status code, the ErrorInfo reason and the fields of the BadRequest detail
+func assertStatus(t *testing.T, err error, code codes.Code, reason string, fields []string) {
+	t.Helper()
+	st := status.Convert(err)
+	require.Equal(t, code, st.Code(), st.Message())
+	if code == codes.OK {
+		return
+	}
+
+	var gotReason string
+	var gotFields []string
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			gotReason = d.GetReason()
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				gotFields = append(gotFields, violation.GetField())
+			}
+		}
+	}
+	assert.Equal(t, reason, gotReason)
+	assert.Equal(t, fields, gotFields)
+}
@@ -0,0 +1,1376 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: friends/v1/friends.proto
+
+package friendspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AddFriendshipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requestor     string                 `protobuf:"bytes,1,opt,name=requestor,proto3" json:"requestor,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddFriendshipRequest) Reset() {
+	*x = AddFriendshipRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddFriendshipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddFriendshipRequest) ProtoMessage() {}
+
+func (x *AddFriendshipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddFriendshipRequest.ProtoReflect.Descriptor instead.
+func (*AddFriendshipRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AddFriendshipRequest) GetRequestor() string {
+	if x != nil {
+		return x.Requestor
+	}
+	return ""
+}
+
+func (x *AddFriendshipRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type AddFriendshipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddFriendshipResponse) Reset() {
+	*x = AddFriendshipResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddFriendshipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddFriendshipResponse) ProtoMessage() {}
+
+func (x *AddFriendshipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddFriendshipResponse.ProtoReflect.Descriptor instead.
+func (*AddFriendshipResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{1}
+}
+
+type ListFriendshipsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Email string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	// as_of list the friends the email had at that time instead of now
+	AsOf          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFriendshipsRequest) Reset() {
+	*x = ListFriendshipsRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFriendshipsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFriendshipsRequest) ProtoMessage() {}
+
+func (x *ListFriendshipsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFriendshipsRequest.ProtoReflect.Descriptor instead.
+func (*ListFriendshipsRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListFriendshipsRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ListFriendshipsRequest) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+type ListFriendshipsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Friends       []string               `protobuf:"bytes,1,rep,name=friends,proto3" json:"friends,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFriendshipsResponse) Reset() {
+	*x = ListFriendshipsResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFriendshipsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFriendshipsResponse) ProtoMessage() {}
+
+func (x *ListFriendshipsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFriendshipsResponse.ProtoReflect.Descriptor instead.
+func (*ListFriendshipsResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListFriendshipsResponse) GetFriends() []string {
+	if x != nil {
+		return x.Friends
+	}
+	return nil
+}
+
+func (x *ListFriendshipsResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type ListCommonFriendsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email1        string                 `protobuf:"bytes,1,opt,name=email1,proto3" json:"email1,omitempty"`
+	Email2        string                 `protobuf:"bytes,2,opt,name=email2,proto3" json:"email2,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommonFriendsRequest) Reset() {
+	*x = ListCommonFriendsRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommonFriendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommonFriendsRequest) ProtoMessage() {}
+
+func (x *ListCommonFriendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommonFriendsRequest.ProtoReflect.Descriptor instead.
+func (*ListCommonFriendsRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListCommonFriendsRequest) GetEmail1() string {
+	if x != nil {
+		return x.Email1
+	}
+	return ""
+}
+
+func (x *ListCommonFriendsRequest) GetEmail2() string {
+	if x != nil {
+		return x.Email2
+	}
+	return ""
+}
+
+type ListCommonFriendsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Friends       []string               `protobuf:"bytes,1,rep,name=friends,proto3" json:"friends,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommonFriendsResponse) Reset() {
+	*x = ListCommonFriendsResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommonFriendsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommonFriendsResponse) ProtoMessage() {}
+
+func (x *ListCommonFriendsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommonFriendsResponse.ProtoReflect.Descriptor instead.
+func (*ListCommonFriendsResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListCommonFriendsResponse) GetFriends() []string {
+	if x != nil {
+		return x.Friends
+	}
+	return nil
+}
+
+func (x *ListCommonFriendsResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type AddSubscriberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requestor     string                 `protobuf:"bytes,1,opt,name=requestor,proto3" json:"requestor,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddSubscriberRequest) Reset() {
+	*x = AddSubscriberRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddSubscriberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddSubscriberRequest) ProtoMessage() {}
+
+func (x *AddSubscriberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddSubscriberRequest.ProtoReflect.Descriptor instead.
+func (*AddSubscriberRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AddSubscriberRequest) GetRequestor() string {
+	if x != nil {
+		return x.Requestor
+	}
+	return ""
+}
+
+func (x *AddSubscriberRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type AddSubscriberResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddSubscriberResponse) Reset() {
+	*x = AddSubscriberResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddSubscriberResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddSubscriberResponse) ProtoMessage() {}
+
+func (x *AddSubscriberResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddSubscriberResponse.ProtoReflect.Descriptor instead.
+func (*AddSubscriberResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{7}
+}
+
+type AddBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requestor     string                 `protobuf:"bytes,1,opt,name=requestor,proto3" json:"requestor,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddBlockRequest) Reset() {
+	*x = AddBlockRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddBlockRequest) ProtoMessage() {}
+
+func (x *AddBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddBlockRequest.ProtoReflect.Descriptor instead.
+func (*AddBlockRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AddBlockRequest) GetRequestor() string {
+	if x != nil {
+		return x.Requestor
+	}
+	return ""
+}
+
+func (x *AddBlockRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type AddBlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddBlockResponse) Reset() {
+	*x = AddBlockResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddBlockResponse) ProtoMessage() {}
+
+func (x *AddBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddBlockResponse.ProtoReflect.Descriptor instead.
+func (*AddBlockResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{9}
+}
+
+type GetListEmailCanReceiveUpdateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sender        string                 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetListEmailCanReceiveUpdateRequest) Reset() {
+	*x = GetListEmailCanReceiveUpdateRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetListEmailCanReceiveUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetListEmailCanReceiveUpdateRequest) ProtoMessage() {}
+
+func (x *GetListEmailCanReceiveUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetListEmailCanReceiveUpdateRequest.ProtoReflect.Descriptor instead.
+func (*GetListEmailCanReceiveUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetListEmailCanReceiveUpdateRequest) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *GetListEmailCanReceiveUpdateRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type GetListEmailCanReceiveUpdateResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Recipients []string               `protobuf:"bytes,1,rep,name=recipients,proto3" json:"recipients,omitempty"`
+	// deferred are the recipients in their quiet hours, they receive the update at until
+	Deferred      []*DeferredRecipient `protobuf:"bytes,2,rep,name=deferred,proto3" json:"deferred,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetListEmailCanReceiveUpdateResponse) Reset() {
+	*x = GetListEmailCanReceiveUpdateResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetListEmailCanReceiveUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetListEmailCanReceiveUpdateResponse) ProtoMessage() {}
+
+func (x *GetListEmailCanReceiveUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetListEmailCanReceiveUpdateResponse.ProtoReflect.Descriptor instead.
+func (*GetListEmailCanReceiveUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetListEmailCanReceiveUpdateResponse) GetRecipients() []string {
+	if x != nil {
+		return x.Recipients
+	}
+	return nil
+}
+
+func (x *GetListEmailCanReceiveUpdateResponse) GetDeferred() []*DeferredRecipient {
+	if x != nil {
+		return x.Deferred
+	}
+	return nil
+}
+
+type DeferredRecipient struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Until         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=until,proto3" json:"until,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeferredRecipient) Reset() {
+	*x = DeferredRecipient{}
+	mi := &file_friends_v1_friends_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeferredRecipient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeferredRecipient) ProtoMessage() {}
+
+func (x *DeferredRecipient) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeferredRecipient.ProtoReflect.Descriptor instead.
+func (*DeferredRecipient) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeferredRecipient) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *DeferredRecipient) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+type ListSubscribersRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Email  string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Limit  int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	// as_of list the subscribers the email had at that time instead of now
+	AsOf          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubscribersRequest) Reset() {
+	*x = ListSubscribersRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubscribersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubscribersRequest) ProtoMessage() {}
+
+func (x *ListSubscribersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubscribersRequest.ProtoReflect.Descriptor instead.
+func (*ListSubscribersRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListSubscribersRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ListSubscribersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListSubscribersRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ListSubscribersRequest) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+type ListSubscribersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subscribers   []string               `protobuf:"bytes,1,rep,name=subscribers,proto3" json:"subscribers,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubscribersResponse) Reset() {
+	*x = ListSubscribersResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubscribersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubscribersResponse) ProtoMessage() {}
+
+func (x *ListSubscribersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubscribersResponse.ProtoReflect.Descriptor instead.
+func (*ListSubscribersResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListSubscribersResponse) GetSubscribers() []string {
+	if x != nil {
+		return x.Subscribers
+	}
+	return nil
+}
+
+func (x *ListSubscribersResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ListSubscribersResponse) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListSubscribersResponse) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListBlocksRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Requestor string                 `protobuf:"bytes,1,opt,name=requestor,proto3" json:"requestor,omitempty"`
+	Limit     int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset    int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	// as_of list the emails the requestor blocked at that time instead of now
+	AsOf          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlocksRequest) Reset() {
+	*x = ListBlocksRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlocksRequest) ProtoMessage() {}
+
+func (x *ListBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlocksRequest.ProtoReflect.Descriptor instead.
+func (*ListBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListBlocksRequest) GetRequestor() string {
+	if x != nil {
+		return x.Requestor
+	}
+	return ""
+}
+
+func (x *ListBlocksRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListBlocksRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ListBlocksRequest) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+type ListBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Blocks        []string               `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlocksResponse) Reset() {
+	*x = ListBlocksResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlocksResponse) ProtoMessage() {}
+
+func (x *ListBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlocksResponse.ProtoReflect.Descriptor instead.
+func (*ListBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListBlocksResponse) GetBlocks() []string {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+func (x *ListBlocksResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ListBlocksResponse) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListBlocksResponse) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type RemoveFriendshipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email1        string                 `protobuf:"bytes,1,opt,name=email1,proto3" json:"email1,omitempty"`
+	Email2        string                 `protobuf:"bytes,2,opt,name=email2,proto3" json:"email2,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFriendshipRequest) Reset() {
+	*x = RemoveFriendshipRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFriendshipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFriendshipRequest) ProtoMessage() {}
+
+func (x *RemoveFriendshipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFriendshipRequest.ProtoReflect.Descriptor instead.
+func (*RemoveFriendshipRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RemoveFriendshipRequest) GetEmail1() string {
+	if x != nil {
+		return x.Email1
+	}
+	return ""
+}
+
+func (x *RemoveFriendshipRequest) GetEmail2() string {
+	if x != nil {
+		return x.Email2
+	}
+	return ""
+}
+
+type RemoveFriendshipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFriendshipResponse) Reset() {
+	*x = RemoveFriendshipResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFriendshipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFriendshipResponse) ProtoMessage() {}
+
+func (x *RemoveFriendshipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFriendshipResponse.ProtoReflect.Descriptor instead.
+func (*RemoveFriendshipResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{18}
+}
+
+type RemoveSubscriberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requestor     string                 `protobuf:"bytes,1,opt,name=requestor,proto3" json:"requestor,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveSubscriberRequest) Reset() {
+	*x = RemoveSubscriberRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveSubscriberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveSubscriberRequest) ProtoMessage() {}
+
+func (x *RemoveSubscriberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveSubscriberRequest.ProtoReflect.Descriptor instead.
+func (*RemoveSubscriberRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RemoveSubscriberRequest) GetRequestor() string {
+	if x != nil {
+		return x.Requestor
+	}
+	return ""
+}
+
+func (x *RemoveSubscriberRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type RemoveSubscriberResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveSubscriberResponse) Reset() {
+	*x = RemoveSubscriberResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveSubscriberResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveSubscriberResponse) ProtoMessage() {}
+
+func (x *RemoveSubscriberResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveSubscriberResponse.ProtoReflect.Descriptor instead.
+func (*RemoveSubscriberResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{20}
+}
+
+type RemoveBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requestor     string                 `protobuf:"bytes,1,opt,name=requestor,proto3" json:"requestor,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveBlockRequest) Reset() {
+	*x = RemoveBlockRequest{}
+	mi := &file_friends_v1_friends_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveBlockRequest) ProtoMessage() {}
+
+func (x *RemoveBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveBlockRequest.ProtoReflect.Descriptor instead.
+func (*RemoveBlockRequest) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RemoveBlockRequest) GetRequestor() string {
+	if x != nil {
+		return x.Requestor
+	}
+	return ""
+}
+
+func (x *RemoveBlockRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type RemoveBlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveBlockResponse) Reset() {
+	*x = RemoveBlockResponse{}
+	mi := &file_friends_v1_friends_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveBlockResponse) ProtoMessage() {}
+
+func (x *RemoveBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_friends_v1_friends_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveBlockResponse.ProtoReflect.Descriptor instead.
+func (*RemoveBlockResponse) Descriptor() ([]byte, []int) {
+	return file_friends_v1_friends_proto_rawDescGZIP(), []int{22}
+}
+
+var File_friends_v1_friends_proto protoreflect.FileDescriptor
+
+const file_friends_v1_friends_proto_rawDesc = "" +
+	"\n" +
+	"\x18friends/v1/friends.proto\x12\n" +
+	"friends.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"L\n" +
+	"\x14AddFriendshipRequest\x12\x1c\n" +
+	"\trequestor\x18\x01 \x01(\tR\trequestor\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\"\x17\n" +
+	"\x15AddFriendshipResponse\"_\n" +
+	"\x16ListFriendshipsRequest\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12/\n" +
+	"\x05as_of\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\"I\n" +
+	"\x17ListFriendshipsResponse\x12\x18\n" +
+	"\afriends\x18\x01 \x03(\tR\afriends\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"J\n" +
+	"\x18ListCommonFriendsRequest\x12\x16\n" +
+	"\x06email1\x18\x01 \x01(\tR\x06email1\x12\x16\n" +
+	"\x06email2\x18\x02 \x01(\tR\x06email2\"K\n" +
+	"\x19ListCommonFriendsResponse\x12\x18\n" +
+	"\afriends\x18\x01 \x03(\tR\afriends\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"L\n" +
+	"\x14AddSubscriberRequest\x12\x1c\n" +
+	"\trequestor\x18\x01 \x01(\tR\trequestor\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\"\x17\n" +
+	"\x15AddSubscriberResponse\"G\n" +
+	"\x0fAddBlockRequest\x12\x1c\n" +
+	"\trequestor\x18\x01 \x01(\tR\trequestor\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\"\x12\n" +
+	"\x10AddBlockResponse\"Q\n" +
+	"#GetListEmailCanReceiveUpdateRequest\x12\x16\n" +
+	"\x06sender\x18\x01 \x01(\tR\x06sender\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\x81\x01\n" +
+	"$GetListEmailCanReceiveUpdateResponse\x12\x1e\n" +
+	"\n" +
+	"recipients\x18\x01 \x03(\tR\n" +
+	"recipients\x129\n" +
+	"\bdeferred\x18\x02 \x03(\v2\x1d.friends.v1.DeferredRecipientR\bdeferred\"[\n" +
+	"\x11DeferredRecipient\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x120\n" +
+	"\x05until\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\"\x8d\x01\n" +
+	"\x16ListSubscribersRequest\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x12/\n" +
+	"\x05as_of\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\"\x7f\n" +
+	"\x17ListSubscribersResponse\x12 \n" +
+	"\vsubscribers\x18\x01 \x03(\tR\vsubscribers\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\"\x90\x01\n" +
+	"\x11ListBlocksRequest\x12\x1c\n" +
+	"\trequestor\x18\x01 \x01(\tR\trequestor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x12/\n" +
+	"\x05as_of\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\"p\n" +
+	"\x12ListBlocksResponse\x12\x16\n" +
+	"\x06blocks\x18\x01 \x03(\tR\x06blocks\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\"I\n" +
+	"\x17RemoveFriendshipRequest\x12\x16\n" +
+	"\x06email1\x18\x01 \x01(\tR\x06email1\x12\x16\n" +
+	"\x06email2\x18\x02 \x01(\tR\x06email2\"\x1a\n" +
+	"\x18RemoveFriendshipResponse\"O\n" +
+	"\x17RemoveSubscriberRequest\x12\x1c\n" +
+	"\trequestor\x18\x01 \x01(\tR\trequestor\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\"\x1a\n" +
+	"\x18RemoveSubscriberResponse\"J\n" +
+	"\x12RemoveBlockRequest\x12\x1c\n" +
+	"\trequestor\x18\x01 \x01(\tR\trequestor\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\"\x15\n" +
+	"\x13RemoveBlockResponse2\xfc\a\n" +
+	"\x0eFriendsService\x12T\n" +
+	"\rAddFriendship\x12 .friends.v1.AddFriendshipRequest\x1a!.friends.v1.AddFriendshipResponse\x12Z\n" +
+	"\x0fListFriendships\x12\".friends.v1.ListFriendshipsRequest\x1a#.friends.v1.ListFriendshipsResponse\x12`\n" +
+	"\x11ListCommonFriends\x12$.friends.v1.ListCommonFriendsRequest\x1a%.friends.v1.ListCommonFriendsResponse\x12T\n" +
+	"\rAddSubscriber\x12 .friends.v1.AddSubscriberRequest\x1a!.friends.v1.AddSubscriberResponse\x12E\n" +
+	"\bAddBlock\x12\x1b.friends.v1.AddBlockRequest\x1a\x1c.friends.v1.AddBlockResponse\x12\x81\x01\n" +
+	"\x1cGetListEmailCanReceiveUpdate\x12/.friends.v1.GetListEmailCanReceiveUpdateRequest\x1a0.friends.v1.GetListEmailCanReceiveUpdateResponse\x12Z\n" +
+	"\x0fListSubscribers\x12\".friends.v1.ListSubscribersRequest\x1a#.friends.v1.ListSubscribersResponse\x12K\n" +
+	"\n" +
+	"ListBlocks\x12\x1d.friends.v1.ListBlocksRequest\x1a\x1e.friends.v1.ListBlocksResponse\x12]\n" +
+	"\x10RemoveFriendship\x12#.friends.v1.RemoveFriendshipRequest\x1a$.friends.v1.RemoveFriendshipResponse\x12]\n" +
+	"\x10RemoveSubscriber\x12#.friends.v1.RemoveSubscriberRequest\x1a$.friends.v1.RemoveSubscriberResponse\x12N\n" +
+	"\vRemoveBlock\x12\x1e.friends.v1.RemoveBlockRequest\x1a\x1f.friends.v1.RemoveBlockResponseBTZRgithub.com/quanluong166/friends_management/internal/grpcserver/friendspb;friendspbb\x06proto3"
+
+var (
+	file_friends_v1_friends_proto_rawDescOnce sync.Once
+	file_friends_v1_friends_proto_rawDescData []byte
+)
+
+func file_friends_v1_friends_proto_rawDescGZIP() []byte {
+	file_friends_v1_friends_proto_rawDescOnce.Do(func() {
+		file_friends_v1_friends_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_friends_v1_friends_proto_rawDesc), len(file_friends_v1_friends_proto_rawDesc)))
+	})
+	return file_friends_v1_friends_proto_rawDescData
+}
+
+var file_friends_v1_friends_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_friends_v1_friends_proto_goTypes = []any{
+	(*AddFriendshipRequest)(nil),                 // 0: friends.v1.AddFriendshipRequest
+	(*AddFriendshipResponse)(nil),                // 1: friends.v1.AddFriendshipResponse
+	(*ListFriendshipsRequest)(nil),               // 2: friends.v1.ListFriendshipsRequest
+	(*ListFriendshipsResponse)(nil),              // 3: friends.v1.ListFriendshipsResponse
+	(*ListCommonFriendsRequest)(nil),             // 4: friends.v1.ListCommonFriendsRequest
+	(*ListCommonFriendsResponse)(nil),            // 5: friends.v1.ListCommonFriendsResponse
+	(*AddSubscriberRequest)(nil),                 // 6: friends.v1.AddSubscriberRequest
+	(*AddSubscriberResponse)(nil),                // 7: friends.v1.AddSubscriberResponse
+	(*AddBlockRequest)(nil),                      // 8: friends.v1.AddBlockRequest
+	(*AddBlockResponse)(nil),                     // 9: friends.v1.AddBlockResponse
+	(*GetListEmailCanReceiveUpdateRequest)(nil),  // 10: friends.v1.GetListEmailCanReceiveUpdateRequest
+	(*GetListEmailCanReceiveUpdateResponse)(nil), // 11: friends.v1.GetListEmailCanReceiveUpdateResponse
+	(*DeferredRecipient)(nil),                    // 12: friends.v1.DeferredRecipient
+	(*ListSubscribersRequest)(nil),               // 13: friends.v1.ListSubscribersRequest
+	(*ListSubscribersResponse)(nil),              // 14: friends.v1.ListSubscribersResponse
+	(*ListBlocksRequest)(nil),                    // 15: friends.v1.ListBlocksRequest
+	(*ListBlocksResponse)(nil),                   // 16: friends.v1.ListBlocksResponse
+	(*RemoveFriendshipRequest)(nil),              // 17: friends.v1.RemoveFriendshipRequest
+	(*RemoveFriendshipResponse)(nil),             // 18: friends.v1.RemoveFriendshipResponse
+	(*RemoveSubscriberRequest)(nil),              // 19: friends.v1.RemoveSubscriberRequest
+	(*RemoveSubscriberResponse)(nil),             // 20: friends.v1.RemoveSubscriberResponse
+	(*RemoveBlockRequest)(nil),                   // 21: friends.v1.RemoveBlockRequest
+	(*RemoveBlockResponse)(nil),                  // 22: friends.v1.RemoveBlockResponse
+	(*timestamppb.Timestamp)(nil),                // 23: google.protobuf.Timestamp
+}
+var file_friends_v1_friends_proto_depIdxs = []int32{
+	23, // 0: friends.v1.ListFriendshipsRequest.as_of:type_name -> google.protobuf.Timestamp
+	12, // 1: friends.v1.GetListEmailCanReceiveUpdateResponse.deferred:type_name -> friends.v1.DeferredRecipient
+	23, // 2: friends.v1.DeferredRecipient.until:type_name -> google.protobuf.Timestamp
+	23, // 3: friends.v1.ListSubscribersRequest.as_of:type_name -> google.protobuf.Timestamp
+	23, // 4: friends.v1.ListBlocksRequest.as_of:type_name -> google.protobuf.Timestamp
+	0,  // 5: friends.v1.FriendsService.AddFriendship:input_type -> friends.v1.AddFriendshipRequest
+	2,  // 6: friends.v1.FriendsService.ListFriendships:input_type -> friends.v1.ListFriendshipsRequest
+	4,  // 7: friends.v1.FriendsService.ListCommonFriends:input_type -> friends.v1.ListCommonFriendsRequest
+	6,  // 8: friends.v1.FriendsService.AddSubscriber:input_type -> friends.v1.AddSubscriberRequest
+	8,  // 9: friends.v1.FriendsService.AddBlock:input_type -> friends.v1.AddBlockRequest
+	10, // 10: friends.v1.FriendsService.GetListEmailCanReceiveUpdate:input_type -> friends.v1.GetListEmailCanReceiveUpdateRequest
+	13, // 11: friends.v1.FriendsService.ListSubscribers:input_type -> friends.v1.ListSubscribersRequest
+	15, // 12: friends.v1.FriendsService.ListBlocks:input_type -> friends.v1.ListBlocksRequest
+	17, // 13: friends.v1.FriendsService.RemoveFriendship:input_type -> friends.v1.RemoveFriendshipRequest
+	19, // 14: friends.v1.FriendsService.RemoveSubscriber:input_type -> friends.v1.RemoveSubscriberRequest
+	21, // 15: friends.v1.FriendsService.RemoveBlock:input_type -> friends.v1.RemoveBlockRequest
+	1,  // 16: friends.v1.FriendsService.AddFriendship:output_type -> friends.v1.AddFriendshipResponse
+	3,  // 17: friends.v1.FriendsService.ListFriendships:output_type -> friends.v1.ListFriendshipsResponse
+	5,  // 18: friends.v1.FriendsService.ListCommonFriends:output_type -> friends.v1.ListCommonFriendsResponse
+	7,  // 19: friends.v1.FriendsService.AddSubscriber:output_type -> friends.v1.AddSubscriberResponse
+	9,  // 20: friends.v1.FriendsService.AddBlock:output_type -> friends.v1.AddBlockResponse
+	11, // 21: friends.v1.FriendsService.GetListEmailCanReceiveUpdate:output_type -> friends.v1.GetListEmailCanReceiveUpdateResponse
+	14, // 22: friends.v1.FriendsService.ListSubscribers:output_type -> friends.v1.ListSubscribersResponse
+	16, // 23: friends.v1.FriendsService.ListBlocks:output_type -> friends.v1.ListBlocksResponse
+	18, // 24: friends.v1.FriendsService.RemoveFriendship:output_type -> friends.v1.RemoveFriendshipResponse
+	20, // 25: friends.v1.FriendsService.RemoveSubscriber:output_type -> friends.v1.RemoveSubscriberResponse
+	22, // 26: friends.v1.FriendsService.RemoveBlock:output_type -> friends.v1.RemoveBlockResponse
+	16, // [16:27] is the sub-list for method output_type
+	5,  // [5:16] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_friends_v1_friends_proto_init() }
+func file_friends_v1_friends_proto_init() {
+	if File_friends_v1_friends_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_friends_v1_friends_proto_rawDesc), len(file_friends_v1_friends_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_friends_v1_friends_proto_goTypes,
+		DependencyIndexes: file_friends_v1_friends_proto_depIdxs,
+		MessageInfos:      file_friends_v1_friends_proto_msgTypes,
+	}.Build()
+	File_friends_v1_friends_proto = out.File
+	file_friends_v1_friends_proto_goTypes = nil
+	file_friends_v1_friends_proto_depIdxs = nil
+}
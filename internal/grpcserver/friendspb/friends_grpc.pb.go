@@ -0,0 +1,505 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: friends/v1/friends.proto
+
+package friendspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	FriendsService_AddFriendship_FullMethodName                = "/friends.v1.FriendsService/AddFriendship"
+	FriendsService_ListFriendships_FullMethodName              = "/friends.v1.FriendsService/ListFriendships"
+	FriendsService_ListCommonFriends_FullMethodName            = "/friends.v1.FriendsService/ListCommonFriends"
+	FriendsService_AddSubscriber_FullMethodName                = "/friends.v1.FriendsService/AddSubscriber"
+	FriendsService_AddBlock_FullMethodName                     = "/friends.v1.FriendsService/AddBlock"
+	FriendsService_GetListEmailCanReceiveUpdate_FullMethodName = "/friends.v1.FriendsService/GetListEmailCanReceiveUpdate"
+	FriendsService_ListSubscribers_FullMethodName              = "/friends.v1.FriendsService/ListSubscribers"
+	FriendsService_ListBlocks_FullMethodName                   = "/friends.v1.FriendsService/ListBlocks"
+	FriendsService_RemoveFriendship_FullMethodName             = "/friends.v1.FriendsService/RemoveFriendship"
+	FriendsService_RemoveSubscriber_FullMethodName             = "/friends.v1.FriendsService/RemoveSubscriber"
+	FriendsService_RemoveBlock_FullMethodName                  = "/friends.v1.FriendsService/RemoveBlock"
+)
+
+// FriendsServiceClient is the client API for FriendsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// FriendsService expose the user relationship api over gRPC, it shares the controller with the REST api
+type FriendsServiceClient interface {
+	AddFriendship(ctx context.Context, in *AddFriendshipRequest, opts ...grpc.CallOption) (*AddFriendshipResponse, error)
+	ListFriendships(ctx context.Context, in *ListFriendshipsRequest, opts ...grpc.CallOption) (*ListFriendshipsResponse, error)
+	ListCommonFriends(ctx context.Context, in *ListCommonFriendsRequest, opts ...grpc.CallOption) (*ListCommonFriendsResponse, error)
+	AddSubscriber(ctx context.Context, in *AddSubscriberRequest, opts ...grpc.CallOption) (*AddSubscriberResponse, error)
+	AddBlock(ctx context.Context, in *AddBlockRequest, opts ...grpc.CallOption) (*AddBlockResponse, error)
+	GetListEmailCanReceiveUpdate(ctx context.Context, in *GetListEmailCanReceiveUpdateRequest, opts ...grpc.CallOption) (*GetListEmailCanReceiveUpdateResponse, error)
+	ListSubscribers(ctx context.Context, in *ListSubscribersRequest, opts ...grpc.CallOption) (*ListSubscribersResponse, error)
+	ListBlocks(ctx context.Context, in *ListBlocksRequest, opts ...grpc.CallOption) (*ListBlocksResponse, error)
+	RemoveFriendship(ctx context.Context, in *RemoveFriendshipRequest, opts ...grpc.CallOption) (*RemoveFriendshipResponse, error)
+	RemoveSubscriber(ctx context.Context, in *RemoveSubscriberRequest, opts ...grpc.CallOption) (*RemoveSubscriberResponse, error)
+	RemoveBlock(ctx context.Context, in *RemoveBlockRequest, opts ...grpc.CallOption) (*RemoveBlockResponse, error)
+}
+
+type friendsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFriendsServiceClient(cc grpc.ClientConnInterface) FriendsServiceClient {
+	return &friendsServiceClient{cc}
+}
+
+func (c *friendsServiceClient) AddFriendship(ctx context.Context, in *AddFriendshipRequest, opts ...grpc.CallOption) (*AddFriendshipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddFriendshipResponse)
+	err := c.cc.Invoke(ctx, FriendsService_AddFriendship_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) ListFriendships(ctx context.Context, in *ListFriendshipsRequest, opts ...grpc.CallOption) (*ListFriendshipsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFriendshipsResponse)
+	err := c.cc.Invoke(ctx, FriendsService_ListFriendships_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) ListCommonFriends(ctx context.Context, in *ListCommonFriendsRequest, opts ...grpc.CallOption) (*ListCommonFriendsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCommonFriendsResponse)
+	err := c.cc.Invoke(ctx, FriendsService_ListCommonFriends_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) AddSubscriber(ctx context.Context, in *AddSubscriberRequest, opts ...grpc.CallOption) (*AddSubscriberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddSubscriberResponse)
+	err := c.cc.Invoke(ctx, FriendsService_AddSubscriber_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) AddBlock(ctx context.Context, in *AddBlockRequest, opts ...grpc.CallOption) (*AddBlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddBlockResponse)
+	err := c.cc.Invoke(ctx, FriendsService_AddBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) GetListEmailCanReceiveUpdate(ctx context.Context, in *GetListEmailCanReceiveUpdateRequest, opts ...grpc.CallOption) (*GetListEmailCanReceiveUpdateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetListEmailCanReceiveUpdateResponse)
+	err := c.cc.Invoke(ctx, FriendsService_GetListEmailCanReceiveUpdate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) ListSubscribers(ctx context.Context, in *ListSubscribersRequest, opts ...grpc.CallOption) (*ListSubscribersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSubscribersResponse)
+	err := c.cc.Invoke(ctx, FriendsService_ListSubscribers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) ListBlocks(ctx context.Context, in *ListBlocksRequest, opts ...grpc.CallOption) (*ListBlocksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBlocksResponse)
+	err := c.cc.Invoke(ctx, FriendsService_ListBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) RemoveFriendship(ctx context.Context, in *RemoveFriendshipRequest, opts ...grpc.CallOption) (*RemoveFriendshipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveFriendshipResponse)
+	err := c.cc.Invoke(ctx, FriendsService_RemoveFriendship_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) RemoveSubscriber(ctx context.Context, in *RemoveSubscriberRequest, opts ...grpc.CallOption) (*RemoveSubscriberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveSubscriberResponse)
+	err := c.cc.Invoke(ctx, FriendsService_RemoveSubscriber_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendsServiceClient) RemoveBlock(ctx context.Context, in *RemoveBlockRequest, opts ...grpc.CallOption) (*RemoveBlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveBlockResponse)
+	err := c.cc.Invoke(ctx, FriendsService_RemoveBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FriendsServiceServer is the server API for FriendsService service.
+// All implementations must embed UnimplementedFriendsServiceServer
+// for forward compatibility.
+//
+// FriendsService expose the user relationship api over gRPC, it shares the controller with the REST api
+type FriendsServiceServer interface {
+	AddFriendship(context.Context, *AddFriendshipRequest) (*AddFriendshipResponse, error)
+	ListFriendships(context.Context, *ListFriendshipsRequest) (*ListFriendshipsResponse, error)
+	ListCommonFriends(context.Context, *ListCommonFriendsRequest) (*ListCommonFriendsResponse, error)
+	AddSubscriber(context.Context, *AddSubscriberRequest) (*AddSubscriberResponse, error)
+	AddBlock(context.Context, *AddBlockRequest) (*AddBlockResponse, error)
+	GetListEmailCanReceiveUpdate(context.Context, *GetListEmailCanReceiveUpdateRequest) (*GetListEmailCanReceiveUpdateResponse, error)
+	ListSubscribers(context.Context, *ListSubscribersRequest) (*ListSubscribersResponse, error)
+	ListBlocks(context.Context, *ListBlocksRequest) (*ListBlocksResponse, error)
+	RemoveFriendship(context.Context, *RemoveFriendshipRequest) (*RemoveFriendshipResponse, error)
+	RemoveSubscriber(context.Context, *RemoveSubscriberRequest) (*RemoveSubscriberResponse, error)
+	RemoveBlock(context.Context, *RemoveBlockRequest) (*RemoveBlockResponse, error)
+	mustEmbedUnimplementedFriendsServiceServer()
+}
+
+// UnimplementedFriendsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFriendsServiceServer struct{}
+
+func (UnimplementedFriendsServiceServer) AddFriendship(context.Context, *AddFriendshipRequest) (*AddFriendshipResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddFriendship not implemented")
+}
+func (UnimplementedFriendsServiceServer) ListFriendships(context.Context, *ListFriendshipsRequest) (*ListFriendshipsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFriendships not implemented")
+}
+func (UnimplementedFriendsServiceServer) ListCommonFriends(context.Context, *ListCommonFriendsRequest) (*ListCommonFriendsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListCommonFriends not implemented")
+}
+func (UnimplementedFriendsServiceServer) AddSubscriber(context.Context, *AddSubscriberRequest) (*AddSubscriberResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddSubscriber not implemented")
+}
+func (UnimplementedFriendsServiceServer) AddBlock(context.Context, *AddBlockRequest) (*AddBlockResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddBlock not implemented")
+}
+func (UnimplementedFriendsServiceServer) GetListEmailCanReceiveUpdate(context.Context, *GetListEmailCanReceiveUpdateRequest) (*GetListEmailCanReceiveUpdateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetListEmailCanReceiveUpdate not implemented")
+}
+func (UnimplementedFriendsServiceServer) ListSubscribers(context.Context, *ListSubscribersRequest) (*ListSubscribersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListSubscribers not implemented")
+}
+func (UnimplementedFriendsServiceServer) ListBlocks(context.Context, *ListBlocksRequest) (*ListBlocksResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListBlocks not implemented")
+}
+func (UnimplementedFriendsServiceServer) RemoveFriendship(context.Context, *RemoveFriendshipRequest) (*RemoveFriendshipResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveFriendship not implemented")
+}
+func (UnimplementedFriendsServiceServer) RemoveSubscriber(context.Context, *RemoveSubscriberRequest) (*RemoveSubscriberResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveSubscriber not implemented")
+}
+func (UnimplementedFriendsServiceServer) RemoveBlock(context.Context, *RemoveBlockRequest) (*RemoveBlockResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveBlock not implemented")
+}
+func (UnimplementedFriendsServiceServer) mustEmbedUnimplementedFriendsServiceServer() {}
+func (UnimplementedFriendsServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeFriendsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FriendsServiceServer will
+// result in compilation errors.
+type UnsafeFriendsServiceServer interface {
+	mustEmbedUnimplementedFriendsServiceServer()
+}
+
+func RegisterFriendsServiceServer(s grpc.ServiceRegistrar, srv FriendsServiceServer) {
+	// If the following call panics, it indicates UnimplementedFriendsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FriendsService_ServiceDesc, srv)
+}
+
+func _FriendsService_AddFriendship_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddFriendshipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).AddFriendship(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_AddFriendship_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).AddFriendship(ctx, req.(*AddFriendshipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_ListFriendships_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFriendshipsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).ListFriendships(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_ListFriendships_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).ListFriendships(ctx, req.(*ListFriendshipsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_ListCommonFriends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCommonFriendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).ListCommonFriends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_ListCommonFriends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).ListCommonFriends(ctx, req.(*ListCommonFriendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_AddSubscriber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSubscriberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).AddSubscriber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_AddSubscriber_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).AddSubscriber(ctx, req.(*AddSubscriberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_AddBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).AddBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_AddBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).AddBlock(ctx, req.(*AddBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_GetListEmailCanReceiveUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetListEmailCanReceiveUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).GetListEmailCanReceiveUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_GetListEmailCanReceiveUpdate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).GetListEmailCanReceiveUpdate(ctx, req.(*GetListEmailCanReceiveUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_ListSubscribers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubscribersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).ListSubscribers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_ListSubscribers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).ListSubscribers(ctx, req.(*ListSubscribersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_ListBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).ListBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_ListBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).ListBlocks(ctx, req.(*ListBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_RemoveFriendship_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveFriendshipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).RemoveFriendship(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_RemoveFriendship_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).RemoveFriendship(ctx, req.(*RemoveFriendshipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_RemoveSubscriber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveSubscriberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).RemoveSubscriber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_RemoveSubscriber_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).RemoveSubscriber(ctx, req.(*RemoveSubscriberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendsService_RemoveBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendsServiceServer).RemoveBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendsService_RemoveBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendsServiceServer).RemoveBlock(ctx, req.(*RemoveBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FriendsService_ServiceDesc is the grpc.ServiceDesc for FriendsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FriendsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "friends.v1.FriendsService",
+	HandlerType: (*FriendsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddFriendship",
+			Handler:    _FriendsService_AddFriendship_Handler,
+		},
+		{
+			MethodName: "ListFriendships",
+			Handler:    _FriendsService_ListFriendships_Handler,
+		},
+		{
+			MethodName: "ListCommonFriends",
+			Handler:    _FriendsService_ListCommonFriends_Handler,
+		},
+		{
+			MethodName: "AddSubscriber",
+			Handler:    _FriendsService_AddSubscriber_Handler,
+		},
+		{
+			MethodName: "AddBlock",
+			Handler:    _FriendsService_AddBlock_Handler,
+		},
+		{
+			MethodName: "GetListEmailCanReceiveUpdate",
+			Handler:    _FriendsService_GetListEmailCanReceiveUpdate_Handler,
+		},
+		{
+			MethodName: "ListSubscribers",
+			Handler:    _FriendsService_ListSubscribers_Handler,
+		},
+		{
+			MethodName: "ListBlocks",
+			Handler:    _FriendsService_ListBlocks_Handler,
+		},
+		{
+			MethodName: "RemoveFriendship",
+			Handler:    _FriendsService_RemoveFriendship_Handler,
+		},
+		{
+			MethodName: "RemoveSubscriber",
+			Handler:    _FriendsService_RemoveSubscriber_Handler,
+		},
+		{
+			MethodName: "RemoveBlock",
+			Handler:    _FriendsService_RemoveBlock_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "friends/v1/friends.proto",
+}
@@ -0,0 +1,106 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/grpcserver/friendspb"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// METADATA_RETRY_AFTER is the header metadata telling a rate limited client how many seconds to wait
+const METADATA_RETRY_AFTER = "retry-after"
+
+// RateLimitKey return the bucket key of the call, false when the call is not limited by this key
+type RateLimitKey func(ctx context.Context) (string, bool)
+
+// RateLimitBudget return the budget of the rpc method, false when the method is not limited
+type RateLimitBudget func(fullMethod string) (ratelimit.Budget, bool)
+
+// RateLimiter limit the calls per peer before the credentials are checked and per requestor after the tenant is resolved,
+// with the same store and keys as the REST api so a caller has one budget over both transports
+type RateLimiter struct {
+	Store  ratelimit.Store
+	Budget RateLimitBudget
+	Logger echo.Logger
+}
+
+// writeMethods are the rpc that create or remove a relationship
+var writeMethods = map[string]bool{
+	friendspb.FriendsService_AddFriendship_FullMethodName:    true,
+	friendspb.FriendsService_AddSubscriber_FullMethodName:    true,
+	friendspb.FriendsService_AddBlock_FullMethodName:         true,
+	friendspb.FriendsService_RemoveFriendship_FullMethodName: true,
+	friendspb.FriendsService_RemoveSubscriber_FullMethodName: true,
+	friendspb.FriendsService_RemoveBlock_FullMethodName:      true,
+}
+
+// MethodBudget classify the rpc methods like the matching REST routes
+func MethodBudget(write, recipients, read ratelimit.Budget) RateLimitBudget {
+	return func(fullMethod string) (ratelimit.Budget, bool) {
+		switch {
+		case fullMethod == friendspb.FriendsService_GetListEmailCanReceiveUpdate_FullMethodName:
+			return recipients, true
+		case writeMethods[fullMethod]:
+			return write, true
+		}
+		return read, true
+	}
+}
+
+// ByPeer key the buckets by the client ip, it does not need authentication so it also throttle invalid credentials
+func ByPeer(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return "ip:" + ip, true
+}
+
+// ByRequestor key the buckets by the authenticated caller in its tenant, it must run after AuthInterceptor and TenantInterceptor
+func ByRequestor(ctx context.Context) (string, bool) {
+	return middleware.RequestorKey(ctx)
+}
+
+// RateLimitInterceptor take one token of the bucket of the key for the budget of the method and reject the call with
+// ResourceExhausted when it is empty. The limiter fail open, a store error is logged and the call is served.
+func (l *RateLimiter) RateLimitInterceptor(key RateLimitKey) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		budget, ok := l.Budget(info.FullMethod)
+		if !ok {
+			return next(ctx, req)
+		}
+
+		bucketKey, ok := key(ctx)
+		if !ok {
+			return next(ctx, req)
+		}
+
+		result, err := l.Store.Take(budget.Name+":"+bucketKey, budget, 1, time.Now())
+		if err != nil {
+			l.Logger.Error(fmt.Errorf("TAKE_RATE_LIMIT_TOKEN_FAIL: %w", err))
+			return next(ctx, req)
+		}
+
+		if !result.Allowed {
+			grpc.SetHeader(ctx, metadata.Pairs(METADATA_RETRY_AFTER, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))))
+			return nil, apperror.ErrRateLimited
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,69 @@
+package grpcserver_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/grpcserver"
+	"github.com/quanluong166/friends_management/internal/grpcserver/friendspb"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func setupLimitedClient(t *testing.T, ctrl *handler.MockUserRelationshipController, apiKey string, write, read ratelimit.Budget) friendspb.FriendsServiceClient {
+	limiter := &grpcserver.RateLimiter{
+		Store:  ratelimit.NewMemoryStore(),
+		Budget: grpcserver.MethodBudget(write, ratelimit.Budget{Name: "recipients", Limit: 10, Period: time.Minute}, read),
+		Logger: echo.New().Logger,
+	}
+	return setupClientWithServer(t, grpcserver.NewServer(ctrl, authenticators, limiter, echo.New().Logger), apiKey)
+}
+
+func TestFriendsServer_RateLimitRequestor(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	ctrl.On("AddBlock", "andy@example.com", "kate@example.com").Return(nil).Once()
+	ctrl.On("ListFriendships", "andy@example.com").Return([]string{}, int64(0), nil)
+	write := ratelimit.Budget{Name: "write", Limit: 1, Period: time.Minute}
+	read := ratelimit.Budget{Name: "read", Limit: 10, Period: time.Minute}
+	client := setupLimitedClient(t, ctrl, andyKey, write, read)
+	req := &friendspb.AddBlockRequest{Requestor: "andy@example.com", Target: "kate@example.com"}
+
+	_, err := client.AddBlock(context.Background(), req)
+	require.NoError(t, err)
+
+	var header metadata.MD
+	_, err = client.AddBlock(context.Background(), req, grpc.Header(&header))
+	assertStatus(t, err, codes.ResourceExhausted, apperror.CODE_RATE_LIMITED, nil)
+	assert.Equal(t, []string{"60"}, header.Get(grpcserver.METADATA_RETRY_AFTER))
+
+	//The reads have their own budget
+	_, err = client.ListFriendships(context.Background(), &friendspb.ListFriendshipsRequest{Email: "andy@example.com"})
+	require.NoError(t, err)
+	ctrl.AssertExpectations(t)
+}
+
+func TestFriendsServer_RateLimitPeer(t *testing.T) {
+	ctrl := new(handler.MockUserRelationshipController)
+	write := ratelimit.Budget{Name: "write", Limit: 10, Period: time.Minute}
+	read := ratelimit.Budget{Name: "read", Limit: 2, Period: time.Minute}
+	client := setupLimitedClient(t, ctrl, "wrong-key", write, read)
+	req := &friendspb.ListFriendshipsRequest{Email: "andy@example.com"}
+
+	//Invalid credentials are throttled too since the peer is limited before they are checked
+	for range 2 {
+		_, err := client.ListFriendships(context.Background(), req)
+		assertStatus(t, err, codes.Unauthenticated, apperror.CODE_UNAUTHENTICATED, nil)
+	}
+
+	_, err := client.ListFriendships(context.Background(), req)
+	assertStatus(t, err, codes.ResourceExhausted, apperror.CODE_RATE_LIMITED, nil)
+	ctrl.AssertNotCalled(t, "ListFriendships", "andy@example.com")
+}
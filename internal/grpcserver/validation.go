@@ -0,0 +1,73 @@
+package grpcserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/pkg/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// requestValidator collect every invalid field of a request, it uses the same field codes as the REST api
+type requestValidator struct {
+	fields []apperror.FieldError
+}
+
+func (v *requestValidator) add(field, code, detail string) {
+	v.fields = append(v.fields, apperror.FieldError{Field: field, Code: code, Detail: detail})
+}
+
+// email check a single required email field
+func (v *requestValidator) email(field, value string) {
+	if len(value) == 0 {
+		v.add(field, apperror.FIELD_REQUIRED, fmt.Sprintf("%s is required", field))
+		return
+	}
+
+	if !utils.IsValidEmail(value) {
+		v.add(field, apperror.FIELD_INVALID_EMAIL, fmt.Sprintf("%q is not a valid email", value))
+	}
+}
+
+// emailPair check two required emails that must be different users
+func (v *requestValidator) emailPair(field1, value1, field2, value2 string) {
+	v.email(field1, value1)
+	v.email(field2, value2)
+	if len(value1) > 0 && strings.EqualFold(value1, value2) {
+		v.add(field2, apperror.FIELD_DUPLICATE, fmt.Sprintf("%q is the same as %s", value2, field1))
+	}
+}
+
+// pagination check limit and offset are not negative
+func (v *requestValidator) pagination(limit, offset int32) {
+	if limit < 0 {
+		v.add("limit", apperror.FIELD_OUT_OF_RANGE, "limit must not be negative")
+	}
+	if offset < 0 {
+		v.add("offset", apperror.FIELD_OUT_OF_RANGE, "offset must not be negative")
+	}
+}
+
+// optionalTime check an optional timestamp is a valid time, it returns nil when the timestamp is not set
+func (v *requestValidator) optionalTime(field string, value *timestamppb.Timestamp) *time.Time {
+	if value == nil {
+		return nil
+	}
+
+	if err := value.CheckValid(); err != nil {
+		v.add(field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be a valid timestamp", field))
+		return nil
+	}
+	t := value.AsTime()
+	return &t
+}
+
+// err return validation error with all the invalid fields, nil when the request is valid
+func (v *requestValidator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return apperror.Validation("INVALID_INPUT", v.fields)
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+// AdminHandler is the handler for the moderation API
+type AdminHandler struct {
+	Controller controller.AdminController
+}
+
+func NewAdminHandler(Controller controller.AdminController) api.Admin {
+	return &AdminHandler{Controller: Controller}
+}
+
+// tenantController get the controller scoped to the tenant of the request
+func (sv *AdminHandler) tenantController(c echo.Context) controller.AdminController {
+	return sv.Controller.WithTenant(tenant.FromContext(c.Request().Context()))
+}
+
+// ListRelationships api for GET /admin/relationships?email=&type=&from=&to=&limit=&offset=
+func (sv *AdminHandler) ListRelationships(c echo.Context) error {
+	var v requestValidator
+	filter := repository.RelationshipFilter{
+		Email: c.QueryParam("email"),
+		Type:  c.QueryParam("type"),
+		From:  v.queryTime(c, "from"),
+		To:    v.queryTime(c, "to"),
+	}
+	if len(filter.Email) > 0 {
+		v.email("email", filter.Email, "EMAIL_IS_REQUIRED")
+	}
+	v.oneOf("type", filter.Type, constant.FRIEND_RELATIONSHIP_TYPE, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, constant.BLOCK_RELATIONSHIP_TYPE)
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		v.add("INVALID_FILTER_INPUT", "to", apperror.FIELD_OUT_OF_RANGE, "to must not be before from")
+	}
+	limit, offset := v.queryPagination(c)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	filter.Limit = normalizeLimit(limit)
+	filter.Offset = offset
+	relationships, count, err := sv.tenantController(c).ListRelationships(actorFrom(c), filter)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListRelationshipsResponse{Success: true, Relationships: make([]api.Relationship, 0, len(relationships)), Count: int(count), Limit: filter.Limit, Offset: filter.Offset}
+	for _, relationship := range relationships {
+		resp.Relationships = append(resp.Relationships, api.Relationship{
+			ID:        relationship.ID,
+			Requestor: relationship.RequestorEmail,
+			Target:    relationship.TargetEmail,
+			Type:      relationship.Type,
+			CreatedAt: relationship.CreatedAt,
+			UpdatedAt: relationship.UpdatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ForceRemoveRelationship api for DELETE /admin/relationships/:id
+func (sv *AdminHandler) ForceRemoveRelationship(c echo.Context) error {
+	var v requestValidator
+	id := v.pathID(c, "id")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).ForceRemoveRelationship(actorFrom(c), id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ForceUnblock api for DELETE /admin/blocks/:email/:other, it removes the blocks in both directions
+func (sv *AdminHandler) ForceUnblock(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	other := v.pathEmail(c, "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).ForceUnblock(actorFrom(c), email, other); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetQuota api for GET /admin/quotas/:email
+func (sv *AdminHandler) GetQuota(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	report, err := sv.tenantController(c).GetQuota(actorFrom(c), email)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, quotaResponse(report))
+}
+
+// SetQuota api for PUT /admin/quotas/:email, the body replace every cap of the override
+func (sv *AdminHandler) SetQuota(c echo.Context) error {
+	var req api.QuotaOverride
+	if err := c.Bind(&req); err != nil {
+		return apperror.BadRequest(err.Error())
+	}
+
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	v.quotaCap("max_friends", req.MaxFriends)
+	v.quotaCap("max_subscriptions", req.MaxSubscriptions)
+	v.quotaCap("max_blocks", req.MaxBlocks)
+	v.quotaCap("max_new_per_day", req.MaxNewPerDay)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	report, err := sv.tenantController(c).SetQuota(actorFrom(c), &model.UserQuota{
+		Email:            email,
+		MaxFriends:       req.MaxFriends,
+		MaxSubscriptions: req.MaxSubscriptions,
+		MaxBlocks:        req.MaxBlocks,
+		MaxNewPerDay:     req.MaxNewPerDay,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, quotaResponse(report))
+}
+
+// ResetQuota api for DELETE /admin/quotas/:email, the defaults apply again
+func (sv *AdminHandler) ResetQuota(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).ResetQuota(actorFrom(c), email); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func quotaResponse(report *controller.QuotaReport) api.QuotaResponse {
+	resp := api.QuotaResponse{
+		Success: true,
+		Email:   report.Email,
+		Quota: api.Quota{
+			MaxFriends:       report.Quota.MaxFriends,
+			MaxSubscriptions: report.Quota.MaxSubscriptions,
+			MaxBlocks:        report.Quota.MaxBlocks,
+			MaxNewPerDay:     report.Quota.MaxNewPerDay,
+		},
+		Usage: api.QuotaUsage{
+			Friends:       report.Usage.Friends,
+			Subscriptions: report.Usage.Subscriptions,
+			Blocks:        report.Usage.Blocks,
+			NewPerDay:     report.Usage.NewPerDay,
+		},
+	}
+	if report.Override != nil {
+		resp.Override = &api.QuotaOverride{
+			MaxFriends:       report.Override.MaxFriends,
+			MaxSubscriptions: report.Override.MaxSubscriptions,
+			MaxBlocks:        report.Override.MaxBlocks,
+			MaxNewPerDay:     report.Override.MaxNewPerDay,
+		}
+	}
+	return resp
+}
+
+// ListRelationshipEvents api for GET /admin/relationship-events?email=&action=&from=&to=&limit=&offset=
+func (sv *AdminHandler) ListRelationshipEvents(c echo.Context) error {
+	var v requestValidator
+	filter := repository.RelationshipEventFilter{
+		Email:  c.QueryParam("email"),
+		Action: c.QueryParam("action"),
+		From:   v.queryTime(c, "from"),
+		To:     v.queryTime(c, "to"),
+	}
+	if len(filter.Email) > 0 {
+		v.email("email", filter.Email, "EMAIL_IS_REQUIRED")
+	}
+	v.oneOf("action", filter.Action, constant.RELATIONSHIP_EVENT_CREATE, constant.RELATIONSHIP_EVENT_DELETE, constant.RELATIONSHIP_EVENT_BLOCK)
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		v.add("INVALID_FILTER_INPUT", "to", apperror.FIELD_OUT_OF_RANGE, "to must not be before from")
+	}
+	limit, offset := v.queryPagination(c)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	filter.Limit = normalizeLimit(limit)
+	filter.Offset = offset
+	events, count, err := sv.tenantController(c).ListRelationshipEvents(actorFrom(c), filter)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListRelationshipEventsResponse{Success: true, Events: make([]api.RelationshipEvent, 0, len(events)), Count: int(count), Limit: filter.Limit, Offset: filter.Offset}
+	for _, event := range events {
+		resp.Events = append(resp.Events, api.RelationshipEvent{
+			ID:        event.ID,
+			Action:    event.Action,
+			Requestor: event.RequestorEmail,
+			Target:    event.TargetEmail,
+			Before:    relationshipStates(event.Before),
+			After:     relationshipStates(event.After),
+			Actor:     event.Actor,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// relationshipStates convert the recorded states, an event without state get an empty list
+func relationshipStates(states []model.RelationshipState) []api.RelationshipState {
+	resp := make([]api.RelationshipState, 0, len(states))
+	for _, state := range states {
+		resp = append(resp, api.RelationshipState{Requestor: state.Requestor, Target: state.Target, Type: state.Type})
+	}
+	return resp
+}
+
+// actorFrom get the caller of an audited action or of a relationship change
+func actorFrom(c echo.Context) controller.Actor {
+	actor := controller.Actor{IP: c.RealIP(), UserAgent: c.Request().UserAgent()}
+	if principal := auth.FromContext(c.Request().Context()); principal != nil {
+		actor.Subject = principal.Subject
+	}
+	return actor
+}
+
+// quotaCap check an optional quota cap is not negative, zero means no cap
+func (v *requestValidator) quotaCap(field string, value *int64) {
+	if value != nil && *value < 0 {
+		v.add("INVALID_QUOTA_INPUT", field, apperror.FIELD_OUT_OF_RANGE, fmt.Sprintf("%s must not be negative", field))
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAdminController struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockAdminController) ListRelationships(actor controller.Actor, filter repository.RelationshipFilter) ([]model.UserRelationship, int64, error) {
+	args := m.Called(actor, filter)
+	var relationships []model.UserRelationship
+	if args.Get(0) != nil {
+		relationships = args.Get(0).([]model.UserRelationship)
+	}
+	return relationships, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAdminController) ForceRemoveRelationship(actor controller.Actor, id uint) error {
+	args := m.Called(actor, id)
+	return args.Error(0)
+}
+
+func (m *MockAdminController) ForceUnblock(actor controller.Actor, email1, email2 string) error {
+	args := m.Called(actor, email1, email2)
+	return args.Error(0)
+}
+
+func (m *MockAdminController) GetQuota(actor controller.Actor, email string) (*controller.QuotaReport, error) {
+	args := m.Called(actor, email)
+	var report *controller.QuotaReport
+	if args.Get(0) != nil {
+		report = args.Get(0).(*controller.QuotaReport)
+	}
+	return report, args.Error(1)
+}
+
+func (m *MockAdminController) SetQuota(actor controller.Actor, override *model.UserQuota) (*controller.QuotaReport, error) {
+	args := m.Called(actor, override)
+	var report *controller.QuotaReport
+	if args.Get(0) != nil {
+		report = args.Get(0).(*controller.QuotaReport)
+	}
+	return report, args.Error(1)
+}
+
+func (m *MockAdminController) ResetQuota(actor controller.Actor, email string) error {
+	args := m.Called(actor, email)
+	return args.Error(0)
+}
+
+func (m *MockAdminController) ListRelationshipEvents(actor controller.Actor, filter repository.RelationshipEventFilter) ([]model.RelationshipEvent, int64, error) {
+	args := m.Called(actor, filter)
+	var events []model.RelationshipEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]model.RelationshipEvent)
+	}
+	return events, args.Get(1).(int64), args.Error(2)
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockAdminController) WithTenant(tenantID string) controller.AdminController {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,260 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler(t *testing.T) {
+	admin := controller.Actor{Subject: adminPrincipal.Subject, IP: "192.0.2.1", UserAgent: "curl/8.0"}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	maxFriends, noCap := int64(10000), int64(0)
+	report := &controller.QuotaReport{
+		Email:    "alice@example.com",
+		Quota:    controller.Quota{MaxFriends: 10000, MaxSubscriptions: 5000, MaxBlocks: 1000, MaxNewPerDay: 0},
+		Override: &model.UserQuota{Email: "alice@example.com", MaxFriends: &maxFriends, MaxNewPerDay: &noCap},
+		Usage:    controller.QuotaUsage{Friends: 6000, Subscriptions: 10, Blocks: 1, NewPerDay: 250},
+	}
+	reportJSON := `{"success":true,"email":"alice@example.com",` +
+		`"quota":{"max_friends":10000,"max_subscriptions":5000,"max_blocks":1000,"max_new_per_day":0},` +
+		`"override":{"max_friends":10000,"max_subscriptions":null,"max_blocks":null,"max_new_per_day":0},` +
+		`"usage":{"friends":6000,"subscriptions":10,"blocks":1,"new_per_day":250}}`
+
+	tcs := map[string]struct {
+		method         string
+		path           string
+		reqBody        string
+		status         int
+		body           string
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"ListRelationships_Success": {
+			method:       http.MethodGet,
+			path:         "/admin/relationships?email=alice@example.com&type=FRIEND&from=2025-01-01T00:00:00Z&limit=5&offset=10",
+			status:       http.StatusOK,
+			body:         `{"success":true,"relationships":[{"id":1,"requestor":"alice@example.com","target":"bob@example.com","type":"FRIEND","created_at":"2025-01-02T03:04:05Z","updated_at":"2025-01-02T03:04:05Z"}],"count":11,"limit":5,"offset":10}`,
+			mockOn:       []string{"ListRelationships"},
+			callArgument: [][]interface{}{{admin, repository.RelationshipFilter{Email: "alice@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE, From: &from, Limit: 5, Offset: 10}}},
+			returnArgument: [][]interface{}{{[]model.UserRelationship{{
+				ID: 1, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE, CreatedAt: createdAt, UpdatedAt: createdAt,
+			}}, int64(11), nil}},
+		},
+		"ListRelationships_DefaultPagination": {
+			method:         http.MethodGet,
+			path:           "/admin/relationships",
+			status:         http.StatusOK,
+			body:           `{"success":true,"relationships":[],"count":0,"limit":20,"offset":0}`,
+			mockOn:         []string{"ListRelationships"},
+			callArgument:   [][]interface{}{{admin, repository.RelationshipFilter{Limit: 20}}},
+			returnArgument: [][]interface{}{{nil, int64(0), nil}},
+		},
+		"ListRelationshipEvents_Success": {
+			method:       http.MethodGet,
+			path:         "/admin/relationship-events?email=alice@example.com&action=BLOCK&from=2025-01-01T00:00:00Z&limit=5",
+			status:       http.StatusOK,
+			body:         `{"success":true,"events":[{"id":3,"action":"BLOCK","requestor":"alice@example.com","target":"bob@example.com","before":[{"requestor":"alice@example.com","target":"bob@example.com","type":"SUBSCRIBER"}],"after":[{"requestor":"alice@example.com","target":"bob@example.com","type":"BLOCK"}],"actor":"alice@example.com","ip":"198.51.100.7","user_agent":"app/2.1","created_at":"2025-01-02T03:04:05Z"}],"count":1,"limit":5,"offset":0}`,
+			mockOn:       []string{"ListRelationshipEvents"},
+			callArgument: [][]interface{}{{admin, repository.RelationshipEventFilter{Email: "alice@example.com", Action: constant.RELATIONSHIP_EVENT_BLOCK, From: &from, Limit: 5}}},
+			returnArgument: [][]interface{}{{[]model.RelationshipEvent{{
+				ID: 3, Action: constant.RELATIONSHIP_EVENT_BLOCK, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com",
+				Before:    []model.RelationshipState{{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}},
+				After:     []model.RelationshipState{{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.BLOCK_RELATIONSHIP_TYPE}},
+				Actor:     "alice@example.com",
+				IP:        "198.51.100.7",
+				UserAgent: "app/2.1",
+				CreatedAt: createdAt,
+			}}, int64(1), nil}},
+		},
+		"ListRelationshipEvents_EmptyStates": {
+			method:       http.MethodGet,
+			path:         "/admin/relationship-events",
+			status:       http.StatusOK,
+			body:         `{"success":true,"events":[{"id":4,"action":"DELETE","requestor":"alice@example.com","target":"bob@example.com","before":[],"after":[],"actor":"","ip":"","user_agent":"","created_at":"2025-01-02T03:04:05Z"}],"count":1,"limit":20,"offset":0}`,
+			mockOn:       []string{"ListRelationshipEvents"},
+			callArgument: [][]interface{}{{admin, repository.RelationshipEventFilter{Limit: 20}}},
+			returnArgument: [][]interface{}{{[]model.RelationshipEvent{{
+				ID: 4, Action: constant.RELATIONSHIP_EVENT_DELETE, RequestorEmail: "alice@example.com", TargetEmail: "bob@example.com", CreatedAt: createdAt,
+			}}, int64(1), nil}},
+		},
+		"ListRelationshipEvents_InvalidAction": {
+			method: http.MethodGet,
+			path:   "/admin/relationship-events?action=UPDATE",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"action","code":"INVALID_VALUE"`,
+		},
+		"ListRelationshipEvents_FromAfterTo": {
+			method: http.MethodGet,
+			path:   "/admin/relationship-events?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"to","code":"OUT_OF_RANGE"`,
+		},
+		"ListRelationships_InvalidType": {
+			method: http.MethodGet,
+			path:   "/admin/relationships?type=ENEMY",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"type","code":"INVALID_VALUE"`,
+		},
+		"ListRelationships_InvalidTime": {
+			method: http.MethodGet,
+			path:   "/admin/relationships?from=yesterday",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"from","code":"INVALID_VALUE"`,
+		},
+		"ListRelationships_FromAfterTo": {
+			method: http.MethodGet,
+			path:   "/admin/relationships?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"to","code":"OUT_OF_RANGE"`,
+		},
+		"ListRelationships_InvalidEmail": {
+			method: http.MethodGet,
+			path:   "/admin/relationships?email=alice",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"email","code":"INVALID_EMAIL"`,
+		},
+		"ForceRemoveRelationship_Success": {
+			method:         http.MethodDelete,
+			path:           "/admin/relationships/7",
+			status:         http.StatusNoContent,
+			mockOn:         []string{"ForceRemoveRelationship"},
+			callArgument:   [][]interface{}{{admin, uint(7)}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"ForceRemoveRelationship_InvalidID": {
+			method: http.MethodDelete,
+			path:   "/admin/relationships/abc",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"id","code":"INVALID_VALUE"`,
+		},
+		"ForceRemoveRelationship_NotFound": {
+			method:         http.MethodDelete,
+			path:           "/admin/relationships/7",
+			status:         http.StatusNotFound,
+			body:           `"detail":"RELATIONSHIP_NOT_FOUND"`,
+			mockOn:         []string{"ForceRemoveRelationship"},
+			callArgument:   [][]interface{}{{admin, uint(7)}},
+			returnArgument: [][]interface{}{{apperror.NotFound("RELATIONSHIP_NOT_FOUND")}},
+		},
+		"ForceUnblock_Success": {
+			method:         http.MethodDelete,
+			path:           "/admin/blocks/alice@example.com/bob@example.com",
+			status:         http.StatusNoContent,
+			mockOn:         []string{"ForceUnblock"},
+			callArgument:   [][]interface{}{{admin, "alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"ForceUnblock_DatabaseError": {
+			method:         http.MethodDelete,
+			path:           "/admin/blocks/alice@example.com/bob@example.com",
+			status:         http.StatusInternalServerError,
+			body:           `"code":"INTERNAL_ERROR"`,
+			mockOn:         []string{"ForceUnblock"},
+			callArgument:   [][]interface{}{{admin, "alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{errors.New("DATABASE_ERROR")}},
+		},
+		"GetQuota_Success": {
+			method:         http.MethodGet,
+			path:           "/admin/quotas/alice@example.com",
+			status:         http.StatusOK,
+			body:           reportJSON,
+			mockOn:         []string{"GetQuota"},
+			callArgument:   [][]interface{}{{admin, "alice@example.com"}},
+			returnArgument: [][]interface{}{{report, nil}},
+		},
+		"GetQuota_DefaultsHaveNoOverride": {
+			method:         http.MethodGet,
+			path:           "/admin/quotas/bob@example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true,"email":"bob@example.com","quota":{"max_friends":5000,"max_subscriptions":5000,"max_blocks":1000,"max_new_per_day":200},"override":null,"usage":{"friends":0,"subscriptions":0,"blocks":0,"new_per_day":0}}`,
+			mockOn:         []string{"GetQuota"},
+			callArgument:   [][]interface{}{{admin, "bob@example.com"}},
+			returnArgument: [][]interface{}{{&controller.QuotaReport{Email: "bob@example.com", Quota: controller.Quota{MaxFriends: 5000, MaxSubscriptions: 5000, MaxBlocks: 1000, MaxNewPerDay: 200}}, nil}},
+		},
+		"SetQuota_Success": {
+			method:         http.MethodPut,
+			path:           "/admin/quotas/alice@example.com",
+			reqBody:        `{"max_friends":10000,"max_new_per_day":0}`,
+			status:         http.StatusOK,
+			body:           reportJSON,
+			mockOn:         []string{"SetQuota"},
+			callArgument:   [][]interface{}{{admin, &model.UserQuota{Email: "alice@example.com", MaxFriends: &maxFriends, MaxNewPerDay: &noCap}}},
+			returnArgument: [][]interface{}{{report, nil}},
+		},
+		"SetQuota_NegativeCap": {
+			method:  http.MethodPut,
+			path:    "/admin/quotas/alice@example.com",
+			reqBody: `{"max_friends":-1}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"max_friends","code":"OUT_OF_RANGE"`,
+		},
+		"SetQuota_InvalidEmail": {
+			method:  http.MethodPut,
+			path:    "/admin/quotas/alice",
+			reqBody: `{"max_friends":10}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"email","code":"INVALID_EMAIL"`,
+		},
+		"ResetQuota_Success": {
+			method:         http.MethodDelete,
+			path:           "/admin/quotas/alice@example.com",
+			status:         http.StatusNoContent,
+			mockOn:         []string{"ResetQuota"},
+			callArgument:   [][]interface{}{{admin, "alice@example.com"}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"ResetQuota_NoOverride": {
+			method:         http.MethodDelete,
+			path:           "/admin/quotas/alice@example.com",
+			status:         http.StatusNotFound,
+			body:           `"detail":"QUOTA_OVERRIDE_NOT_FOUND"`,
+			mockOn:         []string{"ResetQuota"},
+			callArgument:   [][]interface{}{{admin, "alice@example.com"}},
+			returnArgument: [][]interface{}{{apperror.NotFound("QUOTA_OVERRIDE_NOT_FOUND")}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockAdminController)
+			for i, method := range tc.mockOn {
+				mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterAdminRoutes(e, handler.NewAdminHandler(mockController), authenticateAsAdmin)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.reqBody))
+			req.Header.Set("User-Agent", "curl/8.0")
+			if len(tc.reqBody) > 0 {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.JSONEq(t, tc.body, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.body)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Admin is the moderation API, it is only available to callers with the admin scope
+type Admin interface {
+	ListRelationships(c echo.Context) error
+	ForceRemoveRelationship(c echo.Context) error
+	ForceUnblock(c echo.Context) error
+	GetQuota(c echo.Context) error
+	SetQuota(c echo.Context) error
+	ResetQuota(c echo.Context) error
+	ListRelationshipEvents(c echo.Context) error
+}
+
+// Relationship is one row of the relationship graph
+type Relationship struct {
+	ID        uint      `json:"id"`
+	Requestor string    `json:"requestor"`
+	Target    string    `json:"target"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListRelationshipsResponse is the response body for list relationships API
+type ListRelationshipsResponse struct {
+	Success       bool           `json:"success"`
+	Relationships []Relationship `json:"relationships"`
+	Count         int            `json:"count"`
+	Limit         int            `json:"limit"`
+	Offset        int            `json:"offset"`
+}
+
+// Quota is the caps of one email, zero means no cap
+type Quota struct {
+	MaxFriends       int64 `json:"max_friends"`
+	MaxSubscriptions int64 `json:"max_subscriptions"`
+	MaxBlocks        int64 `json:"max_blocks"`
+	MaxNewPerDay     int64 `json:"max_new_per_day"`
+}
+
+// QuotaOverride is the request body for set quota API, a null cap keep the default
+type QuotaOverride struct {
+	MaxFriends       *int64 `json:"max_friends"`
+	MaxSubscriptions *int64 `json:"max_subscriptions"`
+	MaxBlocks        *int64 `json:"max_blocks"`
+	MaxNewPerDay     *int64 `json:"max_new_per_day"`
+}
+
+// QuotaUsage is what one email already created, new_per_day count the last 24 hours
+type QuotaUsage struct {
+	Friends       int64 `json:"friends"`
+	Subscriptions int64 `json:"subscriptions"`
+	Blocks        int64 `json:"blocks"`
+	NewPerDay     int64 `json:"new_per_day"`
+}
+
+// QuotaResponse is the response body for get and set quota API, override is null when the defaults apply
+type QuotaResponse struct {
+	Success  bool           `json:"success"`
+	Email    string         `json:"email"`
+	Quota    Quota          `json:"quota"`
+	Override *QuotaOverride `json:"override"`
+	Usage    QuotaUsage     `json:"usage"`
+}
+
+// RelationshipState is one relationship row before or after a change
+type RelationshipState struct {
+	Requestor string `json:"requestor"`
+	Target    string `json:"target"`
+	Type      string `json:"type"`
+}
+
+// RelationshipEvent is one change of the relationship graph, before is empty for a creation and after is empty for a deletion
+type RelationshipEvent struct {
+	ID        uint                `json:"id"`
+	Action    string              `json:"action"`
+	Requestor string              `json:"requestor"`
+	Target    string              `json:"target"`
+	Before    []RelationshipState `json:"before"`
+	After     []RelationshipState `json:"after"`
+	Actor     string              `json:"actor"`
+	IP        string              `json:"ip"`
+	UserAgent string              `json:"user_agent"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// ListRelationshipEventsResponse is the response body for list relationship events API
+type ListRelationshipEventsResponse struct {
+	Success bool                `json:"success"`
+	Events  []RelationshipEvent `json:"events"`
+	Count   int                 `json:"count"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+}
@@ -0,0 +1,33 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+// NotificationPreference is the API to choose how and when a user is emailed about status updates
+type NotificationPreference interface {
+	GetPreference(c echo.Context) error
+	SetPreference(c echo.Context) error
+}
+
+// NotificationPreferenceRequest is the request body for set notification preference API, frequency is one of IMMEDIATE, HOURLY, DAILY, WEEKLY.
+// The quiet hours are "15:04" times in the IANA timezone, UTC when empty, mentions_only leaves out the updates that do not mention the user
+// and ignore_stranger_mentions leaves out the updates that mention the user when the author is neither a friend nor subscribed to
+type NotificationPreferenceRequest struct {
+	Frequency              string `json:"frequency"`
+	Timezone               string `json:"timezone"`
+	QuietHoursStart        string `json:"quiet_hours_start"`
+	QuietHoursEnd          string `json:"quiet_hours_end"`
+	MentionsOnly           bool   `json:"mentions_only"`
+	IgnoreStrangerMentions bool   `json:"ignore_stranger_mentions"`
+}
+
+// NotificationPreferenceResponse is the response body for get and set notification preference API, the quiet hours are omitted when there are none
+type NotificationPreferenceResponse struct {
+	Success                bool   `json:"success"`
+	Email                  string `json:"email"`
+	Frequency              string `json:"frequency"`
+	Timezone               string `json:"timezone"`
+	QuietHoursStart        string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd          string `json:"quiet_hours_end,omitempty"`
+	MentionsOnly           bool   `json:"mentions_only"`
+	IgnoreStrangerMentions bool   `json:"ignore_stranger_mentions"`
+}
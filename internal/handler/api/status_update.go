@@ -0,0 +1,40 @@
+package api
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// StatusUpdate is the API to post status updates and read the feed of a user
+type StatusUpdate interface {
+	PostUpdate(c echo.Context) error
+	ListFeed(c echo.Context) error
+}
+
+// PostStatusUpdateRequest is the request body for post status update API, the emails mentioned in the text receive it too
+type PostStatusUpdateRequest struct {
+	Text string `json:"text"`
+}
+
+// PostedUpdate is one status update as shown in a feed
+type PostedUpdate struct {
+	ID        uint      `json:"id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PostStatusUpdateResponse is the response body for post status update API
+type PostStatusUpdateResponse struct {
+	Success bool         `json:"success"`
+	Update  PostedUpdate `json:"update"`
+}
+
+// ListFeedResponse is the response body for list feed API, next_before is the before of the next page and is omitted on the last page
+type ListFeedResponse struct {
+	Success    bool           `json:"success"`
+	Updates    []PostedUpdate `json:"updates"`
+	Limit      int            `json:"limit"`
+	NextBefore uint           `json:"next_before,omitempty"`
+}
@@ -0,0 +1,10 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+// Stream push the relationship events of a user as they are published, over server sent events or a websocket.
+// Every event is the same json message delivered to the webhooks, a reconnecting client resumes after the last event id it received.
+type Stream interface {
+	Events(c echo.Context) error
+	EventsWebSocket(c echo.Context) error
+}
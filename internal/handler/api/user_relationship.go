@@ -1,6 +1,10 @@
 package api
 
-import "github.com/labstack/echo/v4"
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
 
 type UserRelationship interface {
 	AddFriend(c echo.Context) error
@@ -9,6 +13,8 @@ type UserRelationship interface {
 	ListCommonFriends(c echo.Context) error
 	AddBlock(c echo.Context) error
 	GetListEmailCanReceiveUpdate(c echo.Context) error
+	ListSubscribers(c echo.Context) error
+	ListBlocks(c echo.Context) error
 }
 
 // AddFriendRequest is the request body for add friend API
@@ -22,15 +28,43 @@ type CommonResponse struct {
 }
 
 // ErrorResponse is the error response body for all API
-
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Code    string          `json:"code,omitempty"`
+	Quota   *QuotaViolation `json:"quota,omitempty"`
+}
+
+// ProblemResponse is the RFC 7807 application/problem+json error body for all API
+type ProblemResponse struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []ProblemFieldError `json:"errors,omitempty"`
+	Quota    *QuotaViolation     `json:"quota,omitempty"`
+}
+
+// ProblemFieldError describe one invalid field in the problem response
+type ProblemFieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// QuotaViolation describe the quota a request would exceed, it is only set for QUOTA_EXCEEDED errors
+type QuotaViolation struct {
+	Name  string `json:"name"`
+	Limit int64  `json:"limit"`
+	Usage int64  `json:"usage"`
 }
 
 // ListFriendRequest is the request body for list friend API
 type ListFriendRequest struct {
-	Email string `json:"email"`
+	Email string     `json:"email"`
+	AsOf  *time.Time `json:"as_of,omitempty"`
 }
 
 // ListFriendResponse is the response body for list friend AP
@@ -70,8 +104,50 @@ type GetListEmailCanReceiveUpdateRequest struct {
 	Text   string `json:"text"`
 }
 
-// GetListEmailCanReceiveUpdateResponse is the response body for get list recipient API
+// GetListEmailCanReceiveUpdateResponse is the response body for get list recipient API, recipients receive the update now
+// and deferred is omitted when no recipient is in its quiet hours
 type GetListEmailCanReceiveUpdateResponse struct {
-	Success    bool     `json:"success"`
-	Recipients []string `json:"recipients"`
+	Success    bool                `json:"success"`
+	Recipients []string            `json:"recipients"`
+	Deferred   []DeferredRecipient `json:"deferred,omitempty"`
+}
+
+// DeferredRecipient is a recipient in its quiet hours, it receives the update at until
+type DeferredRecipient struct {
+	Email string    `json:"email"`
+	Until time.Time `json:"until"`
+}
+
+// ListSubscribersRequest is the request body for list subscribers API
+type ListSubscribersRequest struct {
+	Email  string     `json:"email"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+	AsOf   *time.Time `json:"as_of,omitempty"`
+}
+
+// ListSubscribersResponse is the response body for list subscribers API
+type ListSubscribersResponse struct {
+	Success     bool     `json:"success"`
+	Subscribers []string `json:"subscribers"`
+	Count       int      `json:"count"`
+	Limit       int      `json:"limit"`
+	Offset      int      `json:"offset"`
+}
+
+// ListBlocksRequest is the request body for list blocks API
+type ListBlocksRequest struct {
+	Requestor string     `json:"requestor"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+	AsOf      *time.Time `json:"as_of,omitempty"`
+}
+
+// ListBlocksResponse is the response body for list blocks API
+type ListBlocksResponse struct {
+	Success bool     `json:"success"`
+	Blocks  []string `json:"blocks"`
+	Count   int      `json:"count"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
 }
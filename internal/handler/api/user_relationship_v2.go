@@ -0,0 +1,19 @@
+package api
+
+import "github.com/labstack/echo/v4"
+
+// UserRelationshipV2 is the resource oriented version of the user relationship API.
+// The user email and the other email are path parameters, list filters are query parameters.
+type UserRelationshipV2 interface {
+	ListFriends(c echo.Context) error
+	PutFriend(c echo.Context) error
+	DeleteFriend(c echo.Context) error
+	ListCommonFriends(c echo.Context) error
+	ListSubscribers(c echo.Context) error
+	PutSubscription(c echo.Context) error
+	DeleteSubscription(c echo.Context) error
+	ListBlocks(c echo.Context) error
+	PutBlock(c echo.Context) error
+	DeleteBlock(c echo.Context) error
+	ListRecipients(c echo.Context) error
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Webhook is the API to manage the webhooks of partner apps, it is only available to callers with the admin scope
+type Webhook interface {
+	CreateWebhook(c echo.Context) error
+	ListWebhooks(c echo.Context) error
+	DeleteWebhook(c echo.Context) error
+	ListDeadLetters(c echo.Context) error
+	ReplayDeadLetter(c echo.Context) error
+}
+
+// CreateWebhookRequest is the request body for create webhook API, an empty event_types subscribes to every event
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// WebhookEndpoint is one registered webhook, the secret is never listed
+type WebhookEndpoint struct {
+	ID         uint      `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateWebhookResponse is the response body for create webhook API, it is the only response with the signing secret
+type CreateWebhookResponse struct {
+	Success bool            `json:"success"`
+	Webhook WebhookEndpoint `json:"webhook"`
+	Secret  string          `json:"secret"`
+}
+
+// ListWebhooksResponse is the response body for list webhooks API
+type ListWebhooksResponse struct {
+	Success  bool              `json:"success"`
+	Webhooks []WebhookEndpoint `json:"webhooks"`
+}
+
+// WebhookDeadLetter is a delivery that failed every attempt, payload is the json body that was sent
+type WebhookDeadLetter struct {
+	ID        uint      `json:"id"`
+	EventID   uint      `json:"event_id"`
+	EventType string    `json:"event_type"`
+	Payload   string    `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListWebhookDeadLettersResponse is the response body for list dead letters API
+type ListWebhookDeadLettersResponse struct {
+	Success     bool                `json:"success"`
+	DeadLetters []WebhookDeadLetter `json:"dead_letters"`
+	Count       int                 `json:"count"`
+	Limit       int                 `json:"limit"`
+	Offset      int                 `json:"offset"`
+}
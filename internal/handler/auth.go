@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+)
+
+// authorizeActor check the email acting in the request is the authenticated caller, admins can act as anyone
+func authorizeActor(c echo.Context, email string) error {
+	principal := auth.FromContext(c.Request().Context())
+	if principal == nil {
+		return apperror.ErrUnauthenticated
+	}
+
+	if !principal.CanActAs(email) {
+		return apperror.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+var adminPrincipal = &auth.Principal{Subject: "ops@example.com", Scopes: []string{auth.SCOPE_ADMIN}}
+
+// asAdmin authenticate the request as an admin so the handler accept any requestor
+func asAdmin(req *http.Request) *http.Request {
+	return req.WithContext(auth.NewContext(req.Context(), adminPrincipal))
+}
+
+func authenticateAsAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.SetRequest(asAdmin(c.Request()))
+		return next(c)
+	}
+}
+
+func TestUserRelationshipHandler_AuthorizeActor(t *testing.T) {
+	tcs := map[string]struct {
+		principal *auth.Principal
+		body      string
+		status    int
+		code      string
+	}{
+		"Requestor is the caller": {
+			principal: &auth.Principal{Subject: "Andy@Example.com"},
+			body:      `{"requestor":"andy@example.com","target":"john@example.com"}`,
+			status:    http.StatusOK,
+		},
+		"Requestor is another user": {
+			principal: &auth.Principal{Subject: "mallory@example.com", Scopes: []string{"user"}},
+			body:      `{"requestor":"andy@example.com","target":"john@example.com"}`,
+			status:    http.StatusForbidden,
+			code:      apperror.CODE_FORBIDDEN,
+		},
+		"Admin act as another user": {
+			principal: adminPrincipal,
+			body:      `{"requestor":"andy@example.com","target":"john@example.com"}`,
+			status:    http.StatusOK,
+		},
+		"Not authenticated": {
+			body:   `{"requestor":"andy@example.com","target":"john@example.com"}`,
+			status: http.StatusUnauthorized,
+			code:   apperror.CODE_UNAUTHENTICATED,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockUserRelationshipController)
+			if tc.status == http.StatusOK {
+				mockController.On("AddBlock", "andy@example.com", "john@example.com").Return(nil)
+			}
+			svc := &handler.UserRelationshipHandler{Controller: mockController}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/block", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tc.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.principal))
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if err := svc.AddBlock(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+
+			assert.Equal(t, tc.status, rec.Code)
+			if len(tc.code) > 0 {
+				assert.Contains(t, rec.Body.String(), `"code":"`+tc.code+`"`)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRelationshipV2Handler_AuthorizeActor(t *testing.T) {
+	tcs := map[string]struct {
+		method string
+		path   string
+		status int
+	}{
+		"Block as another user": {
+			method: http.MethodPut,
+			path:   "/api/v2/users/andy@example.com/blocks/john@example.com",
+			status: http.StatusForbidden,
+		},
+		"Recipients of another user": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/andy@example.com/recipients",
+			status: http.StatusForbidden,
+		},
+		"Friends of another user are public": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/andy@example.com/friends",
+			status: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockUserRelationshipController)
+			mockController.On("ListFriendships", "andy@example.com").Return([]string{}, int64(0), nil)
+			authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					principal := &auth.Principal{Subject: "mallory@example.com"}
+					c.SetRequest(c.Request().WithContext(auth.NewContext(c.Request().Context(), principal)))
+					return next(c)
+				}
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterUserRelationshipV2Routes(e, handler.NewUserRelationshipV2Handler(mockController), authenticate)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
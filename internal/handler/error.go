@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+)
+
+// MIMEApplicationProblemJSON is the RFC 7807 media type of the error body
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// statusByCode map domain error code to http status code
+var statusByCode = map[string]int{
+	apperror.CODE_BAD_REQUEST:        http.StatusBadRequest,
+	apperror.CODE_INVALID_INPUT:      http.StatusUnprocessableEntity,
+	apperror.CODE_NOT_FOUND:          http.StatusNotFound,
+	apperror.CODE_ALREADY_FRIENDS:    http.StatusConflict,
+	apperror.CODE_ALREADY_SUBSCRIBED: http.StatusConflict,
+	apperror.CODE_ALREADY_BLOCKED:    http.StatusConflict,
+	apperror.CODE_BLOCKED:            http.StatusConflict,
+	apperror.CODE_IDEMPOTENCY_IN_USE: http.StatusConflict,
+	apperror.CODE_IDEMPOTENCY_REUSED: http.StatusUnprocessableEntity,
+	apperror.CODE_UNAUTHENTICATED:    http.StatusUnauthorized,
+	apperror.CODE_FORBIDDEN:          http.StatusForbidden,
+	apperror.CODE_RATE_LIMITED:       http.StatusTooManyRequests,
+	apperror.CODE_QUOTA_EXCEEDED:     http.StatusForbidden,
+}
+
+// HTTPErrorHandler is the central echo error handler, it map errors returned by handlers to status code and error body.
+// The body is application/problem+json unless the client only accepts application/json, then the legacy ErrorResponse is used.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, appErr := resolveError(err)
+	if status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+
+	var writeErr error
+	switch {
+	case c.Request().Method == http.MethodHead:
+		writeErr = c.NoContent(status)
+	case acceptsLegacyError(c.Request()):
+		writeErr = c.JSON(status, api.ErrorResponse{Success: false, Message: appErr.Message, Code: appErr.Code, Quota: buildQuota(appErr)})
+	default:
+		c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+		writeErr = c.JSON(status, buildProblem(status, appErr, c.Request().URL.Path))
+	}
+	if writeErr != nil {
+		c.Logger().Error(writeErr)
+	}
+}
+
+// resolveError find the status code and the client safe domain error for any error returned by a handler
+func resolveError(err error) (int, *apperror.Error) {
+	if appErr := apperror.As(err); appErr != nil {
+		status, ok := statusByCode[appErr.Code]
+		if ok {
+			return status, appErr
+		}
+	}
+
+	//Errors raised by echo itself such as unknown route or method not allowed
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code < http.StatusInternalServerError {
+		text := strings.ToUpper(strings.ReplaceAll(http.StatusText(httpErr.Code), " ", "_"))
+		return httpErr.Code, &apperror.Error{Code: text, Message: text}
+	}
+
+	//Everything else is an internal failure, the detail is logged and never returned to the client
+	return http.StatusInternalServerError, apperror.ErrInternal
+}
+
+// acceptsLegacyError check if the client asked for application/json without application/problem+json
+func acceptsLegacyError(r *http.Request) bool {
+	accept := r.Header.Get(echo.HeaderAccept)
+	if strings.Contains(accept, MIMEApplicationProblemJSON) {
+		return false
+	}
+	return strings.Contains(accept, echo.MIMEApplicationJSON)
+}
+
+func buildProblem(status int, appErr *apperror.Error, instance string) api.ProblemResponse {
+	problem := api.ProblemResponse{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   appErr.Message,
+		Instance: instance,
+		Code:     appErr.Code,
+		Quota:    buildQuota(appErr),
+	}
+
+	for _, field := range appErr.Fields {
+		problem.Errors = append(problem.Errors, api.ProblemFieldError{
+			Field:  field.Field,
+			Code:   field.Code,
+			Detail: field.Detail,
+		})
+	}
+	return problem
+}
+
+func buildQuota(appErr *apperror.Error) *api.QuotaViolation {
+	if appErr.Quota == nil {
+		return nil
+	}
+	return &api.QuotaViolation{Name: appErr.Quota.Name, Limit: appErr.Quota.Limit, Usage: appErr.Quota.Usage}
+}
@@ -0,0 +1,150 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	tcs := map[string]struct {
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		"BadRequest": {
+			err:     apperror.BadRequest("code=400, message=Syntax error"),
+			status:  http.StatusBadRequest,
+			code:    apperror.CODE_BAD_REQUEST,
+			message: "code=400, message=Syntax error",
+		},
+		"InvalidInput": {
+			err:     apperror.InvalidInput("INVALID_EMAIL_INPUT"),
+			status:  http.StatusUnprocessableEntity,
+			code:    apperror.CODE_INVALID_INPUT,
+			message: "INVALID_EMAIL_INPUT",
+		},
+		"NotFound": {
+			err:     apperror.ErrNotFound,
+			status:  http.StatusNotFound,
+			code:    apperror.CODE_NOT_FOUND,
+			message: "NOT_FOUND",
+		},
+		"WrappedConflict": {
+			err:     fmt.Errorf("ADD_BLOCK_FAIL: %w", apperror.ErrAlreadyBlocked),
+			status:  http.StatusConflict,
+			code:    apperror.CODE_ALREADY_BLOCKED,
+			message: "ALREADY_BEEN_BLOCKED",
+		},
+		"Blocked": {
+			err:     apperror.ErrBlocked,
+			status:  http.StatusConflict,
+			code:    apperror.CODE_BLOCKED,
+			message: "ONE_OF_YOU_BLOCK_EACH_OTHER",
+		},
+		"RateLimited": {
+			err:     apperror.ErrRateLimited,
+			status:  http.StatusTooManyRequests,
+			code:    apperror.CODE_RATE_LIMITED,
+			message: "TOO_MANY_REQUESTS",
+		},
+		"EchoRouteNotFound": {
+			err:     echo.ErrNotFound,
+			status:  http.StatusNotFound,
+			code:    "NOT_FOUND",
+			message: "NOT_FOUND",
+		},
+		"InternalDetailIsHidden": {
+			err:     fmt.Errorf("GET_LIST_FRIENDSHIP_FAIL: %w", errors.New("pq: connection refused")),
+			status:  http.StatusInternalServerError,
+			code:    apperror.CODE_INTERNAL,
+			message: "INTERNAL_SERVER_ERROR",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/friend", nil)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			handler.HTTPErrorHandler(tc.err, c)
+
+			var resp api.ErrorResponse
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, rec.Code)
+			assert.False(t, resp.Success)
+			assert.Equal(t, tc.code, resp.Code)
+			assert.Equal(t, tc.message, resp.Message)
+		})
+	}
+}
+
+func TestHTTPErrorHandler_ContentNegotiation(t *testing.T) {
+	e := echo.New()
+	tcs := map[string]struct {
+		accept      string
+		contentType string
+	}{
+		"NoAcceptHeader": {
+			accept:      "",
+			contentType: handler.MIMEApplicationProblemJSON,
+		},
+		"Wildcard": {
+			accept:      "*/*",
+			contentType: handler.MIMEApplicationProblemJSON,
+		},
+		"ProblemPreferred": {
+			accept:      "application/problem+json, application/json;q=0.9",
+			contentType: handler.MIMEApplicationProblemJSON,
+		},
+		"LegacyClient": {
+			accept:      echo.MIMEApplicationJSON,
+			contentType: echo.MIMEApplicationJSON,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/block", nil)
+			if tc.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			handler.HTTPErrorHandler(apperror.ErrAlreadyBlocked, c)
+
+			assert.Equal(t, http.StatusConflict, rec.Code)
+			assert.Contains(t, rec.Header().Get(echo.HeaderContentType), tc.contentType)
+			if tc.contentType == handler.MIMEApplicationProblemJSON {
+				var resp api.ProblemResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "about:blank", resp.Type)
+				assert.Equal(t, "Conflict", resp.Title)
+				assert.Equal(t, http.StatusConflict, resp.Status)
+				assert.Equal(t, "ALREADY_BEEN_BLOCKED", resp.Detail)
+				assert.Equal(t, "/api/user/relationship/block", resp.Instance)
+				assert.Equal(t, apperror.CODE_ALREADY_BLOCKED, resp.Code)
+			} else {
+				var resp api.ErrorResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "ALREADY_BEEN_BLOCKED", resp.Message)
+				assert.Equal(t, apperror.CODE_ALREADY_BLOCKED, resp.Code)
+			}
+		})
+	}
+}
@@ -3,14 +3,27 @@ package handler
 import (
 	"github.com/quanluong166/friends_management/internal/controller"
 	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/stream"
 )
 
 type Handler struct {
-	UserRelationshipHandler api.UserRelationship
+	UserRelationshipHandler       api.UserRelationship
+	UserRelationshipV2Handler     api.UserRelationshipV2
+	AdminHandler                  api.Admin
+	WebhookHandler                api.Webhook
+	StreamHandler                 api.Stream
+	StatusUpdateHandler           api.StatusUpdate
+	NotificationPreferenceHandler api.NotificationPreference
 }
 
-func NewHandler(userRelationshipController controller.UserRelationshipController) Handler {
+func NewHandler(userRelationshipController controller.UserRelationshipController, adminController controller.AdminController, webhookController controller.WebhookController, statusUpdateController controller.StatusUpdateController, notificationPreferenceController controller.NotificationPreferenceController, hub *stream.Hub) Handler {
 	return Handler{
-		UserRelationshipHandler: NewUserRelationshipHandler(userRelationshipController),
+		UserRelationshipHandler:       NewUserRelationshipHandler(userRelationshipController),
+		UserRelationshipV2Handler:     NewUserRelationshipV2Handler(userRelationshipController),
+		AdminHandler:                  NewAdminHandler(adminController),
+		WebhookHandler:                NewWebhookHandler(webhookController),
+		StreamHandler:                 NewStreamHandler(userRelationshipController, hub),
+		StatusUpdateHandler:           NewStatusUpdateHandler(statusUpdateController),
+		NotificationPreferenceHandler: NewNotificationPreferenceHandler(notificationPreferenceController),
 	}
 }
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+// NotificationPreferenceHandler is the handler for the notification preference API
+type NotificationPreferenceHandler struct {
+	Controller controller.NotificationPreferenceController
+}
+
+func NewNotificationPreferenceHandler(Controller controller.NotificationPreferenceController) api.NotificationPreference {
+	return &NotificationPreferenceHandler{Controller: Controller}
+}
+
+// tenantController get the controller scoped to the tenant of the request
+func (nh *NotificationPreferenceHandler) tenantController(c echo.Context) controller.NotificationPreferenceController {
+	return nh.Controller.WithTenant(tenant.FromContext(c.Request().Context()))
+}
+
+// GetPreference api for GET /users/:email/notification-preferences
+func (nh *NotificationPreferenceHandler) GetPreference(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	preference, err := nh.tenantController(c).GetPreference(email)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, notificationPreferenceResponse(email, preference))
+}
+
+// SetPreference api for PUT /users/:email/notification-preferences
+func (nh *NotificationPreferenceHandler) SetPreference(c echo.Context) error {
+	var req api.NotificationPreferenceRequest
+	if err := c.Bind(&req); err != nil {
+		return apperror.BadRequest(err.Error())
+	}
+
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	v.frequency("frequency", req.Frequency)
+	v.timezone("timezone", req.Timezone)
+	v.quietHours("quiet_hours_start", "quiet_hours_end", req.QuietHoursStart, req.QuietHoursEnd)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	preference, err := nh.tenantController(c).SetPreference(email, model.NotificationPreference{
+		Frequency:              req.Frequency,
+		Timezone:               req.Timezone,
+		QuietHoursStart:        req.QuietHoursStart,
+		QuietHoursEnd:          req.QuietHoursEnd,
+		MentionsOnly:           req.MentionsOnly,
+		IgnoreStrangerMentions: req.IgnoreStrangerMentions,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, notificationPreferenceResponse(email, preference))
+}
+
+func notificationPreferenceResponse(email string, preference *model.NotificationPreference) api.NotificationPreferenceResponse {
+	return api.NotificationPreferenceResponse{
+		Success:                true,
+		Email:                  email,
+		Frequency:              preference.Frequency,
+		Timezone:               preference.Timezone,
+		QuietHoursStart:        preference.QuietHoursStart,
+		QuietHoursEnd:          preference.QuietHoursEnd,
+		MentionsOnly:           preference.MentionsOnly,
+		IgnoreStrangerMentions: preference.IgnoreStrangerMentions,
+	}
+}
+
+// frequency check a required notification frequency is one of the supported ones
+func (v *requestValidator) frequency(field, value string) {
+	if len(value) == 0 {
+		v.add("FREQUENCY_IS_REQUIRED", field, apperror.FIELD_REQUIRED, fmt.Sprintf("%s is required", field))
+		return
+	}
+
+	allowed := []string{
+		constant.NOTIFICATION_FREQUENCY_IMMEDIATE,
+		constant.NOTIFICATION_FREQUENCY_HOURLY,
+		constant.NOTIFICATION_FREQUENCY_DAILY,
+		constant.NOTIFICATION_FREQUENCY_WEEKLY,
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add("INVALID_NOTIFICATION_PREFERENCE_INPUT", field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be one of %s", field, strings.Join(allowed, ", ")))
+}
+
+// timezone check an optional IANA timezone is known
+func (v *requestValidator) timezone(field, value string) {
+	if len(value) == 0 {
+		return
+	}
+	if _, err := time.LoadLocation(value); err != nil || value == "Local" {
+		v.add("INVALID_NOTIFICATION_PREFERENCE_INPUT", field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%q is not an IANA timezone", value))
+	}
+}
+
+// quietHours check the optional quiet hours are two different "15:04" times, set together
+func (v *requestValidator) quietHours(startField, endField, start, end string) {
+	if len(start) == 0 && len(end) == 0 {
+		return
+	}
+
+	valid := true
+	for _, f := range []struct{ field, value string }{{startField, start}, {endField, end}} {
+		if len(f.value) == 0 {
+			v.add("INVALID_NOTIFICATION_PREFERENCE_INPUT", f.field, apperror.FIELD_REQUIRED, fmt.Sprintf("%s and %s are set together", startField, endField))
+			valid = false
+			continue
+		}
+		if _, err := time.Parse("15:04", f.value); err != nil {
+			v.add("INVALID_NOTIFICATION_PREFERENCE_INPUT", f.field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be a time like 22:00", f.field))
+			valid = false
+		}
+	}
+	if valid && start == end {
+		v.add("INVALID_NOTIFICATION_PREFERENCE_INPUT", endField, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must not be the same as %s", endField, startField))
+	}
+}
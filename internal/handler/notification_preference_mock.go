@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotificationPreferenceController struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockNotificationPreferenceController) GetPreference(email string) (*model.NotificationPreference, error) {
+	args := m.Called(email)
+	var preference *model.NotificationPreference
+	if args.Get(0) != nil {
+		preference = args.Get(0).(*model.NotificationPreference)
+	}
+	return preference, args.Error(1)
+}
+
+func (m *MockNotificationPreferenceController) SetPreference(email string, preference model.NotificationPreference) (*model.NotificationPreference, error) {
+	args := m.Called(email, preference)
+	var saved *model.NotificationPreference
+	if args.Get(0) != nil {
+		saved = args.Get(0).(*model.NotificationPreference)
+	}
+	return saved, args.Error(1)
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockNotificationPreferenceController) WithTenant(tenantID string) controller.NotificationPreferenceController {
+	m.Tenant = tenantID
+	return m
+}
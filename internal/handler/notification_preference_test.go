@@ -0,0 +1,149 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreferenceHandler(t *testing.T) {
+	preference := func(frequency string) *model.NotificationPreference {
+		return &model.NotificationPreference{Email: "john@example.com", Frequency: frequency, Timezone: "UTC"}
+	}
+	quiet := model.NotificationPreference{Frequency: constant.NOTIFICATION_FREQUENCY_DAILY, Timezone: "Asia/Ho_Chi_Minh", QuietHoursStart: "22:00", QuietHoursEnd: "07:00", MentionsOnly: true, IgnoreStrangerMentions: true}
+
+	tcs := map[string]struct {
+		method         string
+		path           string
+		reqBody        string
+		status         int
+		body           string
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"GetPreference_Success": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/john@example.com/notification-preferences",
+			status:         http.StatusOK,
+			body:           `{"success":true,"email":"john@example.com","frequency":"IMMEDIATE","timezone":"UTC","mentions_only":false,"ignore_stranger_mentions":false}`,
+			mockOn:         []string{"GetPreference"},
+			callArgument:   [][]interface{}{{"john@example.com"}},
+			returnArgument: [][]interface{}{{preference(constant.NOTIFICATION_FREQUENCY_IMMEDIATE), nil}},
+		},
+		"GetPreference_InvalidEmail": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/john/notification-preferences",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"email","code":"INVALID_EMAIL"`,
+		},
+		"SetPreference_Success": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/john@example.com/notification-preferences",
+			reqBody:        `{"frequency":"DAILY"}`,
+			status:         http.StatusOK,
+			body:           `{"success":true,"email":"john@example.com","frequency":"DAILY","timezone":"UTC","mentions_only":false,"ignore_stranger_mentions":false}`,
+			mockOn:         []string{"SetPreference"},
+			callArgument:   [][]interface{}{{"john@example.com", model.NotificationPreference{Frequency: constant.NOTIFICATION_FREQUENCY_DAILY}}},
+			returnArgument: [][]interface{}{{preference(constant.NOTIFICATION_FREQUENCY_DAILY), nil}},
+		},
+		"SetPreference_QuietHoursAndMentionsOnly": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/john@example.com/notification-preferences",
+			reqBody:        `{"frequency":"DAILY","timezone":"Asia/Ho_Chi_Minh","quiet_hours_start":"22:00","quiet_hours_end":"07:00","mentions_only":true,"ignore_stranger_mentions":true}`,
+			status:         http.StatusOK,
+			body:           `{"success":true,"email":"john@example.com","frequency":"DAILY","timezone":"Asia/Ho_Chi_Minh","quiet_hours_start":"22:00","quiet_hours_end":"07:00","mentions_only":true,"ignore_stranger_mentions":true}`,
+			mockOn:         []string{"SetPreference"},
+			callArgument:   [][]interface{}{{"john@example.com", quiet}},
+			returnArgument: [][]interface{}{{func() *model.NotificationPreference { p := quiet; p.Email = "john@example.com"; return &p }(), nil}},
+		},
+		"SetPreference_UnknownTimezone": {
+			method:  http.MethodPut,
+			path:    "/api/v2/users/john@example.com/notification-preferences",
+			reqBody: `{"frequency":"DAILY","timezone":"Mars/Olympus"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"timezone","code":"INVALID_VALUE"`,
+		},
+		"SetPreference_QuietHoursWithoutEnd": {
+			method:  http.MethodPut,
+			path:    "/api/v2/users/john@example.com/notification-preferences",
+			reqBody: `{"frequency":"DAILY","quiet_hours_start":"22:00"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"quiet_hours_end","code":"REQUIRED"`,
+		},
+		"SetPreference_InvalidQuietHours": {
+			method:  http.MethodPut,
+			path:    "/api/v2/users/john@example.com/notification-preferences",
+			reqBody: `{"frequency":"DAILY","quiet_hours_start":"10pm","quiet_hours_end":"07:00"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"quiet_hours_start","code":"INVALID_VALUE"`,
+		},
+		"SetPreference_EmptyQuietHours": {
+			method:  http.MethodPut,
+			path:    "/api/v2/users/john@example.com/notification-preferences",
+			reqBody: `{"frequency":"DAILY","quiet_hours_start":"07:00","quiet_hours_end":"07:00"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"quiet_hours_end","code":"INVALID_VALUE"`,
+		},
+		"SetPreference_MissingFrequency": {
+			method:  http.MethodPut,
+			path:    "/api/v2/users/john@example.com/notification-preferences",
+			reqBody: `{}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"frequency","code":"REQUIRED"`,
+		},
+		"SetPreference_UnknownFrequency": {
+			method:  http.MethodPut,
+			path:    "/api/v2/users/john@example.com/notification-preferences",
+			reqBody: `{"frequency":"MONTHLY"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"frequency","code":"INVALID_VALUE"`,
+		},
+		"SetPreference_ControllerFail": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/john@example.com/notification-preferences",
+			reqBody:        `{"frequency":"WEEKLY"}`,
+			status:         http.StatusInternalServerError,
+			body:           `"code":"INTERNAL_ERROR"`,
+			mockOn:         []string{"SetPreference"},
+			callArgument:   [][]interface{}{{"john@example.com", model.NotificationPreference{Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY}}},
+			returnArgument: [][]interface{}{{nil, errors.New("db down")}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockNotificationPreferenceController)
+			for i, method := range tc.mockOn {
+				mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterNotificationPreferenceRoutes(e, handler.NewNotificationPreferenceHandler(mockController), authenticateAsAdmin)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.reqBody))
+			if len(tc.reqBody) > 0 {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.JSONEq(t, tc.body, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.body)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+// maxStatusUpdateLength is the maximum number of characters of a status update
+const maxStatusUpdateLength = 1000
+
+// StatusUpdateHandler is the handler for the status update API
+type StatusUpdateHandler struct {
+	Controller controller.StatusUpdateController
+}
+
+func NewStatusUpdateHandler(Controller controller.StatusUpdateController) api.StatusUpdate {
+	return &StatusUpdateHandler{Controller: Controller}
+}
+
+// tenantController get the controller scoped to the tenant of the request
+func (sv *StatusUpdateHandler) tenantController(c echo.Context) controller.StatusUpdateController {
+	return sv.Controller.WithTenant(tenant.FromContext(c.Request().Context()))
+}
+
+// PostUpdate api for POST /users/:email/updates
+func (sv *StatusUpdateHandler) PostUpdate(c echo.Context) error {
+	var req api.PostStatusUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return apperror.BadRequest(err.Error())
+	}
+
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	v.statusText("text", req.Text, maxStatusUpdateLength)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	update, err := sv.tenantController(c).PostUpdate(email, req.Text)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, api.PostStatusUpdateResponse{Success: true, Update: postedUpdate(*update)})
+}
+
+// ListFeed api for GET /users/:email/feed?limit=&before=, newest first, before is the id the page starts after
+func (sv *StatusUpdateHandler) ListFeed(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	limit := v.queryInt(c, "limit")
+	before := v.queryInt(c, "before")
+	v.pagination(limit, 0)
+	if before < 0 {
+		v.add("INVALID_PAGINATION_INPUT", "before", apperror.FIELD_OUT_OF_RANGE, "before must not be negative")
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	limit = normalizeLimit(limit)
+	updates, err := sv.tenantController(c).ListFeed(email, uint(before), limit)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListFeedResponse{Success: true, Updates: make([]api.PostedUpdate, 0, len(updates)), Limit: limit}
+	for _, update := range updates {
+		resp.Updates = append(resp.Updates, postedUpdate(update))
+	}
+	if len(updates) == limit {
+		resp.NextBefore = updates[len(updates)-1].ID
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func postedUpdate(update model.StatusUpdate) api.PostedUpdate {
+	return api.PostedUpdate{ID: update.ID, Author: update.AuthorEmail, Text: update.Text, CreatedAt: update.CreatedAt}
+}
+
+// statusText check a required status update text is not longer than max characters
+func (v *requestValidator) statusText(field, value string, max int) {
+	if len(strings.TrimSpace(value)) == 0 {
+		v.add("TEXT_IS_REQUIRED", field, apperror.FIELD_REQUIRED, fmt.Sprintf("%s is required", field))
+		return
+	}
+	if utf8.RuneCountInString(value) > max {
+		v.add("INVALID_STATUS_UPDATE_INPUT", field, apperror.FIELD_OUT_OF_RANGE, fmt.Sprintf("%s must not be longer than %d characters", field, max))
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStatusUpdateController struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockStatusUpdateController) PostUpdate(author, text string) (*model.StatusUpdate, error) {
+	args := m.Called(author, text)
+	var update *model.StatusUpdate
+	if args.Get(0) != nil {
+		update = args.Get(0).(*model.StatusUpdate)
+	}
+	return update, args.Error(1)
+}
+
+func (m *MockStatusUpdateController) ListFeed(email string, before uint, limit int) ([]model.StatusUpdate, error) {
+	args := m.Called(email, before, limit)
+	var updates []model.StatusUpdate
+	if args.Get(0) != nil {
+		updates = args.Get(0).([]model.StatusUpdate)
+	}
+	return updates, args.Error(1)
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockStatusUpdateController) WithTenant(tenantID string) controller.StatusUpdateController {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,129 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusUpdateHandler(t *testing.T) {
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	update := func(id uint, author string) model.StatusUpdate {
+		return model.StatusUpdate{ID: id, AuthorEmail: author, Text: "Hello World! kate@example.com", FanOut: constant.FAN_OUT_ON_WRITE, CreatedAt: createdAt}
+	}
+
+	tcs := map[string]struct {
+		method         string
+		path           string
+		reqBody        string
+		status         int
+		body           string
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"PostUpdate_Success": {
+			method:         http.MethodPost,
+			path:           "/api/v2/users/john@example.com/updates",
+			reqBody:        `{"text":"Hello World! kate@example.com"}`,
+			status:         http.StatusCreated,
+			body:           `{"success":true,"update":{"id":7,"author":"john@example.com","text":"Hello World! kate@example.com","created_at":"2025-01-02T03:04:05Z"}}`,
+			mockOn:         []string{"PostUpdate"},
+			callArgument:   [][]interface{}{{"john@example.com", "Hello World! kate@example.com"}},
+			returnArgument: [][]interface{}{{func() *model.StatusUpdate { u := update(7, "john@example.com"); return &u }(), nil}},
+		},
+		"PostUpdate_MissingText": {
+			method:  http.MethodPost,
+			path:    "/api/v2/users/john@example.com/updates",
+			reqBody: `{"text":"  "}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"text","code":"REQUIRED"`,
+		},
+		"PostUpdate_TextTooLong": {
+			method:  http.MethodPost,
+			path:    "/api/v2/users/john@example.com/updates",
+			reqBody: `{"text":"` + strings.Repeat("é", 1001) + `"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"text","code":"OUT_OF_RANGE"`,
+		},
+		"PostUpdate_InvalidEmail": {
+			method:  http.MethodPost,
+			path:    "/api/v2/users/john/updates",
+			reqBody: `{"text":"Hello"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"email","code":"INVALID_EMAIL"`,
+		},
+		"PostUpdate_ControllerFail": {
+			method:         http.MethodPost,
+			path:           "/api/v2/users/john@example.com/updates",
+			reqBody:        `{"text":"Hello"}`,
+			status:         http.StatusInternalServerError,
+			body:           `"code":"INTERNAL_ERROR"`,
+			mockOn:         []string{"PostUpdate"},
+			callArgument:   [][]interface{}{{"john@example.com", "Hello"}},
+			returnArgument: [][]interface{}{{nil, errors.New("db down")}},
+		},
+		"ListFeed_FullPage": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/kate@example.com/feed?limit=2&before=10",
+			status:         http.StatusOK,
+			body:           `{"success":true,"updates":[{"id":9,"author":"john@example.com","text":"Hello World! kate@example.com","created_at":"2025-01-02T03:04:05Z"},{"id":7,"author":"lisa@example.com","text":"Hello World! kate@example.com","created_at":"2025-01-02T03:04:05Z"}],"limit":2,"next_before":7}`,
+			mockOn:         []string{"ListFeed"},
+			callArgument:   [][]interface{}{{"kate@example.com", uint(10), 2}},
+			returnArgument: [][]interface{}{{[]model.StatusUpdate{update(9, "john@example.com"), update(7, "lisa@example.com")}, nil}},
+		},
+		"ListFeed_LastPage": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/kate@example.com/feed",
+			status:         http.StatusOK,
+			body:           `{"success":true,"updates":[],"limit":20}`,
+			mockOn:         []string{"ListFeed"},
+			callArgument:   [][]interface{}{{"kate@example.com", uint(0), constant.DEFAULT_PAGE_LIMIT}},
+			returnArgument: [][]interface{}{{nil, nil}},
+		},
+		"ListFeed_NegativeBefore": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/kate@example.com/feed?before=-1",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"before","code":"OUT_OF_RANGE"`,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockStatusUpdateController)
+			for i, method := range tc.mockOn {
+				mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterStatusUpdateRoutes(e, handler.NewStatusUpdateHandler(mockController), authenticateAsAdmin, noop)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.reqBody))
+			if len(tc.reqBody) > 0 {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK || tc.status == http.StatusCreated {
+				assert.JSONEq(t, tc.body, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.body)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/stream"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	//Number of missed events read at once when a client resumes
+	eventPageSize = 100
+)
+
+// heartbeatInterval is how often an idle event stream sends a comment so proxies do not close the connection
+var heartbeatInterval = 25 * time.Second
+
+// StreamHandler is the handler for the event stream API
+type StreamHandler struct {
+	Controller controller.UserRelationshipController
+	Hub        *stream.Hub
+}
+
+func NewStreamHandler(Controller controller.UserRelationshipController, hub *stream.Hub) api.Stream {
+	return &StreamHandler{Controller: Controller, Hub: hub}
+}
+
+// eventFeed is the events of one client, the missed events first then the live ones. A new client without a last event id only
+// gets the live events.
+type eventFeed struct {
+	controller controller.UserRelationshipController
+	email      string
+	lastID     uint
+	sub        *stream.Subscription
+	//ids sent while resuming, the live copy of those events is skipped
+	replayed map[uint]struct{}
+}
+
+// subscribe validate the request and register the client, it is done before the missed events are read so none is lost in between
+func (sv *StreamHandler) subscribe(c echo.Context) (*eventFeed, error) {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	lastID := v.lastEventID(c)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return nil, err
+	}
+
+	tenantID := tenant.FromContext(c.Request().Context())
+	return &eventFeed{
+		controller: sv.Controller.WithTenant(tenantID),
+		email:      email,
+		lastID:     lastID,
+		sub:        sv.Hub.Subscribe(tenantID, email),
+		replayed:   map[uint]struct{}{},
+	}, nil
+}
+
+// replay send the events the client missed since its last event id. The events of the email written just before it are read
+// again in case a lower id committed after the client got the last one, the client drops the ids it already received.
+func (f *eventFeed) replay(send func(outbox.Message) error) error {
+	if f.lastID == 0 {
+		return nil
+	}
+	afterID, err := f.controller.ResumeEventsAfter(f.email, f.lastID)
+	if err != nil {
+		return err
+	}
+	f.replayed[f.lastID] = struct{}{}
+
+	for {
+		events, err := f.controller.ListEventsSince(f.email, afterID, eventPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			afterID = event.ID
+			if _, sent := f.replayed[event.ID]; sent {
+				continue
+			}
+			if err := send(outbox.NewMessage(event)); err != nil {
+				return err
+			}
+			f.replayed[event.ID] = struct{}{}
+		}
+		if len(events) < eventPageSize {
+			return nil
+		}
+	}
+}
+
+// live tell whether a message from the hub was not already sent while resuming
+func (f *eventFeed) live(message outbox.Message) bool {
+	_, sent := f.replayed[message.ID]
+	return !sent
+}
+
+// Events api for GET /users/:email/events, the events are sent as server sent events until the client disconnects.
+// The stream ends when the client is too slow, it reconnects with Last-Event-ID to get what it missed.
+func (sv *StreamHandler) Events(c echo.Context) error {
+	feed, err := sv.subscribe(c)
+	if err != nil {
+		return err
+	}
+	defer feed.sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	send := func(message outbox.Message) error {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Type, data); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+	if err := feed.replay(send); err != nil {
+		c.Logger().Error(fmt.Errorf("REPLAY_EVENTS_FAIL: %w", err))
+		return nil
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case message, ok := <-feed.sub.C:
+			if !ok {
+				return nil
+			}
+			if !feed.live(message) {
+				continue
+			}
+			if err := send(message); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+}
+
+// EventsWebSocket api for GET /users/:email/events/ws, every event is a json text frame.
+// The origin is not checked since the client authenticates with a token and not with cookies.
+func (sv *StreamHandler) EventsWebSocket(c echo.Context) error {
+	feed, err := sv.subscribe(c)
+	if err != nil {
+		return err
+	}
+	defer feed.sub.Close()
+
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		//The client does not send anything, reading only tells when it goes away
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var discard string
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		send := func(message outbox.Message) error {
+			return websocket.JSON.Send(ws, message)
+		}
+		if err := feed.replay(send); err != nil {
+			c.Logger().Error(fmt.Errorf("REPLAY_EVENTS_FAIL: %w", err))
+			return
+		}
+
+		for {
+			select {
+			case message, ok := <-feed.sub.C:
+				if !ok {
+					return
+				}
+				if !feed.live(message) {
+					continue
+				}
+				if err := send(message); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// lastEventID read the id of the last event a reconnecting client received, from the Last-Event-ID header
+// or the last_event_id query parameter for clients that can not set headers
+func (v *requestValidator) lastEventID(c echo.Context) uint {
+	field, raw := "Last-Event-ID", c.Request().Header.Get("Last-Event-ID")
+	if len(raw) == 0 {
+		field, raw = "last_event_id", c.QueryParam("last_event_id")
+	}
+	if len(raw) == 0 {
+		return 0
+	}
+
+	value, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		v.add("INVALID_EVENT_ID_INPUT", field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be a non negative integer", field))
+		return 0
+	}
+	return uint(value)
+}
@@ -0,0 +1,179 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/quanluong166/friends_management/internal/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+var missedEvent = model.OutboxEvent{
+	ID:             42,
+	TenantID:       constant.DEFAULT_TENANT_ID,
+	Type:           constant.DOMAIN_EVENT_BLOCKED,
+	RequestorEmail: "bob@example.com",
+	TargetEmail:    "andy@example.com",
+	CreatedAt:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+// streamServer serve the stream routes
+func streamServer(t *testing.T) (*httptest.Server, *stream.Hub, *handler.MockUserRelationshipController) {
+	mockController := new(handler.MockUserRelationshipController)
+	hub := stream.NewHub(10)
+
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	routes.RegisterStreamRoutes(e, handler.NewStreamHandler(mockController, hub), authenticateAsAdmin)
+	server := httptest.NewServer(e)
+	t.Cleanup(func() {
+		hub.Close()
+		server.Close()
+	})
+	return server, hub, mockController
+}
+
+// resumeAfter41 expect a client that already received the event 41 and missed the event 42, the events since 39 are read again
+// in case a lower id committed late
+func resumeAfter41(mockController *handler.MockUserRelationshipController) {
+	received := model.OutboxEvent{ID: 41, TenantID: constant.DEFAULT_TENANT_ID, Type: constant.DOMAIN_EVENT_SUBSCRIBED, RequestorEmail: "andy@example.com", TargetEmail: "bob@example.com"}
+	mockController.On("ResumeEventsAfter", "andy@example.com", uint(41)).Return(uint(39), nil).Once()
+	mockController.On("ListEventsSince", "andy@example.com", uint(39), 100).Return([]model.OutboxEvent{received, missedEvent}, nil).Once()
+}
+
+// publishLive publish the missed event again, as the relay of another request could, and a new one
+func publishLive(t *testing.T, hub *stream.Hub) {
+	require.NoError(t, hub.Publish(context.Background(), outbox.NewMessage(missedEvent)))
+	require.NoError(t, hub.Publish(context.Background(), outbox.Message{
+		ID: 43, Tenant: constant.DEFAULT_TENANT_ID, Type: constant.DOMAIN_EVENT_UNBLOCKED, Requestor: "bob@example.com", Target: "andy@example.com",
+	}))
+}
+
+func TestStreamHandler_Events(t *testing.T) {
+	server, hub, mockController := streamServer(t)
+	resumeAfter41(mockController)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v2/users/andy@example.com/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "41")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, "id: 42\nevent: Blocked\n"+
+		`data: {"id":42,"tenant":"default","type":"Blocked","requestor":"bob@example.com","target":"andy@example.com","occurred_at":"2025-01-02T03:04:05Z"}`+"\n", readEvent())
+	publishLive(t, hub)
+	assert.Equal(t, "id: 43\nevent: Unblocked\n"+
+		`data: {"id":43,"tenant":"default","type":"Unblocked","requestor":"bob@example.com","target":"andy@example.com","occurred_at":"0001-01-01T00:00:00Z"}`+"\n", readEvent())
+	mockController.AssertExpectations(t)
+}
+
+func TestStreamHandler_EventsWebSocket(t *testing.T) {
+	server, hub, mockController := streamServer(t)
+	resumeAfter41(mockController)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v2/users/andy@example.com/events/ws?last_event_id=41"
+	ws, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var message outbox.Message
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	assert.Equal(t, uint(42), message.ID)
+	publishLive(t, hub)
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	assert.Equal(t, uint(43), message.ID)
+	assert.Equal(t, constant.DOMAIN_EVENT_UNBLOCKED, message.Type)
+	mockController.AssertExpectations(t)
+}
+
+func TestStreamHandler_EventsWebSocketNewClient(t *testing.T) {
+	server, hub, mockController := streamServer(t)
+
+	//A client without a last event id starts with the live events
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v2/users/andy@example.com/events/ws"
+	ws, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	//The subscription is registered before the handshake completes
+	publishLive(t, hub)
+	var message outbox.Message
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	assert.Equal(t, uint(42), message.ID)
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	assert.Equal(t, uint(43), message.ID)
+	mockController.AssertExpectations(t)
+}
+
+func TestStreamHandler_Rejected(t *testing.T) {
+	tcs := map[string]struct {
+		path        string
+		lastEventID string
+		status      int
+		body        string
+	}{
+		"InvalidLastEventID": {
+			path:        "/api/v2/users/andy@example.com/events",
+			lastEventID: "abc",
+			status:      http.StatusUnprocessableEntity,
+			body:        `"field":"Last-Event-ID","code":"INVALID_VALUE"`,
+		},
+		"InvalidQueryLastEventID": {
+			path:   "/api/v2/users/andy@example.com/events/ws?last_event_id=-1",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"last_event_id","code":"INVALID_VALUE"`,
+		},
+		"InvalidEmail": {
+			path:   "/api/v2/users/andy/events",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"email"`,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterStreamRoutes(e, handler.NewStreamHandler(new(handler.MockUserRelationshipController), stream.NewHub(1)), authenticateAsAdmin)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if len(tc.lastEventID) > 0 {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.body)
+		})
+	}
+}
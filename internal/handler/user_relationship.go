@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
 	"github.com/quanluong166/friends_management/internal/controller"
 	"github.com/quanluong166/friends_management/internal/handler/api"
-	"github.com/quanluong166/friends_management/pkg/utils"
+	"github.com/quanluong166/friends_management/internal/tenant"
 
 	"github.com/labstack/echo/v4"
 )
@@ -17,39 +19,31 @@ func NewUserRelationshipHandler(Controller controller.UserRelationshipController
 	return &UserRelationshipHandler{Controller: Controller}
 }
 
+// tenantController get the controller scoped to the tenant of the request, changes are recorded with the caller as actor
+func (sv *UserRelationshipHandler) tenantController(c echo.Context) controller.UserRelationshipController {
+	return sv.Controller.WithTenant(tenant.FromContext(c.Request().Context())).WithActor(actorFrom(c))
+}
+
 // AddFriend api for make friend connection
 func (sv *UserRelationshipHandler) AddFriend(c echo.Context) error {
 	var req api.AddFriendRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	if len(req.Friends) < 2 {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "AT_LEAST_TWO_EMAILS_ARE_REQUIRED",
-		})
+	var v requestValidator
+	v.emailList("friends", req.Friends, 2)
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	for _, email := range req.Friends {
-		isEmail := utils.IsValidEmail(email)
-		if !isEmail {
-			return c.JSON(400, api.ErrorResponse{
-				Success: false,
-				Message: "INVALID_EMAIL_INPUT",
-			})
-		}
+	if err := authorizeActor(c, req.Friends[0]); err != nil {
+		return err
 	}
 
-	err := sv.Controller.AddFriendship(req.Friends[0], req.Friends[1])
+	err := sv.tenantController(c).AddFriendship(req.Friends[0], req.Friends[1])
 	if err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(200, api.CommonResponse{Success: true})
@@ -59,26 +53,25 @@ func (sv *UserRelationshipHandler) AddFriend(c echo.Context) error {
 func (sv *UserRelationshipHandler) ListFriend(c echo.Context) error {
 	var req api.ListFriendRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	isEmail := utils.IsValidEmail(req.Email)
-	if !isEmail {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "INVALID_EMAIL_INPUT",
-		})
+	var v requestValidator
+	v.email("email", req.Email, "EMAIL_IS_REQUIRED")
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	friends, count, err := sv.Controller.ListFriendships(req.Email)
+	var friends []string
+	var count int64
+	var err error
+	if req.AsOf != nil {
+		friends, count, err = sv.tenantController(c).ListFriendshipsAsOf(req.Email, *req.AsOf)
+	} else {
+		friends, count, err = sv.tenantController(c).ListFriendships(req.Email)
+	}
 	if err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(200, api.ListFriendResponse{Success: true, Friends: friends, Count: int(count)})
@@ -88,35 +81,22 @@ func (sv *UserRelationshipHandler) ListFriend(c echo.Context) error {
 func (sv *UserRelationshipHandler) ListCommonFriends(c echo.Context) error {
 	var req api.ListCommonFriendsRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	if len(req.Friends) < 2 {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "AT_LEAST_TWO_EMAILS_ARE_REQUIRED",
-		})
+	var v requestValidator
+	v.emailList("friends", req.Friends, 2)
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	for _, email := range req.Friends {
-		isEmail := utils.IsValidEmail(email)
-		if !isEmail {
-			return c.JSON(400, api.ErrorResponse{
-				Success: false,
-				Message: "INVALID_EMAIL_INPUT",
-			})
-		}
+	if err := authorizeActor(c, req.Friends[0]); err != nil {
+		return err
 	}
 
-	commonFriends, count, err := sv.Controller.ListCommonFriends(req.Friends[0], req.Friends[1])
+	commonFriends, count, err := sv.tenantController(c).ListCommonFriends(req.Friends[0], req.Friends[1])
 	if err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return err
 	}
 	return c.JSON(200, api.ListCommonFriendsResponse{Success: true, Friends: commonFriends, Count: int(count)})
 }
@@ -125,32 +105,23 @@ func (sv *UserRelationshipHandler) ListCommonFriends(c echo.Context) error {
 func (sv *UserRelationshipHandler) AddSubscriber(c echo.Context) error {
 	var req api.AddSubscriberRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	if len(req.Requestor) == 0 || len(req.Target) == 0 {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "REQUESTOR_AND_TARGET_ARE_REQUIRED",
-		})
+	var v requestValidator
+	v.email("requestor", req.Requestor, "REQUESTOR_AND_TARGET_ARE_REQUIRED")
+	v.email("target", req.Target, "REQUESTOR_AND_TARGET_ARE_REQUIRED")
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	if !utils.IsValidEmail(req.Requestor) || !utils.IsValidEmail(req.Target) {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "INVALID_EMAIL_INPUT",
-		})
+	if err := authorizeActor(c, req.Requestor); err != nil {
+		return err
 	}
 
-	err := sv.Controller.AddSubscriber(req.Requestor, req.Target)
+	err := sv.tenantController(c).AddSubscriber(req.Requestor, req.Target)
 	if err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(200, api.CommonResponse{Success: true})
@@ -160,32 +131,23 @@ func (sv *UserRelationshipHandler) AddSubscriber(c echo.Context) error {
 func (sv *UserRelationshipHandler) AddBlock(c echo.Context) error {
 	var req api.AddBlockRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	if len(req.Requestor) == 0 || len(req.Target) == 0 {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "REQUESTOR_AND_TARGET_ARE_REQUIRED",
-		})
+	var v requestValidator
+	v.email("requestor", req.Requestor, "REQUESTOR_AND_TARGET_ARE_REQUIRED")
+	v.email("target", req.Target, "REQUESTOR_AND_TARGET_ARE_REQUIRED")
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	if !utils.IsValidEmail(req.Requestor) || !utils.IsValidEmail(req.Target) {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "INVALID_EMAIL_INPUT",
-		})
+	if err := authorizeActor(c, req.Requestor); err != nil {
+		return err
 	}
 
-	err := sv.Controller.AddBlock(req.Requestor, req.Target)
+	err := sv.tenantController(c).AddBlock(req.Requestor, req.Target)
 	if err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(200, api.CommonResponse{Success: true})
@@ -195,33 +157,108 @@ func (sv *UserRelationshipHandler) AddBlock(c echo.Context) error {
 func (sv *UserRelationshipHandler) GetListEmailCanReceiveUpdate(c echo.Context) error {
 	var req api.GetListEmailCanReceiveUpdateRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
+	}
+
+	var v requestValidator
+	v.email("sender", req.Sender, "SENDER_IS_REQUIRED")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, req.Sender); err != nil {
+		return err
+	}
+
+	recipients, err := sv.tenantController(c).GetListEmailCanReceiveUpdate(req.Sender, req.Text)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, recipientsResponse(recipients))
+}
+
+func recipientsResponse(recipients *controller.Recipients) api.GetListEmailCanReceiveUpdateResponse {
+	resp := api.GetListEmailCanReceiveUpdateResponse{Success: true, Recipients: recipients.Now}
+	for _, deferred := range recipients.Deferred {
+		resp.Deferred = append(resp.Deferred, api.DeferredRecipient{Email: deferred.Email, Until: deferred.Until})
+	}
+	return resp
+}
+
+// ListSubscribers api for get list subscriber email of the email
+func (sv *UserRelationshipHandler) ListSubscribers(c echo.Context) error {
+	var req api.ListSubscribersRequest
+	if err := c.Bind(&req); err != nil {
+		return apperror.BadRequest(err.Error())
 	}
 
-	if len(req.Sender) == 0 {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "SENDER_IS_REQUIRED",
-		})
+	var v requestValidator
+	v.email("email", req.Email, "EMAIL_IS_REQUIRED")
+	v.pagination(req.Limit, req.Offset)
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	if !utils.IsValidEmail(req.Sender) {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: "INVALID_EMAIL_INPUT",
-		})
+	limit := normalizeLimit(req.Limit)
+	var subscribers []string
+	var count int64
+	var err error
+	if req.AsOf != nil {
+		subscribers, count, err = sv.tenantController(c).ListSubscribersAsOf(req.Email, *req.AsOf, limit, req.Offset)
+	} else {
+		subscribers, count, err = sv.tenantController(c).ListSubscribers(req.Email, limit, req.Offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, api.ListSubscribersResponse{Success: true, Subscribers: subscribers, Count: int(count), Limit: limit, Offset: req.Offset})
+}
+
+// ListBlocks api for get list email blocked by the requestor
+func (sv *UserRelationshipHandler) ListBlocks(c echo.Context) error {
+	var req api.ListBlocksRequest
+	if err := c.Bind(&req); err != nil {
+		return apperror.BadRequest(err.Error())
+	}
+
+	var v requestValidator
+	v.email("requestor", req.Requestor, "REQUESTOR_IS_REQUIRED")
+	v.pagination(req.Limit, req.Offset)
+	if err := v.err(); err != nil {
+		return err
 	}
 
-	recipients, err := sv.Controller.GetListEmailCanReceiveUpdate(req.Sender, req.Text)
+	if err := authorizeActor(c, req.Requestor); err != nil {
+		return err
+	}
+
+	limit := normalizeLimit(req.Limit)
+	var blocks []string
+	var count int64
+	var err error
+	if req.AsOf != nil {
+		blocks, count, err = sv.tenantController(c).ListBlocksAsOf(req.Requestor, *req.AsOf, limit, req.Offset)
+	} else {
+		blocks, count, err = sv.tenantController(c).ListBlocks(req.Requestor, limit, req.Offset)
+	}
 	if err != nil {
-		return c.JSON(400, api.ErrorResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		return err
+	}
+
+	return c.JSON(200, api.ListBlocksResponse{Success: true, Blocks: blocks, Count: int(count), Limit: limit, Offset: req.Offset})
+}
+
+// normalizeLimit apply the default and max page size
+func normalizeLimit(limit int) int {
+	if limit == 0 {
+		limit = constant.DEFAULT_PAGE_LIMIT
+	}
+
+	if limit > constant.MAX_PAGE_LIMIT {
+		limit = constant.MAX_PAGE_LIMIT
 	}
 
-	return c.JSON(200, api.GetListEmailCanReceiveUpdateResponse{Success: true, Recipients: recipients})
+	return limit
 }
@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
 	"github.com/stretchr/testify/mock"
 )
 
 type MockUserRelationshipController struct {
 	mock.Mock
+	Tenant string
+	Actor  controller.Actor
 }
 
 func (m *MockUserRelationshipController) AddFriendship(email1, email2 string) error {
@@ -57,11 +63,16 @@ func (m *MockUserRelationshipController) AddBlock(requestor, target string) erro
 	return args.Error(0)
 }
 
-func (m *MockUserRelationshipController) GetListEmailCanReceiveUpdate(senderEmail, text string) ([]string, error) {
+func (m *MockUserRelationshipController) IsBlocking(requestor, target string) (bool, error) {
+	args := m.Called(requestor, target)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRelationshipController) GetListEmailCanReceiveUpdate(senderEmail, text string) (*controller.Recipients, error) {
 	args := m.Called(senderEmail, text)
-	var listEmails []string
+	var recipients *controller.Recipients
 	if args.Get(0) != nil {
-		listEmails = args.Get(0).([]string)
+		recipients = args.Get(0).(*controller.Recipients)
 	}
 
 	var err error
@@ -69,5 +80,143 @@ func (m *MockUserRelationshipController) GetListEmailCanReceiveUpdate(senderEmai
 		err = args.Get(1).(error)
 	}
 
-	return listEmails, err
+	return recipients, err
+}
+
+func (m *MockUserRelationshipController) ListSubscribers(email string, limit, offset int) ([]string, int64, error) {
+	args := m.Called(email, limit, offset)
+	var subscribers []string
+	if args.Get(0) != nil {
+		subscribers = args.Get(0).([]string)
+	}
+
+	count := args.Get(1).(int64)
+
+	var err error
+	if args.Get(2) != nil {
+		err = args.Get(2).(error)
+	}
+
+	return subscribers, count, err
+}
+
+func (m *MockUserRelationshipController) ListBlocks(requestor string, limit, offset int) ([]string, int64, error) {
+	args := m.Called(requestor, limit, offset)
+	var blocks []string
+	if args.Get(0) != nil {
+		blocks = args.Get(0).([]string)
+	}
+
+	count := args.Get(1).(int64)
+
+	var err error
+	if args.Get(2) != nil {
+		err = args.Get(2).(error)
+	}
+
+	return blocks, count, err
+}
+
+func (m *MockUserRelationshipController) ListFriendshipsAsOf(email string, asOf time.Time) ([]string, int64, error) {
+	args := m.Called(email, asOf)
+	var friends []string
+	if args.Get(0) != nil {
+		friends = args.Get(0).([]string)
+	}
+	return friends, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRelationshipController) ListSubscribersAsOf(email string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	args := m.Called(email, asOf, limit, offset)
+	var subscribers []string
+	if args.Get(0) != nil {
+		subscribers = args.Get(0).([]string)
+	}
+	return subscribers, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRelationshipController) ListBlocksAsOf(requestor string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	args := m.Called(requestor, asOf, limit, offset)
+	var blocks []string
+	if args.Get(0) != nil {
+		blocks = args.Get(0).([]string)
+	}
+	return blocks, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRelationshipController) ResumeEventsAfter(email string, lastID uint) (uint, error) {
+	args := m.Called(email, lastID)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *MockUserRelationshipController) ListEventsSince(email string, afterID uint, limit int) ([]model.OutboxEvent, error) {
+	args := m.Called(email, afterID, limit)
+	var events []model.OutboxEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]model.OutboxEvent)
+	}
+	return events, args.Error(1)
+}
+
+func (m *MockUserRelationshipController) RemoveFriendship(email1, email2 string) error {
+	args := m.Called(email1, email2)
+	return args.Error(0)
+}
+
+func (m *MockUserRelationshipController) RemoveSubscriber(requestor, target string) error {
+	args := m.Called(requestor, target)
+	return args.Error(0)
+}
+
+func (m *MockUserRelationshipController) RemoveBlock(requestor, target string) error {
+	args := m.Called(requestor, target)
+	return args.Error(0)
+}
+
+func (m *MockUserRelationshipController) ListFriendshipsByEmails(emails []string) (map[string][]string, error) {
+	args := m.Called(emails)
+	var result map[string][]string
+	if args.Get(0) != nil {
+		result = args.Get(0).(map[string][]string)
+	}
+	return result, args.Error(1)
+}
+
+func (m *MockUserRelationshipController) ListSubscribersByEmails(emails []string) (map[string][]string, error) {
+	args := m.Called(emails)
+	var result map[string][]string
+	if args.Get(0) != nil {
+		result = args.Get(0).(map[string][]string)
+	}
+	return result, args.Error(1)
+}
+
+func (m *MockUserRelationshipController) ListSubscriptionsByEmails(emails []string) (map[string][]string, error) {
+	args := m.Called(emails)
+	var result map[string][]string
+	if args.Get(0) != nil {
+		result = args.Get(0).(map[string][]string)
+	}
+	return result, args.Error(1)
+}
+
+func (m *MockUserRelationshipController) ListBlockConnectionsByEmails(emails []string) (map[string][]string, error) {
+	args := m.Called(emails)
+	var result map[string][]string
+	if args.Get(0) != nil {
+		result = args.Get(0).(map[string][]string)
+	}
+	return result, args.Error(1)
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockUserRelationshipController) WithTenant(tenantID string) controller.UserRelationshipController {
+	m.Tenant = tenantID
+	return m
+}
+
+// WithActor record the actor and return the same mock
+func (m *MockUserRelationshipController) WithActor(actor controller.Actor) controller.UserRelationshipController {
+	m.Actor = actor
+	return m
 }
@@ -3,11 +3,15 @@ package handler_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/controller"
 	"github.com/quanluong166/friends_management/internal/handler"
 	"github.com/quanluong166/friends_management/internal/handler/api"
 
@@ -22,6 +26,7 @@ func TestUserRelationshipHandler_AddFriend(t *testing.T) {
 		email1         string
 		email2         string
 		err            error
+		status         int
 		mockOn         []string
 		callArgument   [][]interface{}
 		returnArgument [][]interface{}
@@ -39,6 +44,7 @@ func TestUserRelationshipHandler_AddFriend(t *testing.T) {
 			},
 		},
 		"Error_AtLeastTwoEmailsAreRequired": {
+			status:         http.StatusUnprocessableEntity,
 			email2:         "friend2@example.com",
 			err:            errors.New("AT_LEAST_TWO_EMAILS_ARE_REQUIRED"),
 			mockOn:         []string{},
@@ -46,6 +52,7 @@ func TestUserRelationshipHandler_AddFriend(t *testing.T) {
 			returnArgument: [][]interface{}{},
 		},
 		"Error_InvalidEmail": {
+			status: http.StatusUnprocessableEntity,
 			email1: "invalid-email",
 			email2: "friend2@example.com",
 			err:    errors.New("INVALID_EMAIL_INPUT"),
@@ -55,10 +62,24 @@ func TestUserRelationshipHandler_AddFriend(t *testing.T) {
 			},
 			returnArgument: [][]interface{}{},
 		},
+		"Error_AlreadyFriends": {
+			status: http.StatusConflict,
+			email1: "friend1@example.com",
+			email2: "friend2@example.com",
+			err:    apperror.ErrAlreadyFriends,
+			mockOn: []string{"AddFriendship"},
+			callArgument: [][]interface{}{
+				{"friend1@example.com", "friend2@example.com"},
+			},
+			returnArgument: [][]interface{}{
+				{fmt.Errorf("ADD_FRIENDSHIP_FAIL: %w", apperror.ErrAlreadyFriends)},
+			},
+		},
 		"Error_AddFriendshipFailed": {
+			status: http.StatusInternalServerError,
 			email1: "friend1@example.com",
 			email2: "friend2@example.com",
-			err:    errors.New("DATABASE_ERROR"),
+			err:    errors.New("INTERNAL_SERVER_ERROR"),
 			mockOn: []string{"AddFriendship"},
 			callArgument: [][]interface{}{
 				{"friend1@example.com", "friend2@example.com"},
@@ -81,24 +102,27 @@ func TestUserRelationshipHandler_AddFriend(t *testing.T) {
 			reqBody, _ := buildRequestBody(tc.email1, tc.email2)
 			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/add-friend", strings.NewReader(reqBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			if assert.NoError(t, svc.AddFriend(c)) {
-				if tc.err != nil {
-					var resp api.ErrorResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusBadRequest, rec.Code)
-					assert.Equal(t, tc.err.Error(), resp.Message)
-					assert.False(t, resp.Success)
-				} else {
-					var resp api.CommonResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.Equal(t, http.StatusOK, rec.Code)
-					assert.NoError(t, err)
-					assert.True(t, resp.Success)
-					mockController.AssertExpectations(t)
-				}
+			if err := svc.AddFriend(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.CommonResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.NoError(t, err)
+				assert.True(t, resp.Success)
+				mockController.AssertExpectations(t)
 			}
 		})
 	}
@@ -111,7 +135,9 @@ func TestUserRelationshipHandler_ListFriend(t *testing.T) {
 
 	tcs := map[string]struct {
 		email          string
+		asOf           string
 		err            error
+		status         int
 		mockOn         []string
 		callArgument   [][]interface{}
 		returnArgument [][]interface{}
@@ -123,7 +149,16 @@ func TestUserRelationshipHandler_ListFriend(t *testing.T) {
 			returnArgument: [][]interface{}{{expectedFriends, int64(2), nil}},
 			err:            nil,
 		},
+		"Success_AsOf": {
+			email:          "test@example.com",
+			asOf:           "2025-03-01T00:00:00Z",
+			mockOn:         []string{"ListFriendshipsAsOf"},
+			callArgument:   [][]interface{}{{"test@example.com", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)}},
+			returnArgument: [][]interface{}{{expectedFriends, int64(2), nil}},
+			err:            nil,
+		},
 		"Error_InvalidEmail": {
+			status:         http.StatusUnprocessableEntity,
 			email:          "invalid-email",
 			mockOn:         []string{},
 			callArgument:   [][]interface{}{},
@@ -131,11 +166,12 @@ func TestUserRelationshipHandler_ListFriend(t *testing.T) {
 			err:            errors.New("INVALID_EMAIL_INPUT"),
 		},
 		"Error_DatabaseError": {
+			status:         http.StatusInternalServerError,
 			email:          "test@example.com",
 			mockOn:         []string{"ListFriendships"},
 			callArgument:   [][]interface{}{{"test@example.com"}},
 			returnArgument: [][]interface{}{{nil, int64(0), errors.New("DATABASE_ERROR")}},
-			err:            errors.New("DATABASE_ERROR"),
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
 		},
 	}
 
@@ -149,27 +185,33 @@ func TestUserRelationshipHandler_ListFriend(t *testing.T) {
 				Controller: mockController,
 			}
 			reqBody := `{"email":"` + tc.email + `"}`
+			if len(tc.asOf) > 0 {
+				reqBody = `{"email":"` + tc.email + `","as_of":"` + tc.asOf + `"}`
+			}
 			req := httptest.NewRequest(http.MethodGet, "/api/user/relationship/list-friend", strings.NewReader(reqBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			if assert.NoError(t, svc.ListFriend(c)) {
-				if tc.err != nil {
-					var resp api.ErrorResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusBadRequest, rec.Code)
-					assert.Equal(t, tc.err.Error(), resp.Message)
-					assert.False(t, resp.Success)
-				} else {
-					var resp api.ListFriendResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusOK, rec.Code)
-					assert.True(t, resp.Success)
-					assert.Equal(t, expectedFriends, resp.Friends)
-					assert.Equal(t, int(2), resp.Count)
-				}
+			if err := svc.ListFriend(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.ListFriendResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				assert.Equal(t, expectedFriends, resp.Friends)
+				assert.Equal(t, int(2), resp.Count)
 			}
 			mockController.AssertExpectations(t)
 		})
@@ -184,6 +226,7 @@ func TestUserRelationshipHandler_ListCommonFriends(t *testing.T) {
 		email1         string
 		email2         string
 		err            error
+		status         int
 		mockOn         []string
 		callArgument   [][]interface{}
 		returnArgument [][]interface{}
@@ -197,6 +240,7 @@ func TestUserRelationshipHandler_ListCommonFriends(t *testing.T) {
 			err:            nil,
 		},
 		"Error_AtLeastTwoEmailsAreRequired": {
+			status:         http.StatusUnprocessableEntity,
 			email2:         "test2@example.com",
 			mockOn:         []string{},
 			callArgument:   [][]interface{}{},
@@ -204,6 +248,7 @@ func TestUserRelationshipHandler_ListCommonFriends(t *testing.T) {
 			err:            errors.New("AT_LEAST_TWO_EMAILS_ARE_REQUIRED"),
 		},
 		"Error_InvalidEmail": {
+			status:         http.StatusUnprocessableEntity,
 			email1:         "invalid-email",
 			email2:         "test2@example.com",
 			mockOn:         []string{},
@@ -212,12 +257,13 @@ func TestUserRelationshipHandler_ListCommonFriends(t *testing.T) {
 			err:            errors.New("INVALID_EMAIL_INPUT"),
 		},
 		"Error_DatabaseError": {
+			status:         http.StatusInternalServerError,
 			email1:         "test1@example.com",
 			email2:         "test2@example.com",
 			mockOn:         []string{"ListCommonFriends"},
 			callArgument:   [][]interface{}{{"test1@example.com", "test2@example.com"}},
 			returnArgument: [][]interface{}{{nil, int64(0), errors.New("DATABASE_ERROR")}},
-			err:            errors.New("DATABASE_ERROR"),
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
 		},
 	}
 
@@ -233,25 +279,28 @@ func TestUserRelationshipHandler_ListCommonFriends(t *testing.T) {
 			reqBody, _ := buildRequestBody(tc.email1, tc.email2)
 			req := httptest.NewRequest(http.MethodGet, "/api/user/relationship/list-common-friends", strings.NewReader(reqBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			if assert.NoError(t, svc.ListCommonFriends(c)) {
-				if tc.err != nil {
-					var resp api.ErrorResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusBadRequest, rec.Code)
-					assert.Equal(t, tc.err.Error(), resp.Message)
-					assert.False(t, resp.Success)
-				} else {
-					var resp api.ListCommonFriendsResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusOK, rec.Code)
-					assert.True(t, resp.Success)
-					assert.Equal(t, expectedCommonFriends, resp.Friends)
-					assert.Equal(t, int(2), resp.Count)
-				}
+			if err := svc.ListCommonFriends(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.ListCommonFriendsResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				assert.Equal(t, expectedCommonFriends, resp.Friends)
+				assert.Equal(t, int(2), resp.Count)
 			}
 			mockController.AssertExpectations(t)
 		})
@@ -265,6 +314,7 @@ func TestUserRelationshipHandler_AddSubscriber(t *testing.T) {
 		requestor      string
 		target         string
 		err            error
+		status         int
 		mockOn         []string
 		callArgument   [][]interface{}
 		returnArgument [][]interface{}
@@ -278,6 +328,7 @@ func TestUserRelationshipHandler_AddSubscriber(t *testing.T) {
 			returnArgument: [][]interface{}{{nil}},
 		},
 		"Error_EmptyRequestorOrTarget": {
+			status:         http.StatusUnprocessableEntity,
 			requestor:      "",
 			target:         "",
 			err:            errors.New("REQUESTOR_AND_TARGET_ARE_REQUIRED"),
@@ -286,6 +337,7 @@ func TestUserRelationshipHandler_AddSubscriber(t *testing.T) {
 			returnArgument: [][]interface{}{{errors.New("REQUESTOR_AND_TARGET_ARE_REQUIRED")}},
 		},
 		"Error_InvalidEmail": {
+			status:         http.StatusUnprocessableEntity,
 			requestor:      "invalid-email",
 			target:         "test2@example.com",
 			err:            errors.New("INVALID_EMAIL_INPUT"),
@@ -294,9 +346,10 @@ func TestUserRelationshipHandler_AddSubscriber(t *testing.T) {
 			returnArgument: [][]interface{}{},
 		},
 		"Error_AddSubscriberFailed": {
+			status:         http.StatusInternalServerError,
 			requestor:      "test1@example.com",
 			target:         "test2@example.com",
-			err:            errors.New("DATABASE_ERROR"),
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
 			mockOn:         []string{"AddSubscriber"},
 			callArgument:   [][]interface{}{{"test1@example.com", "test2@example.com"}},
 			returnArgument: [][]interface{}{{errors.New("DATABASE_ERROR")}},
@@ -315,24 +368,27 @@ func TestUserRelationshipHandler_AddSubscriber(t *testing.T) {
 			reqBody := `{"requestor":"` + tc.requestor + `","target":"` + tc.target + `"}`
 			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/add-subscriber", strings.NewReader(reqBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			if assert.NoError(t, svc.AddSubscriber(c)) {
-				if tc.err != nil {
-					var resp api.ErrorResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusBadRequest, rec.Code)
-					assert.Equal(t, tc.err.Error(), resp.Message)
-					assert.False(t, resp.Success)
-				} else {
-					var resp api.CommonResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusOK, rec.Code)
-					assert.True(t, resp.Success)
-					mockController.AssertExpectations(t)
-				}
+			if err := svc.AddSubscriber(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.CommonResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				mockController.AssertExpectations(t)
 			}
 		})
 	}
@@ -345,6 +401,7 @@ func TestUserRelationshipHandler_AddBlock(t *testing.T) {
 		requestor      string
 		target         string
 		err            error
+		status         int
 		mockOn         []string
 		callArgument   [][]interface{}
 		returnArgument [][]interface{}
@@ -358,6 +415,7 @@ func TestUserRelationshipHandler_AddBlock(t *testing.T) {
 			returnArgument: [][]interface{}{{nil}},
 		},
 		"Error_EmptyRequestorOrTarget": {
+			status:         http.StatusUnprocessableEntity,
 			requestor:      "",
 			target:         "",
 			err:            errors.New("REQUESTOR_AND_TARGET_ARE_REQUIRED"),
@@ -366,6 +424,7 @@ func TestUserRelationshipHandler_AddBlock(t *testing.T) {
 			returnArgument: [][]interface{}{{errors.New("")}},
 		},
 		"Error_InvalidEmail": {
+			status:         http.StatusUnprocessableEntity,
 			requestor:      "invalid-email",
 			target:         "test2@example.com",
 			err:            errors.New("INVALID_EMAIL_INPUT"),
@@ -374,9 +433,10 @@ func TestUserRelationshipHandler_AddBlock(t *testing.T) {
 			returnArgument: [][]interface{}{},
 		},
 		"Error_AddBlockFailed": {
+			status:         http.StatusInternalServerError,
 			requestor:      "test1@example.com",
 			target:         "test2@example.com",
-			err:            errors.New("DATABASE_ERROR"),
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
 			mockOn:         []string{"AddBlock"},
 			callArgument:   [][]interface{}{{"test1@example.com", "test2@example.com"}},
 			returnArgument: [][]interface{}{{errors.New("DATABASE_ERROR")}},
@@ -395,24 +455,27 @@ func TestUserRelationshipHandler_AddBlock(t *testing.T) {
 			reqBody := `{"requestor":"` + tc.requestor + `","target":"` + tc.target + `"}`
 			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/add-block", strings.NewReader(reqBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			if assert.NoError(t, svc.AddBlock(c)) {
-				if tc.err != nil {
-					var resp api.ErrorResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusBadRequest, rec.Code)
-					assert.Equal(t, tc.err.Error(), resp.Message)
-					assert.False(t, resp.Success)
-				} else {
-					var resp api.CommonResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusOK, rec.Code)
-					assert.True(t, resp.Success)
-					mockController.AssertExpectations(t)
-				}
+			if err := svc.AddBlock(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.CommonResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				mockController.AssertExpectations(t)
 			}
 		})
 	}
@@ -426,6 +489,7 @@ func TestUserRelationshipHandler_GetListEmailCanReceiveUpdate(t *testing.T) {
 	tcs := map[string]struct {
 		senderEmail    string
 		err            error
+		status         int
 		mockOn         []string
 		callArgument   [][]interface{}
 		returnArgument [][]interface{}
@@ -434,10 +498,11 @@ func TestUserRelationshipHandler_GetListEmailCanReceiveUpdate(t *testing.T) {
 			senderEmail:    "test1@example.com",
 			mockOn:         []string{"GetListEmailCanReceiveUpdate"},
 			callArgument:   [][]interface{}{{"test1@example.com", text}},
-			returnArgument: [][]interface{}{{expectedListRecipients, nil}},
+			returnArgument: [][]interface{}{{&controller.Recipients{Now: expectedListRecipients}, nil}},
 			err:            nil,
 		},
 		"Error_EmptySenderEmail": {
+			status:         http.StatusUnprocessableEntity,
 			senderEmail:    "",
 			mockOn:         []string{},
 			callArgument:   [][]interface{}{},
@@ -445,6 +510,7 @@ func TestUserRelationshipHandler_GetListEmailCanReceiveUpdate(t *testing.T) {
 			err:            errors.New("SENDER_IS_REQUIRED"),
 		},
 		"Error_InvalidEmail": {
+			status:         http.StatusUnprocessableEntity,
 			senderEmail:    "invalid-email",
 			mockOn:         []string{},
 			callArgument:   [][]interface{}{},
@@ -452,11 +518,12 @@ func TestUserRelationshipHandler_GetListEmailCanReceiveUpdate(t *testing.T) {
 			err:            errors.New("INVALID_EMAIL_INPUT"),
 		},
 		"Error_DatabaseError": {
+			status:         http.StatusInternalServerError,
 			senderEmail:    "test1@example.com",
 			mockOn:         []string{"GetListEmailCanReceiveUpdate"},
 			callArgument:   [][]interface{}{{"test1@example.com", text}},
 			returnArgument: [][]interface{}{{nil, errors.New("DATABASE_ERROR")}},
-			err:            errors.New("DATABASE_ERROR"),
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
 		},
 	}
 
@@ -472,27 +539,291 @@ func TestUserRelationshipHandler_GetListEmailCanReceiveUpdate(t *testing.T) {
 			reqBody := `{"sender":"` + tc.senderEmail + `","text":"` + text + `"}`
 			req := httptest.NewRequest(http.MethodGet, "/api/user/relationship/get-list-email-receive-update", strings.NewReader(reqBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if err := svc.GetListEmailCanReceiveUpdate(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.GetListEmailCanReceiveUpdateResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				assert.Equal(t, expectedListRecipients, resp.Recipients)
+				assert.Equal(t, len(expectedListRecipients), len(resp.Recipients))
+				mockController.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUserRelationshipHandler_ListSubscribers(t *testing.T) {
+	// Setup
+	e := echo.New()
+	expectedSubscribers := []string{"subscriber1@example.com", "subscriber2@example.com"}
+
+	tcs := map[string]struct {
+		reqBody        string
+		err            error
+		status         int
+		limit          int
+		offset         int
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"Success_DefaultPagination": {
+			reqBody:        `{"email":"test@example.com"}`,
+			limit:          20,
+			offset:         0,
+			mockOn:         []string{"ListSubscribers"},
+			callArgument:   [][]interface{}{{"test@example.com", 20, 0}},
+			returnArgument: [][]interface{}{{expectedSubscribers, int64(2), nil}},
+		},
+		"Success_LimitCapped": {
+			reqBody:        `{"email":"test@example.com","limit":1000,"offset":5}`,
+			limit:          100,
+			offset:         5,
+			mockOn:         []string{"ListSubscribers"},
+			callArgument:   [][]interface{}{{"test@example.com", 100, 5}},
+			returnArgument: [][]interface{}{{expectedSubscribers, int64(2), nil}},
+		},
+		"Error_InvalidEmail": {
+			status:  http.StatusUnprocessableEntity,
+			reqBody: `{"email":"invalid-email"}`,
+			err:     errors.New("INVALID_EMAIL_INPUT"),
+		},
+		"Error_InvalidPagination": {
+			status:  http.StatusUnprocessableEntity,
+			reqBody: `{"email":"test@example.com","offset":-1}`,
+			err:     errors.New("INVALID_PAGINATION_INPUT"),
+		},
+		"Error_DatabaseError": {
+			status:         http.StatusInternalServerError,
+			reqBody:        `{"email":"test@example.com"}`,
+			mockOn:         []string{"ListSubscribers"},
+			callArgument:   [][]interface{}{{"test@example.com", 20, 0}},
+			returnArgument: [][]interface{}{{nil, int64(0), errors.New("DATABASE_ERROR")}},
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockUserRelationshipController)
+			for i, method := range tc.mockOn {
+				mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			svc := &handler.UserRelationshipHandler{
+				Controller: mockController,
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/subscribers", strings.NewReader(tc.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			if assert.NoError(t, svc.GetListEmailCanReceiveUpdate(c)) {
-				if tc.err != nil {
-					var resp api.ErrorResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusBadRequest, rec.Code)
-					assert.Equal(t, tc.err.Error(), resp.Message)
-					assert.False(t, resp.Success)
-				} else {
-					var resp api.GetListEmailCanReceiveUpdateResponse
-					err := json.Unmarshal(rec.Body.Bytes(), &resp)
-					assert.NoError(t, err)
-					assert.Equal(t, http.StatusOK, rec.Code)
-					assert.True(t, resp.Success)
-					assert.Equal(t, expectedListRecipients, resp.Recipients)
-					assert.Equal(t, len(expectedListRecipients), len(resp.Recipients))
-					mockController.AssertExpectations(t)
-				}
+			if err := svc.ListSubscribers(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
 			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.ListSubscribersResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				assert.Equal(t, expectedSubscribers, resp.Subscribers)
+				assert.Equal(t, 2, resp.Count)
+				assert.Equal(t, tc.limit, resp.Limit)
+				assert.Equal(t, tc.offset, resp.Offset)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRelationshipHandler_ListBlocks(t *testing.T) {
+	// Setup
+	e := echo.New()
+	expectedBlocks := []string{"blocked1@example.com"}
+
+	tcs := map[string]struct {
+		reqBody        string
+		err            error
+		status         int
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"Success": {
+			reqBody:        `{"requestor":"test@example.com","limit":10,"offset":10}`,
+			mockOn:         []string{"ListBlocks"},
+			callArgument:   [][]interface{}{{"test@example.com", 10, 10}},
+			returnArgument: [][]interface{}{{expectedBlocks, int64(11), nil}},
+		},
+		"Error_EmptyRequestor": {
+			status:  http.StatusUnprocessableEntity,
+			reqBody: `{"requestor":""}`,
+			err:     errors.New("REQUESTOR_IS_REQUIRED"),
+		},
+		"Error_InvalidEmail": {
+			status:  http.StatusUnprocessableEntity,
+			reqBody: `{"requestor":"invalid-email"}`,
+			err:     errors.New("INVALID_EMAIL_INPUT"),
+		},
+		"Error_InvalidPagination": {
+			status:  http.StatusUnprocessableEntity,
+			reqBody: `{"requestor":"test@example.com","limit":-5}`,
+			err:     errors.New("INVALID_PAGINATION_INPUT"),
+		},
+		"Error_DatabaseError": {
+			status:         http.StatusInternalServerError,
+			reqBody:        `{"requestor":"test@example.com"}`,
+			mockOn:         []string{"ListBlocks"},
+			callArgument:   [][]interface{}{{"test@example.com", 20, 0}},
+			returnArgument: [][]interface{}{{nil, int64(0), errors.New("DATABASE_ERROR")}},
+			err:            errors.New("INTERNAL_SERVER_ERROR"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockUserRelationshipController)
+			for i, method := range tc.mockOn {
+				mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			svc := &handler.UserRelationshipHandler{
+				Controller: mockController,
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/blocks", strings.NewReader(tc.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if err := svc.ListBlocks(c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+			if tc.err != nil {
+				var resp api.ErrorResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.status, rec.Code)
+				assert.Equal(t, tc.err.Error(), resp.Message)
+				assert.False(t, resp.Success)
+			} else {
+				var resp api.ListBlocksResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.True(t, resp.Success)
+				assert.Equal(t, expectedBlocks, resp.Blocks)
+				assert.Equal(t, 11, resp.Count)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRelationshipHandler_ProblemDetails(t *testing.T) {
+	// Setup
+	e := echo.New()
+	tcs := map[string]struct {
+		reqBody string
+		call    func(svc *handler.UserRelationshipHandler, c echo.Context) error
+		detail  string
+		errors  []api.ProblemFieldError
+	}{
+		"AddFriend_AllInvalidItemsAreReported": {
+			reqBody: `{"friends":["invalid-email","a@example.com","A@example.com","also-invalid"]}`,
+			call: func(svc *handler.UserRelationshipHandler, c echo.Context) error {
+				return svc.AddFriend(c)
+			},
+			detail: "INVALID_EMAIL_INPUT",
+			errors: []api.ProblemFieldError{
+				{Field: "friends[0]", Code: apperror.FIELD_INVALID_EMAIL, Detail: `"invalid-email" is not a valid email`},
+				{Field: "friends[2]", Code: apperror.FIELD_DUPLICATE, Detail: `"A@example.com" is the same as friends[1]`},
+				{Field: "friends[3]", Code: apperror.FIELD_INVALID_EMAIL, Detail: `"also-invalid" is not a valid email`},
+			},
+		},
+		"AddFriend_TooFewItems": {
+			reqBody: `{"friends":["bad"]}`,
+			call: func(svc *handler.UserRelationshipHandler, c echo.Context) error {
+				return svc.AddFriend(c)
+			},
+			detail: "AT_LEAST_TWO_EMAILS_ARE_REQUIRED",
+			errors: []api.ProblemFieldError{
+				{Field: "friends", Code: apperror.FIELD_TOO_FEW_ITEMS, Detail: "at least 2 emails are required"},
+				{Field: "friends[0]", Code: apperror.FIELD_INVALID_EMAIL, Detail: `"bad" is not a valid email`},
+			},
+		},
+		"AddBlock_MissingFields": {
+			reqBody: `{}`,
+			call: func(svc *handler.UserRelationshipHandler, c echo.Context) error {
+				return svc.AddBlock(c)
+			},
+			detail: "REQUESTOR_AND_TARGET_ARE_REQUIRED",
+			errors: []api.ProblemFieldError{
+				{Field: "requestor", Code: apperror.FIELD_REQUIRED, Detail: "requestor is required"},
+				{Field: "target", Code: apperror.FIELD_REQUIRED, Detail: "target is required"},
+			},
+		},
+		"ListBlocks_InvalidPagination": {
+			reqBody: `{"requestor":"test@example.com","limit":-1,"offset":-1}`,
+			call: func(svc *handler.UserRelationshipHandler, c echo.Context) error {
+				return svc.ListBlocks(c)
+			},
+			detail: "INVALID_PAGINATION_INPUT",
+			errors: []api.ProblemFieldError{
+				{Field: "limit", Code: apperror.FIELD_OUT_OF_RANGE, Detail: "limit must not be negative"},
+				{Field: "offset", Code: apperror.FIELD_OUT_OF_RANGE, Detail: "offset must not be negative"},
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockUserRelationshipController)
+			svc := &handler.UserRelationshipHandler{
+				Controller: mockController,
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/friend", strings.NewReader(tc.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, handler.MIMEApplicationProblemJSON)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if err := tc.call(svc, c); err != nil {
+				handler.HTTPErrorHandler(err, c)
+			}
+
+			var resp api.ProblemResponse
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			assert.Equal(t, handler.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Status)
+			assert.Equal(t, apperror.CODE_INVALID_INPUT, resp.Code)
+			assert.Equal(t, tc.detail, resp.Detail)
+			assert.Equal(t, tc.errors, resp.Errors)
+			mockController.AssertExpectations(t)
 		})
 	}
 }
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+// UserRelationshipV2Handler is the handler for the v2 user relationship API
+type UserRelationshipV2Handler struct {
+	Controller controller.UserRelationshipController
+}
+
+func NewUserRelationshipV2Handler(Controller controller.UserRelationshipController) api.UserRelationshipV2 {
+	return &UserRelationshipV2Handler{Controller: Controller}
+}
+
+// tenantController get the controller scoped to the tenant of the request, changes are recorded with the caller as actor
+func (sv *UserRelationshipV2Handler) tenantController(c echo.Context) controller.UserRelationshipController {
+	return sv.Controller.WithTenant(tenant.FromContext(c.Request().Context())).WithActor(actorFrom(c))
+}
+
+// ListFriends api for GET /users/:email/friends
+func (sv *UserRelationshipV2Handler) ListFriends(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	asOf := v.queryTime(c, "as_of")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	var friends []string
+	var count int64
+	var err error
+	if asOf != nil {
+		friends, count, err = sv.tenantController(c).ListFriendshipsAsOf(email, *asOf)
+	} else {
+		friends, count, err = sv.tenantController(c).ListFriendships(email)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.ListFriendResponse{Success: true, Friends: friends, Count: int(count)})
+}
+
+// PutFriend api for PUT /users/:email/friends/:other, it succeeds when the two emails are already friends
+func (sv *UserRelationshipV2Handler) PutFriend(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	err := sv.tenantController(c).AddFriendship(email, other)
+	if err != nil && !errors.Is(err, apperror.ErrAlreadyFriends) {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.CommonResponse{Success: true})
+}
+
+// DeleteFriend api for DELETE /users/:email/friends/:other
+func (sv *UserRelationshipV2Handler) DeleteFriend(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).RemoveFriendship(email, other); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListCommonFriends api for GET /users/:email/common-friends/:other
+func (sv *UserRelationshipV2Handler) ListCommonFriends(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	commonFriends, count, err := sv.tenantController(c).ListCommonFriends(email, other)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.ListCommonFriendsResponse{Success: true, Friends: commonFriends, Count: int(count)})
+}
+
+// ListSubscribers api for GET /users/:email/subscribers?limit=&offset=
+func (sv *UserRelationshipV2Handler) ListSubscribers(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	limit, offset := v.queryPagination(c)
+	asOf := v.queryTime(c, "as_of")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	limit = normalizeLimit(limit)
+	var subscribers []string
+	var count int64
+	var err error
+	if asOf != nil {
+		subscribers, count, err = sv.tenantController(c).ListSubscribersAsOf(email, *asOf, limit, offset)
+	} else {
+		subscribers, count, err = sv.tenantController(c).ListSubscribers(email, limit, offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.ListSubscribersResponse{Success: true, Subscribers: subscribers, Count: int(count), Limit: limit, Offset: offset})
+}
+
+// PutSubscription api for PUT /users/:email/subscriptions/:other, the user subscribe to the other email
+func (sv *UserRelationshipV2Handler) PutSubscription(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	err := sv.tenantController(c).AddSubscriber(email, other)
+	if err != nil && !errors.Is(err, apperror.ErrAlreadySubscribed) {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.CommonResponse{Success: true})
+}
+
+// DeleteSubscription api for DELETE /users/:email/subscriptions/:other
+func (sv *UserRelationshipV2Handler) DeleteSubscription(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).RemoveSubscriber(email, other); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListBlocks api for GET /users/:email/blocks?limit=&offset=
+func (sv *UserRelationshipV2Handler) ListBlocks(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	limit, offset := v.queryPagination(c)
+	asOf := v.queryTime(c, "as_of")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	limit = normalizeLimit(limit)
+	var blocks []string
+	var count int64
+	var err error
+	if asOf != nil {
+		blocks, count, err = sv.tenantController(c).ListBlocksAsOf(email, *asOf, limit, offset)
+	} else {
+		blocks, count, err = sv.tenantController(c).ListBlocks(email, limit, offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.ListBlocksResponse{Success: true, Blocks: blocks, Count: int(count), Limit: limit, Offset: offset})
+}
+
+// PutBlock api for PUT /users/:email/blocks/:other, putting a block the user already holds succeeds
+func (sv *UserRelationshipV2Handler) PutBlock(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	ctrl := sv.tenantController(c)
+	err := ctrl.AddBlock(email, other)
+	if errors.Is(err, apperror.ErrAlreadyBlocked) {
+		//Putting the block again succeeds, the error is only kept when the block is held by the other user
+		blocking, checkErr := ctrl.IsBlocking(email, other)
+		if checkErr != nil {
+			return checkErr
+		}
+		if blocking {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, api.CommonResponse{Success: true})
+}
+
+// DeleteBlock api for DELETE /users/:email/blocks/:other, only a block created by the user can be removed
+func (sv *UserRelationshipV2Handler) DeleteBlock(c echo.Context) error {
+	var v requestValidator
+	email, other := v.pathEmailPair(c, "email", "other")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).RemoveBlock(email, other); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListRecipients api for GET /users/:email/recipients?text=
+func (sv *UserRelationshipV2Handler) ListRecipients(c echo.Context) error {
+	var v requestValidator
+	email := v.pathEmail(c, "email")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := authorizeActor(c, email); err != nil {
+		return err
+	}
+
+	recipients, err := sv.tenantController(c).GetListEmailCanReceiveUpdate(email, c.QueryParam("text"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, recipientsResponse(recipients))
+}
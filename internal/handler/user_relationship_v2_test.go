@@ -0,0 +1,335 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRelationshipV2Handler(t *testing.T) {
+	asOf := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tcs := map[string]struct {
+		method         string
+		path           string
+		status         int
+		body           string
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+	}{
+		"ListFriends_Success": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/friends",
+			status:         http.StatusOK,
+			body:           `{"success":true,"friends":["bob@example.com"],"count":1}`,
+			mockOn:         []string{"ListFriendships"},
+			callArgument:   [][]interface{}{{"alice@example.com"}},
+			returnArgument: [][]interface{}{{[]string{"bob@example.com"}, int64(1), nil}},
+		},
+		"ListFriends_EscapedEmail": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice%40example.com/friends",
+			status:         http.StatusOK,
+			body:           `{"success":true,"friends":[],"count":0}`,
+			mockOn:         []string{"ListFriendships"},
+			callArgument:   [][]interface{}{{"alice@example.com"}},
+			returnArgument: [][]interface{}{{[]string{}, int64(0), nil}},
+		},
+		"ListFriends_InvalidEmail": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/alice/friends",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"email","code":"INVALID_EMAIL"`,
+		},
+		"ListFriends_AsOf": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/friends?as_of=2025-03-01T00:00:00Z",
+			status:         http.StatusOK,
+			body:           `{"success":true,"friends":["carol@example.com"],"count":1}`,
+			mockOn:         []string{"ListFriendshipsAsOf"},
+			callArgument:   [][]interface{}{{"alice@example.com", asOf}},
+			returnArgument: [][]interface{}{{[]string{"carol@example.com"}, int64(1), nil}},
+		},
+		"ListFriends_InvalidAsOf": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/alice@example.com/friends?as_of=2025-03-01",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"as_of","code":"INVALID_VALUE"`,
+		},
+		"ListSubscribers_AsOf": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/subscribers?as_of=2025-03-01T00:00:00Z&limit=5",
+			status:         http.StatusOK,
+			body:           `{"success":true,"subscribers":["carol@example.com"],"count":1,"limit":5,"offset":0}`,
+			mockOn:         []string{"ListSubscribersAsOf"},
+			callArgument:   [][]interface{}{{"alice@example.com", asOf, 5, 0}},
+			returnArgument: [][]interface{}{{[]string{"carol@example.com"}, int64(1), nil}},
+		},
+		"ListBlocks_AsOf": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/blocks?as_of=2025-03-01T00:00:00Z",
+			status:         http.StatusOK,
+			body:           `{"success":true,"blocks":["carol@example.com"],"count":1,"limit":20,"offset":0}`,
+			mockOn:         []string{"ListBlocksAsOf"},
+			callArgument:   [][]interface{}{{"alice@example.com", asOf, 20, 0}},
+			returnArgument: [][]interface{}{{[]string{"carol@example.com"}, int64(1), nil}},
+		},
+		"PutFriend_Success": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/alice@example.com/friends/bob@example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true}`,
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"PutFriend_AlreadyFriendsIsIdempotent": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/alice@example.com/friends/bob@example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true}`,
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrAlreadyFriends}},
+		},
+		"PutFriend_Blocked": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/alice@example.com/friends/bob@example.com",
+			status:         http.StatusConflict,
+			body:           `"code":"BLOCKED"`,
+			mockOn:         []string{"AddFriendship"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrBlocked}},
+		},
+		"DeleteFriend_Success": {
+			method:         http.MethodDelete,
+			path:           "/api/v2/users/alice@example.com/friends/bob@example.com",
+			status:         http.StatusNoContent,
+			mockOn:         []string{"RemoveFriendship"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"DeleteFriend_NotFound": {
+			method:         http.MethodDelete,
+			path:           "/api/v2/users/alice@example.com/friends/bob@example.com",
+			status:         http.StatusNotFound,
+			body:           `"detail":"FRIENDSHIP_NOT_FOUND"`,
+			mockOn:         []string{"RemoveFriendship"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{apperror.NotFound("FRIENDSHIP_NOT_FOUND")}},
+		},
+		"ListCommonFriends_Success": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/common-friends/bob@example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true,"friends":["carol@example.com"],"count":1}`,
+			mockOn:         []string{"ListCommonFriends"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{[]string{"carol@example.com"}, int64(1), nil}},
+		},
+		"ListSubscribers_Pagination": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/subscribers?limit=5&offset=10",
+			status:         http.StatusOK,
+			body:           `{"success":true,"subscribers":["bob@example.com"],"count":11,"limit":5,"offset":10}`,
+			mockOn:         []string{"ListSubscribers"},
+			callArgument:   [][]interface{}{{"alice@example.com", 5, 10}},
+			returnArgument: [][]interface{}{{[]string{"bob@example.com"}, int64(11), nil}},
+		},
+		"ListSubscribers_InvalidLimit": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/alice@example.com/subscribers?limit=abc",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"limit"`,
+		},
+		"PutSubscription_AlreadySubscribedIsIdempotent": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/alice@example.com/subscriptions/bob@example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true}`,
+			mockOn:         []string{"AddSubscriber"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrAlreadySubscribed}},
+		},
+		"DeleteSubscription_Success": {
+			method:         http.MethodDelete,
+			path:           "/api/v2/users/alice@example.com/subscriptions/bob@example.com",
+			status:         http.StatusNoContent,
+			mockOn:         []string{"RemoveSubscriber"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"ListBlocks_DefaultPagination": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/blocks",
+			status:         http.StatusOK,
+			body:           `{"success":true,"blocks":[],"count":0,"limit":20,"offset":0}`,
+			mockOn:         []string{"ListBlocks"},
+			callArgument:   [][]interface{}{{"alice@example.com", 20, 0}},
+			returnArgument: [][]interface{}{{[]string{}, int64(0), nil}},
+		},
+		"PutBlock_AlreadyBlockedIsIdempotent": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/alice@example.com/blocks/bob@example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true}`,
+			mockOn:         []string{"AddBlock", "IsBlocking"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}, {"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrAlreadyBlocked}, {true, nil}},
+		},
+		"PutBlock_BlockedByTheOtherUser": {
+			method:         http.MethodPut,
+			path:           "/api/v2/users/alice@example.com/blocks/bob@example.com",
+			status:         http.StatusConflict,
+			body:           `"code":"ALREADY_BLOCKED"`,
+			mockOn:         []string{"AddBlock", "IsBlocking"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}, {"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{apperror.ErrAlreadyBlocked}, {false, nil}},
+		},
+		"DeleteBlock_DatabaseError": {
+			method:         http.MethodDelete,
+			path:           "/api/v2/users/alice@example.com/blocks/bob@example.com",
+			status:         http.StatusInternalServerError,
+			body:           `"code":"INTERNAL_ERROR"`,
+			mockOn:         []string{"RemoveBlock"},
+			callArgument:   [][]interface{}{{"alice@example.com", "bob@example.com"}},
+			returnArgument: [][]interface{}{{errors.New("DATABASE_ERROR")}},
+		},
+		"PutFriend_SameEmail": {
+			method: http.MethodPut,
+			path:   "/api/v2/users/alice@example.com/friends/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"DeleteFriend_SameEmail": {
+			method: http.MethodDelete,
+			path:   "/api/v2/users/alice@example.com/friends/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"ListCommonFriends_SameEmail": {
+			method: http.MethodGet,
+			path:   "/api/v2/users/alice@example.com/common-friends/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"PutSubscription_SameEmail": {
+			method: http.MethodPut,
+			path:   "/api/v2/users/alice@example.com/subscriptions/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"DeleteSubscription_SameEmail": {
+			method: http.MethodDelete,
+			path:   "/api/v2/users/alice@example.com/subscriptions/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"PutBlock_SameEmail": {
+			method: http.MethodPut,
+			path:   "/api/v2/users/alice@example.com/blocks/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"DeleteBlock_SameEmail": {
+			method: http.MethodDelete,
+			path:   "/api/v2/users/alice@example.com/blocks/Alice@example.com",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"other","code":"DUPLICATE_EMAIL"`,
+		},
+		"ListRecipients_Success": {
+			method:         http.MethodGet,
+			path:           "/api/v2/users/alice@example.com/recipients?text=hello+kate%40example.com",
+			status:         http.StatusOK,
+			body:           `{"success":true,"recipients":["kate@example.com"]}`,
+			mockOn:         []string{"GetListEmailCanReceiveUpdate"},
+			callArgument:   [][]interface{}{{"alice@example.com", "hello kate@example.com"}},
+			returnArgument: [][]interface{}{{&controller.Recipients{Now: []string{"kate@example.com"}}, nil}},
+		},
+		"ListRecipients_Deferred": {
+			method:       http.MethodGet,
+			path:         "/api/v2/users/alice@example.com/recipients?text=hello",
+			status:       http.StatusOK,
+			body:         `{"success":true,"recipients":["kate@example.com"],"deferred":[{"email":"lisa@example.com","until":"2025-01-02T06:00:00Z"}]}`,
+			mockOn:       []string{"GetListEmailCanReceiveUpdate"},
+			callArgument: [][]interface{}{{"alice@example.com", "hello"}},
+			returnArgument: [][]interface{}{{&controller.Recipients{
+				Now:      []string{"kate@example.com"},
+				Deferred: []controller.DeferredRecipient{{Email: "lisa@example.com", Until: time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC)}},
+			}, nil}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockUserRelationshipController)
+			for i, method := range tc.mockOn {
+				mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterUserRelationshipV2Routes(e, handler.NewUserRelationshipV2Handler(mockController), authenticateAsAdmin)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.JSONEq(t, tc.body, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.body)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserRelationshipV2Handler_Tenant(t *testing.T) {
+	mockController := new(handler.MockUserRelationshipController)
+	mockController.On("ListFriendships", "alice@example.com").Return([]string{}, int64(0), nil)
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	withTenant := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return authenticateAsAdmin(middleware.Tenant()(next))
+	}
+	routes.RegisterUserRelationshipV2Routes(e, handler.NewUserRelationshipV2Handler(mockController), withTenant)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/users/alice@example.com/friends", nil)
+	req.Header.Set(middleware.HeaderTenantID, "acme")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "acme", mockController.Tenant)
+	mockController.AssertExpectations(t)
+}
+
+func TestUserRelationshipV2Handler_Actor(t *testing.T) {
+	mockController := new(handler.MockUserRelationshipController)
+	mockController.On("RemoveSubscriber", "alice@example.com", "bob@example.com").Return(nil)
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	routes.RegisterUserRelationshipV2Routes(e, handler.NewUserRelationshipV2Handler(mockController), authenticateAsAdmin)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/users/alice@example.com/subscriptions/bob@example.com", nil)
+	req.Header.Set("User-Agent", "app/2.1")
+	req.RemoteAddr = "198.51.100.7:5000"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, controller.Actor{Subject: adminPrincipal.Subject, IP: "198.51.100.7", UserAgent: "app/2.1"}, mockController.Actor)
+	mockController.AssertExpectations(t)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/pkg/utils"
+)
+
+// requestValidator collect every invalid field of a request instead of stopping at the first one
+type requestValidator struct {
+	fields []apperror.FieldError
+	//message of the first failed rule, kept for clients that still read the legacy error body
+	message string
+}
+
+func (v *requestValidator) add(message, field, code, detail string) {
+	if v.message == "" {
+		v.message = message
+	}
+	v.fields = append(v.fields, apperror.FieldError{Field: field, Code: code, Detail: detail})
+}
+
+// email check a single required email field, requiredMessage is the legacy message when it is missing
+func (v *requestValidator) email(field, value, requiredMessage string) {
+	if len(value) == 0 {
+		v.add(requiredMessage, field, apperror.FIELD_REQUIRED, fmt.Sprintf("%s is required", field))
+		return
+	}
+
+	if !utils.IsValidEmail(value) {
+		v.add("INVALID_EMAIL_INPUT", field, apperror.FIELD_INVALID_EMAIL, fmt.Sprintf("%q is not a valid email", value))
+	}
+}
+
+// emailList check an array of emails has at least min items, every item is valid and no email is repeated
+func (v *requestValidator) emailList(field string, values []string, min int) {
+	if len(values) < min {
+		if min == 1 {
+			v.add("AT_LEAST_ONE_EMAIL_IS_REQUIRED", field, apperror.FIELD_TOO_FEW_ITEMS, "at least 1 email is required")
+		} else {
+			v.add(fmt.Sprintf("AT_LEAST_%s_EMAILS_ARE_REQUIRED", countWord(min)), field, apperror.FIELD_TOO_FEW_ITEMS, fmt.Sprintf("at least %d emails are required", min))
+		}
+	}
+
+	seen := make(map[string]int)
+	for i, value := range values {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		if !utils.IsValidEmail(value) {
+			v.add("INVALID_EMAIL_INPUT", itemField, apperror.FIELD_INVALID_EMAIL, fmt.Sprintf("%q is not a valid email", value))
+			continue
+		}
+
+		key := strings.ToLower(value)
+		if first, ok := seen[key]; ok {
+			v.add("DUPLICATE_EMAIL_INPUT", itemField, apperror.FIELD_DUPLICATE, fmt.Sprintf("%q is the same as %s[%d]", value, field, first))
+			continue
+		}
+		seen[key] = i
+	}
+}
+
+// countWord spell the small counts of the legacy messages like AT_LEAST_TWO_EMAILS_ARE_REQUIRED, larger counts are written in digits
+func countWord(n int) string {
+	words := []string{"ZERO", "ONE", "TWO", "THREE", "FOUR", "FIVE", "SIX", "SEVEN", "EIGHT", "NINE", "TEN"}
+	if n >= 0 && n < len(words) {
+		return words[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// pagination check limit and offset are not negative
+func (v *requestValidator) pagination(limit, offset int) {
+	if limit < 0 {
+		v.add("INVALID_PAGINATION_INPUT", "limit", apperror.FIELD_OUT_OF_RANGE, "limit must not be negative")
+	}
+	if offset < 0 {
+		v.add("INVALID_PAGINATION_INPUT", "offset", apperror.FIELD_OUT_OF_RANGE, "offset must not be negative")
+	}
+}
+
+// pathEmail read and validate an email path parameter
+func (v *requestValidator) pathEmail(c echo.Context, name string) string {
+	value, err := url.PathUnescape(c.Param(name))
+	if err != nil {
+		value = c.Param(name)
+	}
+	v.email(name, value, "EMAIL_IS_REQUIRED")
+	return value
+}
+
+// pathEmailPair read and validate the two email path parameters of a relationship, a user cannot have a relationship with themselves
+func (v *requestValidator) pathEmailPair(c echo.Context, name, otherName string) (string, string) {
+	email := v.pathEmail(c, name)
+	other := v.pathEmail(c, otherName)
+	if utils.IsValidEmail(email) && strings.EqualFold(email, other) {
+		v.add("DUPLICATE_EMAIL_INPUT", otherName, apperror.FIELD_DUPLICATE, fmt.Sprintf("%q is the same as %s", other, name))
+	}
+	return email, other
+}
+
+// queryPagination read and validate the limit and offset query parameters
+func (v *requestValidator) queryPagination(c echo.Context) (int, int) {
+	limit := v.queryInt(c, "limit")
+	offset := v.queryInt(c, "offset")
+	v.pagination(limit, offset)
+	return limit, offset
+}
+
+func (v *requestValidator) queryInt(c echo.Context, name string) int {
+	raw := c.QueryParam(name)
+	if len(raw) == 0 {
+		return 0
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		v.add("INVALID_PAGINATION_INPUT", name, apperror.FIELD_OUT_OF_RANGE, fmt.Sprintf("%s must be an integer", name))
+		return 0
+	}
+	return value
+}
+
+// oneOf check an optional field has one of the allowed values
+func (v *requestValidator) oneOf(field, value string, allowed ...string) {
+	if len(value) == 0 {
+		return
+	}
+
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add("INVALID_FILTER_INPUT", field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be one of %s", field, strings.Join(allowed, ", ")))
+}
+
+// queryTime read an optional RFC 3339 time query parameter
+func (v *requestValidator) queryTime(c echo.Context, name string) *time.Time {
+	raw := c.QueryParam(name)
+	if len(raw) == 0 {
+		return nil
+	}
+
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		v.add("INVALID_FILTER_INPUT", name, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be an RFC 3339 time", name))
+		return nil
+	}
+	return &value
+}
+
+// pathID read a numeric id path parameter
+func (v *requestValidator) pathID(c echo.Context, name string) uint {
+	value, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || value == 0 {
+		v.add("INVALID_ID_INPUT", name, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be a positive integer", name))
+		return 0
+	}
+	return uint(value)
+}
+
+// err return validation error with all the invalid fields, nil when the request is valid
+func (v *requestValidator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return apperror.Validation(v.message, v.fields)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+// domainEvents are the event types a webhook can subscribe to
+var domainEvents = []string{
+	constant.DOMAIN_EVENT_FRIENDSHIP_CREATED,
+	constant.DOMAIN_EVENT_FRIENDSHIP_REMOVED,
+	constant.DOMAIN_EVENT_SUBSCRIBED,
+	constant.DOMAIN_EVENT_UNSUBSCRIBED,
+	constant.DOMAIN_EVENT_BLOCKED,
+	constant.DOMAIN_EVENT_UNBLOCKED,
+}
+
+// WebhookHandler is the handler for the webhook API
+type WebhookHandler struct {
+	Controller controller.WebhookController
+}
+
+func NewWebhookHandler(Controller controller.WebhookController) api.Webhook {
+	return &WebhookHandler{Controller: Controller}
+}
+
+// tenantController get the controller scoped to the tenant of the request
+func (sv *WebhookHandler) tenantController(c echo.Context) controller.WebhookController {
+	return sv.Controller.WithTenant(tenant.FromContext(c.Request().Context()))
+}
+
+// CreateWebhook api for POST /admin/webhooks
+func (sv *WebhookHandler) CreateWebhook(c echo.Context) error {
+	var req api.CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return apperror.BadRequest(err.Error())
+	}
+
+	var v requestValidator
+	v.webhookURL("url", req.URL)
+	v.eventTypes("event_types", req.EventTypes, domainEvents...)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	webhook := &model.Webhook{URL: req.URL, EventTypes: req.EventTypes}
+	if err := sv.tenantController(c).CreateWebhook(actorFrom(c), webhook); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, api.CreateWebhookResponse{Success: true, Webhook: webhookEndpoint(*webhook), Secret: webhook.Secret})
+}
+
+// ListWebhooks api for GET /admin/webhooks
+func (sv *WebhookHandler) ListWebhooks(c echo.Context) error {
+	webhooks, err := sv.tenantController(c).ListWebhooks(actorFrom(c))
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListWebhooksResponse{Success: true, Webhooks: make([]api.WebhookEndpoint, 0, len(webhooks))}
+	for _, webhook := range webhooks {
+		resp.Webhooks = append(resp.Webhooks, webhookEndpoint(webhook))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeleteWebhook api for DELETE /admin/webhooks/:id, the pending deliveries are dropped
+func (sv *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	var v requestValidator
+	id := v.pathID(c, "id")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).DeleteWebhook(actorFrom(c), id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListDeadLetters api for GET /admin/webhooks/:id/dead-letters?limit=&offset=
+func (sv *WebhookHandler) ListDeadLetters(c echo.Context) error {
+	var v requestValidator
+	id := v.pathID(c, "id")
+	limit, offset := v.queryPagination(c)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	limit = normalizeLimit(limit)
+	deadLetters, count, err := sv.tenantController(c).ListDeadLetters(actorFrom(c), id, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListWebhookDeadLettersResponse{Success: true, DeadLetters: make([]api.WebhookDeadLetter, 0, len(deadLetters)), Count: int(count), Limit: limit, Offset: offset}
+	for _, deadLetter := range deadLetters {
+		resp.DeadLetters = append(resp.DeadLetters, api.WebhookDeadLetter{
+			ID:        deadLetter.ID,
+			EventID:   deadLetter.EventID,
+			EventType: deadLetter.EventType,
+			Payload:   deadLetter.Payload,
+			Attempts:  deadLetter.Attempts,
+			LastError: deadLetter.LastError,
+			CreatedAt: deadLetter.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ReplayDeadLetter api for POST /admin/webhooks/:id/dead-letters/:letter/replay, the delivery is queued again
+func (sv *WebhookHandler) ReplayDeadLetter(c echo.Context) error {
+	var v requestValidator
+	id := v.pathID(c, "id")
+	letter := v.pathID(c, "letter")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := sv.tenantController(c).ReplayDeadLetter(actorFrom(c), id, letter); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func webhookEndpoint(webhook model.Webhook) api.WebhookEndpoint {
+	eventTypes := webhook.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return api.WebhookEndpoint{
+		ID:         webhook.ID,
+		URL:        webhook.URL,
+		EventTypes: eventTypes,
+		CreatedBy:  webhook.CreatedBy,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+// webhookURL check a required absolute http or https url
+func (v *requestValidator) webhookURL(field, value string) {
+	if len(value) == 0 {
+		v.add("WEBHOOK_URL_IS_REQUIRED", field, apperror.FIELD_REQUIRED, fmt.Sprintf("%s is required", field))
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		v.add("INVALID_WEBHOOK_INPUT", field, apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%s must be an absolute http or https url", field))
+	}
+}
+
+// eventTypes check every item of an optional array is one of the allowed event types
+func (v *requestValidator) eventTypes(field string, values []string, allowed ...string) {
+	for i, value := range values {
+		valid := false
+		for _, a := range allowed {
+			valid = valid || value == a
+		}
+		if !valid {
+			v.add("INVALID_WEBHOOK_INPUT", fmt.Sprintf("%s[%d]", field, i), apperror.FIELD_INVALID_VALUE, fmt.Sprintf("%q must be one of %s", value, strings.Join(allowed, ", ")))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookController struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockWebhookController) CreateWebhook(actor controller.Actor, webhook *model.Webhook) error {
+	args := m.Called(actor, webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookController) ListWebhooks(actor controller.Actor) ([]model.Webhook, error) {
+	args := m.Called(actor)
+	var webhooks []model.Webhook
+	if args.Get(0) != nil {
+		webhooks = args.Get(0).([]model.Webhook)
+	}
+	return webhooks, args.Error(1)
+}
+
+func (m *MockWebhookController) DeleteWebhook(actor controller.Actor, id uint) error {
+	args := m.Called(actor, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookController) ListDeadLetters(actor controller.Actor, webhookID uint, limit, offset int) ([]model.WebhookDeadLetter, int64, error) {
+	args := m.Called(actor, webhookID, limit, offset)
+	var deadLetters []model.WebhookDeadLetter
+	if args.Get(0) != nil {
+		deadLetters = args.Get(0).([]model.WebhookDeadLetter)
+	}
+	return deadLetters, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockWebhookController) ReplayDeadLetter(actor controller.Actor, webhookID, id uint) error {
+	args := m.Called(actor, webhookID, id)
+	return args.Error(0)
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockWebhookController) WithTenant(tenantID string) controller.WebhookController {
+	m.Tenant = tenantID
+	return m
+}
@@ -0,0 +1,178 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	admin := controller.Actor{Subject: adminPrincipal.Subject, IP: "192.0.2.1", UserAgent: "curl/8.0"}
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := func(args mock.Arguments) {
+		webhook := args.Get(1).(*model.Webhook)
+		webhook.ID, webhook.Secret, webhook.CreatedBy, webhook.CreatedAt = 3, "whsec_abc", admin.Subject, createdAt
+	}
+
+	tcs := map[string]struct {
+		method         string
+		path           string
+		reqBody        string
+		status         int
+		body           string
+		mockOn         []string
+		callArgument   [][]interface{}
+		returnArgument [][]interface{}
+		run            func(args mock.Arguments)
+	}{
+		"CreateWebhook_Success": {
+			method:         http.MethodPost,
+			path:           "/admin/webhooks",
+			reqBody:        `{"url":"https://partner.example.com/hook","event_types":["Blocked","Unblocked"]}`,
+			status:         http.StatusCreated,
+			body:           `{"success":true,"webhook":{"id":3,"url":"https://partner.example.com/hook","event_types":["Blocked","Unblocked"],"created_by":"` + admin.Subject + `","created_at":"2025-01-02T03:04:05Z"},"secret":"whsec_abc"}`,
+			mockOn:         []string{"CreateWebhook"},
+			callArgument:   [][]interface{}{{admin, &model.Webhook{URL: "https://partner.example.com/hook", EventTypes: []string{constant.DOMAIN_EVENT_BLOCKED, constant.DOMAIN_EVENT_UNBLOCKED}}}},
+			returnArgument: [][]interface{}{{nil}},
+			run:            created,
+		},
+		"CreateWebhook_EveryEvent": {
+			method:         http.MethodPost,
+			path:           "/admin/webhooks",
+			reqBody:        `{"url":"http://partner.example.com/hook"}`,
+			status:         http.StatusCreated,
+			body:           `{"success":true,"webhook":{"id":3,"url":"http://partner.example.com/hook","event_types":[],"created_by":"` + admin.Subject + `","created_at":"2025-01-02T03:04:05Z"},"secret":"whsec_abc"}`,
+			mockOn:         []string{"CreateWebhook"},
+			callArgument:   [][]interface{}{{admin, &model.Webhook{URL: "http://partner.example.com/hook"}}},
+			returnArgument: [][]interface{}{{nil}},
+			run:            created,
+		},
+		"CreateWebhook_MissingURL": {
+			method:  http.MethodPost,
+			path:    "/admin/webhooks",
+			reqBody: `{"event_types":["Blocked"]}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"url","code":"REQUIRED"`,
+		},
+		"CreateWebhook_RelativeURL": {
+			method:  http.MethodPost,
+			path:    "/admin/webhooks",
+			reqBody: `{"url":"/hook"}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"url","code":"INVALID_VALUE"`,
+		},
+		"CreateWebhook_UnknownEventType": {
+			method:  http.MethodPost,
+			path:    "/admin/webhooks",
+			reqBody: `{"url":"https://partner.example.com/hook","event_types":["Blocked","Poked"]}`,
+			status:  http.StatusUnprocessableEntity,
+			body:    `"field":"event_types[1]","code":"INVALID_VALUE"`,
+		},
+		"ListWebhooks_Success": {
+			method:       http.MethodGet,
+			path:         "/admin/webhooks",
+			status:       http.StatusOK,
+			body:         `{"success":true,"webhooks":[{"id":3,"url":"https://partner.example.com/hook","event_types":["Blocked"],"created_by":"ops","created_at":"2025-01-02T03:04:05Z"}]}`,
+			mockOn:       []string{"ListWebhooks"},
+			callArgument: [][]interface{}{{admin}},
+			returnArgument: [][]interface{}{{[]model.Webhook{{
+				ID: 3, URL: "https://partner.example.com/hook", EventTypes: []string{constant.DOMAIN_EVENT_BLOCKED}, Secret: "whsec_abc", CreatedBy: "ops", CreatedAt: createdAt,
+			}}, nil}},
+		},
+		"DeleteWebhook_Success": {
+			method:         http.MethodDelete,
+			path:           "/admin/webhooks/3",
+			status:         http.StatusNoContent,
+			mockOn:         []string{"DeleteWebhook"},
+			callArgument:   [][]interface{}{{admin, uint(3)}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"DeleteWebhook_NotFound": {
+			method:         http.MethodDelete,
+			path:           "/admin/webhooks/3",
+			status:         http.StatusNotFound,
+			body:           `"detail":"WEBHOOK_NOT_FOUND"`,
+			mockOn:         []string{"DeleteWebhook"},
+			callArgument:   [][]interface{}{{admin, uint(3)}},
+			returnArgument: [][]interface{}{{apperror.NotFound("WEBHOOK_NOT_FOUND")}},
+		},
+		"ListDeadLetters_Success": {
+			method:       http.MethodGet,
+			path:         "/admin/webhooks/3/dead-letters?limit=5",
+			status:       http.StatusOK,
+			body:         `{"success":true,"dead_letters":[{"id":9,"event_id":42,"event_type":"Blocked","payload":"{\"id\":42}","attempts":8,"last_error":"WEBHOOK_STATUS: 500","created_at":"2025-01-02T03:04:05Z"}],"count":1,"limit":5,"offset":0}`,
+			mockOn:       []string{"ListDeadLetters"},
+			callArgument: [][]interface{}{{admin, uint(3), 5, 0}},
+			returnArgument: [][]interface{}{{[]model.WebhookDeadLetter{{
+				ID: 9, WebhookID: 3, EventID: 42, EventType: constant.DOMAIN_EVENT_BLOCKED, Payload: `{"id":42}`, Attempts: 8, LastError: "WEBHOOK_STATUS: 500", CreatedAt: createdAt,
+			}}, int64(1), nil}},
+		},
+		"ListDeadLetters_InvalidID": {
+			method: http.MethodGet,
+			path:   "/admin/webhooks/abc/dead-letters",
+			status: http.StatusUnprocessableEntity,
+			body:   `"field":"id","code":"INVALID_VALUE"`,
+		},
+		"ReplayDeadLetter_Success": {
+			method:         http.MethodPost,
+			path:           "/admin/webhooks/3/dead-letters/9/replay",
+			status:         http.StatusAccepted,
+			mockOn:         []string{"ReplayDeadLetter"},
+			callArgument:   [][]interface{}{{admin, uint(3), uint(9)}},
+			returnArgument: [][]interface{}{{nil}},
+		},
+		"ReplayDeadLetter_DatabaseError": {
+			method:         http.MethodPost,
+			path:           "/admin/webhooks/3/dead-letters/9/replay",
+			status:         http.StatusInternalServerError,
+			body:           `"code":"INTERNAL_ERROR"`,
+			mockOn:         []string{"ReplayDeadLetter"},
+			callArgument:   [][]interface{}{{admin, uint(3), uint(9)}},
+			returnArgument: [][]interface{}{{errors.New("DATABASE_ERROR")}},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			mockController := new(handler.MockWebhookController)
+			for i, method := range tc.mockOn {
+				call := mockController.On(method, tc.callArgument[i]...).Return(tc.returnArgument[i]...)
+				if tc.run != nil {
+					call.Run(tc.run)
+				}
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			routes.RegisterWebhookRoutes(e, handler.NewWebhookHandler(mockController), authenticateAsAdmin)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.reqBody))
+			req.Header.Set("User-Agent", "curl/8.0")
+			if len(tc.reqBody) > 0 {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK || tc.status == http.StatusCreated {
+				assert.JSONEq(t, tc.body, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.body)
+			}
+			mockController.AssertExpectations(t)
+		})
+	}
+}
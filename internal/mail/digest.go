@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+// DigestCutoffs return when the digests of each frequency close at now: hourly digests at the top of every hour,
+// daily digests at midnight and weekly digests at midnight on monday, all in UTC
+func DigestCutoffs(now time.Time) repository.DigestCutoffs {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	sinceMonday := (int(midnight.Weekday()) + 6) % 7
+	return repository.DigestCutoffs{
+		Immediate: now,
+		Hourly:    now.Truncate(time.Hour),
+		Daily:     midnight,
+		Weekly:    midnight.AddDate(0, 0, -sinceMonday),
+	}
+}
+
+// Scheduler batch the pending status updates of every recipient into one digest email once its digest closes.
+// The updates of an author blocked by the recipient, or that blocked the recipient, since it was posted are left out.
+type Scheduler struct {
+	db        *gorm.DB
+	repo      repository.EmailDeliveryRepository
+	batchSize int
+}
+
+func NewScheduler(db *gorm.DB, repo repository.EmailDeliveryRepository, batchSize int) *Scheduler {
+	return &Scheduler{db: db, repo: repo, batchSize: batchSize}
+}
+
+// RunOnce queue the digests that are due at now and return how many were queued.
+// The batch runs in a transaction holding the scheduler lock so only one instance builds the digests.
+func (s *Scheduler) RunOnce(now time.Time) (int, error) {
+	queued := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		locked, err := repo.TryLockScheduler()
+		if err != nil {
+			return fmt.Errorf("LOCK_DIGEST_SCHEDULER_FAIL: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		cutoffs := DigestCutoffs(now)
+		digests, err := repo.ListDueDigests(cutoffs, s.batchSize)
+		if err != nil {
+			return fmt.Errorf("LIST_DUE_DIGESTS_FAIL: %w", err)
+		}
+
+		for _, digest := range digests {
+			repo := repo.WithTenant(digest.TenantID)
+			if err := repo.DropBlockedDigestEntries(digest.Recipient); err != nil {
+				return fmt.Errorf("DROP_BLOCKED_DIGEST_ENTRIES_FAIL: %w", err)
+			}
+
+			entries, err := repo.ListDigestEntries(digest.Recipient, cutoffs.For(digest.Frequency))
+			if err != nil {
+				return fmt.Errorf("LIST_DIGEST_ENTRIES_FAIL: %w", err)
+			}
+			if len(entries) == 0 {
+				continue
+			}
+
+			ids := make([]uint, 0, len(entries))
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+			//A digest due in the quiet hours of the recipient is sent when they end
+			at := now
+			preference := model.NotificationPreference{Timezone: digest.Timezone, QuietHoursStart: digest.QuietHoursStart, QuietHoursEnd: digest.QuietHoursEnd}
+			if until, quiet := preference.QuietUntil(now); quiet {
+				at = until
+			}
+			if _, err := repo.CreateDigest(digest.Recipient, ids, at); err != nil {
+				return fmt.Errorf("CREATE_DIGEST_FAIL: %w", err)
+			}
+			queued++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return queued, nil
+}
+
+// Run queue the due digests every interval until the process exits
+func (s *Scheduler) Run(interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.RunOnce(time.Now()); err != nil {
+			logger.Error(fmt.Errorf("SCHEDULE_DIGESTS_FAIL: %w", err))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package mail_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/mail"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestCutoffs(t *testing.T) {
+	//Thursday
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("ICT", 7*3600))
+
+	cutoffs := mail.DigestCutoffs(now)
+	assert.Equal(t, now.UTC(), cutoffs.Immediate)
+	assert.Equal(t, time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC), cutoffs.Hourly)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), cutoffs.Daily)
+	assert.Equal(t, time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), cutoffs.Weekly)
+
+	//A monday is the start of its own week
+	monday := mail.DigestCutoffs(time.Date(2024, 12, 30, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), monday.Weekly)
+}
+
+func TestScheduler_RunOnce(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	cutoffs := mail.DigestCutoffs(now)
+
+	testCases := map[string]struct {
+		locked      bool
+		digests     []repository.PendingDigest
+		listErr     error
+		setup       func(repo *controller.MockEmailDeliveryRepository)
+		count       int
+		tenant      string
+		expectedErr string
+	}{
+		"DigestQueued": {
+			locked:  true,
+			digests: []repository.PendingDigest{{TenantID: "tenant-b", Recipient: "bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_DAILY}},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("DropBlockedDigestEntries", "bob@example.com").Return(nil).Once()
+				repo.On("ListDigestEntries", "bob@example.com", cutoffs.Daily).Return([]model.DigestEntry{{ID: 3}, {ID: 4}}, nil).Once()
+				repo.On("CreateDigest", "bob@example.com", []uint{3, 4}, now).Return(&model.EmailDelivery{ID: 9}, nil).Once()
+			},
+			count:  1,
+			tenant: "tenant-b",
+		},
+		"DigestDeferredUntilQuietHoursEnd": {
+			locked: true,
+			digests: []repository.PendingDigest{{
+				TenantID: "tenant-b", Recipient: "bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_HOURLY,
+				Timezone: "Europe/Paris", QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+			}},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("DropBlockedDigestEntries", "bob@example.com").Return(nil).Once()
+				repo.On("ListDigestEntries", "bob@example.com", cutoffs.Hourly).Return([]model.DigestEntry{{ID: 5}}, nil).Once()
+				//04:04 in Paris
+				repo.On("CreateDigest", "bob@example.com", []uint{5}, time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC)).Return(&model.EmailDelivery{ID: 10}, nil).Once()
+			},
+			count:  1,
+			tenant: "tenant-b",
+		},
+		"EveryEntryBlocked": {
+			locked:  true,
+			digests: []repository.PendingDigest{{TenantID: "tenant-b", Recipient: "bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY}},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("DropBlockedDigestEntries", "bob@example.com").Return(nil).Once()
+				repo.On("ListDigestEntries", "bob@example.com", cutoffs.Weekly).Return(nil, nil).Once()
+			},
+			tenant: "tenant-b",
+		},
+		"LockHeldByAnotherInstance": {},
+		"ListFail": {
+			locked:      true,
+			listErr:     errors.New("DATABASE_ERROR"),
+			expectedErr: "LIST_DUE_DIGESTS_FAIL: DATABASE_ERROR",
+		},
+		"DropBlockedFail": {
+			locked:  true,
+			digests: []repository.PendingDigest{{TenantID: "tenant-b", Recipient: "bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_HOURLY}},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("DropBlockedDigestEntries", "bob@example.com").Return(errors.New("DATABASE_ERROR")).Once()
+			},
+			tenant:      "tenant-b",
+			expectedErr: "DROP_BLOCKED_DIGEST_ENTRIES_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.expectedErr == "" {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			repo := new(controller.MockEmailDeliveryRepository)
+			repo.On("TryLockScheduler").Return(tc.locked, nil).Once()
+			if tc.locked {
+				repo.On("ListDueDigests", cutoffs, 100).Return(tc.digests, tc.listErr).Once()
+			}
+			if tc.setup != nil {
+				tc.setup(repo)
+			}
+
+			count, err := mail.NewScheduler(db, repo, 100).RunOnce(now)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.count, count)
+			}
+			assert.Equal(t, tc.tenant, repo.Tenant)
+			repo.AssertExpectations(t)
+			require.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package mail_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer is a fake smtp server accepting one email, rcptReply is its answer to RCPT TO
+type smtpServer struct {
+	addr     string
+	commands chan string
+	data     chan string
+}
+
+func newSMTPServer(t *testing.T, rcptReply string) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{addr: listener.Addr().String(), commands: make(chan string, 16), data: make(chan string, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimSpace(line)
+			s.commands <- command
+			switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "RCPT":
+				reply(rcptReply)
+			case "DATA":
+				reply("354 end with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				s.data <- data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	server := newSMTPServer(t, "250 ok")
+	msg := mail.Message{
+		From:    "noreply@example.com",
+		To:      "bob@example.com",
+		Subject: "alice@example.com posted an update",
+		Text:    "Hello Bob",
+		HTML:    "<p>Hello Bob</p>",
+		Date:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	sender := mail.SMTPSender{Addr: server.addr, Timeout: 5 * time.Second}
+	require.NoError(t, sender.Send(context.Background(), msg))
+
+	data := <-server.data
+	assert.Contains(t, data, "From: noreply@example.com\r\n")
+	assert.Contains(t, data, "To: bob@example.com\r\n")
+	assert.Contains(t, data, "Subject: alice@example.com posted an update\r\n")
+	assert.Contains(t, data, "Content-Type: multipart/alternative")
+	assert.Contains(t, data, "Hello Bob")
+	assert.Contains(t, data, "<p>Hello Bob</p>")
+
+	close(server.commands)
+	var commands []string
+	for command := range server.commands {
+		commands = append(commands, strings.SplitN(command, " ", 2)[0])
+	}
+	assert.Equal(t, []string{"EHLO", "MAIL", "RCPT", "DATA", "QUIT"}, commands)
+}
+
+func TestSMTPSender_SendRejected(t *testing.T) {
+	server := newSMTPServer(t, "550 mailbox unavailable")
+
+	sender := mail.SMTPSender{Addr: server.addr, Timeout: 5 * time.Second}
+	err := sender.Send(context.Background(), mail.Message{From: "noreply@example.com", To: "nobody@example.com"})
+	var reply *textproto.Error
+	require.ErrorAs(t, err, &reply)
+	assert.Equal(t, 550, reply.Code)
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/quanluong166/friends_management/internal/model"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Data is what a template renders for one recipient, Update is the status update of an immediate email and
+// Updates the status updates of a digest, oldest first
+type Data struct {
+	Recipient string
+	Update    model.StatusUpdate
+	Updates   []model.StatusUpdate
+}
+
+// Templates render the subject and the bodies of the emails, each template <name> has the files
+// <name>.subject.tmpl, <name>.txt.tmpl and <name>.html.tmpl. The html body escapes the data.
+type Templates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// LoadTemplates parse the templates of dir, the templates shipped with the service are used when dir is empty
+func LoadTemplates(dir string) (*Templates, error) {
+	fsys, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	}
+
+	subject, err := texttemplate.ParseFS(fsys, "*.subject.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("PARSE_EMAIL_TEMPLATES_FAIL: %w", err)
+	}
+	text, err := texttemplate.ParseFS(fsys, "*.txt.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("PARSE_EMAIL_TEMPLATES_FAIL: %w", err)
+	}
+	html, err := htmltemplate.ParseFS(fsys, "*.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("PARSE_EMAIL_TEMPLATES_FAIL: %w", err)
+	}
+	return &Templates{subject: subject, text: text, html: html}, nil
+}
+
+// Render the template for one recipient, the sender and the recipient of the message are not set
+func (t *Templates) Render(name string, data Data) (Message, error) {
+	subject, text, html := t.subject.Lookup(name+".subject.tmpl"), t.text.Lookup(name+".txt.tmpl"), t.html.Lookup(name+".html.tmpl")
+	if subject == nil || text == nil || html == nil {
+		return Message{}, fmt.Errorf("UNKNOWN_EMAIL_TEMPLATE: %s", name)
+	}
+
+	var subjectBuf, textBuf, htmlBuf bytes.Buffer
+	if err := subject.Execute(&subjectBuf, data); err != nil {
+		return Message{}, fmt.Errorf("RENDER_EMAIL_FAIL: %w", err)
+	}
+	if err := text.Execute(&textBuf, data); err != nil {
+		return Message{}, fmt.Errorf("RENDER_EMAIL_FAIL: %w", err)
+	}
+	if err := html.Execute(&htmlBuf, data); err != nil {
+		return Message{}, fmt.Errorf("RENDER_EMAIL_FAIL: %w", err)
+	}
+	//A header can not span lines
+	return Message{
+		Subject: strings.Join(strings.Fields(subjectBuf.String()), " "),
+		Text:    textBuf.String(),
+		HTML:    htmlBuf.String(),
+	}, nil
+}
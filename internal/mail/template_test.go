@@ -0,0 +1,81 @@
+package mail_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/mail"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = model.StatusUpdate{
+	ID:          7,
+	AuthorEmail: "alice@example.com",
+	Text:        "<b>hello</b> kate@example.com",
+	CreatedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestTemplates_Render(t *testing.T) {
+	templates, err := mail.LoadTemplates("")
+	require.NoError(t, err)
+
+	msg, err := templates.Render(constant.EMAIL_TEMPLATE_STATUS_UPDATE, mail.Data{Recipient: "bob@example.com", Update: update})
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com posted an update", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi bob@example.com,")
+	assert.Contains(t, msg.Text, "posted on Jan 2, 2025 at 03:04 UTC")
+	assert.Contains(t, msg.Text, "<b>hello</b> kate@example.com")
+	//The text of the update is escaped in the html body
+	assert.Contains(t, msg.HTML, "&lt;b&gt;hello&lt;/b&gt; kate@example.com")
+
+	other, err := templates.Render(constant.EMAIL_TEMPLATE_STATUS_UPDATE, mail.Data{Recipient: "kate@example.com", Update: update})
+	require.NoError(t, err)
+	assert.Contains(t, other.Text, "Hi kate@example.com,")
+
+	_, err = templates.Render("unknown", mail.Data{})
+	assert.EqualError(t, err, "UNKNOWN_EMAIL_TEMPLATE: unknown")
+}
+
+func TestTemplates_RenderDigest(t *testing.T) {
+	templates, err := mail.LoadTemplates("")
+	require.NoError(t, err)
+
+	other := model.StatusUpdate{ID: 8, AuthorEmail: "carol@example.com", Text: "bye", CreatedAt: update.CreatedAt}
+	msg, err := templates.Render(constant.EMAIL_TEMPLATE_DIGEST, mail.Data{Recipient: "bob@example.com", Updates: []model.StatusUpdate{update, other}})
+	require.NoError(t, err)
+	assert.Equal(t, "2 new updates from the people you follow", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi bob@example.com,")
+	assert.Contains(t, msg.Text, "alice@example.com on Jan 2, 2025 at 03:04 UTC:\n<b>hello</b> kate@example.com")
+	assert.Contains(t, msg.Text, "carol@example.com on Jan 2, 2025 at 03:04 UTC:\nbye")
+	assert.Contains(t, msg.HTML, "&lt;b&gt;hello&lt;/b&gt;")
+
+	msg, err = templates.Render(constant.EMAIL_TEMPLATE_DIGEST, mail.Data{Recipient: "bob@example.com", Updates: []model.StatusUpdate{other}})
+	require.NoError(t, err)
+	assert.Equal(t, "1 new update from the people you follow", msg.Subject)
+}
+
+func TestLoadTemplates_Dir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"status_update.subject.tmpl": "New from\n{{.Update.AuthorEmail}}\n",
+		"status_update.txt.tmpl":     "{{.Update.Text}}",
+		"status_update.html.tmpl":    "<p>{{.Update.Text}}</p>",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	templates, err := mail.LoadTemplates(dir)
+	require.NoError(t, err)
+	msg, err := templates.Render(constant.EMAIL_TEMPLATE_STATUS_UPDATE, mail.Data{Recipient: "bob@example.com", Update: update})
+	require.NoError(t, err)
+	assert.Equal(t, "New from alice@example.com", msg.Subject)
+	assert.Equal(t, "<b>hello</b> kate@example.com", msg.Text)
+
+	_, err = mail.LoadTemplates(t.TempDir())
+	assert.ErrorContains(t, err, "PARSE_EMAIL_TEMPLATES_FAIL")
+}
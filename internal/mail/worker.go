@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+	//claimLease is how long the claimed emails are kept from the other instances, the emails not sent before it ends are left
+	//to the next claim
+	claimLease = 10 * time.Minute
+)
+
+// Worker send the queued emails, a failed email is retried with a backoff and marked FAILED after maxAttempts.
+// An email rejected with a permanent smtp error or that can not be rendered is not retried.
+type Worker struct {
+	db          *gorm.DB
+	repo        repository.EmailDeliveryRepository
+	sender      Sender
+	templates   *Templates
+	from        string
+	batchSize   int
+	maxAttempts int
+}
+
+func NewWorker(db *gorm.DB, repo repository.EmailDeliveryRepository, sender Sender, templates *Templates, from string, batchSize, maxAttempts int) *Worker {
+	return &Worker{db: db, repo: repo, sender: sender, templates: templates, from: from, batchSize: batchSize, maxAttempts: maxAttempts}
+}
+
+// RunOnce attempt one batch of due emails and return how many were sent.
+// The batch is claimed in a short transaction and the emails are sent outside of it, so instances send different emails
+// at the same time and each email is marked on its own: a failure to mark one email only sends that email again.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	claimedAt := time.Now()
+	var deliveries []model.EmailDelivery
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deliveries, err = w.repo.WithTx(tx).ClaimDue(claimedAt, claimLease, w.batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("CLAIM_EMAIL_DELIVERIES_FAIL: %w", err)
+	}
+
+	sent := 0
+	var errs []error
+	for i := range deliveries {
+		//Another instance can claim the emails again once the lease ended
+		if time.Since(claimedAt) >= claimLease {
+			break
+		}
+		ok, err := w.deliver(ctx, &deliveries[i])
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// deliver send one claimed email and record the outcome, it reports whether the email was sent
+func (w *Worker) deliver(ctx context.Context, delivery *model.EmailDelivery) (bool, error) {
+	retry, sendErr := w.send(ctx, delivery)
+	now := time.Now()
+	if sendErr == nil {
+		if err := w.repo.MarkSent(delivery.ID, now); err != nil {
+			return true, fmt.Errorf("MARK_EMAIL_SENT_FAIL: %w", err)
+		}
+		return true, nil
+	}
+
+	delivery.Attempts++
+	if !retry || delivery.Attempts >= w.maxAttempts {
+		if err := w.repo.MarkUndeliverable(delivery.ID, delivery.Attempts, sendErr.Error()); err != nil {
+			return false, fmt.Errorf("MARK_EMAIL_UNDELIVERABLE_FAIL: %w", err)
+		}
+		return false, nil
+	}
+
+	nextAttemptAt := now.Add(outbox.Backoff(delivery.Attempts, retryBaseDelay, retryMaxDelay))
+	if err := w.repo.MarkFailed(delivery.ID, delivery.Attempts, nextAttemptAt, sendErr.Error()); err != nil {
+		return false, fmt.Errorf("MARK_EMAIL_FAILED_FAIL: %w", err)
+	}
+	return false, nil
+}
+
+// Run send the due emails every interval until the process exits
+func (w *Worker) Run(interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := w.RunOnce(context.Background()); err != nil {
+			logger.Error(fmt.Errorf("DELIVER_EMAILS_FAIL: %w", err))
+		}
+	}
+}
+
+// send render the email for its recipient and send it, retry tells whether a failure can succeed on another attempt
+func (w *Worker) send(ctx context.Context, delivery *model.EmailDelivery) (bool, error) {
+	data := Data{Recipient: delivery.Recipient}
+	if delivery.StatusUpdate != nil {
+		data.Update = *delivery.StatusUpdate
+	}
+	for _, entry := range delivery.DigestEntries {
+		data.Updates = append(data.Updates, entry.StatusUpdate)
+	}
+
+	msg, err := w.templates.Render(delivery.Template, data)
+	if err != nil {
+		return false, err
+	}
+	msg.From, msg.To, msg.Date = w.from, delivery.Recipient, time.Now()
+
+	if err := w.sender.Send(ctx, msg); err != nil {
+		//5xx replies are permanent, the server will refuse the email again
+		var reply *textproto.Error
+		return !(errors.As(err, &reply) && reply.Code >= 500), err
+	}
+	return true, nil
+}
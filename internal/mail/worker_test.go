@@ -0,0 +1,190 @@
+package mail_test
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/mail"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeSender record the emails and fail them with err
+type fakeSender struct {
+	sent []mail.Message
+	err  error
+}
+
+func (s *fakeSender) Send(ctx context.Context, msg mail.Message) error {
+	s.sent = append(s.sent, msg)
+	return s.err
+}
+
+func delivery(template string, attempts int) model.EmailDelivery {
+	statusUpdateID := update.ID
+	return model.EmailDelivery{
+		ID:             9,
+		StatusUpdateID: &statusUpdateID,
+		StatusUpdate:   &update,
+		Recipient:      "bob@example.com",
+		Template:       template,
+		Status:         constant.EMAIL_STATUS_PENDING,
+		Attempts:       attempts,
+	}
+}
+
+func secondDelivery() model.EmailDelivery {
+	second := delivery(constant.EMAIL_TEMPLATE_STATUS_UPDATE, 0)
+	second.ID = 10
+	return second
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	testCases := map[string]struct {
+		deliveries  []model.EmailDelivery
+		claimErr    error
+		sendErr     error
+		setup       func(repo *controller.MockEmailDeliveryRepository)
+		sent        int
+		count       int
+		expectedErr string
+	}{
+		"Sent": {
+			deliveries: []model.EmailDelivery{delivery(constant.EMAIL_TEMPLATE_STATUS_UPDATE, 0)},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("MarkSent", uint(9), mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+			sent:  1,
+			count: 1,
+		},
+		"DigestSent": {
+			deliveries: []model.EmailDelivery{{
+				ID:            9,
+				Recipient:     "bob@example.com",
+				Template:      constant.EMAIL_TEMPLATE_DIGEST,
+				Status:        constant.EMAIL_STATUS_PENDING,
+				DigestEntries: []model.DigestEntry{{StatusUpdate: update}, {StatusUpdate: model.StatusUpdate{ID: 8, AuthorEmail: "carol@example.com", Text: "bye"}}},
+			}},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("MarkSent", uint(9), mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+			sent:  1,
+			count: 1,
+		},
+		"FailedIsRetriedWithBackoff": {
+			deliveries: []model.EmailDelivery{delivery(constant.EMAIL_TEMPLATE_STATUS_UPDATE, 2)},
+			sendErr:    &textproto.Error{Code: 421, Msg: "try again later"},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				before := time.Now()
+				repo.On("MarkFailed", uint(9), 3, mock.MatchedBy(func(next time.Time) bool {
+					//third failure waits 4 times the base delay
+					return !next.Before(before.Add(2*time.Minute)) && next.Before(time.Now().Add(2*time.Minute+time.Second))
+				}), `421 "try again later"`).Return(nil).Once()
+			},
+			sent: 1,
+		},
+		"LastAttemptMarkedFailed": {
+			deliveries: []model.EmailDelivery{delivery(constant.EMAIL_TEMPLATE_STATUS_UPDATE, 4)},
+			sendErr:    errors.New("connection refused"),
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("MarkUndeliverable", uint(9), 5, "connection refused").Return(nil).Once()
+			},
+			sent: 1,
+		},
+		"PermanentErrorNotRetried": {
+			deliveries: []model.EmailDelivery{delivery(constant.EMAIL_TEMPLATE_STATUS_UPDATE, 0)},
+			sendErr:    &textproto.Error{Code: 550, Msg: "mailbox unavailable"},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("MarkUndeliverable", uint(9), 1, `550 "mailbox unavailable"`).Return(nil).Once()
+			},
+			sent: 1,
+		},
+		"UnknownTemplateNotRetried": {
+			deliveries: []model.EmailDelivery{delivery("removed", 0)},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("MarkUndeliverable", uint(9), 1, "UNKNOWN_EMAIL_TEMPLATE: removed").Return(nil).Once()
+			},
+		},
+		"MarkFailureDoesNotStopTheBatch": {
+			deliveries: []model.EmailDelivery{delivery(constant.EMAIL_TEMPLATE_STATUS_UPDATE, 0), secondDelivery()},
+			setup: func(repo *controller.MockEmailDeliveryRepository) {
+				repo.On("MarkSent", uint(9), mock.AnythingOfType("time.Time")).Return(errors.New("DATABASE_ERROR")).Once()
+				repo.On("MarkSent", uint(10), mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+			sent:        2,
+			count:       2,
+			expectedErr: "MARK_EMAIL_SENT_FAIL: DATABASE_ERROR",
+		},
+		"NothingDue": {},
+		"ClaimFail": {
+			claimErr:    errors.New("DATABASE_ERROR"),
+			expectedErr: "CLAIM_EMAIL_DELIVERIES_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	templates, err := mail.LoadTemplates("")
+	require.NoError(t, err)
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			//Only the claim runs in the transaction
+			sqlMock.ExpectBegin()
+			if tc.claimErr == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			repo := new(controller.MockEmailDeliveryRepository)
+			repo.On("ClaimDue", mock.AnythingOfType("time.Time"), 10*time.Minute, 100).Return(tc.deliveries, tc.claimErr).Once()
+			if tc.setup != nil {
+				tc.setup(repo)
+			}
+
+			sender := &fakeSender{err: tc.sendErr}
+			count, err := mail.NewWorker(db, repo, sender, templates, "noreply@example.com", 100, 5).RunOnce(context.Background())
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.count, count)
+			require.Len(t, sender.sent, tc.sent)
+			for _, msg := range sender.sent {
+				assert.Equal(t, "noreply@example.com", msg.From)
+				assert.Equal(t, "bob@example.com", msg.To)
+				assert.Contains(t, msg.Text, "Hi bob@example.com,")
+			}
+			repo.AssertExpectations(t)
+			require.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func setupMockTxDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return db, mock
+}
@@ -0,0 +1,343 @@
+package mention
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/quanluong166/friends_management/pkg/utils"
+	"golang.org/x/net/idna"
+)
+
+const (
+	//maxLocalLength and maxDomainLength are the longest parts of an email, they also bound the work done around every @
+	maxLocalLength  = 64
+	maxDomainLength = 253
+)
+
+// codeElements are the html elements whose content is never scanned for mentions
+var codeElements = []string{"pre", "code", "script", "style"}
+
+// Extract return the emails mentioned in the text, in the order they first appear, lower cased with an ascii domain and without duplicates.
+// The text may be plain text, markdown or html: mailto links, <email> autolinks, "Name" <email> and the explicit @email form are understood,
+// html entities are decoded, trailing punctuation is dropped and the emails in code blocks, code spans and code elements are ignored.
+func Extract(text string) []string {
+	text = stripFencedCode(text)
+	text = stripCodeElements(text)
+	text = stripCodeSpans(text)
+	text = stripTags(text)
+	text = html.UnescapeString(text)
+	text = expandMailto(text)
+
+	var emails []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(text); i++ {
+		if text[i] != '@' {
+			continue
+		}
+		email, ok := emailAt(text, i)
+		if !ok || seen[email] {
+			continue
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+	return emails
+}
+
+// stripFencedCode blank the markdown code blocks fenced by ``` or ~~~, a block that is not closed runs to the end of the text
+func stripFencedCode(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var fence string
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) > 3 {
+			trimmed = ""
+		}
+		if fence == "" {
+			if marker := fenceMarker(trimmed); marker != "" {
+				fence = marker
+				lines[i] = "\n"
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); strings.HasPrefix(marker, fence) && strings.TrimSpace(trimmed[len(marker):]) == "" {
+			fence = ""
+		}
+		lines[i] = "\n"
+	}
+	return strings.Join(lines, "")
+}
+
+// fenceMarker return the run of at least three backticks or tildes the line starts with
+func fenceMarker(line string) string {
+	if len(line) == 0 || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
+
+// stripCodeElements blank the code elements of html with their content, an element that is not closed runs to the end of the text
+func stripCodeElements(text string) string {
+	lower := asciiLower(text)
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		name := codeElementAt(lower, i)
+		if name == "" {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		end := len(text)
+		if j := strings.Index(lower[i:], "</"+name); j >= 0 {
+			end = i + j + len("</"+name)
+			if k := strings.IndexByte(text[end:], '>'); k >= 0 {
+				end += k + 1
+			}
+		}
+		b.WriteByte(' ')
+		i = end
+	}
+	return b.String()
+}
+
+// codeElementAt return the name of the code element opened at i
+func codeElementAt(lower string, i int) string {
+	if lower[i] != '<' {
+		return ""
+	}
+	for _, name := range codeElements {
+		rest := lower[i+1:]
+		if !strings.HasPrefix(rest, name) {
+			continue
+		}
+		if len(rest) == len(name) || rest[len(name)] == '>' || rest[len(name)] == '/' || isSpace(rest[len(name)]) {
+			return name
+		}
+	}
+	return ""
+}
+
+// stripCodeSpans blank the markdown code spans, a run of backticks that is not closed by a run of the same length is kept
+func stripCodeSpans(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		n := backtickRun(text, i)
+		closing := -1
+		for j := i + n; j < len(text); {
+			if text[j] != '`' {
+				j++
+				continue
+			}
+			m := backtickRun(text, j)
+			if m == n {
+				closing = j
+				break
+			}
+			j += m
+		}
+		if closing < 0 {
+			b.WriteString(text[i : i+n])
+			i += n
+			continue
+		}
+		b.WriteByte(' ')
+		i = closing + n
+	}
+	return b.String()
+}
+
+func backtickRun(text string, i int) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == '`' {
+		n++
+	}
+	return n
+}
+
+// stripTags replace the html tags by the mailto targets of their attributes and the autolinks by their email,
+// a < that does not start a tag or an autolink is kept
+func stripTags(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] != '<' {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(text[i+1:], '>')
+		if end < 0 {
+			b.WriteString(text[i:])
+			break
+		}
+		inner := text[i+1 : i+1+end]
+
+		switch {
+		case isAutolink(inner):
+			b.WriteString(" " + inner + " ")
+		case isTag(inner):
+			b.WriteByte(' ')
+			for _, target := range mailtoTargets(inner) {
+				b.WriteString("mailto:" + target + " ")
+			}
+		default:
+			b.WriteByte('<')
+			i++
+			continue
+		}
+		i += end + 2
+	}
+	return b.String()
+}
+
+// isAutolink report whether the content of <> is an email, with or without mailto:
+func isAutolink(inner string) bool {
+	return strings.IndexByte(inner, '@') > 0 && strings.IndexFunc(inner, unicode.IsSpace) < 0 && !strings.ContainsAny(inner, "<\"'")
+}
+
+// isTag report whether the content of <> is an html tag, a closing tag, a comment or a declaration
+func isTag(inner string) bool {
+	if len(inner) == 0 {
+		return false
+	}
+	c := inner[0]
+	return c == '/' || c == '!' || c == '?' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// mailtoTargets return the raw targets of the mailto links in a tag
+func mailtoTargets(tag string) []string {
+	var targets []string
+	lower := asciiLower(tag)
+	for i := strings.Index(lower, "mailto:"); i >= 0; {
+		start := i + len("mailto:")
+		end := start
+		for end < len(tag) && !isSpace(tag[end]) && tag[end] != '"' && tag[end] != '\'' && tag[end] != '>' {
+			end++
+		}
+		targets = append(targets, tag[start:end])
+
+		next := strings.Index(lower[end:], "mailto:")
+		if next < 0 {
+			break
+		}
+		i = end + next
+	}
+	return targets
+}
+
+// expandMailto replace the mailto links by their percent decoded addresses, without the query and one address per comma
+func expandMailto(text string) string {
+	lower := asciiLower(text)
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if !strings.HasPrefix(lower[i:], "mailto:") {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		start := i + len("mailto:")
+		end := start
+		for end < len(text) && !isSpace(text[end]) && !strings.ContainsRune(")]>\"'", rune(text[end])) {
+			end++
+		}
+		target := text[start:end]
+		if q := strings.IndexByte(target, '?'); q >= 0 {
+			target = target[:q]
+		}
+		if decoded, err := url.PathUnescape(target); err == nil {
+			target = decoded
+		}
+		b.WriteString(" " + strings.ReplaceAll(target, ",", " ") + " ")
+		i = end
+	}
+	return b.String()
+}
+
+// emailAt read the email around the @ at i and normalize it
+func emailAt(text string, at int) (string, bool) {
+	start := at
+	for start > 0 && at-start < maxLocalLength && isLocalChar(text[start-1]) {
+		start--
+	}
+	//The path of an url like https://example.com/bob@example.com is not a mention
+	if start > 0 && at-start < maxLocalLength && text[start-1] == '/' {
+		return "", false
+	}
+
+	end := at + 1
+	for end < len(text) && end-at <= maxDomainLength {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if r != '.' && r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		end += size
+	}
+
+	local := strings.TrimLeft(text[start:at], ".")
+	domain := strings.TrimRight(text[at+1:end], ".-")
+	//_email_ is markdown emphasis, not part of the address
+	if strings.HasPrefix(local, "_") && at+1+len(domain) < len(text) && text[at+1+len(domain)] == '_' {
+		local = strings.TrimLeft(local, "_")
+	}
+	return normalize(local, domain)
+}
+
+// normalize lower case the email and convert its domain to ascii, it reports false when the result is not a valid email
+func normalize(local, domain string) (string, bool) {
+	if len(local) == 0 || len(local) > maxLocalLength || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return "", false
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || len(ascii) > maxDomainLength || !strings.Contains(ascii, ".") {
+		return "", false
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", false
+		}
+	}
+
+	email := strings.ToLower(local) + "@" + strings.ToLower(ascii)
+	if !utils.IsValidEmail(email) {
+		return "", false
+	}
+	return email, true
+}
+
+func isLocalChar(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("._%+-", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// asciiLower lower case the ascii letters only, so the indexes of the result are the indexes of s
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
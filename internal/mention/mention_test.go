@@ -0,0 +1,134 @@
+package mention_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/mention"
+	"github.com/quanluong166/friends_management/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	tcs := map[string]struct {
+		text   string
+		emails []string
+	}{
+		"Plain text": {
+			text:   "hello carol@example.com and alice@example.com",
+			emails: []string{"carol@example.com", "alice@example.com"},
+		},
+		"No email": {
+			text: "hello world @everyone",
+		},
+		"Trailing punctuation": {
+			text:   "thanks bob@example.com. ask (carol@example.com), dave@example.com! or erin@example.com?",
+			emails: []string{"bob@example.com", "carol@example.com", "dave@example.com", "erin@example.com"},
+		},
+		"Upper case and duplicates": {
+			text:   "Bob@Example.COM and bob@example.com and BOB@EXAMPLE.COM",
+			emails: []string{"bob@example.com"},
+		},
+		"Explicit mention": {
+			text:   "cc @bob@example.com and @carol@example.com:",
+			emails: []string{"bob@example.com", "carol@example.com"},
+		},
+		"Display name": {
+			text:   `from "Bob Smith" <bob@example.com> and Carol <CAROL@example.com>`,
+			emails: []string{"bob@example.com", "carol@example.com"},
+		},
+		"Mailto link": {
+			text:   "write to mailto:bob@example.com?subject=hi or mailto:carol%40example.com,dave@example.com",
+			emails: []string{"bob@example.com", "carol@example.com", "dave@example.com"},
+		},
+		"Markdown link": {
+			text:   "ask [Bob](mailto:bob@example.com) or [carol@example.com](mailto:carol@example.com)",
+			emails: []string{"bob@example.com", "carol@example.com"},
+		},
+		"Markdown autolink and emphasis": {
+			text:   "ask <bob@example.com>, **carol@example.com** or _dave@example.com_",
+			emails: []string{"bob@example.com", "carol@example.com", "dave@example.com"},
+		},
+		"Html anchor": {
+			text:   `<p>ask <a href="mailto:Bob@Example.com">Bob</a> or <b>carol@example.com</b></p>`,
+			emails: []string{"bob@example.com", "carol@example.com"},
+		},
+		"Html entities": {
+			text:   "ask bob&#64;example.com or carol&commat;example.com",
+			emails: []string{"bob@example.com", "carol@example.com"},
+		},
+		"Internationalized domain": {
+			text:   "ask bob@bücher.de or carol@例え.jp",
+			emails: []string{"bob@xn--bcher-kva.de", "carol@xn--r8jz45g.jp"},
+		},
+		"Fenced code block": {
+			text:   "ask bob@example.com\n```\ngit config user.email carol@example.com\n```\nthen dave@example.com",
+			emails: []string{"bob@example.com", "dave@example.com"},
+		},
+		"Unclosed code block": {
+			text:   "ask bob@example.com\n~~~\ncarol@example.com",
+			emails: []string{"bob@example.com"},
+		},
+		"Code span": {
+			text:   "ask bob@example.com, not `carol@example.com` or ``dave@example.com``",
+			emails: []string{"bob@example.com"},
+		},
+		"Html code": {
+			text:   "ask bob@example.com, not <code>carol@example.com</code> or <PRE class=\"x\">dave@example.com</PRE>",
+			emails: []string{"bob@example.com"},
+		},
+		"Url": {
+			text:   "see https://example.com/bob@example.com and ftp://carol@example.com, ask dave@example.com",
+			emails: []string{"dave@example.com"},
+		},
+		"Invalid emails": {
+			text: "bob@localhost, .@example.com, carol..x@example.com, dave@-example.com, erin@example..com",
+		},
+	}
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.emails, mention.Extract(tc.text))
+		})
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	f.Add("hello carol@example.com and alice@example.com")
+	f.Add(`"Bob" <bob@example.com>, <a href="mailto:carol%40example.com?subject=hi">Carol</a>`)
+	f.Add("[Bob](mailto:bob@example.com) _carol@example.com_ `dave@example.com`")
+	f.Add("```\nbob@example.com\n```\n@carol@bücher.de.")
+	f.Add("<pre>bob@example.com</pre> bob&#64;example.com <code")
+	f.Fuzz(func(t *testing.T, text string) {
+		emails := mention.Extract(text)
+
+		seen := make(map[string]bool)
+		for _, email := range emails {
+			if !utils.IsValidEmail(email) {
+				t.Fatalf("invalid email %q in %q", email, text)
+			}
+			if email != strings.ToLower(email) {
+				t.Fatalf("email %q is not lower cased", email)
+			}
+			if seen[email] {
+				t.Fatalf("duplicated email %q in %q", email, text)
+			}
+			seen[email] = true
+		}
+
+		again := mention.Extract(strings.Join(emails, " "))
+		if len(again) != len(emails) {
+			t.Fatalf("extracting %q again returned %q", emails, again)
+		}
+		for i := range emails {
+			if again[i] != emails[i] {
+				t.Fatalf("extracting %q again returned %q", emails, again)
+			}
+		}
+
+		if !strings.ContainsAny(text, "`~") {
+			if fenced := mention.Extract("```\n" + text + "\n```"); len(fenced) != 0 {
+				t.Fatalf("emails %q extracted from a code block", fenced)
+			}
+		}
+	})
+}
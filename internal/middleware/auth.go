@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+)
+
+const (
+	HeaderAPIKey = "X-API-Key"
+
+	bearerPrefix = "Bearer "
+)
+
+// Authenticate reject requests without valid credentials, the principal is stored in the request context for the handlers.
+// Credentials are a JWT in "Authorization: Bearer <token>" or a static key in X-API-Key.
+func Authenticate(authenticators ...auth.Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			credentials := auth.Credentials{APIKey: c.Request().Header.Get(HeaderAPIKey)}
+			authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+			if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+				credentials.BearerToken = strings.TrimSpace(authorization[len(bearerPrefix):])
+			}
+
+			principal, err := auth.Authenticate(credentials, authenticators...)
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return err
+			}
+
+			c.SetRequest(c.Request().WithContext(auth.NewContext(c.Request().Context(), principal)))
+			return next(c)
+		}
+	}
+}
+
+// QueryToken use the access_token query parameter as bearer token when the request has no Authorization header,
+// it is only meant for the streaming routes since a browser EventSource or websocket can not set headers
+func QueryToken() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if token := c.QueryParam("access_token"); token != "" && c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+				c.Request().Header.Set(echo.HeaderAuthorization, bearerPrefix+token)
+			}
+			return next(c)
+		}
+	}
+}
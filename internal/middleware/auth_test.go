@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	testCases := map[string]struct {
+		header  string
+		value   string
+		status  int
+		subject string
+	}{
+		"API key": {
+			header:  middleware.HeaderAPIKey,
+			value:   "svc-key",
+			status:  http.StatusOK,
+			subject: "billing-service",
+		},
+		"Unknown API key": {
+			header: middleware.HeaderAPIKey,
+			value:  "wrong-key",
+			status: http.StatusUnauthorized,
+		},
+		"Invalid bearer token": {
+			header: echo.HeaderAuthorization,
+			value:  "Bearer not-a-jwt",
+			status: http.StatusUnauthorized,
+		},
+		"Missing credentials": {
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	authenticators := []auth.Authenticator{
+		auth.NewHMACAuthenticator([]byte("test-secret")),
+		auth.NewAPIKeyAuthenticator(map[string]auth.Principal{"svc-key": {Subject: "billing-service"}}),
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			e.GET("/", func(c echo.Context) error {
+				return c.String(http.StatusOK, auth.FromContext(c.Request().Context()).Subject)
+			}, middleware.Authenticate(authenticators...))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if len(tc.header) > 0 {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.Equal(t, tc.subject, rec.Body.String())
+			} else {
+				assert.Equal(t, "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+				assert.Contains(t, rec.Body.String(), `"code":"UNAUTHENTICATED"`)
+			}
+		})
+	}
+}
+
+func TestQueryToken(t *testing.T) {
+	testCases := map[string]struct {
+		path          string
+		authorization string
+		expected      string
+	}{
+		"Token from query": {
+			path:     "/?access_token=abc",
+			expected: "Bearer abc",
+		},
+		"Header wins": {
+			path:          "/?access_token=abc",
+			authorization: "Bearer header-token",
+			expected:      "Bearer header-token",
+		},
+		"No token": {
+			path: "/",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				return c.String(http.StatusOK, c.Request().Header.Get(echo.HeaderAuthorization))
+			}, middleware.QueryToken())
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if len(tc.authorization) > 0 {
+				req.Header.Set(echo.HeaderAuthorization, tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expected, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderLink        = "Link"
+)
+
+// Deprecated mark every response of the route as deprecated and point clients to the successor API
+func Deprecated(successor string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(HeaderDeprecation, "true")
+			c.Response().Header().Set(HeaderLink, "<"+successor+`>; rel="successor-version"`)
+			return next(c)
+		}
+	}
+}
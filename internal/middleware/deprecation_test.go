@@ -0,0 +1,26 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	e := echo.New()
+	e.POST("/api/user/relationship/list", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, middleware.Deprecated("/api/v2"))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/list", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(middleware.HeaderDeprecation))
+	assert.Equal(t, `</api/v2>; rel="successor-version"`, rec.Header().Get(middleware.HeaderLink))
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency store the first response of every Idempotency-Key per authenticated caller and replay it for retries.
+// A retry that arrives while the first request is still running gets 409, a key reused with another body gets 422.
+// Requests without the header are not affected. It must run after Authenticate and Tenant.
+func Idempotency(repo repository.IdempotencyKeyRepository, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if len(key) == 0 {
+				return next(c)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return apperror.BadRequest("IDEMPOTENCY_KEY_TOO_LONG")
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return apperror.BadRequest(err.Error())
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			//The key is reserved for the caller, not the email of the request, so a caller can not claim the keys of another
+			//user before the handler checks it can act as the email
+			principal := auth.FromContext(c.Request().Context())
+			if principal == nil {
+				return apperror.ErrUnauthenticated
+			}
+
+			//Keys of different tenants never collide, the tenant is resolved before this middleware
+			repo := repo.WithTenant(tenant.FromContext(c.Request().Context()))
+			fingerprint := requestFingerprint(c.Request(), body)
+			record, reserved, err := repo.Reserve(key, strings.ToLower(principal.Subject), fingerprint, time.Now().Add(ttl))
+			if err != nil {
+				return fmt.Errorf("RESERVE_IDEMPOTENCY_KEY_FAIL: %w", err)
+			}
+
+			if !reserved {
+				if record.Fingerprint != fingerprint {
+					return apperror.ErrIdempotencyReused
+				}
+
+				if record.Status != constant.IDEMPOTENCY_STATUS_COMPLETED {
+					return apperror.ErrIdempotencyInUse
+				}
+
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+				return c.Blob(record.ResponseCode, record.ContentType, record.ResponseBody)
+			}
+
+			//A panicking handler leaves no response to store, the key is released so the client can retry it
+			defer func() {
+				if r := recover(); r != nil {
+					if err := repo.Release(record.ID); err != nil {
+						c.Logger().Error(err)
+					}
+					panic(r)
+				}
+			}()
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			//Unexpected failures are not stored so the client can retry with the same key
+			if c.Response().Status >= http.StatusInternalServerError {
+				if err := repo.Release(record.ID); err != nil {
+					c.Logger().Error(err)
+				}
+				return nil
+			}
+
+			contentType := c.Response().Header().Get(echo.HeaderContentType)
+			if err := repo.Complete(record.ID, c.Response().Status, contentType, recorder.body.Bytes()); err != nil {
+				c.Logger().Error(err)
+			}
+			return nil
+		}
+	}
+}
+
+// responseRecorder copy the response body while it is written to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// requestFingerprint identify the request so a key can not be reused for another request
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// PurgeExpiredIdempotencyKeys delete expired records every interval, it never returns so run it in a goroutine
+func PurgeExpiredIdempotencyKeys(repo repository.IdempotencyKeyRepository, interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if _, err := repo.DeleteExpired(now); err != nil {
+			logger.Error(fmt.Errorf("PURGE_EXPIRED_IDEMPOTENCY_KEYS_FAIL: %w", err))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyKeyRepository struct {
+	mock.Mock
+	Tenant string
+}
+
+func (m *MockIdempotencyKeyRepository) Reserve(key, requestor, fingerprint string, expiresAt time.Time) (*model.IdempotencyKey, bool, error) {
+	args := m.Called(key, requestor, fingerprint, expiresAt)
+	var record *model.IdempotencyKey
+	if args.Get(0) != nil {
+		record = args.Get(0).(*model.IdempotencyKey)
+	}
+	return record, args.Bool(1), args.Error(2)
+}
+
+func (m *MockIdempotencyKeyRepository) Complete(id uint, responseCode int, contentType string, responseBody []byte) error {
+	args := m.Called(id, responseCode, contentType, responseBody)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyKeyRepository) Release(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// WithTenant record the tenant and return the same mock so expectations are shared by every tenant
+func (m *MockIdempotencyKeyRepository) WithTenant(tenantID string) repository.IdempotencyKeyRepository {
+	m.Tenant = tenantID
+	return m
+}
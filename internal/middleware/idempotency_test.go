@@ -0,0 +1,248 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const reqBody = `{"requestor":"alice@example.com","target":"bob@example.com"}`
+
+func TestIdempotency(t *testing.T) {
+	tcs := map[string]struct {
+		key          string
+		setup        func(repo *middleware.MockIdempotencyKeyRepository)
+		handlerErr   error
+		handlerCalls int
+		status       int
+		body         string
+		replayed     bool
+	}{
+		"NoHeader_PassThrough": {
+			key:          "",
+			setup:        func(repo *middleware.MockIdempotencyKeyRepository) {},
+			handlerCalls: 1,
+			status:       http.StatusOK,
+			body:         `{"success":true}`,
+		},
+		"FirstRequest_ResponseStored": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(&model.IdempotencyKey{ID: 7}, true, nil)
+				repo.On("Complete", uint(7), http.StatusOK, echo.MIMEApplicationJSON, []byte(`{"success":true}`+"\n")).
+					Return(nil)
+			},
+			handlerCalls: 1,
+			status:       http.StatusOK,
+			body:         `{"success":true}`,
+		},
+		"FirstRequest_ConflictStored": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(&model.IdempotencyKey{ID: 7}, true, nil)
+				repo.On("Complete", uint(7), http.StatusConflict, echo.MIMEApplicationJSON, mock.Anything).
+					Return(nil)
+			},
+			handlerErr:   apperror.ErrAlreadySubscribed,
+			handlerCalls: 1,
+			status:       http.StatusConflict,
+			body:         `"code":"ALREADY_SUBSCRIBED"`,
+		},
+		"FirstRequest_InternalErrorReleased": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(&model.IdempotencyKey{ID: 7}, true, nil)
+				repo.On("Release", uint(7)).Return(nil)
+			},
+			handlerErr:   errors.New("DATABASE_ERROR"),
+			handlerCalls: 1,
+			status:       http.StatusInternalServerError,
+			body:         `"code":"INTERNAL_ERROR"`,
+		},
+		"Retry_ResponseReplayed": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(&model.IdempotencyKey{
+						ID:           7,
+						Fingerprint:  fingerprintOf(t, reqBody),
+						Status:       constant.IDEMPOTENCY_STATUS_COMPLETED,
+						ResponseCode: http.StatusOK,
+						ContentType:  echo.MIMEApplicationJSON,
+						ResponseBody: []byte(`{"success":true}`),
+					}, false, nil)
+			},
+			handlerCalls: 0,
+			status:       http.StatusOK,
+			body:         `{"success":true}`,
+			replayed:     true,
+		},
+		"Retry_WhileInProgress": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(&model.IdempotencyKey{
+						ID:          7,
+						Fingerprint: fingerprintOf(t, reqBody),
+						Status:      constant.IDEMPOTENCY_STATUS_IN_PROGRESS,
+					}, false, nil)
+			},
+			handlerCalls: 0,
+			status:       http.StatusConflict,
+			body:         `"code":"IDEMPOTENCY_KEY_IN_USE"`,
+		},
+		"Retry_DifferentBody": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(&model.IdempotencyKey{
+						ID:          7,
+						Fingerprint: "another-request",
+						Status:      constant.IDEMPOTENCY_STATUS_COMPLETED,
+					}, false, nil)
+			},
+			handlerCalls: 0,
+			status:       http.StatusUnprocessableEntity,
+			body:         `"code":"IDEMPOTENCY_KEY_REUSED"`,
+		},
+		"ReserveFailed": {
+			key: "key-1",
+			setup: func(repo *middleware.MockIdempotencyKeyRepository) {
+				repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+					Return(nil, false, errors.New("DATABASE_ERROR"))
+			},
+			handlerCalls: 0,
+			status:       http.StatusInternalServerError,
+			body:         `"code":"INTERNAL_ERROR"`,
+		},
+		"KeyTooLong": {
+			key:          strings.Repeat("k", 256),
+			setup:        func(repo *middleware.MockIdempotencyKeyRepository) {},
+			handlerCalls: 0,
+			status:       http.StatusBadRequest,
+			body:         `"message":"IDEMPOTENCY_KEY_TOO_LONG"`,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			repo := new(middleware.MockIdempotencyKeyRepository)
+			tc.setup(repo)
+
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			calls := 0
+			e.POST("/api/user/relationship/subscriber", func(c echo.Context) error {
+				calls++
+				if tc.handlerErr != nil {
+					return tc.handlerErr
+				}
+				return c.JSON(http.StatusOK, map[string]bool{"success": true})
+			}, middleware.Idempotency(repo, time.Hour))
+
+			req := withCaller(httptest.NewRequest(http.MethodPost, "/api/user/relationship/subscriber", strings.NewReader(reqBody)), "alice@example.com")
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			if tc.key != "" {
+				req.Header.Set(middleware.HeaderIdempotencyKey, tc.key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.body)
+			assert.Equal(t, tc.handlerCalls, calls)
+			if tc.replayed {
+				assert.Equal(t, "true", rec.Header().Get(middleware.HeaderIdempotentReplayed))
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+// fingerprintOf capture the fingerprint the middleware compute for the body on the first request
+func fingerprintOf(t *testing.T, body string) string {
+	var fingerprint string
+	repo := new(middleware.MockIdempotencyKeyRepository)
+	repo.On("Reserve", "probe", "alice@example.com", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { fingerprint = args.String(2) }).
+		Return(nil, false, errors.New("probe"))
+
+	e := echo.New()
+	e.POST("/api/user/relationship/subscriber", func(c echo.Context) error { return nil }, middleware.Idempotency(repo, time.Hour))
+	req := withCaller(httptest.NewRequest(http.MethodPost, "/api/user/relationship/subscriber", strings.NewReader(body)), "alice@example.com")
+	req.Header.Set(middleware.HeaderIdempotencyKey, "probe")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	if fingerprint == "" {
+		t.Fatal("fingerprint was not captured")
+	}
+	return fingerprint
+}
+
+// withCaller set the principal of the request like Authenticate does
+func withCaller(req *http.Request, subject string) *http.Request {
+	return req.WithContext(auth.NewContext(req.Context(), &auth.Principal{Subject: subject}))
+}
+
+func TestIdempotency_KeyOfTheCaller(t *testing.T) {
+	repo := new(middleware.MockIdempotencyKeyRepository)
+	//The key is reserved for the caller even when the request acts as another email
+	repo.On("Reserve", "key-1", "ops@example.com", mock.Anything, mock.Anything).
+		Return(nil, false, errors.New("db down"))
+
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.POST("/api/v2/users/:email/updates", func(c echo.Context) error { return nil }, middleware.Idempotency(repo, time.Hour))
+	req := withCaller(httptest.NewRequest(http.MethodPost, "/api/v2/users/alice@example.com/updates", strings.NewReader(`{"text":"hello"}`)), "Ops@example.com")
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	repo.AssertExpectations(t)
+}
+
+func TestIdempotency_Unauthenticated(t *testing.T) {
+	repo := new(middleware.MockIdempotencyKeyRepository)
+
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.POST("/api/user/relationship/subscriber", func(c echo.Context) error { return nil }, middleware.Idempotency(repo, time.Hour))
+	req := httptest.NewRequest(http.MethodPost, "/api/user/relationship/subscriber", strings.NewReader(reqBody))
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	repo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIdempotency_PanicReleasesTheKey(t *testing.T) {
+	repo := new(middleware.MockIdempotencyKeyRepository)
+	repo.On("Reserve", "key-1", "alice@example.com", mock.Anything, mock.Anything).
+		Return(&model.IdempotencyKey{ID: 7, Status: constant.IDEMPOTENCY_STATUS_IN_PROGRESS}, true, nil)
+	repo.On("Release", uint(7)).Return(nil)
+
+	e := echo.New()
+	e.POST("/api/user/relationship/subscriber", func(c echo.Context) error { panic("boom") }, middleware.Idempotency(repo, time.Hour))
+	req := withCaller(httptest.NewRequest(http.MethodPost, "/api/user/relationship/subscriber", strings.NewReader(reqBody)), "alice@example.com")
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+
+	assert.PanicsWithValue(t, "boom", func() { e.ServeHTTP(httptest.NewRecorder(), req) })
+	repo.AssertExpectations(t)
+}
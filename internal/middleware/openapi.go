@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+)
+
+// OpenAPIValidator validate every request against the OpenAPI document, routes missing from the document are not affected.
+// A body that does not match the schema gets 400, an invalid path or query parameter gets 422.
+// With validateResponses the response is buffered and a response that does not match the document is replaced by 500,
+// it is meant for tests so a handler can not drift from the published contract. Event streams and websockets are never buffered.
+func OpenAPIValidator(doc *openapi3.T, validateResponses bool) (echo.MiddlewareFunc, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("CREATE_OPENAPI_ROUTER_FAIL: %w", err)
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route, pathParams, err := router.FindRoute(c.Request())
+			if err != nil {
+				return next(c)
+			}
+
+			requestInput := &openapi3filter.RequestValidationInput{
+				Request:    c.Request(),
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			if err := openapi3filter.ValidateRequest(c.Request().Context(), requestInput); err != nil {
+				return requestValidationError(err)
+			}
+
+			if !validateResponses || streaming(c.Request()) {
+				return next(c)
+			}
+
+			writer := c.Response().Writer
+			buffer := &bufferedResponseWriter{ResponseWriter: writer, status: http.StatusOK}
+			c.Response().Writer = buffer
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			c.Response().Writer = writer
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: requestInput,
+				Status:                 buffer.status,
+				Header:                 c.Response().Header(),
+				Body:                   io.NopCloser(bytes.NewReader(buffer.body.Bytes())),
+				Options:                options,
+			}
+			if err := openapi3filter.ValidateResponse(c.Request().Context(), responseInput); err != nil {
+				c.Response().Committed = false
+				c.Response().Status = http.StatusOK
+				c.Response().Size = 0
+				c.Response().Header().Del(echo.HeaderContentType)
+				c.Response().Header().Del(echo.HeaderContentLength)
+				c.Error(fmt.Errorf("RESPONSE_DOES_NOT_MATCH_OPENAPI_SPEC: %w", err))
+				return nil
+			}
+
+			if buffer.wroteHeader {
+				writer.WriteHeader(buffer.status)
+			}
+			if _, err := writer.Write(buffer.body.Bytes()); err != nil {
+				c.Logger().Error(err)
+			}
+			return nil
+		}
+	}, nil
+}
+
+// streaming tell whether the response is sent as it is produced, it can not be held until it is complete
+func streaming(r *http.Request) bool {
+	return strings.Contains(r.Header.Get(echo.HeaderAccept), "text/event-stream") ||
+		strings.EqualFold(r.Header.Get(echo.HeaderUpgrade), "websocket")
+}
+
+// bufferedResponseWriter hold the response until it is validated
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.wroteHeader = true
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// requestValidationError convert the validation errors to a domain error with one field error per invalid field
+func requestValidationError(err error) *apperror.Error {
+	var fields []apperror.FieldError
+	invalidBody := false
+	for _, e := range flattenErrors(err) {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(e, &requestErr) {
+			fields = append(fields, apperror.FieldError{Code: apperror.FIELD_SCHEMA_MISMATCH, Detail: e.Error()})
+			continue
+		}
+
+		if requestErr.Parameter != nil {
+			fields = append(fields, apperror.FieldError{Field: requestErr.Parameter.Name, Code: apperror.FIELD_SCHEMA_MISMATCH, Detail: requestErr.Error()})
+			continue
+		}
+
+		invalidBody = true
+		schemaErrs := flattenErrors(requestErr.Err)
+		if len(schemaErrs) == 0 {
+			fields = append(fields, apperror.FieldError{Code: apperror.FIELD_SCHEMA_MISMATCH, Detail: requestErr.Error()})
+		}
+		for _, schemaErr := range schemaErrs {
+			field := apperror.FieldError{Code: apperror.FIELD_SCHEMA_MISMATCH, Detail: schemaErr.Error()}
+			var s *openapi3.SchemaError
+			if errors.As(schemaErr, &s) {
+				field.Field = strings.Join(s.JSONPointer(), ".")
+				field.Detail = s.Reason
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	if invalidBody {
+		return &apperror.Error{Code: apperror.CODE_BAD_REQUEST, Message: "REQUEST_BODY_DOES_NOT_MATCH_SCHEMA", Fields: fields}
+	}
+	return apperror.Validation("INVALID_REQUEST_PARAMETER", fields)
+}
+
+// flattenErrors expand the multi errors collected by the validator
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var errs []error
+		for _, e := range multi {
+			errs = append(errs, flattenErrors(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
@@ -0,0 +1,128 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIValidator(t *testing.T) {
+	testCases := map[string]struct {
+		validateResponses bool
+		method            string
+		path              string
+		body              string
+		handler           echo.HandlerFunc
+		status            int
+		response          string
+	}{
+		"Valid request": {
+			method: http.MethodPost,
+			path:   "/api/user/relationship/friend",
+			body:   `{"friends":["andy@example.com","john@example.com"]}`,
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusOK, api.CommonResponse{Success: true})
+			},
+			status:   http.StatusOK,
+			response: `{"success":true}`,
+		},
+		"Body does not match schema": {
+			method: http.MethodPost,
+			path:   "/api/user/relationship/friend",
+			body:   `{"friends":"andy@example.com"}`,
+			status: http.StatusBadRequest,
+			response: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"REQUEST_BODY_DOES_NOT_MATCH_SCHEMA","instance":"/api/user/relationship/friend","code":"BAD_REQUEST",` +
+				`"errors":[{"field":"friends","code":"SCHEMA_MISMATCH","detail":"value must be an array"}]}`,
+		},
+		"Invalid query parameter": {
+			method:   http.MethodGet,
+			path:     "/api/v2/users/andy@example.com/subscribers?limit=abc",
+			status:   http.StatusUnprocessableEntity,
+			response: `"code":"INVALID_INPUT"`,
+		},
+		"Route missing from the spec is not validated": {
+			method: http.MethodPost,
+			path:   "/internal",
+			body:   `not json`,
+			handler: func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			},
+			status: http.StatusNoContent,
+		},
+		"Valid response": {
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/api/v2/users/andy@example.com/friends",
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusOK, api.ListFriendResponse{Success: true, Friends: []string{"john@example.com"}, Count: 1})
+			},
+			status:   http.StatusOK,
+			response: `{"success":true,"friends":["john@example.com"],"count":1}`,
+		},
+		"Response does not match schema": {
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/api/v2/users/andy@example.com/friends",
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusOK, map[string]interface{}{"success": "yes"})
+			},
+			status:   http.StatusInternalServerError,
+			response: `"code":"INTERNAL_ERROR"`,
+		},
+		"Error response is validated": {
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/api/v2/users/andy@example.com/friends",
+			handler: func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusNotFound)
+			},
+			status:   http.StatusNotFound,
+			response: `"status":404`,
+		},
+	}
+
+	doc, err := openapi.NewSpec()
+	require.NoError(t, err)
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			validator, err := middleware.OpenAPIValidator(doc, tc.validateResponses)
+			require.NoError(t, err)
+
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			e.Use(validator)
+			h := tc.handler
+			if h == nil {
+				h = func(c echo.Context) error {
+					t.Fatal("handler must not be called")
+					return nil
+				}
+			}
+			e.Any("/*", h)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if len(tc.body) > 0 {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if strings.HasPrefix(tc.response, "{") {
+				assert.JSONEq(t, tc.response, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tc.response)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimitKey return the bucket key of the request, false when the request is not limited by this key
+type RateLimitKey func(c echo.Context) (string, bool)
+
+// RateLimitBudget return the budget of the route, false when the route is not limited
+type RateLimitBudget func(c echo.Context) (ratelimit.Budget, bool)
+
+// ByIP key the buckets by the client ip, it does not need authentication so it also throttle invalid credentials
+func ByIP(c echo.Context) (string, bool) {
+	return "ip:" + c.RealIP(), true
+}
+
+// ByRequestor key the buckets by the authenticated caller in its tenant, it must run after Authenticate and Tenant
+func ByRequestor(c echo.Context) (string, bool) {
+	return RequestorKey(c.Request().Context())
+}
+
+// RequestorKey return the bucket key of the authenticated caller of the context in its tenant, every transport use it so
+// a caller has the same buckets over REST, GraphQL and gRPC
+func RequestorKey(ctx context.Context) (string, bool) {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return "", false
+	}
+	return "user:" + tenant.FromContext(ctx) + ":" + strings.ToLower(principal.Subject), true
+}
+
+// RateLimit take one token of the bucket of the key for the budget of the route and reject the request with 429 when it is empty.
+// The RateLimit-* headers describe the most restrictive bucket when several limiters run on the same request.
+// The limiter fail open, a store error is logged and the request is served.
+func RateLimit(store ratelimit.Store, key RateLimitKey, budgetOf RateLimitBudget) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			budget, ok := budgetOf(c)
+			if !ok {
+				return next(c)
+			}
+
+			bucketKey, ok := key(c)
+			if !ok {
+				return next(c)
+			}
+
+			if err := take(store, bucketKey, budget, 1, c.Response().Header(), c.Logger()); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// ChargeRateLimit return a function taking cost tokens of the bucket of the authenticated caller for the budget, for the handlers
+// that only know the cost of a request once they read it. It sets the same headers as RateLimit, returns ErrRateLimited when the
+// bucket does not have the tokens and fail open like RateLimit.
+func ChargeRateLimit(store ratelimit.Store, budget ratelimit.Budget, logger echo.Logger) func(w http.ResponseWriter, r *http.Request, cost int) error {
+	return func(w http.ResponseWriter, r *http.Request, cost int) error {
+		bucketKey, ok := RequestorKey(r.Context())
+		if !ok || cost <= 0 {
+			return nil
+		}
+		return take(store, bucketKey, budget, cost, w.Header(), logger)
+	}
+}
+
+// take consume cost tokens of the bucket and describe it in the headers, a store error is logged and the request is allowed
+func take(store ratelimit.Store, bucketKey string, budget ratelimit.Budget, cost int, header http.Header, logger echo.Logger) error {
+	result, err := store.Take(budget.Name+":"+bucketKey, budget, cost, time.Now())
+	if err != nil {
+		logger.Error(fmt.Errorf("TAKE_RATE_LIMIT_TOKEN_FAIL: %w", err))
+		return nil
+	}
+
+	setRateLimitHeaders(header, budget, result)
+	if !result.Allowed {
+		header.Set(echo.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		return apperror.ErrRateLimited
+	}
+	return nil
+}
+
+// setRateLimitHeaders describe the bucket unless a previous limiter already described a more restrictive one
+func setRateLimitHeaders(header http.Header, budget ratelimit.Budget, result ratelimit.Result) {
+	if previous, err := strconv.Atoi(header.Get(HeaderRateLimitRemaining)); err == nil && previous < result.Remaining {
+		return
+	}
+
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	header.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+	header.Set(HeaderRateLimitPolicy, fmt.Sprintf("%d;w=%d", budget.Limit, ceilSeconds(budget.Period)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// PurgeIdleRateLimitBuckets delete the buckets not used for idleAfter every interval, it never returns so run it in a goroutine
+func PurgeIdleRateLimitBuckets(repo repository.RateLimitBucketRepository, interval, idleAfter time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if _, err := repo.DeleteIdle(now.Add(-idleAfter)); err != nil {
+			logger.Error(fmt.Errorf("PURGE_IDLE_RATE_LIMIT_BUCKETS_FAIL: %w", err))
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRateLimitStore struct {
+	mock.Mock
+}
+
+func (m *MockRateLimitStore) Take(key string, budget ratelimit.Budget, cost int, now time.Time) (ratelimit.Result, error) {
+	args := m.Called(key, budget, cost, now)
+	return args.Get(0).(ratelimit.Result), args.Error(1)
+}
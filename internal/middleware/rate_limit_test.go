@@ -0,0 +1,244 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// rateLimitRequest describe who send a request, the principal is set between the ip and the requestor limiters like Authenticate does
+type rateLimitRequest struct {
+	ip      string
+	subject string
+	tenant  string
+}
+
+func newRateLimitServer(store ratelimit.Store, ipBudget, requestorBudget middleware.RateLimitBudget) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			if subject := c.Request().Header.Get("X-Test-Subject"); len(subject) > 0 {
+				ctx = auth.NewContext(ctx, &auth.Principal{Subject: subject})
+			}
+			if tenantID := c.Request().Header.Get(middleware.HeaderTenantID); len(tenantID) > 0 {
+				ctx = tenant.NewContext(ctx, tenantID)
+			}
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+	e.POST("/friend", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]bool{"success": true})
+	}, middleware.RateLimit(store, middleware.ByIP, ipBudget), authenticate, middleware.RateLimit(store, middleware.ByRequestor, requestorBudget))
+	return e
+}
+
+func fixedBudget(limit int, period time.Duration) middleware.RateLimitBudget {
+	return func(c echo.Context) (ratelimit.Budget, bool) {
+		return ratelimit.Budget{Name: "write", Limit: limit, Period: period}, true
+	}
+}
+
+func noBudget(c echo.Context) (ratelimit.Budget, bool) {
+	return ratelimit.Budget{}, false
+}
+
+func sendRateLimited(e *echo.Echo, r rateLimitRequest) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/friend", nil)
+	req.Header.Set(echo.HeaderXRealIP, r.ip)
+	if len(r.subject) > 0 {
+		req.Header.Set("X-Test-Subject", r.subject)
+	}
+	if len(r.tenant) > 0 {
+		req.Header.Set(middleware.HeaderTenantID, r.tenant)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit_Headers(t *testing.T) {
+	e := newRateLimitServer(ratelimit.NewMemoryStore(), noBudget, fixedBudget(2, time.Minute))
+	alice := rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"}
+
+	rec := sendRateLimited(e, alice)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get(middleware.HeaderRateLimitLimit))
+	assert.Equal(t, "1", rec.Header().Get(middleware.HeaderRateLimitRemaining))
+	assert.Equal(t, "30", rec.Header().Get(middleware.HeaderRateLimitReset))
+	assert.Equal(t, "2;w=60", rec.Header().Get(middleware.HeaderRateLimitPolicy))
+	assert.Empty(t, rec.Header().Get(echo.HeaderRetryAfter))
+
+	rec = sendRateLimited(e, alice)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get(middleware.HeaderRateLimitRemaining))
+
+	rec = sendRateLimited(e, alice)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get(middleware.HeaderRateLimitRemaining))
+	assert.Equal(t, "30", rec.Header().Get(echo.HeaderRetryAfter))
+	assert.Contains(t, rec.Body.String(), `"code":"RATE_LIMITED"`)
+}
+
+func TestRateLimit_Keys(t *testing.T) {
+	tcs := map[string]struct {
+		ipBudget        middleware.RateLimitBudget
+		requestorBudget middleware.RateLimitBudget
+		first           rateLimitRequest
+		second          rateLimitRequest
+		status          int
+	}{
+		"Same requestor from another ip": {
+			ipBudget:        noBudget,
+			requestorBudget: fixedBudget(1, time.Minute),
+			first:           rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"},
+			second:          rateLimitRequest{ip: "10.0.0.2", subject: "alice@example.com"},
+			status:          http.StatusTooManyRequests,
+		},
+		"Requestor is case insensitive": {
+			ipBudget:        noBudget,
+			requestorBudget: fixedBudget(1, time.Minute),
+			first:           rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"},
+			second:          rateLimitRequest{ip: "10.0.0.1", subject: "Alice@Example.com"},
+			status:          http.StatusTooManyRequests,
+		},
+		"Another requestor": {
+			ipBudget:        noBudget,
+			requestorBudget: fixedBudget(1, time.Minute),
+			first:           rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"},
+			second:          rateLimitRequest{ip: "10.0.0.1", subject: "bob@example.com"},
+			status:          http.StatusOK,
+		},
+		"Same requestor in another tenant": {
+			ipBudget:        noBudget,
+			requestorBudget: fixedBudget(1, time.Minute),
+			first:           rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"},
+			second:          rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com", tenant: "acme"},
+			status:          http.StatusOK,
+		},
+		"Same ip without credentials": {
+			ipBudget:        fixedBudget(1, time.Minute),
+			requestorBudget: noBudget,
+			first:           rateLimitRequest{ip: "10.0.0.1"},
+			second:          rateLimitRequest{ip: "10.0.0.1"},
+			status:          http.StatusTooManyRequests,
+		},
+		"Same ip for different requestors": {
+			ipBudget:        fixedBudget(1, time.Minute),
+			requestorBudget: fixedBudget(10, time.Minute),
+			first:           rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"},
+			second:          rateLimitRequest{ip: "10.0.0.1", subject: "bob@example.com"},
+			status:          http.StatusTooManyRequests,
+		},
+		"Another ip": {
+			ipBudget:        fixedBudget(1, time.Minute),
+			requestorBudget: noBudget,
+			first:           rateLimitRequest{ip: "10.0.0.1"},
+			second:          rateLimitRequest{ip: "10.0.0.2"},
+			status:          http.StatusOK,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			e := newRateLimitServer(ratelimit.NewMemoryStore(), tc.ipBudget, tc.requestorBudget)
+
+			assert.Equal(t, http.StatusOK, sendRateLimited(e, tc.first).Code)
+			assert.Equal(t, tc.status, sendRateLimited(e, tc.second).Code)
+		})
+	}
+}
+
+func TestRateLimit_MostRestrictiveHeaders(t *testing.T) {
+	tcs := map[string]struct {
+		ipBudget        middleware.RateLimitBudget
+		requestorBudget middleware.RateLimitBudget
+		limit           string
+		remaining       string
+	}{
+		"Requestor is more restrictive": {
+			ipBudget:        fixedBudget(100, time.Minute),
+			requestorBudget: fixedBudget(5, time.Minute),
+			limit:           "5",
+			remaining:       "4",
+		},
+		"Ip is more restrictive": {
+			ipBudget:        fixedBudget(5, time.Minute),
+			requestorBudget: fixedBudget(100, time.Minute),
+			limit:           "5",
+			remaining:       "4",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			e := newRateLimitServer(ratelimit.NewMemoryStore(), tc.ipBudget, tc.requestorBudget)
+
+			rec := sendRateLimited(e, rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"})
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.limit, rec.Header().Get(middleware.HeaderRateLimitLimit))
+			assert.Equal(t, tc.remaining, rec.Header().Get(middleware.HeaderRateLimitRemaining))
+		})
+	}
+}
+
+func TestRateLimit_NotLimited(t *testing.T) {
+	store := new(middleware.MockRateLimitStore)
+	e := newRateLimitServer(store, noBudget, noBudget)
+
+	rec := sendRateLimited(e, rateLimitRequest{ip: "10.0.0.1", subject: "alice@example.com"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(middleware.HeaderRateLimitLimit))
+	store.AssertNotCalled(t, "Take", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRateLimit_StoreErrorFailOpen(t *testing.T) {
+	store := new(middleware.MockRateLimitStore)
+	store.On("Take", "write:ip:10.0.0.1", mock.Anything, 1, mock.Anything).
+		Return(ratelimit.Result{}, errors.New("DATABASE_ERROR"))
+	e := newRateLimitServer(store, fixedBudget(1, time.Minute), noBudget)
+
+	rec := sendRateLimited(e, rateLimitRequest{ip: "10.0.0.1"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(middleware.HeaderRateLimitLimit))
+	store.AssertExpectations(t)
+}
+
+func TestChargeRateLimit(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	budget := ratelimit.Budget{Name: "read", Limit: 10, Period: 10 * time.Second}
+	charge := middleware.ChargeRateLimit(store, budget, echo.New().Logger)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{Subject: "Andy@example.com"}))
+
+	rec := httptest.NewRecorder()
+	assert.NoError(t, charge(rec, req, 8))
+	assert.Equal(t, "2", rec.Header().Get(middleware.HeaderRateLimitRemaining))
+
+	//The caller has the same bucket as the requestor limiter
+	bucketKey, _ := middleware.RequestorKey(req.Context())
+	result, err := store.Take("read:"+bucketKey, budget, 1, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Remaining)
+
+	rec = httptest.NewRecorder()
+	assert.ErrorIs(t, charge(rec, req, 5), apperror.ErrRateLimited)
+	assert.Equal(t, "4", rec.Header().Get(echo.HeaderRetryAfter))
+
+	//Anonymous requests are left to the ip limiter
+	assert.NoError(t, charge(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil), 5))
+}
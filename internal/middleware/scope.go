@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+)
+
+// RequireScope reject authenticated callers without the scope, it must run after Authenticate
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal := auth.FromContext(c.Request().Context())
+			if principal == nil {
+				return apperror.ErrUnauthenticated
+			}
+
+			if !principal.HasScope(scope) {
+				return apperror.Forbidden("ADMIN_SCOPE_IS_REQUIRED")
+			}
+			return next(c)
+		}
+	}
+}
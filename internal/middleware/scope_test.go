@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	tcs := map[string]struct {
+		principal *auth.Principal
+		status    int
+	}{
+		"Admin": {
+			principal: &auth.Principal{Subject: "ops@example.com", Scopes: []string{auth.SCOPE_ADMIN}},
+			status:    http.StatusOK,
+		},
+		"Missing scope": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			status:    http.StatusForbidden,
+		},
+		"Not authenticated": {
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			e.GET("/admin/relationships", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, middleware.RequireScope(auth.SCOPE_ADMIN))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/relationships", nil)
+			if tc.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.principal))
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
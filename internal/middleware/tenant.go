@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/tenant"
+)
+
+const HeaderTenantID = "X-Tenant-ID"
+
+// Tenant resolve the tenant of the request from the credentials or the X-Tenant-ID header, it must run after Authenticate
+func Tenant() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal := auth.FromContext(c.Request().Context())
+			tenantID, err := tenant.Resolve(principal, c.Request().Header.Get(HeaderTenantID))
+			if err != nil {
+				return err
+			}
+
+			c.SetRequest(c.Request().WithContext(tenant.NewContext(c.Request().Context(), tenantID)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenant(t *testing.T) {
+	tcs := map[string]struct {
+		principal *auth.Principal
+		header    string
+		status    int
+		tenant    string
+	}{
+		"Default tenant": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			status:    http.StatusOK,
+			tenant:    constant.DEFAULT_TENANT_ID,
+		},
+		"Header": {
+			principal: &auth.Principal{Subject: "billing-service", Scopes: []string{auth.SCOPE_SERVICE}},
+			header:    "acme",
+			status:    http.StatusOK,
+			tenant:    "acme",
+		},
+		"Claim": {
+			principal: &auth.Principal{Subject: "andy@example.com", Tenant: "acme"},
+			status:    http.StatusOK,
+			tenant:    "acme",
+		},
+		"Header does not match the claim": {
+			principal: &auth.Principal{Subject: "andy@example.com", Tenant: "acme"},
+			header:    "globex",
+			status:    http.StatusForbidden,
+		},
+		"Header from an admin": {
+			principal: &auth.Principal{Subject: "ops@example.com", Scopes: []string{auth.SCOPE_ADMIN}},
+			header:    "acme",
+			status:    http.StatusOK,
+			tenant:    "acme",
+		},
+		"Header from a user token": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			header:    "globex",
+			status:    http.StatusForbidden,
+		},
+		"Default tenant header from a user token": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			header:    constant.DEFAULT_TENANT_ID,
+			status:    http.StatusOK,
+			tenant:    constant.DEFAULT_TENANT_ID,
+		},
+		"Invalid header": {
+			principal: &auth.Principal{Subject: "billing-service", Scopes: []string{auth.SCOPE_SERVICE}},
+			header:    "acme corp",
+			status:    http.StatusUnprocessableEntity,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = handler.HTTPErrorHandler
+			e.GET("/", func(c echo.Context) error {
+				return c.String(http.StatusOK, tenant.FromContext(c.Request().Context()))
+			}, middleware.Tenant())
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(auth.NewContext(req.Context(), tc.principal))
+			if len(tc.header) > 0 {
+				req.Header.Set(middleware.HeaderTenantID, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.Equal(t, tc.tenant, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+type AdminAuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TenantID  string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Actor     string    `gorm:"type:varchar(255);not null;index" json:"actor"`
+	Action    string    `gorm:"type:varchar(64);not null" json:"action"`
+	Details   string    `gorm:"type:jsonb" json:"details"`
+	IP        string    `gorm:"type:varchar(64)" json:"ip"`
+	UserAgent string    `gorm:"type:text" json:"user_agent"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
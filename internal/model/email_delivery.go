@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+// EmailDelivery is the email sent to one recipient, either one status update or a digest of DigestEntries when
+// StatusUpdateID is nil. Status tells whether it was sent.
+type EmailDelivery struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	TenantID       string        `gorm:"type:varchar(64);not null;default:'default'" json:"tenant_id"`
+	StatusUpdateID *uint         `gorm:"uniqueIndex:idx_email_delivery_recipient" json:"status_update_id"`
+	StatusUpdate   *StatusUpdate `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	DigestEntries  []DigestEntry `json:"-"`
+	Recipient      string        `gorm:"type:varchar(255);not null;uniqueIndex:idx_email_delivery_recipient" json:"recipient"`
+	Template       string        `gorm:"type:varchar(64);not null" json:"template"`
+	Status         string        `gorm:"type:varchar(16);not null;index:idx_email_delivery_due;check:status IN ('PENDING', 'SENT', 'FAILED')" json:"status"`
+	Attempts       int           `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time     `gorm:"not null;index:idx_email_delivery_due" json:"next_attempt_at"`
+	LastError      string        `gorm:"type:text" json:"last_error"`
+	SentAt         *time.Time    `json:"sent_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// DigestEntry is a status update waiting for the next digest of one recipient, EmailDeliveryID is set once it is in a digest
+type DigestEntry struct {
+	ID              uint         `gorm:"primaryKey" json:"id"`
+	TenantID        string       `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_digest_entry_recipient" json:"tenant_id"`
+	Recipient       string       `gorm:"type:varchar(255);not null;uniqueIndex:idx_digest_entry_recipient" json:"recipient"`
+	StatusUpdateID  uint         `gorm:"not null;uniqueIndex:idx_digest_entry_recipient" json:"status_update_id"`
+	StatusUpdate    StatusUpdate `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	EmailDeliveryID *uint        `gorm:"index" json:"email_delivery_id"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
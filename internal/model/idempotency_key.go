@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TenantID     string    `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_idempotency_tenant_key_requestor" json:"tenant_id"`
+	Key          string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_tenant_key_requestor" json:"key"`
+	Requestor    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_tenant_key_requestor" json:"requestor"`
+	Fingerprint  string    `gorm:"type:varchar(64);not null" json:"fingerprint"`
+	Status       string    `gorm:"type:text;check:status IN ('IN_PROGRESS', 'COMPLETED')" json:"status"`
+	ResponseCode int       `json:"response_code"`
+	ContentType  string    `gorm:"type:varchar(255)" json:"content_type"`
+	ResponseBody []byte    `json:"response_body"`
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
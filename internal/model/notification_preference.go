@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+)
+
+// NotificationPreference is how and when one email is sent the status updates it receives, an email without one is sent
+// every update immediately. QuietHoursStart and QuietHoursEnd are "15:04" times in Timezone, both empty when there are no quiet hours.
+// IgnoreStrangerMentions leave the email out of the updates that mention it when the author is neither a friend nor subscribed to.
+type NotificationPreference struct {
+	ID                     uint      `gorm:"primaryKey" json:"id"`
+	TenantID               string    `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_notification_preference_email" json:"tenant_id"`
+	Email                  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_notification_preference_email" json:"email"`
+	Frequency              string    `gorm:"type:varchar(16);not null;check:frequency IN ('IMMEDIATE', 'HOURLY', 'DAILY', 'WEEKLY')" json:"frequency"`
+	Timezone               string    `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	QuietHoursStart        string    `gorm:"type:varchar(5);not null;default:''" json:"quiet_hours_start"`
+	QuietHoursEnd          string    `gorm:"type:varchar(5);not null;default:''" json:"quiet_hours_end"`
+	MentionsOnly           bool      `gorm:"not null;default:false" json:"mentions_only"`
+	IgnoreStrangerMentions bool      `gorm:"not null;default:false" json:"ignore_stranger_mentions"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// QuietUntil report whether now is in the quiet hours and when they end, quiet hours that end before they start
+// span midnight. Invalid quiet hours or timezone are ignored.
+func (p NotificationPreference) QuietUntil(now time.Time) (time.Time, bool) {
+	start, err := time.Parse("15:04", p.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", p.QuietHoursEnd)
+	if err != nil {
+		return time.Time{}, false
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	quiet := startMinute <= minute && minute < endMinute
+	if endMinute < startMinute {
+		quiet = minute >= startMinute || minute < endMinute
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, location)
+	if !until.After(local) {
+		until = time.Date(local.Year(), local.Month(), local.Day()+1, end.Hour(), end.Minute(), 0, 0, location)
+	}
+	return until.UTC(), true
+}
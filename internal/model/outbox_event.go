@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// OutboxEvent is a domain event written in the transaction of the change and published later by the relay
+type OutboxEvent struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	TenantID string `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Type     string `gorm:"type:varchar(64);not null" json:"type"`
+	//RequestorEmail is the user who made the change, the events of one user are published in order
+	RequestorEmail string     `gorm:"type:varchar(255);not null" json:"requestor_email"`
+	TargetEmail    string     `gorm:"type:varchar(255);not null" json:"target_email"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PublishedAt    *time.Time `gorm:"index" json:"published_at"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastError      string     `gorm:"type:text" json:"last_error"`
+	//DeadLetteredAt is set when the event failed every attempt, it is not published again
+	DeadLetteredAt *time.Time `gorm:"index" json:"dead_lettered_at"`
+}
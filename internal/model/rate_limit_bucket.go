@@ -0,0 +1,12 @@
+package model
+
+import (
+	"time"
+)
+
+// RateLimitBucket is a token bucket shared by every instance of the api, the key already contains the tenant
+type RateLimitBucket struct {
+	Key       string    `gorm:"type:varchar(512);primaryKey" json:"key"`
+	Tokens    float64   `gorm:"not null" json:"tokens"`
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
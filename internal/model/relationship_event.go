@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// RelationshipState is one relationship row as it was before or after a change
+type RelationshipState struct {
+	Requestor string `json:"requestor"`
+	Target    string `json:"target"`
+	Type      string `json:"type"`
+}
+
+// RelationshipEvent is one change of the relationship graph, events are only appended and never updated or deleted
+type RelationshipEvent struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	TenantID       string              `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Action         string              `gorm:"type:varchar(32);not null" json:"action"`
+	RequestorEmail string              `gorm:"type:varchar(255);not null;index" json:"requestor_email"`
+	TargetEmail    string              `gorm:"type:varchar(255);not null;index" json:"target_email"`
+	Before         []RelationshipState `gorm:"type:jsonb;serializer:json" json:"before"`
+	After          []RelationshipState `gorm:"type:jsonb;serializer:json" json:"after"`
+	Actor          string              `gorm:"type:varchar(255)" json:"actor"`
+	IP             string              `gorm:"type:varchar(64)" json:"ip"`
+	UserAgent      string              `gorm:"type:text" json:"user_agent"`
+	CreatedAt      time.Time           `gorm:"index" json:"created_at"`
+}
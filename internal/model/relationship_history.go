@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// RelationshipHistory is the interval of time a relationship was valid, ValidTo is nil while the relationship still exists
+type RelationshipHistory struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	TenantID       string     `gorm:"type:varchar(64);not null;default:'default';index:idx_relationship_history_requestor;index:idx_relationship_history_target" json:"tenant_id"`
+	RequestorEmail string     `gorm:"type:varchar(255);not null;index:idx_relationship_history_requestor" json:"requestor_email"`
+	TargetEmail    string     `gorm:"type:varchar(255);not null;index:idx_relationship_history_target" json:"target_email"`
+	Type           string     `gorm:"type:text;check:type IN ('FRIEND', 'BLOCK', 'SUBSCRIBER')" json:"type"`
+	ValidFrom      time.Time  `gorm:"not null" json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// StatusUpdate is a text posted by a user, FanOut tells how it reaches the feeds of the friends and subscribers of the author
+type StatusUpdate struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    string    `gorm:"type:varchar(64);not null;default:'default';index:idx_status_update_author" json:"tenant_id"`
+	AuthorEmail string    `gorm:"type:varchar(255);not null;index:idx_status_update_author" json:"author_email"`
+	Text        string    `gorm:"type:text;not null" json:"text"`
+	FanOut      string    `gorm:"type:text;check:fan_out IN ('WRITE', 'READ')" json:"fan_out"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FeedEntry is a status update written to the feed of one recipient when it is posted
+type FeedEntry struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	TenantID       string       `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_feed_entry_owner" json:"tenant_id"`
+	OwnerEmail     string       `gorm:"type:varchar(255);not null;uniqueIndex:idx_feed_entry_owner" json:"owner_email"`
+	StatusUpdateID uint         `gorm:"not null;uniqueIndex:idx_feed_entry_owner" json:"status_update_id"`
+	StatusUpdate   StatusUpdate `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
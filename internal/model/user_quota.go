@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// UserQuota override the default quotas of one email set by an admin, a nil cap use the default and zero means no cap
+type UserQuota struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	TenantID         string    `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_user_quota_tenant_email" json:"tenant_id"`
+	Email            string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_quota_tenant_email" json:"email"`
+	MaxFriends       *int64    `json:"max_friends"`
+	MaxSubscriptions *int64    `json:"max_subscriptions"`
+	MaxBlocks        *int64    `json:"max_blocks"`
+	MaxNewPerDay     *int64    `json:"max_new_per_day"`
+	UpdatedBy        string    `gorm:"type:varchar(255)" json:"updated_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
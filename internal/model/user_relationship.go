@@ -6,6 +6,7 @@ import (
 
 type UserRelationship struct {
 	ID             uint      `gorm:"primaryKey" json:"id"`
+	TenantID       string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
 	RequestorEmail string    `gorm:"type:varchar(255);not null" json:"requestor_email"`
 	TargetEmail    string    `gorm:"type:varchar(255);not null" json:"target_email"`
 	Type           string    `gorm:"type:text;check:type IN ('FRIEND', 'BLOCK', 'SUBSCRIBER')" json:"type"`
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+)
+
+// Webhook is an endpoint of a partner app notified of the domain events of its tenant, an empty EventTypes means every event
+type Webhook struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TenantID   string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	URL        string    `gorm:"type:text;not null" json:"url"`
+	EventTypes []string  `gorm:"type:jsonb;serializer:json" json:"event_types"`
+	Secret     string    `gorm:"type:varchar(128);not null" json:"-"`
+	CreatedBy  string    `gorm:"type:varchar(255)" json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Subscribes report whether the events of the type are delivered to the webhook
+func (w Webhook) Subscribes(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event waiting to be delivered to one webhook, the row is kept once delivered
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	TenantID      string     `gorm:"type:varchar(64);not null;default:'default'" json:"tenant_id"`
+	WebhookID     uint       `gorm:"not null;uniqueIndex:idx_webhook_delivery_event" json:"webhook_id"`
+	Webhook       Webhook    `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	EventID       uint       `gorm:"not null;uniqueIndex:idx_webhook_delivery_event" json:"event_id"`
+	EventType     string     `gorm:"type:varchar(64);not null" json:"event_type"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	DeliveredAt   *time.Time `gorm:"index" json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// WebhookDeadLetter is a delivery that failed every attempt, it is delivered again when replayed
+type WebhookDeadLetter struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TenantID  string    `gorm:"type:varchar(64);not null;default:'default'" json:"tenant_id"`
+	WebhookID uint      `gorm:"not null;index" json:"webhook_id"`
+	Webhook   Webhook   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	EventID   uint      `gorm:"not null" json:"event_id"`
+	EventType string    `gorm:"type:varchar(64);not null" json:"event_type"`
+	Payload   string    `gorm:"type:text;not null" json:"payload"`
+	Attempts  int       `gorm:"not null" json:"attempts"`
+	LastError string    `gorm:"type:text" json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
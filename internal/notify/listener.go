@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/repository"
+)
+
+const (
+	//healthyAfter is how long a connection must listen before a failure starts the retry delay over
+	healthyAfter = time.Minute
+	//lateCommitWindow is how long a skipped id is read again after reconnecting before it is given up as rolled back
+	lateCommitWindow = 5 * time.Minute
+	//maxGaps is the most skipped ids remembered below an event, the closest ones
+	maxGaps = 1000
+)
+
+// Conn is a connection listening to the change notifications
+type Conn interface {
+	WaitForNotification(ctx context.Context) (string, error)
+	Close() error
+}
+
+// Dialer open a connection and start listening, the notifications sent before it returns are not received
+type Dialer func(ctx context.Context) (Conn, error)
+
+// Listener receive the relationship changes made by every instance and publish them to the sink of this process.
+// The notifications sent while it is disconnected are lost, they are read again from the outbox when it reconnects.
+type Listener struct {
+	dial      Dialer
+	repo      repository.OutboxEventRepository
+	sink      outbox.Sink
+	batchSize int
+
+	//the events published to the sink, set on the first connection
+	cursor  cursor
+	started bool
+}
+
+func NewListener(dial Dialer, repo repository.OutboxEventRepository, sink outbox.Sink, batchSize int) *Listener {
+	return &Listener{dial: dial, repo: repo, sink: sink, batchSize: batchSize, cursor: cursor{gaps: map[uint]time.Time{}}}
+}
+
+// Listen publish the notifications until the connection fails or ctx is done, it always returns an error
+func (l *Listener) Listen(ctx context.Context) error {
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("LISTEN_FAIL: %w", err)
+	}
+	defer conn.Close()
+
+	//Listening starts before the missed events are read so none is lost in between, the copies are skipped
+	if err := l.recover(ctx); err != nil {
+		return err
+	}
+
+	for {
+		payload, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("WAIT_FOR_NOTIFICATION_FAIL: %w", err)
+		}
+
+		var message outbox.Message
+		if err := json.Unmarshal([]byte(payload), &message); err != nil {
+			//The event is read from the outbox after reconnecting
+			return fmt.Errorf("DECODE_NOTIFICATION_FAIL: %w", err)
+		}
+		if l.cursor.published(message.ID) {
+			continue
+		}
+		if err := l.publish(ctx, message); err != nil {
+			return err
+		}
+	}
+}
+
+// recover publish the events written while the listener was disconnected, the first connection only start from the latest event.
+// The ids are not committed in order, so the ids skipped before the latest event are read again in case they committed since.
+func (l *Listener) recover(ctx context.Context) error {
+	if !l.started {
+		lastID, err := l.repo.LastID()
+		if err != nil {
+			return fmt.Errorf("GET_LAST_OUTBOX_EVENT_FAIL: %w", err)
+		}
+		l.cursor.lastID, l.started = lastID, true
+		return nil
+	}
+
+	afterID := l.cursor.after(time.Now())
+	for {
+		events, err := l.repo.ListAfter(afterID, l.batchSize)
+		if err != nil {
+			return fmt.Errorf("LIST_MISSED_OUTBOX_EVENTS_FAIL: %w", err)
+		}
+
+		for _, event := range events {
+			afterID = event.ID
+			if l.cursor.published(event.ID) {
+				continue
+			}
+			if err := l.publish(ctx, outbox.NewMessage(event)); err != nil {
+				return err
+			}
+		}
+		if len(events) < l.batchSize {
+			return nil
+		}
+	}
+}
+
+func (l *Listener) publish(ctx context.Context, message outbox.Message) error {
+	if err := l.sink.Publish(ctx, message); err != nil {
+		return fmt.Errorf("PUBLISH_CHANGE_FAIL: %w", err)
+	}
+	l.cursor.add(message.ID, time.Now())
+	return nil
+}
+
+// cursor remember the latest published id and the lower ids not published yet. An id is skipped when its transaction is
+// rolled back or when it commits after a later id, the gaps are kept for lateCommitWindow in case they are published late.
+type cursor struct {
+	lastID uint
+	gaps   map[uint]time.Time
+}
+
+// add record a published id, the ids skipped since the latest one become gaps
+func (c *cursor) add(id uint, now time.Time) {
+	if id <= c.lastID {
+		delete(c.gaps, id)
+		return
+	}
+	from := c.lastID + 1
+	if id-from > maxGaps {
+		from = id - maxGaps
+	}
+	for gap := from; gap < id; gap++ {
+		c.gaps[gap] = now
+	}
+	c.lastID = id
+}
+
+// published report whether the id was already published
+func (c *cursor) published(id uint) bool {
+	if id > c.lastID {
+		return false
+	}
+	_, gap := c.gaps[id]
+	return !gap
+}
+
+// after return the id to read the missed events after, just before the oldest gap still in the window. The older gaps are dropped.
+func (c *cursor) after(now time.Time) uint {
+	afterID := c.lastID
+	for id, at := range c.gaps {
+		if now.Sub(at) > lateCommitWindow {
+			delete(c.gaps, id)
+			continue
+		}
+		afterID = min(afterID, id-1)
+	}
+	return afterID
+}
+
+// Run listen until the process exits, a lost connection is opened again after retry, doubled after every failure up to a minute
+func (l *Listener) Run(retry time.Duration, logger echo.Logger) {
+	failures := 0
+	for {
+		started := time.Now()
+		err := l.Listen(context.Background())
+		logger.Error(fmt.Errorf("LISTEN_CHANGES_FAIL: %w", err))
+
+		if time.Since(started) > healthyAfter {
+			failures = 0
+		}
+		failures++
+		time.Sleep(outbox.Backoff(failures, retry, time.Minute))
+	}
+}
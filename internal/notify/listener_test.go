@@ -0,0 +1,160 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/notify"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+var errConnectionLost = errors.New("CONNECTION_LOST")
+
+// fakeConn return the payloads in order then lose the connection
+type fakeConn struct {
+	payloads []string
+	closed   bool
+}
+
+func (c *fakeConn) WaitForNotification(ctx context.Context) (string, error) {
+	if len(c.payloads) == 0 {
+		return "", errConnectionLost
+	}
+	payload := c.payloads[0]
+	c.payloads = c.payloads[1:]
+	return payload, nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+// dialer return the connections in order, one per call
+func dialer(conns ...*fakeConn) notify.Dialer {
+	return func(ctx context.Context) (notify.Conn, error) {
+		if len(conns) == 0 {
+			return nil, errConnectionLost
+		}
+		conn := conns[0]
+		conns = conns[1:]
+		return conn, nil
+	}
+}
+
+func payload(id uint) string {
+	return fmt.Sprintf(`{"id":%d,"tenant":"tenant-b","type":"Blocked","requestor":"alice@example.com","target":"bob@example.com"}`, id)
+}
+
+func received(c chan outbox.Message) []uint {
+	var ids []uint
+	for {
+		select {
+		case m := <-c:
+			ids = append(ids, m.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestListener_Listen(t *testing.T) {
+	first := &fakeConn{payloads: []string{payload(41), payload(42)}}
+	//43 and 44 were written while disconnected, 44 is also notified after reconnecting
+	second := &fakeConn{payloads: []string{payload(44), payload(45)}}
+	repo := new(controller.MockOutboxEventRepository)
+	repo.On("LastID").Return(uint(40), nil).Once()
+	repo.On("ListAfter", uint(42), 2).Return([]model.OutboxEvent{
+		{ID: 43, TenantID: "tenant-b", Type: constant.DOMAIN_EVENT_SUBSCRIBED},
+		{ID: 44, TenantID: "tenant-b", Type: constant.DOMAIN_EVENT_BLOCKED},
+	}, nil).Once()
+	repo.On("ListAfter", uint(44), 2).Return([]model.OutboxEvent{}, nil).Once()
+	messages := make(chan outbox.Message, 10)
+
+	listener := notify.NewListener(dialer(first, second), repo, outbox.ChannelSink{C: messages}, 2)
+	require.ErrorIs(t, listener.Listen(context.Background()), errConnectionLost)
+	require.True(t, first.closed)
+	require.Equal(t, []uint{41, 42}, received(messages))
+
+	require.ErrorIs(t, listener.Listen(context.Background()), errConnectionLost)
+	require.True(t, second.closed)
+	require.Equal(t, []uint{43, 44, 45}, received(messages))
+
+	require.EqualError(t, listener.Listen(context.Background()), "LISTEN_FAIL: CONNECTION_LOST")
+	repo.AssertExpectations(t)
+}
+
+func TestListener_ListenLateCommit(t *testing.T) {
+	//42 was written before 43 but committed after it, while the listener was disconnected
+	first := &fakeConn{payloads: []string{payload(41), payload(43)}}
+	second := &fakeConn{payloads: []string{payload(44)}}
+	third := &fakeConn{}
+	repo := new(controller.MockOutboxEventRepository)
+	repo.On("LastID").Return(uint(40), nil).Once()
+	repo.On("ListAfter", uint(41), 100).Return([]model.OutboxEvent{
+		{ID: 42, TenantID: "tenant-b", Type: constant.DOMAIN_EVENT_SUBSCRIBED},
+		{ID: 43, TenantID: "tenant-b", Type: constant.DOMAIN_EVENT_BLOCKED},
+	}, nil).Once()
+	repo.On("ListAfter", uint(44), 100).Return([]model.OutboxEvent{}, nil).Once()
+	messages := make(chan outbox.Message, 10)
+
+	listener := notify.NewListener(dialer(first, second, third), repo, outbox.ChannelSink{C: messages}, 100)
+	require.ErrorIs(t, listener.Listen(context.Background()), errConnectionLost)
+	require.Equal(t, []uint{41, 43}, received(messages))
+
+	require.ErrorIs(t, listener.Listen(context.Background()), errConnectionLost)
+	require.Equal(t, []uint{42, 44}, received(messages))
+
+	//Nothing is missing below 44 anymore
+	require.ErrorIs(t, listener.Listen(context.Background()), errConnectionLost)
+	require.Empty(t, received(messages))
+	repo.AssertExpectations(t)
+}
+
+func TestListener_ListenFail(t *testing.T) {
+	testCases := map[string]struct {
+		payloads    []string
+		lastIDErr   error
+		sinkErr     error
+		expectedErr string
+	}{
+		"LastIDFail": {
+			lastIDErr:   errors.New("DATABASE_ERROR"),
+			expectedErr: "GET_LAST_OUTBOX_EVENT_FAIL: DATABASE_ERROR",
+		},
+		"InvalidPayload": {
+			payloads:    []string{"not json"},
+			expectedErr: "DECODE_NOTIFICATION_FAIL: invalid character 'o' in literal null (expecting 'u')",
+		},
+		"SinkFail": {
+			payloads:    []string{payload(41)},
+			sinkErr:     errors.New("SINK_DOWN"),
+			expectedErr: "PUBLISH_CHANGE_FAIL: SINK_DOWN",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repo := new(controller.MockOutboxEventRepository)
+			repo.On("LastID").Return(uint(40), tc.lastIDErr).Once()
+
+			listener := notify.NewListener(dialer(&fakeConn{payloads: tc.payloads}), repo, failingSink{err: tc.sinkErr}, 100)
+			require.EqualError(t, listener.Listen(context.Background()), tc.expectedErr)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+type failingSink struct {
+	err error
+}
+
+func (s failingSink) Publish(ctx context.Context, message outbox.Message) error {
+	return s.err
+}
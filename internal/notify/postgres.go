@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// postgresConn hold a connection of the pool for as long as it listens
+type postgresConn struct {
+	conn *sql.Conn
+}
+
+// PostgresDialer listen to the channel on a dedicated connection of the pool of db
+func PostgresDialer(db *gorm.DB, channel string) Dialer {
+	return func(ctx context.Context) (Conn, error) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.ExecContext(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return postgresConn{conn: conn}, nil
+	}
+}
+
+func (c postgresConn) WaitForNotification(ctx context.Context) (string, error) {
+	var payload string
+	err := c.conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("UNSUPPORTED_DRIVER_CONN: %T", driverConn)
+		}
+
+		notification, err := stdlibConn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		payload = notification.Payload
+		return nil
+	})
+	return payload, err
+}
+
+// Close return the connection to the pool, it stops listening first so the connection can be reused
+func (c postgresConn) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = c.conn.ExecContext(ctx, "UNLISTEN *")
+	return c.conn.Close()
+}
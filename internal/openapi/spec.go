@@ -0,0 +1,244 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+)
+
+const (
+	MIMEApplicationJSON        = "application/json"
+	MIMEApplicationProblemJSON = "application/problem+json"
+)
+
+// operation describe one route of the api, request and response are zero values of the types in internal/handler/api
+type operation struct {
+	method   string
+	path     string
+	summary  string
+	query    []string
+	request  interface{}
+	response interface{}
+	status   int
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+var operations = []operation{
+	//v1, deprecated
+	{method: http.MethodPost, path: "/api/user/relationship/friend", summary: "Create friend connection", request: api.AddFriendRequest{}, response: api.CommonResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/subscriber", summary: "Subscribe to updates", request: api.AddSubscriberRequest{}, response: api.CommonResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/block", summary: "Block updates", request: api.AddBlockRequest{}, response: api.CommonResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/list", summary: "Retrieve friends by email", request: api.ListFriendRequest{}, response: api.ListFriendResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/common-friends", summary: "Get common friends", request: api.ListCommonFriendsRequest{}, response: api.ListCommonFriendsResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/recipients", summary: "Get recipients of an update", request: api.GetListEmailCanReceiveUpdateRequest{}, response: api.GetListEmailCanReceiveUpdateResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/subscribers", summary: "List subscribers", request: api.ListSubscribersRequest{}, response: api.ListSubscribersResponse{}},
+	{method: http.MethodPost, path: "/api/user/relationship/blocks", summary: "List blocks", request: api.ListBlocksRequest{}, response: api.ListBlocksResponse{}},
+
+	//v2
+	{method: http.MethodGet, path: "/api/v2/users/{email}/friends", summary: "List friends", query: []string{"as_of"}, response: api.ListFriendResponse{}},
+	{method: http.MethodPut, path: "/api/v2/users/{email}/friends/{other}", summary: "Make friend connection", response: api.CommonResponse{}},
+	{method: http.MethodDelete, path: "/api/v2/users/{email}/friends/{other}", summary: "Remove friend connection", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/common-friends/{other}", summary: "List common friends", response: api.ListCommonFriendsResponse{}},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/subscribers", summary: "List subscribers", query: []string{"limit", "offset", "as_of"}, response: api.ListSubscribersResponse{}},
+	{method: http.MethodPut, path: "/api/v2/users/{email}/subscriptions/{other}", summary: "Subscribe to updates", response: api.CommonResponse{}},
+	{method: http.MethodDelete, path: "/api/v2/users/{email}/subscriptions/{other}", summary: "Unsubscribe", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/blocks", summary: "List blocks", query: []string{"limit", "offset", "as_of"}, response: api.ListBlocksResponse{}},
+	{method: http.MethodPut, path: "/api/v2/users/{email}/blocks/{other}", summary: "Block updates", response: api.CommonResponse{}},
+	{method: http.MethodDelete, path: "/api/v2/users/{email}/blocks/{other}", summary: "Remove block", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/recipients", summary: "Get recipients of an update, now and after their quiet hours", query: []string{"text"}, response: api.GetListEmailCanReceiveUpdateResponse{}},
+
+	//status updates
+	{method: http.MethodPost, path: "/api/v2/users/{email}/updates", summary: "Post a status update", request: api.PostStatusUpdateRequest{}, response: api.PostStatusUpdateResponse{}, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/feed", summary: "List the status updates a user received, newest first", query: []string{"limit", "before"}, response: api.ListFeedResponse{}},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/notification-preferences", summary: "Get how and when a user is emailed about status updates", response: api.NotificationPreferenceResponse{}},
+	{method: http.MethodPut, path: "/api/v2/users/{email}/notification-preferences", summary: "Set the digest frequency, quiet hours and mentions only preference of a user", request: api.NotificationPreferenceRequest{}, response: api.NotificationPreferenceResponse{}},
+
+	//event stream, the response is a text/event-stream or a websocket and not a json body
+	{method: http.MethodGet, path: "/api/v2/users/{email}/events", summary: "Stream the relationship events of a user as server sent events", query: []string{"access_token", "last_event_id"}, status: http.StatusOK},
+	{method: http.MethodGet, path: "/api/v2/users/{email}/events/ws", summary: "Stream the relationship events of a user over a websocket", query: []string{"access_token", "last_event_id"}, status: http.StatusSwitchingProtocols},
+
+	//admin, require the admin scope
+	{method: http.MethodGet, path: "/admin/relationships", summary: "List relationships", query: []string{"email", "type", "from", "to", "limit", "offset"}, response: api.ListRelationshipsResponse{}},
+	{method: http.MethodDelete, path: "/admin/relationships/{id}", summary: "Force remove a relationship", status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/admin/blocks/{email}/{other}", summary: "Force remove the blocks between two emails", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/admin/quotas/{email}", summary: "Get the quota of an email", response: api.QuotaResponse{}},
+	{method: http.MethodPut, path: "/admin/quotas/{email}", summary: "Override the quota of an email", request: api.QuotaOverride{}, response: api.QuotaResponse{}},
+	{method: http.MethodDelete, path: "/admin/quotas/{email}", summary: "Remove the quota override of an email", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/admin/relationship-events", summary: "List the history of the relationships", query: []string{"email", "action", "from", "to", "limit", "offset"}, response: api.ListRelationshipEventsResponse{}},
+	{method: http.MethodPost, path: "/admin/webhooks", summary: "Register a webhook", request: api.CreateWebhookRequest{}, response: api.CreateWebhookResponse{}, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/admin/webhooks", summary: "List the webhooks", response: api.ListWebhooksResponse{}},
+	{method: http.MethodDelete, path: "/admin/webhooks/{id}", summary: "Delete a webhook", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/admin/webhooks/{id}/dead-letters", summary: "List the deliveries of a webhook that failed every attempt", query: []string{"limit", "offset"}, response: api.ListWebhookDeadLettersResponse{}},
+	{method: http.MethodPost, path: "/admin/webhooks/{id}/dead-letters/{letter}/replay", summary: "Queue a dead letter again", status: http.StatusAccepted},
+}
+
+// queryParams is the schema of every supported query parameter
+var queryParams = map[string]*openapi3.Schema{
+	"limit":  openapi3.NewIntegerSchema(),
+	"offset": openapi3.NewIntegerSchema(),
+	"text":   openapi3.NewStringSchema(),
+	"email":  openapi3.NewStringSchema(),
+	"type":   openapi3.NewStringSchema().WithEnum("FRIEND", "SUBSCRIBER", "BLOCK"),
+	"action": openapi3.NewStringSchema().WithEnum("CREATE", "DELETE", "BLOCK"),
+	"from":   openapi3.NewDateTimeSchema(),
+	"to":     openapi3.NewDateTimeSchema(),
+	"as_of":  openapi3.NewDateTimeSchema(),
+	"before": openapi3.NewIntegerSchema().WithMin(0),
+
+	"access_token":  openapi3.NewStringSchema(),
+	"last_event_id": openapi3.NewIntegerSchema().WithMin(0),
+}
+
+// NewSpec build the OpenAPI 3 document, schemas are generated from the request and response types of internal/handler/api
+func NewSpec() (*openapi3.T, error) {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   "Friends Management API",
+			Version: "2.0.0",
+		},
+		Servers: openapi3.Servers{{URL: "/"}},
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"bearerAuth": &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()},
+				"apiKey": &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("apiKey").WithIn("header").WithName("X-API-Key")},
+			},
+		},
+		//Every operation accept a JWT or a static API key
+		Security: openapi3.SecurityRequirements{
+			openapi3.NewSecurityRequirement().Authenticate("bearerAuth"),
+			openapi3.NewSecurityRequirement().Authenticate("apiKey"),
+		},
+	}
+
+	s := &schemaBuilder{schemas: doc.Components.Schemas}
+	errorResponse, err := s.ref(api.ErrorResponse{})
+	if err != nil {
+		return nil, err
+	}
+	problemResponse, err := s.ref(api.ProblemResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range operations {
+		o := openapi3.NewOperation()
+		o.Summary = op.summary
+		o.OperationID = operationID(op)
+
+		for _, match := range pathParamPattern.FindAllStringSubmatch(op.path, -1) {
+			o.AddParameter(openapi3.NewPathParameter(match[1]).WithSchema(openapi3.NewStringSchema()))
+		}
+		for _, name := range op.query {
+			o.AddParameter(openapi3.NewQueryParameter(name).WithSchema(queryParams[name]))
+		}
+		o.AddParameter(openapi3.NewHeaderParameter("X-Tenant-ID").
+			WithDescription("Tenant of the request, credentials bound to a tenant can only send their own").
+			WithSchema(openapi3.NewStringSchema().WithPattern(`^[A-Za-z0-9_-]{1,64}$`)))
+
+		if op.request != nil {
+			schema, err := s.ref(op.request)
+			if err != nil {
+				return nil, err
+			}
+			o.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+				WithRequired(true).
+				WithContent(openapi3.NewContentWithJSONSchemaRef(schema))}
+		}
+
+		o.Responses = openapi3.NewResponses()
+		if op.response != nil {
+			schema, err := s.ref(op.response)
+			if err != nil {
+				return nil, err
+			}
+			//status is only set for the responses with a body that are not 200
+			status := http.StatusOK
+			if op.status != 0 {
+				status = op.status
+			}
+			o.AddResponse(status, openapi3.NewResponse().
+				WithDescription("Success").
+				WithContent(openapi3.NewContentWithJSONSchemaRef(schema)))
+		} else {
+			o.AddResponse(op.status, openapi3.NewResponse().WithDescription("Success"))
+		}
+		errorContent := openapi3.Content{
+			MIMEApplicationProblemJSON: openapi3.NewMediaType().WithSchemaRef(problemResponse),
+			MIMEApplicationJSON:        openapi3.NewMediaType().WithSchemaRef(errorResponse),
+		}
+		//The admin api is not rate limited
+		if !strings.HasPrefix(op.path, "/admin/") {
+			tooManyRequests := openapi3.NewResponse().
+				WithDescription("Rate limit exceeded, retry after the Retry-After seconds").
+				WithContent(errorContent)
+			tooManyRequests.Headers = rateLimitHeaders()
+			o.AddResponse(http.StatusTooManyRequests, tooManyRequests)
+		}
+		o.Responses.Delete("default")
+		o.Responses.Set("default", &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription("Error, application/problem+json unless the client only accepts application/json").
+			WithContent(errorContent)})
+
+		doc.AddOperation(op.path, op.method, o)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("INVALID_OPENAPI_SPEC: %w", err)
+	}
+	return doc, nil
+}
+
+// rateLimitHeaders describe the headers sent when a request is rejected by the rate limiter
+func rateLimitHeaders() openapi3.Headers {
+	header := func(description string, schema *openapi3.Schema) *openapi3.HeaderRef {
+		return &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{Description: description, Schema: schema.NewRef()}}}
+	}
+	return openapi3.Headers{
+		"RateLimit-Limit":     header("Requests allowed by the budget of the route", openapi3.NewIntegerSchema()),
+		"RateLimit-Remaining": header("Requests left in the bucket", openapi3.NewIntegerSchema()),
+		"RateLimit-Reset":     header("Seconds until the bucket is full again", openapi3.NewIntegerSchema()),
+		"RateLimit-Policy":    header("Budget of the route as limit;w=window seconds", openapi3.NewStringSchema()),
+		"Retry-After":         header("Seconds until the next request is allowed", openapi3.NewIntegerSchema()),
+	}
+}
+
+// schemaBuilder generate a component schema once per type and return a reference to it
+type schemaBuilder struct {
+	schemas openapi3.Schemas
+}
+
+func (s *schemaBuilder) ref(value interface{}) (*openapi3.SchemaRef, error) {
+	name := reflect.TypeOf(value).Name()
+	if _, ok := s.schemas[name]; !ok {
+		schema, err := openapi3gen.NewSchemaRefForValue(value, s.schemas, openapi3gen.SchemaCustomizer(nullableSlices))
+		if err != nil {
+			return nil, fmt.Errorf("GENERATE_SCHEMA_FAIL: %s: %w", name, err)
+		}
+		s.schemas[name] = schema
+	}
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, s.schemas[name].Value), nil
+}
+
+// nullableSlices mark slices as nullable because a nil slice is encoded as null
+func nullableSlices(name string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		schema.Nullable = true
+	}
+	return nil
+}
+
+func operationID(op operation) string {
+	return op.method + " " + op.path
+}
@@ -0,0 +1,54 @@
+package openapi_test
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/handler"
+	"github.com/quanluong166/friends_management/internal/openapi"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/quanluong166/friends_management/internal/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSpec(t *testing.T) {
+	doc, err := openapi.NewSpec()
+	require.NoError(t, err)
+
+	controller := &handler.MockUserRelationshipController{}
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	e := echo.New()
+	routes.RegisterUserRelationshipRoutes(e, handler.NewUserRelationshipHandler(controller), noop, noop)
+	routes.RegisterUserRelationshipV2Routes(e, handler.NewUserRelationshipV2Handler(controller), noop)
+	routes.RegisterAdminRoutes(e, handler.NewAdminHandler(new(handler.MockAdminController)), noop)
+	routes.RegisterWebhookRoutes(e, handler.NewWebhookHandler(new(handler.MockWebhookController)), noop)
+	routes.RegisterStreamRoutes(e, handler.NewStreamHandler(controller, stream.NewHub(1)), noop)
+	routes.RegisterStatusUpdateRoutes(e, handler.NewStatusUpdateHandler(new(handler.MockStatusUpdateController)), noop, noop)
+	routes.RegisterNotificationPreferenceRoutes(e, handler.NewNotificationPreferenceHandler(new(handler.MockNotificationPreferenceController)), noop)
+
+	//Every route of the api must be documented
+	param := regexp.MustCompile(`:(\w+)`)
+	for _, route := range e.Routes() {
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		path := param.ReplaceAllString(route.Path, "{$1}")
+		item := doc.Paths.Find(path)
+		if !assert.NotNil(t, item, path) {
+			continue
+		}
+		op := item.GetOperation(route.Method)
+		if assert.NotNil(t, op, route.Method+" "+path) {
+			//Only the admin api is not rate limited
+			limited := !strings.HasPrefix(path, "/admin/")
+			assert.Equal(t, limited, op.Responses.Status(http.StatusTooManyRequests) != nil, route.Method+" "+path)
+		}
+	}
+
+	assert.NotNil(t, doc.Components.Schemas["ProblemResponse"])
+	assert.NotNil(t, doc.Components.Schemas["ErrorResponse"])
+}
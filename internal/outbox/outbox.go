@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+)
+
+// Message is a domain event as delivered to the sinks, ID is unique so consumers can drop the duplicates of a redelivery
+type Message struct {
+	ID         uint      `json:"id"`
+	Tenant     string    `json:"tenant"`
+	Type       string    `json:"type"`
+	Requestor  string    `json:"requestor"`
+	Target     string    `json:"target"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Sink deliver the messages to downstream services, a message is published again until Publish returns nil
+type Sink interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+// NewMessage convert an outbox row to the message delivered to the sinks
+func NewMessage(event model.OutboxEvent) Message {
+	return Message{
+		ID:         event.ID,
+		Tenant:     event.TenantID,
+		Type:       event.Type,
+		Requestor:  event.RequestorEmail,
+		Target:     event.TargetEmail,
+		OccurredAt: event.CreatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+	//claimLease is how long the claimed events are kept from the other instances, the events not published before it ends
+	//are left to the next claim
+	claimLease = 5 * time.Minute
+)
+
+// Relay publish the pending outbox events to the sink, every event is published at least once and the events of a user in order.
+// An event that failed maxAttempts times is dead lettered and the later events of the user are published without it.
+type Relay struct {
+	db          *gorm.DB
+	repo        repository.OutboxEventRepository
+	sink        Sink
+	batchSize   int
+	maxAttempts int
+	now         func() time.Time
+}
+
+func NewRelay(db *gorm.DB, repo repository.OutboxEventRepository, sink Sink, batchSize, maxAttempts int) *Relay {
+	return &Relay{db: db, repo: repo, sink: sink, batchSize: batchSize, maxAttempts: maxAttempts, now: time.Now}
+}
+
+// RunOnce publish one batch of pending events and return how many were published.
+// The batch is claimed in a short transaction and published outside of it, so instances publish the events of different users
+// at the same time and each event is marked on its own: a crash after publishing only redelivers the events not marked yet.
+// Once an event of a user fails the later events of the same user are held back and released for the next batch.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	claimedAt := r.now()
+	var events []model.OutboxEvent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		events, err = r.repo.WithTx(tx).ClaimPending(claimedAt, claimLease, r.batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("CLAIM_OUTBOX_EVENTS_FAIL: %w", err)
+	}
+
+	published := 0
+	var errs []error
+	var released []uint
+	heldBack := make(map[string]bool)
+	for _, event := range events {
+		key := event.TenantID + "/" + event.RequestorEmail
+		if heldBack[key] {
+			released = append(released, event.ID)
+			continue
+		}
+		//Another instance can claim the events again once the lease ended
+		if r.now().Sub(claimedAt) >= claimLease {
+			break
+		}
+
+		ok, done, err := r.publish(ctx, event)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			published++
+		}
+		//A later event must not be published before this one, including when this one could not be marked
+		if !done || err != nil {
+			heldBack[key] = true
+		}
+	}
+
+	if len(released) > 0 {
+		if err := r.repo.ReleaseClaim(released); err != nil {
+			errs = append(errs, fmt.Errorf("RELEASE_OUTBOX_EVENTS_FAIL: %w", err))
+		}
+	}
+	return published, errors.Join(errs...)
+}
+
+// publish send one claimed event to the sink and record the outcome, it reports whether the event was published and whether
+// it is done with, published or dead lettered, so the later events of the user can follow
+func (r *Relay) publish(ctx context.Context, event model.OutboxEvent) (bool, bool, error) {
+	publishErr := r.sink.Publish(ctx, NewMessage(event))
+	now := r.now()
+	if publishErr == nil {
+		if err := r.repo.MarkPublished(event.ID, now); err != nil {
+			return true, true, fmt.Errorf("MARK_OUTBOX_EVENT_PUBLISHED_FAIL: %w", err)
+		}
+		return true, true, nil
+	}
+
+	attempts := event.Attempts + 1
+	if attempts >= r.maxAttempts {
+		if err := r.repo.MarkDeadLettered(event.ID, attempts, publishErr.Error(), now); err != nil {
+			return false, true, fmt.Errorf("MARK_OUTBOX_EVENT_DEAD_LETTERED_FAIL: %w", err)
+		}
+		return false, true, nil
+	}
+
+	if err := r.repo.MarkFailed(event.ID, attempts, now.Add(Backoff(attempts, retryBaseDelay, retryMaxDelay)), publishErr.Error()); err != nil {
+		return false, false, fmt.Errorf("MARK_OUTBOX_EVENT_FAILED_FAIL: %w", err)
+	}
+	return false, false, nil
+}
+
+// Run publish the pending events every interval until the process exits
+func (r *Relay) Run(interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := r.RunOnce(context.Background()); err != nil {
+			logger.Error(fmt.Errorf("RELAY_OUTBOX_FAIL: %w", err))
+		}
+	}
+}
+
+// Backoff is the delay before the next attempt, base after the first failure and doubled after every other one up to max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
@@ -0,0 +1,175 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordSink remember the published messages and fail the ones of the emails in fail
+type recordSink struct {
+	fail      map[string]bool
+	published []uint
+}
+
+func (s *recordSink) Publish(ctx context.Context, message outbox.Message) error {
+	if s.fail[message.Requestor] {
+		return errors.New("SINK_DOWN")
+	}
+	s.published = append(s.published, message.ID)
+	return nil
+}
+
+func outboxEvent(id uint, requestor string) model.OutboxEvent {
+	return model.OutboxEvent{
+		ID:             id,
+		TenantID:       constant.DEFAULT_TENANT_ID,
+		Type:           constant.DOMAIN_EVENT_SUBSCRIBED,
+		RequestorEmail: requestor,
+		TargetEmail:    "target@example.com",
+		CreatedAt:      time.Now(),
+	}
+}
+
+func TestRelay_RunOnce(t *testing.T) {
+	retried := outboxEvent(2, "bob@example.com")
+	retried.Attempts = 2
+	lastAttempt := outboxEvent(1, "bob@example.com")
+	lastAttempt.Attempts = 4
+
+	testCases := map[string]struct {
+		events       []model.OutboxEvent
+		claimErr     error
+		fail         map[string]bool
+		failed       []uint
+		deadLettered []uint
+		released     []uint
+		markErr      error
+		published    []uint
+		count        int
+		expectedErr  string
+	}{
+		"PublishInOrder": {
+			events:    []model.OutboxEvent{outboxEvent(1, "alice@example.com"), outboxEvent(2, "bob@example.com"), outboxEvent(3, "alice@example.com")},
+			published: []uint{1, 2, 3},
+			count:     3,
+		},
+		"FailureHoldsBackLaterEventsOfTheUser": {
+			events:    []model.OutboxEvent{outboxEvent(1, "alice@example.com"), retried, outboxEvent(3, "alice@example.com"), outboxEvent(4, "bob@example.com")},
+			fail:      map[string]bool{"bob@example.com": true},
+			failed:    []uint{2},
+			released:  []uint{4},
+			published: []uint{1, 3},
+			count:     2,
+		},
+		"LastAttemptIsDeadLetteredAndLaterEventsFollow": {
+			events:       []model.OutboxEvent{lastAttempt, outboxEvent(2, "bob@example.com")},
+			fail:         map[string]bool{"bob@example.com": true},
+			deadLettered: []uint{1},
+			failed:       []uint{2},
+		},
+		"NothingPending": {},
+		"ClaimFail": {
+			claimErr:    errors.New("DATABASE_ERROR"),
+			expectedErr: "CLAIM_OUTBOX_EVENTS_FAIL: DATABASE_ERROR",
+		},
+		"MarkPublishedFailHoldsBackLaterEventsOfTheUser": {
+			events:      []model.OutboxEvent{outboxEvent(1, "alice@example.com"), outboxEvent(2, "alice@example.com")},
+			markErr:     errors.New("DATABASE_ERROR"),
+			published:   []uint{1},
+			released:    []uint{2},
+			count:       1,
+			expectedErr: "MARK_OUTBOX_EVENT_PUBLISHED_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.claimErr == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+
+			repo := new(controller.MockOutboxEventRepository)
+			repo.On("ClaimPending", mock.AnythingOfType("time.Time"), 5*time.Minute, 100).Return(tc.events, tc.claimErr).Once()
+			for _, id := range tc.failed {
+				repo.On("MarkFailed", id, mock.AnythingOfType("int"), mock.AnythingOfType("time.Time"), "SINK_DOWN").Return(nil).Once()
+			}
+			for _, id := range tc.deadLettered {
+				repo.On("MarkDeadLettered", id, 5, "SINK_DOWN", mock.AnythingOfType("time.Time")).Return(nil).Once()
+			}
+			for _, id := range tc.published {
+				repo.On("MarkPublished", id, mock.AnythingOfType("time.Time")).Return(tc.markErr).Once()
+			}
+			if tc.released != nil {
+				repo.On("ReleaseClaim", tc.released).Return(nil).Once()
+			}
+
+			sink := &recordSink{fail: tc.fail}
+			count, err := outbox.NewRelay(db, repo, sink, 100, 5).RunOnce(context.Background())
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.count, count)
+			require.Equal(t, tc.published, sink.published)
+			repo.AssertExpectations(t)
+			require.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRelay_RunOnce_RetryBackoff(t *testing.T) {
+	db, sqlMock := setupMockTxDB(t)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	event := outboxEvent(1, "alice@example.com")
+	event.Attempts = 3
+	before := time.Now()
+
+	repo := new(controller.MockOutboxEventRepository)
+	repo.On("ClaimPending", mock.AnythingOfType("time.Time"), 5*time.Minute, 10).Return([]model.OutboxEvent{event}, nil).Once()
+	repo.On("MarkFailed", uint(1), 4, mock.MatchedBy(func(next time.Time) bool {
+		return !next.Before(before.Add(8*time.Second)) && next.Before(time.Now().Add(9*time.Second))
+	}), "SINK_DOWN").Return(nil).Once()
+
+	sink := &recordSink{fail: map[string]bool{"alice@example.com": true}}
+	count, err := outbox.NewRelay(db, repo, sink, 10, 20).RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, count)
+	repo.AssertExpectations(t)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func setupMockTxDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return db, mock
+}
@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LogSink write every message as one json line, it is the default when no downstream service is configured
+type LogSink struct {
+	Logger echo.Logger
+}
+
+func (s LogSink) Publish(ctx context.Context, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	s.Logger.Info(string(data))
+	return nil
+}
+
+// HTTPSink post every message as json to URL, any status other than 2xx is a failed delivery
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s HTTPSink) Publish(ctx context.Context, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Event-ID", strconv.FormatUint(uint64(message.ID), 10))
+	req.Header.Set("X-Event-Type", message.Type)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("OUTBOX_HTTP_SINK_STATUS: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ChannelSink send every message to C for consumers in the same process, it waits until the message is received
+type ChannelSink struct {
+	C chan<- Message
+}
+
+func (s ChannelSink) Publish(ctx context.Context, message Message) error {
+	select {
+	case s.C <- message:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sinks publish every message to each sink in order, when one fails the message is published again to all of them
+type Sinks []Sink
+
+func (s Sinks) Publish(ctx context.Context, message Message) error {
+	for _, sink := range s {
+		if err := sink.Publish(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
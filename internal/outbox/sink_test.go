@@ -0,0 +1,74 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSink_Publish(t *testing.T) {
+	message := outbox.Message{
+		ID:         7,
+		Tenant:     constant.DEFAULT_TENANT_ID,
+		Type:       constant.DOMAIN_EVENT_BLOCKED,
+		Requestor:  "alice@example.com",
+		Target:     "bob@example.com",
+		OccurredAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	testCases := map[string]struct {
+		status      int
+		expectedErr string
+	}{
+		"Delivered": {
+			status: http.StatusNoContent,
+		},
+		"ServerError": {
+			status:      http.StatusServiceUnavailable,
+			expectedErr: "OUTBOX_HTTP_SINK_STATUS: 503",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var received outbox.Message
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "7", r.Header.Get("X-Event-ID"))
+				require.Equal(t, constant.DOMAIN_EVENT_BLOCKED, r.Header.Get("X-Event-Type"))
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			sink := outbox.HTTPSink{URL: server.URL, Client: server.Client()}
+			err := sink.Publish(context.Background(), message)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, message, received)
+		})
+	}
+}
+
+func TestChannelSink_Publish(t *testing.T) {
+	messages := make(chan outbox.Message, 1)
+	sink := outbox.ChannelSink{C: messages}
+
+	require.NoError(t, sink.Publish(context.Background(), outbox.Message{ID: 1}))
+	require.Equal(t, uint(1), (<-messages).ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	unbuffered := outbox.ChannelSink{C: make(chan outbox.Message)}
+	require.ErrorIs(t, unbuffered.Publish(ctx, outbox.Message{ID: 2}), context.Canceled)
+}
@@ -0,0 +1,77 @@
+package ratelimit_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	budget := ratelimit.Budget{Name: "write", Limit: 1, Period: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := store.Take("write:user:default:alice@example.com", budget, 1, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take("write:user:default:alice@example.com", budget, 1, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	//Every key has its own bucket
+	result, err = store.Take("write:user:default:bob@example.com", budget, 1, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStore_SweepIdleBuckets(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	budget := ratelimit.Budget{Name: "write", Limit: 1, Period: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := store.Take("write:ip:10.0.0.1", budget, 1, now)
+	require.NoError(t, err)
+	_, err = store.Take("write:ip:10.0.0.2", budget, 1, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.Len())
+
+	//The first bucket is full again and forgotten, the second one still remember its spent token
+	_, err = store.Take("write:ip:10.0.0.3", budget, 1, now.Add(time.Minute+time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.Len())
+
+	result, err := store.Take("write:ip:10.0.0.2", budget, 1, now.Add(time.Minute+time.Second))
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryStore_Concurrent(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	budget := ratelimit.Budget{Name: "write", Limit: 50, Period: time.Hour}
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take("write:ip:10.0.0.1", budget, 1, now)
+			assert.NoError(t, err)
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, allowed)
+}
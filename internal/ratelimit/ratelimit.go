@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Budget allow Limit requests per Period, tokens are refilled evenly so a client can burst up to Limit
+type Budget struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// ParseBudget parse a budget written as "limit/period" like "30/1m"
+func ParseBudget(name, value string) (Budget, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Budget{}, fmt.Errorf("INVALID_RATE_LIMIT_BUDGET: %s must be limit/period", name)
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return Budget{}, fmt.Errorf("INVALID_RATE_LIMIT_BUDGET: %s limit must be a positive integer", name)
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Budget{}, fmt.Errorf("INVALID_RATE_LIMIT_BUDGET: %s period must be a positive duration", name)
+	}
+	return Budget{Name: name, Limit: limit, Period: period}, nil
+}
+
+// rate is the number of tokens refilled per second
+func (b Budget) rate() float64 {
+	return float64(b.Limit) / b.Period.Seconds()
+}
+
+// Result is the state of a bucket after a request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	//Reset is how long until the bucket is full again
+	Reset time.Duration
+	//RetryAfter is how long until the next request is allowed, zero when the request was allowed
+	RetryAfter time.Duration
+}
+
+// Store keep the buckets, Take consume cost tokens of the bucket of the key when it has them
+type Store interface {
+	Take(key string, budget Budget, cost int, now time.Time) (Result, error)
+}
+
+// Bucket is the persisted state of a token bucket
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket create a full bucket
+func NewBucket(budget Budget, now time.Time) Bucket {
+	return Bucket{Tokens: float64(budget.Limit), UpdatedAt: now}
+}
+
+// Take refill the bucket for the elapsed time then consume cost tokens if it has them, a cost above the limit costs the limit
+// so the request can still run once the bucket is full
+func (b *Bucket) Take(budget Budget, cost int, now time.Time) Result {
+	need := float64(min(max(cost, 1), budget.Limit))
+	//Instances of a shared store may have a slightly different clock, time never goes back for a bucket
+	if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(float64(budget.Limit), b.Tokens+elapsed.Seconds()*budget.rate())
+		b.UpdatedAt = now
+	}
+
+	result := Result{Limit: budget.Limit}
+	if b.Tokens >= need {
+		b.Tokens -= need
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((need - b.Tokens) / budget.rate())
+	}
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.Reset = seconds((float64(budget.Limit) - b.Tokens) / budget.rate())
+	return result
+}
+
+// Idle check the bucket would be full at now, so forgetting it does not change any result
+func (b *Bucket) Idle(budget Budget, now time.Time) bool {
+	return b.Tokens+now.Sub(b.UpdatedAt).Seconds()*budget.rate() >= float64(budget.Limit)
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
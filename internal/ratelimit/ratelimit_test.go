@@ -0,0 +1,130 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBudget(t *testing.T) {
+	tcs := map[string]struct {
+		value  string
+		budget ratelimit.Budget
+		hasErr bool
+	}{
+		"Valid": {
+			value:  "30/1m",
+			budget: ratelimit.Budget{Name: "write", Limit: 30, Period: time.Minute},
+		},
+		"MissingPeriod": {
+			value:  "30",
+			hasErr: true,
+		},
+		"InvalidLimit": {
+			value:  "many/1m",
+			hasErr: true,
+		},
+		"ZeroLimit": {
+			value:  "0/1m",
+			hasErr: true,
+		},
+		"InvalidPeriod": {
+			value:  "30/minute",
+			hasErr: true,
+		},
+		"NegativePeriod": {
+			value:  "30/-1m",
+			hasErr: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			budget, err := ratelimit.ParseBudget("write", tc.value)
+			if tc.hasErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.budget, budget)
+		})
+	}
+}
+
+func TestBucket_Take(t *testing.T) {
+	budget := ratelimit.Budget{Name: "write", Limit: 3, Period: 3 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := ratelimit.NewBucket(budget, now)
+
+	//A full bucket allow a burst of the whole limit
+	for remaining := 2; remaining >= 0; remaining-- {
+		result := bucket.Take(budget, 1, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+		assert.Zero(t, result.RetryAfter)
+	}
+
+	result := bucket.Take(budget, 1, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	//One token is refilled every second
+	result = bucket.Take(budget, 1, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	//The bucket never hold more than the limit
+	result = bucket.Take(budget, 1, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+	assert.Equal(t, time.Second, result.Reset)
+}
+
+func TestBucket_TakeClockSkew(t *testing.T) {
+	budget := ratelimit.Budget{Name: "write", Limit: 1, Period: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := ratelimit.NewBucket(budget, now)
+
+	assert.True(t, bucket.Take(budget, 1, now).Allowed)
+
+	//An instance with a late clock neither refill the bucket nor move its time back
+	assert.False(t, bucket.Take(budget, 1, now.Add(-time.Minute)).Allowed)
+	assert.Equal(t, now, bucket.UpdatedAt)
+}
+
+func TestBucket_Idle(t *testing.T) {
+	budget := ratelimit.Budget{Name: "write", Limit: 2, Period: 2 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := ratelimit.NewBucket(budget, now)
+	bucket.Take(budget, 1, now)
+
+	assert.False(t, bucket.Idle(budget, now))
+	assert.True(t, bucket.Idle(budget, now.Add(time.Second)))
+}
+
+func TestBucket_TakeCost(t *testing.T) {
+	budget := ratelimit.Budget{Name: "read", Limit: 10, Period: 10 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := ratelimit.NewBucket(budget, now)
+
+	result := bucket.Take(budget, 7, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 3, result.Remaining)
+
+	//The request waits until the bucket has the whole cost
+	result = bucket.Take(budget, 5, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 3, result.Remaining)
+	assert.Equal(t, 2*time.Second, result.RetryAfter)
+
+	//A cost above the limit costs the limit
+	result = bucket.Take(budget, 50, now.Add(time.Minute))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
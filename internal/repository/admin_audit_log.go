@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+)
+
+type adminAuditLogRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// AdminAuditLogRepository all the functions to record the actions of admins
+type AdminAuditLogRepository interface {
+	Create(log *model.AdminAuditLog) error
+	WithTx(tx *gorm.DB) AdminAuditLogRepository
+	WithTenant(tenantID string) AdminAuditLogRepository
+}
+
+func NewAdminAuditLogRepository(db *gorm.DB) AdminAuditLogRepository {
+	return &adminAuditLogRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// Create support insert one audit record in the tenant
+func (r *adminAuditLogRepository) Create(log *model.AdminAuditLog) error {
+	log.TenantID = r.tenantID
+	return r.db.Create(log).Error
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *adminAuditLogRepository) WithTx(tx *gorm.DB) AdminAuditLogRepository {
+	return &adminAuditLogRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that record the actions in the tenant
+func (r *adminAuditLogRepository) WithTenant(tenantID string) AdminAuditLogRepository {
+	return &adminAuditLogRepository{db: r.db, tenantID: tenantID}
+}
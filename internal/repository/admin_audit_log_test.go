@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAuditLogCreate(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewAdminAuditLogRepository(db)
+	log := &model.AdminAuditLog{
+		Actor:     "ops@example.com",
+		Action:    "FORCE_UNBLOCK",
+		Details:   `{"email1":"alice@example.com","email2":"bob@example.com"}`,
+		IP:        "10.0.0.1",
+		UserAgent: "curl/8.0",
+		CreatedAt: time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "admin_audit_logs"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, log.Actor, log.Action, log.Details, log.IP, log.UserAgent, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Create(log))
+	require.Equal(t, uint(1), log.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminAuditLogCreate_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewAdminAuditLogRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "admin_audit_logs"`)).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	require.Error(t, repo.Create(&model.AdminAuditLog{Actor: "ops@example.com", Action: "FORCE_UNBLOCK"}))
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// digestSchedulerLockID is the postgres advisory lock held by the instance building the digests
+const digestSchedulerLockID = 7_315_200_047
+
+// DigestCutoffs are the times before which the pending digest entries of each frequency are due.
+// Immediate is for the recipients that left the digests while entries were still pending.
+type DigestCutoffs struct {
+	Immediate time.Time
+	Hourly    time.Time
+	Daily     time.Time
+	Weekly    time.Time
+}
+
+// For return the cutoff of the frequency
+func (c DigestCutoffs) For(frequency string) time.Time {
+	switch frequency {
+	case constant.NOTIFICATION_FREQUENCY_HOURLY:
+		return c.Hourly
+	case constant.NOTIFICATION_FREQUENCY_DAILY:
+		return c.Daily
+	case constant.NOTIFICATION_FREQUENCY_WEEKLY:
+		return c.Weekly
+	}
+	return c.Immediate
+}
+
+// PendingDigest is a recipient with digest entries that are due, with the quiet hours of the recipient
+type PendingDigest struct {
+	TenantID        string
+	Recipient       string
+	Frequency       string
+	Timezone        string
+	QuietHoursStart string
+	QuietHoursEnd   string
+}
+
+type emailDeliveryRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// EmailDeliveryRepository all the functions to queue and send the emails of the status updates,
+// the scheduling and sending functions work on every tenant
+type EmailDeliveryRepository interface {
+	Enqueue(statusUpdateID uint, template string, recipients []string, at, sendAt time.Time) error
+	EnqueueDigest(statusUpdateID uint, recipients []string, at time.Time) error
+	TryLockScheduler() (bool, error)
+	ListDueDigests(cutoffs DigestCutoffs, limit int) ([]PendingDigest, error)
+	DropBlockedDigestEntries(recipient string) error
+	ListDigestEntries(recipient string, before time.Time) ([]model.DigestEntry, error)
+	CreateDigest(recipient string, entryIDs []uint, at time.Time) (*model.EmailDelivery, error)
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.EmailDelivery, error)
+	MarkSent(id uint, at time.Time) error
+	MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkUndeliverable(id uint, attempts int, lastError string) error
+	WithTx(tx *gorm.DB) EmailDeliveryRepository
+	WithTenant(tenantID string) EmailDeliveryRepository
+}
+
+func NewEmailDeliveryRepository(db *gorm.DB) EmailDeliveryRepository {
+	return &emailDeliveryRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// Enqueue support queue the email of a status update posted at for every recipient in the tenant, the emails are not sent before sendAt.
+// A recipient already queued is skipped
+func (r *emailDeliveryRepository) Enqueue(statusUpdateID uint, template string, recipients []string, at, sendAt time.Time) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	deliveries := make([]model.EmailDelivery, 0, len(recipients))
+	for _, recipient := range recipients {
+		deliveries = append(deliveries, model.EmailDelivery{
+			TenantID:       r.tenantID,
+			StatusUpdateID: &statusUpdateID,
+			Recipient:      recipient,
+			Template:       template,
+			Status:         constant.EMAIL_STATUS_PENDING,
+			NextAttemptAt:  sendAt,
+			CreatedAt:      at,
+		})
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(deliveries, feedEntryBatchSize).Error
+}
+
+// EnqueueDigest support keep a status update for the next digest of every recipient in the tenant, a recipient that already has it is skipped
+func (r *emailDeliveryRepository) EnqueueDigest(statusUpdateID uint, recipients []string, at time.Time) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	entries := make([]model.DigestEntry, 0, len(recipients))
+	for _, recipient := range recipients {
+		entries = append(entries, model.DigestEntry{TenantID: r.tenantID, Recipient: recipient, StatusUpdateID: statusUpdateID, CreatedAt: at})
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(entries, feedEntryBatchSize).Error
+}
+
+// TryLockScheduler take the digest lock until the end of the transaction, it returns false when another instance holds it
+func (r *emailDeliveryRepository) TryLockScheduler() (bool, error) {
+	var locked bool
+	if err := r.db.Raw("SELECT pg_try_advisory_xact_lock(?)", digestSchedulerLockID).Scan(&locked).Error; err != nil {
+		return false, err
+	}
+	return locked, nil
+}
+
+// ListDueDigests support query the recipients of every tenant with pending entries older than the cutoff of their frequency,
+// the preference of a recipient is matched case-insensitively and a recipient without one has the immediate frequency
+func (r *emailDeliveryRepository) ListDueDigests(cutoffs DigestCutoffs, limit int) ([]PendingDigest, error) {
+	var digests []PendingDigest
+	err := r.db.Table("digest_entries").
+		Select("DISTINCT digest_entries.tenant_id, digest_entries.recipient, COALESCE(notification_preferences.frequency, ?) AS frequency, "+
+			"COALESCE(notification_preferences.timezone, ?) AS timezone, COALESCE(notification_preferences.quiet_hours_start, ?) AS quiet_hours_start, "+
+			"COALESCE(notification_preferences.quiet_hours_end, ?) AS quiet_hours_end", constant.NOTIFICATION_FREQUENCY_IMMEDIATE, "UTC", "", "").
+		Joins("LEFT JOIN notification_preferences ON notification_preferences.tenant_id = digest_entries.tenant_id AND LOWER(notification_preferences.email) = LOWER(digest_entries.recipient)").
+		Where("digest_entries.email_delivery_id IS NULL AND digest_entries.created_at < CASE COALESCE(notification_preferences.frequency, ?) WHEN ? THEN ? WHEN ? THEN ? WHEN ? THEN ? ELSE ? END",
+			constant.NOTIFICATION_FREQUENCY_IMMEDIATE,
+			constant.NOTIFICATION_FREQUENCY_HOURLY, cutoffs.Hourly,
+			constant.NOTIFICATION_FREQUENCY_DAILY, cutoffs.Daily,
+			constant.NOTIFICATION_FREQUENCY_WEEKLY, cutoffs.Weekly,
+			cutoffs.Immediate).
+		Order("digest_entries.tenant_id, digest_entries.recipient").Limit(limit).Scan(&digests).Error
+	if err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// DropBlockedDigestEntries support delete the pending entries of the recipient whose author was blocked by the recipient
+// or blocked the recipient after the update was posted, the emails of the blocks are compared case-insensitively
+func (r *emailDeliveryRepository) DropBlockedDigestEntries(recipient string) error {
+	blocked := r.db.Model(&model.StatusUpdate{}).Select("status_updates.id").
+		Joins("JOIN user_relationships ON user_relationships.tenant_id = status_updates.tenant_id AND user_relationships.type = ? AND "+
+			"((LOWER(user_relationships.requestor_email) = LOWER(?) AND LOWER(user_relationships.target_email) = LOWER(status_updates.author_email)) OR "+
+			"(LOWER(user_relationships.requestor_email) = LOWER(status_updates.author_email) AND LOWER(user_relationships.target_email) = LOWER(?)))",
+			constant.BLOCK_RELATIONSHIP_TYPE, recipient, recipient).
+		Where("status_updates.tenant_id = ?", r.tenantID)
+
+	return r.db.Where("tenant_id = ? AND recipient = ? AND email_delivery_id IS NULL AND status_update_id IN (?)", r.tenantID, recipient, blocked).
+		Delete(&model.DigestEntry{}).Error
+}
+
+// ListDigestEntries support query the pending entries of the recipient posted before a time, oldest first with their status update
+func (r *emailDeliveryRepository) ListDigestEntries(recipient string, before time.Time) ([]model.DigestEntry, error) {
+	var entries []model.DigestEntry
+	err := r.db.Preload("StatusUpdate").
+		Where("tenant_id = ? AND recipient = ? AND email_delivery_id IS NULL AND created_at < ?", r.tenantID, recipient, before).
+		Order("status_update_id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CreateDigest support queue one digest email with the entries for the recipient, it must run in a transaction
+func (r *emailDeliveryRepository) CreateDigest(recipient string, entryIDs []uint, at time.Time) (*model.EmailDelivery, error) {
+	delivery := &model.EmailDelivery{
+		TenantID:      r.tenantID,
+		Recipient:     recipient,
+		Template:      constant.EMAIL_TEMPLATE_DIGEST,
+		Status:        constant.EMAIL_STATUS_PENDING,
+		NextAttemptAt: at,
+		CreatedAt:     at,
+	}
+	if err := r.db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	err := r.db.Model(&model.DigestEntry{}).Where("id IN ?", entryIDs).Update("email_delivery_id", delivery.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ClaimDue support claim the oldest pending emails of every tenant that can be attempted now, with their status update
+// or the status updates of their digest. The claimed emails are not due again before now plus lease, so the other instances
+// skip them while they are sent and the emails of an instance that stopped are attempted again after the lease.
+// It must run in a transaction, the rows claimed by a concurrent transaction are skipped.
+func (r *emailDeliveryRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.EmailDelivery, error) {
+	var ids []uint
+	err := r.db.Model(&model.EmailDelivery{}).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", constant.EMAIL_STATUS_PENDING, now).
+		Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	if err := r.db.Model(&model.EmailDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error; err != nil {
+		return nil, err
+	}
+
+	var deliveries []model.EmailDelivery
+	err = r.db.Preload("StatusUpdate").
+		Preload("DigestEntries", func(db *gorm.DB) *gorm.DB { return db.Order("status_update_id") }).
+		Preload("DigestEntries.StatusUpdate").
+		Where("id IN ?", ids).Order("id").Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// MarkSent support record the email was accepted by the smtp server
+func (r *emailDeliveryRepository) MarkSent(id uint, at time.Time) error {
+	return r.db.Model(&model.EmailDelivery{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":  constant.EMAIL_STATUS_SENT,
+			"sent_at": at,
+		}).Error
+}
+
+// MarkFailed support record a failed attempt and when it can be retried
+func (r *emailDeliveryRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.db.Model(&model.EmailDelivery{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+// MarkUndeliverable support record the email will not be attempted again
+func (r *emailDeliveryRepository) MarkUndeliverable(id uint, attempts int, lastError string) error {
+	return r.db.Model(&model.EmailDelivery{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     constant.EMAIL_STATUS_FAILED,
+			"attempts":   attempts,
+			"last_error": lastError,
+		}).Error
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *emailDeliveryRepository) WithTx(tx *gorm.DB) EmailDeliveryRepository {
+	return &emailDeliveryRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that queue the emails of the tenant
+func (r *emailDeliveryRepository) WithTenant(tenantID string) EmailDeliveryRepository {
+	return &emailDeliveryRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,218 @@
+package repository_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailDeliveryEnqueue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	sendAt := at.Add(8 * time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "email_deliveries" ("tenant_id","status_update_id","recipient","template","status","attempts","next_attempt_at","last_error","sent_at","created_at") `+
+		`VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10),($11,$12,$13,$14,$15,$16,$17,$18,$19,$20) ON CONFLICT DO NOTHING RETURNING "id"`)).
+		WithArgs("tenant-b", 7, "bob@example.com", constant.EMAIL_TEMPLATE_STATUS_UPDATE, constant.EMAIL_STATUS_PENDING, 0, sendAt, "", nil, at,
+			"tenant-b", 7, "carol@example.com", constant.EMAIL_TEMPLATE_STATUS_UPDATE, constant.EMAIL_STATUS_PENDING, 0, sendAt, "", nil, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	repo := repository.NewEmailDeliveryRepository(db).WithTenant("tenant-b")
+	require.NoError(t, repo.Enqueue(7, constant.EMAIL_TEMPLATE_STATUS_UPDATE, []string{"bob@example.com", "carol@example.com"}, at, sendAt))
+	require.NoError(t, repo.Enqueue(7, constant.EMAIL_TEMPLATE_STATUS_UPDATE, nil, at, at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryClaimDue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "email_deliveries" WHERE status = $1 AND next_attempt_at <= $2 ORDER BY id LIMIT $3 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(constant.EMAIL_STATUS_PENDING, now, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "email_deliveries" SET "next_attempt_at"=$1 WHERE id IN ($2,$3)`)).
+		WithArgs(now.Add(10*time.Minute), 9, 10).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "email_deliveries" WHERE id IN ($1,$2) ORDER BY id`)).
+		WithArgs(9, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "status_update_id", "recipient", "template"}).
+			AddRow(9, "tenant-b", 7, "bob@example.com", constant.EMAIL_TEMPLATE_STATUS_UPDATE).
+			AddRow(10, "tenant-b", nil, "carol@example.com", constant.EMAIL_TEMPLATE_DIGEST))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "digest_entries" WHERE "digest_entries"."email_delivery_id" IN ($1,$2) ORDER BY status_update_id`)).
+		WithArgs(9, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status_update_id", "email_delivery_id"}).AddRow(3, 8, 10))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "status_updates" WHERE "status_updates"."id" = $1`)).
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_email", "text"}).AddRow(8, "dave@example.com", "bye"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "status_updates" WHERE "status_updates"."id" = $1`)).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_email", "text"}).AddRow(7, "alice@example.com", "hello"))
+
+	deliveries, err := repository.NewEmailDeliveryRepository(db).ClaimDue(now, 10*time.Minute, 50)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, "alice@example.com", deliveries[0].StatusUpdate.AuthorEmail)
+	require.Nil(t, deliveries[1].StatusUpdate)
+	require.Len(t, deliveries[1].DigestEntries, 1)
+	require.Equal(t, "dave@example.com", deliveries[1].DigestEntries[0].StatusUpdate.AuthorEmail)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryClaimDue_NothingDue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "email_deliveries"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	deliveries, err := repository.NewEmailDeliveryRepository(db).ClaimDue(time.Now(), 10*time.Minute, 50)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryMarkSent(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "email_deliveries" SET "sent_at"=$1,"status"=$2 WHERE id = $3`)).
+		WithArgs(at, constant.EMAIL_STATUS_SENT, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewEmailDeliveryRepository(db).MarkSent(9, at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryMarkUndeliverable(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "email_deliveries" SET "attempts"=$1,"last_error"=$2,"status"=$3 WHERE id = $4`)).
+		WithArgs(5, "550 mailbox unavailable", constant.EMAIL_STATUS_FAILED, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewEmailDeliveryRepository(db).MarkUndeliverable(9, 5, "550 mailbox unavailable"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryEnqueueDigest(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "digest_entries" ("tenant_id","recipient","status_update_id","email_delivery_id","created_at") `+
+		`VALUES ($1,$2,$3,$4,$5) ON CONFLICT DO NOTHING RETURNING "id"`)).
+		WithArgs("tenant-b", "bob@example.com", 7, nil, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	repo := repository.NewEmailDeliveryRepository(db).WithTenant("tenant-b")
+	require.NoError(t, repo.EnqueueDigest(7, []string{"bob@example.com"}, at))
+	require.NoError(t, repo.EnqueueDigest(7, nil, at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryListDueDigests(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	cutoffs := repository.DigestCutoffs{Immediate: now, Hourly: now.Add(-time.Minute), Daily: now.Add(-time.Hour), Weekly: now.Add(-24 * time.Hour)}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT digest_entries.tenant_id, digest_entries.recipient, COALESCE(notification_preferences.frequency, $1) AS frequency, `+
+		`COALESCE(notification_preferences.timezone, $2) AS timezone, COALESCE(notification_preferences.quiet_hours_start, $3) AS quiet_hours_start, `+
+		`COALESCE(notification_preferences.quiet_hours_end, $4) AS quiet_hours_end FROM "digest_entries" `+
+		`LEFT JOIN notification_preferences ON notification_preferences.tenant_id = digest_entries.tenant_id AND LOWER(notification_preferences.email) = LOWER(digest_entries.recipient) `+
+		`WHERE digest_entries.email_delivery_id IS NULL AND digest_entries.created_at < CASE COALESCE(notification_preferences.frequency, $5) WHEN $6 THEN $7 WHEN $8 THEN $9 WHEN $10 THEN $11 ELSE $12 END `+
+		`ORDER BY digest_entries.tenant_id, digest_entries.recipient LIMIT $13`)).
+		WithArgs(constant.NOTIFICATION_FREQUENCY_IMMEDIATE, "UTC", "", "", constant.NOTIFICATION_FREQUENCY_IMMEDIATE,
+			constant.NOTIFICATION_FREQUENCY_HOURLY, cutoffs.Hourly, constant.NOTIFICATION_FREQUENCY_DAILY, cutoffs.Daily,
+			constant.NOTIFICATION_FREQUENCY_WEEKLY, cutoffs.Weekly, cutoffs.Immediate, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "recipient", "frequency", "timezone", "quiet_hours_start", "quiet_hours_end"}).
+			AddRow("tenant-b", "bob@example.com", constant.NOTIFICATION_FREQUENCY_DAILY, "Europe/Paris", "22:00", "07:00"))
+
+	digests, err := repository.NewEmailDeliveryRepository(db).ListDueDigests(cutoffs, 100)
+	require.NoError(t, err)
+	require.Equal(t, []repository.PendingDigest{{
+		TenantID: "tenant-b", Recipient: "bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_DAILY,
+		Timezone: "Europe/Paris", QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+	}}, digests)
+	require.Equal(t, cutoffs.Daily, cutoffs.For(constant.NOTIFICATION_FREQUENCY_DAILY))
+	require.Equal(t, cutoffs.Immediate, cutoffs.For(constant.NOTIFICATION_FREQUENCY_IMMEDIATE))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryListDueDigests_MixedCase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	//The entry of Bob@example.com gets the preference saved for bob@example.com
+	now := time.Now()
+	cutoffs := repository.DigestCutoffs{Immediate: now, Hourly: now, Daily: now, Weekly: now}
+	mock.ExpectQuery(regexp.QuoteMeta(`LEFT JOIN notification_preferences ON notification_preferences.tenant_id = digest_entries.tenant_id AND `+
+		`LOWER(notification_preferences.email) = LOWER(digest_entries.recipient) `)).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "recipient", "frequency", "timezone", "quiet_hours_start", "quiet_hours_end"}).
+			AddRow("tenant-b", "Bob@example.com", constant.NOTIFICATION_FREQUENCY_WEEKLY, "UTC", "", ""))
+
+	digests, err := repository.NewEmailDeliveryRepository(db).ListDueDigests(cutoffs, 100)
+	require.NoError(t, err)
+	require.Equal(t, []repository.PendingDigest{{
+		TenantID: "tenant-b", Recipient: "Bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_WEEKLY, Timezone: "UTC",
+	}}, digests)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryDropBlockedDigestEntries_MixedCase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "digest_entries" WHERE tenant_id = $1 AND recipient = $2 AND email_delivery_id IS NULL AND status_update_id IN `+
+		`(SELECT status_updates.id FROM "status_updates" JOIN user_relationships ON user_relationships.tenant_id = status_updates.tenant_id AND user_relationships.type = $3 AND `+
+		`((LOWER(user_relationships.requestor_email) = LOWER($4) AND LOWER(user_relationships.target_email) = LOWER(status_updates.author_email)) OR `+
+		`(LOWER(user_relationships.requestor_email) = LOWER(status_updates.author_email) AND LOWER(user_relationships.target_email) = LOWER($5))) WHERE status_updates.tenant_id = $6)`)).
+		WithArgs("tenant-b", "Bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE, "Bob@example.com", "Bob@example.com", "tenant-b").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewEmailDeliveryRepository(db).WithTenant("tenant-b").DropBlockedDigestEntries("Bob@example.com"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailDeliveryCreateDigest(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "email_deliveries"`)).
+		WithArgs("tenant-b", nil, "bob@example.com", constant.EMAIL_TEMPLATE_DIGEST, constant.EMAIL_STATUS_PENDING, 0, at, "", nil, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "digest_entries" SET "email_delivery_id"=$1 WHERE id IN ($2,$3)`)).
+		WithArgs(9, 3, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	delivery, err := repository.NewEmailDeliveryRepository(db).WithTenant("tenant-b").CreateDigest("bob@example.com", []uint{3, 4}, at)
+	require.NoError(t, err)
+	require.Equal(t, uint(9), delivery.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyKeyRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// IdempotencyKeyRepository all the functions to store and replay the first response of an idempotency key
+type IdempotencyKeyRepository interface {
+	Reserve(key, requestor, fingerprint string, expiresAt time.Time) (*model.IdempotencyKey, bool, error)
+	Complete(id uint, responseCode int, contentType string, responseBody []byte) error
+	Release(id uint) error
+	DeleteExpired(now time.Time) (int64, error)
+	WithTenant(tenantID string) IdempotencyKeyRepository
+}
+
+func NewIdempotencyKeyRepository(db *gorm.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the keys of the tenant
+func (r *idempotencyKeyRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
+}
+
+// Reserve create an in progress record for the key and requestor in the tenant.
+// When the key was already used the existing record is returned with reserved false.
+func (r *idempotencyKeyRepository) Reserve(key, requestor, fingerprint string, expiresAt time.Time) (*model.IdempotencyKey, bool, error) {
+	//An expired record does not block the key anymore
+	err := r.scoped().Where("key = ? AND requestor = ? AND expires_at < ?", key, requestor, time.Now()).Delete(&model.IdempotencyKey{}).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	record := &model.IdempotencyKey{
+		TenantID:    r.tenantID,
+		Key:         key,
+		Requestor:   requestor,
+		Fingerprint: fingerprint,
+		Status:      constant.IDEMPOTENCY_STATUS_IN_PROGRESS,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return record, true, nil
+	}
+
+	var existing model.IdempotencyKey
+	if err := r.scoped().Where("key = ? AND requestor = ?", key, requestor).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// Complete store the response of the first request so retries can replay it
+func (r *idempotencyKeyRepository) Complete(id uint, responseCode int, contentType string, responseBody []byte) error {
+	err := r.scoped().Model(&model.IdempotencyKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        constant.IDEMPOTENCY_STATUS_COMPLETED,
+			"response_code": responseCode,
+			"content_type":  contentType,
+			"response_body": responseBody,
+			"updated_at":    time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Release delete the record so the key can be used again, used when the first request failed unexpectedly
+func (r *idempotencyKeyRepository) Release(id uint) error {
+	err := r.scoped().Where("id = ?", id).Delete(&model.IdempotencyKey{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired delete all the records that passed their TTL, it is housekeeping so every tenant is purged
+func (r *idempotencyKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&model.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// WithTenant return a repository that only read and write the keys of the tenant
+func (r *idempotencyKeyRepository) WithTenant(tenantID string) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,122 @@
+package repository_test
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyReserve_NewKey(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewIdempotencyKeyRepository(db)
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "key-1", "alice@example.com", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "idempotency_keys"`) + `.*ON CONFLICT DO NOTHING`).
+		WithArgs(constant.DEFAULT_TENANT_ID, "key-1", "alice@example.com", "fingerprint", constant.IDEMPOTENCY_STATUS_IN_PROGRESS, 0, "", sqlmock.AnyArg(), expiresAt, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	record, reserved, err := repo.Reserve("key-1", "alice@example.com", "fingerprint", expiresAt)
+	require.NoError(t, err)
+	require.True(t, reserved)
+	require.Equal(t, uint(1), record.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyKeyReserve_ExistingKey(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewIdempotencyKeyRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "idempotency_keys"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	rows := sqlmock.NewRows([]string{"id", "key", "requestor", "fingerprint", "status", "response_code"}).
+		AddRow(3, "key-1", "alice@example.com", "fingerprint", constant.IDEMPOTENCY_STATUS_COMPLETED, 200)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "idempotency_keys"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "key-1", "alice@example.com", 1).
+		WillReturnRows(rows)
+
+	record, reserved, err := repo.Reserve("key-1", "alice@example.com", "fingerprint", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, reserved)
+	require.Equal(t, uint(3), record.ID)
+	require.Equal(t, constant.IDEMPOTENCY_STATUS_COMPLETED, record.Status)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyKeyReserve_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewIdempotencyKeyRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys"`)).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	record, reserved, err := repo.Reserve("key-1", "alice@example.com", "fingerprint", time.Now().Add(time.Hour))
+	require.Error(t, err)
+	require.False(t, reserved)
+	require.Nil(t, record)
+}
+
+func TestIdempotencyKeyComplete(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewIdempotencyKeyRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "idempotency_keys"`)).
+		WithArgs("application/json", []byte(`{"success":true}`), 200, constant.IDEMPOTENCY_STATUS_COMPLETED, sqlmock.AnyArg(), constant.DEFAULT_TENANT_ID, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.Complete(3, 200, "application/json", []byte(`{"success":true}`))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyKeyDeleteExpired(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewIdempotencyKeyRepository(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys" WHERE expires_at < $1`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	deleted, err := repo.DeleteExpired(now)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
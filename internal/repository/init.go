@@ -3,11 +3,33 @@ package repository
 import "gorm.io/gorm"
 
 type Repository struct {
-	UserRelationshipRepo UserRelationshipRepository
+	UserRelationshipRepo       UserRelationshipRepository
+	IdempotencyKeyRepo         IdempotencyKeyRepository
+	AdminAuditLogRepo          AdminAuditLogRepository
+	RateLimitBucketRepo        RateLimitBucketRepository
+	UserQuotaRepo              UserQuotaRepository
+	RelationshipEventRepo      RelationshipEventRepository
+	OutboxEventRepo            OutboxEventRepository
+	WebhookRepo                WebhookRepository
+	WebhookDeliveryRepo        WebhookDeliveryRepository
+	StatusUpdateRepo           StatusUpdateRepository
+	EmailDeliveryRepo          EmailDeliveryRepository
+	NotificationPreferenceRepo NotificationPreferenceRepository
 }
 
 func NewRepositoy(db *gorm.DB) Repository {
 	return Repository{
-		UserRelationshipRepo: NewUserRelationshipRepository(db),
+		UserRelationshipRepo:       NewUserRelationshipRepository(db),
+		IdempotencyKeyRepo:         NewIdempotencyKeyRepository(db),
+		AdminAuditLogRepo:          NewAdminAuditLogRepository(db),
+		RateLimitBucketRepo:        NewRateLimitBucketRepository(db),
+		UserQuotaRepo:              NewUserQuotaRepository(db),
+		RelationshipEventRepo:      NewRelationshipEventRepository(db),
+		OutboxEventRepo:            NewOutboxEventRepository(db),
+		WebhookRepo:                NewWebhookRepository(db),
+		WebhookDeliveryRepo:        NewWebhookDeliveryRepository(db),
+		StatusUpdateRepo:           NewStatusUpdateRepository(db),
+		EmailDeliveryRepo:          NewEmailDeliveryRepository(db),
+		NotificationPreferenceRepo: NewNotificationPreferenceRepository(db),
 	}
 }
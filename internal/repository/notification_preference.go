@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// preferenceLookupBatchSize is the number of emails looked up per statement, it keeps the bound parameters under the postgres limit
+const preferenceLookupBatchSize = 1000
+
+type notificationPreferenceRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// NotificationPreferenceRepository all the functions to manage how often the emails of a tenant are notified
+type NotificationPreferenceRepository interface {
+	GetByEmail(email string) (*model.NotificationPreference, error)
+	Upsert(preference *model.NotificationPreference) error
+	ListByEmails(emails []string) (map[string]model.NotificationPreference, error)
+	WithTx(tx *gorm.DB) NotificationPreferenceRepository
+	WithTenant(tenantID string) NotificationPreferenceRepository
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the preferences of the tenant
+func (r *notificationPreferenceRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
+}
+
+// GetByEmail support query the preference of the email, it returns gorm.ErrRecordNotFound when the email has none
+func (r *notificationPreferenceRepository) GetByEmail(email string) (*model.NotificationPreference, error) {
+	var preference model.NotificationPreference
+	if err := r.scoped().Where("email = ?", email).First(&preference).Error; err != nil {
+		return nil, err
+	}
+	return &preference, nil
+}
+
+// Upsert create the preference of the email or replace it
+func (r *notificationPreferenceRepository) Upsert(preference *model.NotificationPreference) error {
+	preference.TenantID = r.tenantID
+	preference.CreatedAt = time.Now()
+	preference.UpdatedAt = time.Now()
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"frequency", "timezone", "quiet_hours_start", "quiet_hours_end", "mentions_only", "ignore_stranger_mentions", "updated_at"}),
+	}).Create(preference).Error
+}
+
+// ListByEmails support get the preferences of the emails that have one, keyed by email as given.
+// The emails are compared case-insensitively since relationships keep the emails as they were entered.
+func (r *notificationPreferenceRepository) ListByEmails(emails []string) (map[string]model.NotificationPreference, error) {
+	preferences := make(map[string]model.NotificationPreference)
+	for start := 0; start < len(emails); start += preferenceLookupBatchSize {
+		end := min(start+preferenceLookupBatchSize, len(emails))
+
+		var batch []model.NotificationPreference
+		if err := r.scoped().Where("LOWER(email) IN ?", utils.ToLower(emails[start:end])).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		found := make(map[string]model.NotificationPreference, len(batch))
+		for _, preference := range batch {
+			found[strings.ToLower(preference.Email)] = preference
+		}
+		for _, email := range emails[start:end] {
+			if preference, ok := found[strings.ToLower(email)]; ok {
+				preferences[email] = preference
+			}
+		}
+	}
+	return preferences, nil
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *notificationPreferenceRepository) WithTx(tx *gorm.DB) NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that only read and write the preferences of the tenant
+func (r *notificationPreferenceRepository) WithTenant(tenantID string) NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferenceUpsert(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	preference := &model.NotificationPreference{
+		Email: "bob@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_DAILY,
+		Timezone: "Europe/Paris", QuietHoursStart: "22:00", QuietHoursEnd: "07:00", MentionsOnly: true, IgnoreStrangerMentions: true,
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "notification_preferences" ("tenant_id","email","frequency","timezone","quiet_hours_start","quiet_hours_end","mentions_only","ignore_stranger_mentions","created_at","updated_at") `+
+		`VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) ON CONFLICT ("tenant_id","email") DO UPDATE SET "frequency"="excluded"."frequency","timezone"="excluded"."timezone",`+
+		`"quiet_hours_start"="excluded"."quiet_hours_start","quiet_hours_end"="excluded"."quiet_hours_end","mentions_only"="excluded"."mentions_only",`+
+		`"ignore_stranger_mentions"="excluded"."ignore_stranger_mentions","updated_at"="excluded"."updated_at" RETURNING "id"`)).
+		WithArgs("tenant-b", "bob@example.com", constant.NOTIFICATION_FREQUENCY_DAILY, "Europe/Paris", "22:00", "07:00", true, true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewNotificationPreferenceRepository(db).WithTenant("tenant-b").Upsert(preference))
+	require.Equal(t, "tenant-b", preference.TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotificationPreferenceGetByEmail(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "notification_preferences" WHERE tenant_id = $1 AND email = $2 ORDER BY "notification_preferences"."id" LIMIT $3`)).
+		WithArgs("tenant-b", "bob@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "frequency"}).AddRow(1, "bob@example.com", constant.NOTIFICATION_FREQUENCY_WEEKLY))
+
+	preference, err := repository.NewNotificationPreferenceRepository(db).WithTenant("tenant-b").GetByEmail("bob@example.com")
+	require.NoError(t, err)
+	require.Equal(t, constant.NOTIFICATION_FREQUENCY_WEEKLY, preference.Frequency)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotificationPreferenceListByEmails(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "notification_preferences" WHERE tenant_id = $1 AND LOWER(email) IN ($2,$3)`)).
+		WithArgs("tenant-b", "bob@example.com", "carol@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "frequency", "timezone", "quiet_hours_start", "quiet_hours_end", "mentions_only"}).
+			AddRow(1, "carol@example.com", constant.NOTIFICATION_FREQUENCY_HOURLY, "Asia/Ho_Chi_Minh", "22:00", "07:00", true))
+
+	//The preferences are keyed by the emails as given
+	repo := repository.NewNotificationPreferenceRepository(db).WithTenant("tenant-b")
+	preferences, err := repo.ListByEmails([]string{"bob@example.com", "Carol@Example.com"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]model.NotificationPreference{"Carol@Example.com": {
+		ID: 1, Email: "carol@example.com", Frequency: constant.NOTIFICATION_FREQUENCY_HOURLY,
+		Timezone: "Asia/Ho_Chi_Minh", QuietHoursStart: "22:00", QuietHoursEnd: "07:00", MentionsOnly: true,
+	}}, preferences)
+
+	preferences, err = repo.ListByEmails(nil)
+	require.NoError(t, err)
+	require.Empty(t, preferences)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxEventRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// OutboxEventRepository all the functions to write domain events and relay them, the relay functions work on every tenant
+type OutboxEventRepository interface {
+	Create(event *model.OutboxEvent) error
+	ClaimPending(now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error)
+	ReleaseClaim(ids []uint) error
+	MarkPublished(id uint, at time.Time) error
+	MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkDeadLettered(id uint, attempts int, lastError string, at time.Time) error
+	ListForEmail(email string, afterID uint, limit int) ([]model.OutboxEvent, error)
+	ResumeAfterID(email string, lastID uint, window time.Duration) (uint, error)
+	Notify(channel, payload string) error
+	LastID() (uint, error)
+	ListAfter(afterID uint, limit int) ([]model.OutboxEvent, error)
+	WithTx(tx *gorm.DB) OutboxEventRepository
+	WithTenant(tenantID string) OutboxEventRepository
+}
+
+func NewOutboxEventRepository(db *gorm.DB) OutboxEventRepository {
+	return &outboxEventRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// Create support write one domain event in the tenant, it must run in the transaction of the change
+func (r *outboxEventRepository) Create(event *model.OutboxEvent) error {
+	event.TenantID = r.tenantID
+	return r.db.Create(event).Error
+}
+
+// ClaimPending support claim the unpublished events of every tenant, oldest first, for the requestors whose oldest unpublished
+// event can be attempted now. Only the instance holding the oldest event of a requestor publishes the events of the requestor,
+// so they stay in order. The claimed events are not due again before now plus lease and the events of an instance that stopped
+// are attempted again after the lease. It must run in a transaction, the rows claimed by a concurrent transaction are skipped.
+func (r *outboxEventRepository) ClaimPending(now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error) {
+	var heads []uint
+	err := r.db.Model(&model.OutboxEvent{}).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL AND dead_lettered_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", now).
+		Where("NOT EXISTS (SELECT 1 FROM outbox_events AS earlier WHERE earlier.tenant_id = outbox_events.tenant_id "+
+			"AND earlier.requestor_email = outbox_events.requestor_email AND earlier.id < outbox_events.id "+
+			"AND earlier.published_at IS NULL AND earlier.dead_lettered_at IS NULL)").
+		Order("id").Limit(limit).Pluck("id", &heads).Error
+	if err != nil || len(heads) == 0 {
+		return nil, err
+	}
+
+	var events []model.OutboxEvent
+	err = r.db.Where("published_at IS NULL AND dead_lettered_at IS NULL").
+		Where("(tenant_id, requestor_email) IN (?)", r.db.Model(&model.OutboxEvent{}).Select("tenant_id", "requestor_email").Where("id IN ?", heads)).
+		Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	if err := r.db.Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReleaseClaim support make the claimed events that were not attempted due again, they are the later events of a requestor
+// so they never waited for a retry
+func (r *outboxEventRepository) ReleaseClaim(ids []uint) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id IN ? AND published_at IS NULL", ids).Update("next_attempt_at", nil).Error
+}
+
+// MarkPublished support record the event was delivered to the sink
+func (r *outboxEventRepository) MarkPublished(id uint, at time.Time) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).Update("published_at", at).Error
+}
+
+// MarkFailed support record a failed delivery and when the event can be retried
+func (r *outboxEventRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+// MarkDeadLettered support record the event failed every attempt, the later events of the requestor are published without it
+func (r *outboxEventRepository) MarkDeadLettered(id uint, attempts int, lastError string, at time.Time) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":         attempts,
+			"last_error":       lastError,
+			"dead_lettered_at": at,
+		}).Error
+}
+
+// ListForEmail support query the events of the tenant where the email is the requestor or the target, after an id
+func (r *outboxEventRepository) ListForEmail(email string, afterID uint, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := r.db.Where("tenant_id = ? AND id > ?", r.tenantID, afterID).
+		Where("requestor_email = ? OR target_email = ?", email, email).
+		Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ResumeAfterID support get the id to read the events of the email after, for a client that received lastID. The ids are not
+// committed in order, so the events of the email written in the window before lastID are read again: an event with a lower id
+// can commit after lastID was sent.
+func (r *outboxEventRepository) ResumeAfterID(email string, lastID uint, window time.Duration) (uint, error) {
+	var last model.OutboxEvent
+	err := r.db.Select("created_at").Where("tenant_id = ? AND id = ?", r.tenantID, lastID).Take(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return lastID, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var firstID *uint
+	err = r.db.Model(&model.OutboxEvent{}).Select("MIN(id)").
+		Where("tenant_id = ? AND id <= ? AND created_at >= ?", r.tenantID, lastID, last.CreatedAt.Add(-window)).
+		Where("requestor_email = ? OR target_email = ?", email, email).
+		Scan(&firstID).Error
+	if err != nil {
+		return 0, err
+	}
+	if firstID == nil {
+		return lastID, nil
+	}
+	return *firstID - 1, nil
+}
+
+// Notify support send a notification to the listeners of the channel, in a transaction it is only sent when the transaction commits
+func (r *outboxEventRepository) Notify(channel, payload string) error {
+	return r.db.Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// LastID support get the id of the latest event of every tenant, 0 when there is none
+func (r *outboxEventRepository) LastID() (uint, error) {
+	var id uint
+	if err := r.db.Model(&model.OutboxEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListAfter support query the events of every tenant after an id, oldest first. The ids are not committed in order,
+// an id lower than the latest event can still show up later
+func (r *outboxEventRepository) ListAfter(afterID uint, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	if err := r.db.Where("id > ?", afterID).Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *outboxEventRepository) WithTx(tx *gorm.DB) OutboxEventRepository {
+	return &outboxEventRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that write the events of the tenant
+func (r *outboxEventRepository) WithTenant(tenantID string) OutboxEventRepository {
+	return &outboxEventRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,243 @@
+package repository_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxEventCreate(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewOutboxEventRepository(db).WithTenant("tenant-b")
+	event := &model.OutboxEvent{
+		Type:           constant.DOMAIN_EVENT_FRIENDSHIP_CREATED,
+		RequestorEmail: "alice@example.com",
+		TargetEmail:    "bob@example.com",
+		CreatedAt:      time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbox_events"`)).
+		WithArgs("tenant-b", event.Type, event.RequestorEmail, event.TargetEmail, sqlmock.AnyArg(), nil, 0, nil, "", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Create(event))
+	require.Equal(t, uint(1), event.ID)
+	require.Equal(t, "tenant-b", event.TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventClaimPending(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "outbox_events" WHERE (published_at IS NULL AND dead_lettered_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= $1)) `+
+		`AND (NOT EXISTS (SELECT 1 FROM outbox_events AS earlier WHERE earlier.tenant_id = outbox_events.tenant_id AND earlier.requestor_email = outbox_events.requestor_email `+
+		`AND earlier.id < outbox_events.id AND earlier.published_at IS NULL AND earlier.dead_lettered_at IS NULL)) ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(now, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox_events" WHERE (published_at IS NULL AND dead_lettered_at IS NULL) `+
+		`AND (tenant_id, requestor_email) IN (SELECT "tenant_id","requestor_email" FROM "outbox_events" WHERE id IN ($1,$2)) ORDER BY id LIMIT $3`)).
+		WithArgs(1, 2, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "type", "requestor_email"}).
+			AddRow(1, constant.DEFAULT_TENANT_ID, constant.DOMAIN_EVENT_SUBSCRIBED, "alice@example.com").
+			AddRow(2, "tenant-b", constant.DOMAIN_EVENT_BLOCKED, "bob@example.com").
+			AddRow(3, constant.DEFAULT_TENANT_ID, constant.DOMAIN_EVENT_UNSUBSCRIBED, "alice@example.com"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "next_attempt_at"=$1 WHERE id IN ($2,$3,$4)`)).
+		WithArgs(now.Add(5*time.Minute), 1, 2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	events, err := repository.NewOutboxEventRepository(db).ClaimPending(now, 5*time.Minute, 50)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "tenant-b", events[1].TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventClaimPending_NothingDue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "outbox_events"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	events, err := repository.NewOutboxEventRepository(db).ClaimPending(time.Now(), 5*time.Minute, 50)
+	require.NoError(t, err)
+	require.Empty(t, events)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventReleaseClaim(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "next_attempt_at"=$1 WHERE id IN ($2,$3) AND published_at IS NULL`)).
+		WithArgs(nil, 3, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewOutboxEventRepository(db).ReleaseClaim([]uint{3, 4}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventMarkDeadLettered(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "attempts"=$1,"dead_lettered_at"=$2,"last_error"=$3 WHERE id = $4`)).
+		WithArgs(20, at, "SINK_DOWN", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewOutboxEventRepository(db).MarkDeadLettered(3, 20, "SINK_DOWN", at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventMarkPublished(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "published_at"=$1 WHERE id = $2`)).
+		WithArgs(at, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewOutboxEventRepository(db).MarkPublished(3, at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventMarkFailed(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	next := time.Now().Add(time.Minute)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "attempts"=$1,"last_error"=$2,"next_attempt_at"=$3 WHERE id = $4`)).
+		WithArgs(2, "SINK_DOWN", next, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewOutboxEventRepository(db).MarkFailed(3, 2, next, "SINK_DOWN"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventListForEmail(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox_events" WHERE (tenant_id = $1 AND id > $2) AND (requestor_email = $3 OR target_email = $4) ORDER BY id LIMIT $5`)).
+		WithArgs("tenant-b", 41, "alice@example.com", "alice@example.com", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "type", "requestor_email", "target_email"}).
+			AddRow(42, "tenant-b", constant.DOMAIN_EVENT_BLOCKED, "bob@example.com", "alice@example.com"))
+
+	events, err := repository.NewOutboxEventRepository(db).WithTenant("tenant-b").ListForEmail("alice@example.com", 41, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint(42), events[0].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventNotify(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+		WithArgs(constant.NOTIFY_CHANNEL_RELATIONSHIP_CHANGES, `{"id":42}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repository.NewOutboxEventRepository(db).Notify(constant.NOTIFY_CHANNEL_RELATIONSHIP_CHANGES, `{"id":42}`))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventLastID(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(id), 0) FROM "outbox_events"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(42))
+
+	id, err := repository.NewOutboxEventRepository(db).LastID()
+	require.NoError(t, err)
+	require.Equal(t, uint(42), id)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventListAfter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox_events" WHERE id > $1 ORDER BY id LIMIT $2`)).
+		WithArgs(41, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "type"}).
+			AddRow(42, "tenant-b", constant.DOMAIN_EVENT_BLOCKED).
+			AddRow(43, constant.DEFAULT_TENANT_ID, constant.DOMAIN_EVENT_SUBSCRIBED))
+
+	events, err := repository.NewOutboxEventRepository(db).ListAfter(41, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "tenant-b", events[0].TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxEventResumeAfterID(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tcs := map[string]struct {
+		lastRows  *sqlmock.Rows
+		firstRows *sqlmock.Rows
+		expected  uint
+	}{
+		"EventsInTheWindow": {
+			lastRows:  sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt),
+			firstRows: sqlmock.NewRows([]string{"min"}).AddRow(40),
+			expected:  39,
+		},
+		"NoEventInTheWindow": {
+			lastRows:  sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt),
+			firstRows: sqlmock.NewRows([]string{"min"}).AddRow(nil),
+			expected:  42,
+		},
+		"UnknownLastEvent": {
+			lastRows: sqlmock.NewRows([]string{"created_at"}),
+			expected: 42,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "created_at" FROM "outbox_events" WHERE tenant_id = $1 AND id = $2 LIMIT $3`)).
+				WithArgs("tenant-b", 42, 1).
+				WillReturnRows(tc.lastRows)
+			if tc.firstRows != nil {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT MIN(id) FROM "outbox_events" WHERE (tenant_id = $1 AND id <= $2 AND created_at >= $3) AND (requestor_email = $4 OR target_email = $5)`)).
+					WithArgs("tenant-b", 42, createdAt.Add(-time.Minute), "alice@example.com", "alice@example.com").
+					WillReturnRows(tc.firstRows)
+			}
+
+			afterID, err := repository.NewOutboxEventRepository(db).WithTenant("tenant-b").ResumeAfterID("alice@example.com", 42, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, afterID)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
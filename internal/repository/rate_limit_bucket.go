@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rateLimitBucketRepository struct {
+	db *gorm.DB
+}
+
+// RateLimitBucketRepository is the rate limit store for deployments with more than one instance
+type RateLimitBucketRepository interface {
+	ratelimit.Store
+	DeleteIdle(before time.Time) (int64, error)
+}
+
+func NewRateLimitBucketRepository(db *gorm.DB) RateLimitBucketRepository {
+	return &rateLimitBucketRepository{db: db}
+}
+
+// Take consume cost tokens of the bucket of the key, the row is locked so concurrent instances never spend the same token
+func (r *rateLimitBucketRepository) Take(key string, budget ratelimit.Budget, cost int, now time.Time) (ratelimit.Result, error) {
+	var result ratelimit.Result
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		full := model.RateLimitBucket{Key: key, Tokens: float64(budget.Limit), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&full).Error; err != nil {
+			return err
+		}
+
+		var row model.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		bucket := ratelimit.Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}
+		result = bucket.Take(budget, cost, now)
+		return tx.Model(&model.RateLimitBucket{}).
+			Where("key = ?", key).
+			Updates(map[string]interface{}{
+				"tokens":     bucket.Tokens,
+				"updated_at": bucket.UpdatedAt,
+			}).Error
+	})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return result, nil
+}
+
+// DeleteIdle delete the buckets not used since before, they are full again so deleting them does not change any limit
+func (r *rateLimitBucketRepository) DeleteIdle(before time.Time) (int64, error) {
+	result := r.db.Where("updated_at < ?", before).Delete(&model.RateLimitBucket{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitBucketTake(t *testing.T) {
+	budget := ratelimit.Budget{Name: "write", Limit: 3, Period: 3 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)
+
+	tcs := map[string]struct {
+		tokens    float64
+		updatedAt time.Time
+		expTokens float64
+		allowed   bool
+	}{
+		"NewBucket": {
+			tokens:    3,
+			updatedAt: now,
+			expTokens: 2,
+			allowed:   true,
+		},
+		"Refilled": {
+			tokens:    0,
+			updatedAt: now.Add(-2 * time.Second),
+			expTokens: 1,
+			allowed:   true,
+		},
+		"Empty": {
+			tokens:    0.5,
+			updatedAt: now,
+			expTokens: 0.5,
+			allowed:   false,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+
+			repo := repository.NewRateLimitBucketRepository(db)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "rate_limit_buckets" ("key","tokens","updated_at") VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`)).
+				WithArgs("write:ip:10.0.0.1", float64(3), now).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "rate_limit_buckets" WHERE key = $1 ORDER BY "rate_limit_buckets"."key" LIMIT $2 FOR UPDATE`)).
+				WithArgs("write:ip:10.0.0.1", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"key", "tokens", "updated_at"}).AddRow("write:ip:10.0.0.1", tc.tokens, tc.updatedAt))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "rate_limit_buckets" SET "tokens"=$1,"updated_at"=$2 WHERE key = $3`)).
+				WithArgs(tc.expTokens, now, "write:ip:10.0.0.1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			result, err := repo.Take("write:ip:10.0.0.1", budget, 1, now)
+			require.NoError(t, err)
+			require.Equal(t, tc.allowed, result.Allowed)
+			require.Equal(t, 3, result.Limit)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRateLimitBucketTake_Error(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewRateLimitBucketRepository(db)
+	budget := ratelimit.Budget{Name: "write", Limit: 3, Period: time.Minute}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "rate_limit_buckets"`)).
+		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	_, err := repo.Take("write:ip:10.0.0.1", budget, 1, time.Now())
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRateLimitBucketDeleteIdle(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewRateLimitBucketRepository(db)
+	before := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "rate_limit_buckets" WHERE updated_at < $1`)).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+
+	deleted, err := repo.DeleteIdle(before)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+)
+
+// RelationshipEventFilter filter the relationship events listed by admins, empty fields are not applied
+type RelationshipEventFilter struct {
+	//Email match the requestor or the target
+	Email  string     `json:"email,omitempty"`
+	Action string     `json:"action,omitempty"`
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+type relationshipEventRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// RelationshipEventRepository all the functions to append and query the history of the relationship graph, events are never updated or deleted
+type RelationshipEventRepository interface {
+	Create(event *model.RelationshipEvent) error
+	List(filter RelationshipEventFilter) ([]model.RelationshipEvent, int64, error)
+	CountCreatedSince(requestor string, since time.Time) (int64, error)
+	WithTx(tx *gorm.DB) RelationshipEventRepository
+	WithTenant(tenantID string) RelationshipEventRepository
+}
+
+func NewRelationshipEventRepository(db *gorm.DB) RelationshipEventRepository {
+	return &relationshipEventRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the events of the tenant
+func (r *relationshipEventRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
+}
+
+// Create support append one event in the tenant
+func (r *relationshipEventRepository) Create(event *model.RelationshipEvent) error {
+	event.TenantID = r.tenantID
+	return r.db.Create(event).Error
+}
+
+// List support query one page of the events matching the filter, oldest first, and the total count
+func (r *relationshipEventRepository) List(filter RelationshipEventFilter) ([]model.RelationshipEvent, int64, error) {
+	query := r.scoped().Model(&model.RelationshipEvent{})
+	if len(filter.Email) > 0 {
+		query = query.Where("requestor_email = ? OR target_email = ?", filter.Email, filter.Email)
+	}
+	if len(filter.Action) > 0 {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []model.RelationshipEvent
+	if err := query.Session(&gorm.Session{}).Order("id").Limit(filter.Limit).Offset(filter.Offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// CountCreatedSince support count the relationships the requestor created since the time, the relationships removed since then
+// are still counted
+func (r *relationshipEventRepository) CountCreatedSince(requestor string, since time.Time) (int64, error) {
+	var total int64
+	if err := r.scoped().Model(&model.RelationshipEvent{}).
+		Where("requestor_email = ? AND action IN ? AND created_at >= ?", requestor, []string{constant.RELATIONSHIP_EVENT_CREATE, constant.RELATIONSHIP_EVENT_BLOCK}, since).
+		Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *relationshipEventRepository) WithTx(tx *gorm.DB) RelationshipEventRepository {
+	return &relationshipEventRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that only read and append the events of the tenant
+func (r *relationshipEventRepository) WithTenant(tenantID string) RelationshipEventRepository {
+	return &relationshipEventRepository{db: r.db, tenantID: tenantID}
+}
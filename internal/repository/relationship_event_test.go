@@ -0,0 +1,122 @@
+package repository_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationshipEventCreate(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewRelationshipEventRepository(db).WithTenant("tenant-b")
+	event := &model.RelationshipEvent{
+		Action:         constant.RELATIONSHIP_EVENT_BLOCK,
+		RequestorEmail: "alice@example.com",
+		TargetEmail:    "bob@example.com",
+		Before:         []model.RelationshipState{{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.SUBSCRIBER_RELATIONSHIOP_TYPE}},
+		After:          []model.RelationshipState{{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.BLOCK_RELATIONSHIP_TYPE}},
+		Actor:          "alice@example.com",
+		IP:             "10.0.0.1",
+		UserAgent:      "curl/8.0",
+		CreatedAt:      time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "relationship_events"`)).
+		WithArgs("tenant-b", event.Action, event.RequestorEmail, event.TargetEmail,
+			`[{"requestor":"alice@example.com","target":"bob@example.com","type":"SUBSCRIBER"}]`,
+			`[{"requestor":"alice@example.com","target":"bob@example.com","type":"BLOCK"}]`,
+			event.Actor, event.IP, event.UserAgent, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Create(event))
+	require.Equal(t, uint(1), event.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelationshipEventList(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tcs := map[string]struct {
+		filter repository.RelationshipEventFilter
+		where  string
+		args   []driver.Value
+	}{
+		"NoFilter": {
+			filter: repository.RelationshipEventFilter{Limit: 20},
+			where:  `WHERE tenant_id = $1`,
+			args:   []driver.Value{constant.DEFAULT_TENANT_ID},
+		},
+		"EmailAndTimeRange": {
+			filter: repository.RelationshipEventFilter{Email: "alice@example.com", Action: constant.RELATIONSHIP_EVENT_DELETE, From: &from, To: &to, Limit: 20, Offset: 40},
+			where:  `WHERE tenant_id = $1 AND (requestor_email = $2 OR target_email = $3) AND action = $4 AND created_at >= $5 AND created_at <= $6`,
+			args:   []driver.Value{constant.DEFAULT_TENANT_ID, "alice@example.com", "alice@example.com", constant.RELATIONSHIP_EVENT_DELETE, from, to},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+
+			repo := repository.NewRelationshipEventRepository(db)
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "relationship_events" ` + tc.where)).
+				WithArgs(tc.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(41))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "relationship_events" ` + tc.where + ` ORDER BY id`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "action", "requestor_email", "target_email", "before", "after"}).
+					AddRow(41, constant.RELATIONSHIP_EVENT_DELETE, "alice@example.com", "bob@example.com", `[{"requestor":"alice@example.com","target":"bob@example.com","type":"FRIEND"}]`, nil))
+
+			events, total, err := repo.List(tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, int64(41), total)
+			require.Len(t, events, 1)
+			require.Equal(t, []model.RelationshipState{{Requestor: "alice@example.com", Target: "bob@example.com", Type: constant.FRIEND_RELATIONSHIP_TYPE}}, events[0].Before)
+			require.Empty(t, events[0].After)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRelationshipEventList_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewRelationshipEventRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "relationship_events"`)).
+		WillReturnError(sql.ErrConnDone)
+
+	_, _, err := repo.List(repository.RelationshipEventFilter{Limit: 20})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestRelationshipEventCountCreatedSince(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := repository.NewRelationshipEventRepository(db).WithTenant("tenant-b")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "relationship_events" WHERE tenant_id = $1 AND (requestor_email = $2 AND action IN ($3,$4) AND created_at >= $5)`)).
+		WithArgs("tenant-b", "alice@example.com", constant.RELATIONSHIP_EVENT_CREATE, constant.RELATIONSHIP_EVENT_BLOCK, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	total, err := repo.CountCreatedSince("alice@example.com", since)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// feedEntryBatchSize is the number of feed entries inserted per statement
+const feedEntryBatchSize = 1000
+
+type statusUpdateRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// StatusUpdateRepository all the functions to post status updates and read the feeds of a tenant
+type StatusUpdateRepository interface {
+	Create(update *model.StatusUpdate) error
+	CreateFeedEntries(statusUpdateID uint, owners []string, at time.Time) error
+	ListFeed(email string, before uint, limit int) ([]model.StatusUpdate, error)
+	WithTx(tx *gorm.DB) StatusUpdateRepository
+	WithTenant(tenantID string) StatusUpdateRepository
+}
+
+func NewStatusUpdateRepository(db *gorm.DB) StatusUpdateRepository {
+	return &statusUpdateRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the rows of the tenant
+func (r *statusUpdateRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
+}
+
+// Create support store a status update in the tenant
+func (r *statusUpdateRepository) Create(update *model.StatusUpdate) error {
+	update.TenantID = r.tenantID
+	return r.db.Create(update).Error
+}
+
+// CreateFeedEntries support write the status update to the feed of every owner, an owner that already has it is skipped
+func (r *statusUpdateRepository) CreateFeedEntries(statusUpdateID uint, owners []string, at time.Time) error {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	entries := make([]model.FeedEntry, 0, len(owners))
+	for _, owner := range owners {
+		entries = append(entries, model.FeedEntry{TenantID: r.tenantID, OwnerEmail: owner, StatusUpdateID: statusUpdateID, CreatedAt: at})
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(entries, feedEntryBatchSize).Error
+}
+
+// ListFeed support query one page of the feed of the email, newest first and before an id when it is not 0.
+// The feed is the updates written to it and the updates fanned out on read by the authors the email is friend with or subscribed to,
+// without the updates of the authors the email blocked.
+func (r *statusUpdateRepository) ListFeed(email string, before uint, limit int) ([]model.StatusUpdate, error) {
+	written := r.db.Model(&model.FeedEntry{}).Select("status_update_id").
+		Where("tenant_id = ? AND owner_email = ?", r.tenantID, email)
+	followed := r.db.Model(&model.UserRelationship{}).Select("target_email").
+		Where("tenant_id = ? AND requestor_email = ? AND type IN ?", r.tenantID, email, []string{constant.FRIEND_RELATIONSHIP_TYPE, constant.SUBSCRIBER_RELATIONSHIOP_TYPE})
+	blocked := r.db.Model(&model.UserRelationship{}).Select("target_email").
+		Where("tenant_id = ? AND requestor_email = ? AND type = ?", r.tenantID, email, constant.BLOCK_RELATIONSHIP_TYPE)
+
+	//Updates fanned out on read are read from the followed authors, and the reader does not see the authors it blocked whether
+	//the update was written to its feed before the block or is fanned out on read
+	query := r.scoped().Where("(id IN (?) OR (fan_out = ? AND author_email IN (?))) AND author_email NOT IN (?)", written, constant.FAN_OUT_ON_READ, followed, blocked)
+	if before > 0 {
+		query = query.Where("id < ?", before)
+	}
+
+	var updates []model.StatusUpdate
+	if err := query.Order("id DESC").Limit(limit).Find(&updates).Error; err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *statusUpdateRepository) WithTx(tx *gorm.DB) StatusUpdateRepository {
+	return &statusUpdateRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that operate on the rows of the tenant
+func (r *statusUpdateRepository) WithTenant(tenantID string) StatusUpdateRepository {
+	return &statusUpdateRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,90 @@
+package repository_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusUpdateCreate(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	update := &model.StatusUpdate{AuthorEmail: "alice@example.com", Text: "hello", FanOut: constant.FAN_OUT_ON_WRITE, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "status_updates"`)).
+		WithArgs("tenant-b", update.AuthorEmail, update.Text, update.FanOut, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewStatusUpdateRepository(db).WithTenant("tenant-b").Create(update))
+	require.Equal(t, uint(7), update.ID)
+	require.Equal(t, "tenant-b", update.TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusUpdateCreateFeedEntries(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "feed_entries" ("tenant_id","owner_email","status_update_id","created_at") VALUES ($1,$2,$3,$4),($5,$6,$7,$8) ON CONFLICT DO NOTHING RETURNING "id"`)).
+		WithArgs("tenant-b", "bob@example.com", 7, at, "tenant-b", "carol@example.com", 7, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	repo := repository.NewStatusUpdateRepository(db).WithTenant("tenant-b")
+	require.NoError(t, repo.CreateFeedEntries(7, []string{"bob@example.com", "carol@example.com"}, at))
+	require.NoError(t, repo.CreateFeedEntries(7, nil, at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusUpdateListFeed(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "status_updates" WHERE tenant_id = $1 AND `+
+		`((id IN (SELECT "status_update_id" FROM "feed_entries" WHERE tenant_id = $2 AND owner_email = $3) `+
+		`OR (fan_out = $4 AND author_email IN (SELECT "target_email" FROM "user_relationships" WHERE tenant_id = $5 AND requestor_email = $6 AND type IN ($7,$8)))) `+
+		`AND author_email NOT IN (SELECT "target_email" FROM "user_relationships" WHERE tenant_id = $9 AND requestor_email = $10 AND type = $11)) `+
+		`AND id < $12 ORDER BY id DESC LIMIT $13`)).
+		WithArgs("tenant-b", "tenant-b", "bob@example.com", constant.FAN_OUT_ON_READ, "tenant-b", "bob@example.com",
+			constant.FRIEND_RELATIONSHIP_TYPE, constant.SUBSCRIBER_RELATIONSHIOP_TYPE,
+			"tenant-b", "bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE, 50, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_email", "text"}).
+			AddRow(49, "alice@example.com", "hello").
+			AddRow(12, "celebrity@example.com", "hi all"))
+
+	updates, err := repository.NewStatusUpdateRepository(db).WithTenant("tenant-b").ListFeed("bob@example.com", 50, 20)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	require.Equal(t, uint(49), updates[0].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusUpdateListFeed_BlockedAfterPosting(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	//bob blocked alice after her update was written to his feed, the block filter applies to the written entries too
+	mock.ExpectQuery(regexp.QuoteMeta(`((id IN (SELECT "status_update_id" FROM "feed_entries" WHERE tenant_id = $2 AND owner_email = $3) OR (`)+`.*`+
+		regexp.QuoteMeta(`)) AND author_email NOT IN (SELECT "target_email" FROM "user_relationships" WHERE tenant_id = $9 AND requestor_email = $10 AND type = $11)) ORDER BY id DESC`)).
+		WithArgs("tenant-b", "tenant-b", "bob@example.com", constant.FAN_OUT_ON_READ, "tenant-b", "bob@example.com",
+			constant.FRIEND_RELATIONSHIP_TYPE, constant.SUBSCRIBER_RELATIONSHIOP_TYPE,
+			"tenant-b", "bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_email", "text"}).AddRow(12, "celebrity@example.com", "hi all"))
+
+	updates, err := repository.NewStatusUpdateRepository(db).WithTenant("tenant-b").ListFeed("bob@example.com", 0, 20)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.Equal(t, "celebrity@example.com", updates[0].AuthorEmail)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
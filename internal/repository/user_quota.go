@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type userQuotaRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// UserQuotaRepository all the functions to manage the quotas overridden by admins
+type UserQuotaRepository interface {
+	GetByEmail(email string) (*model.UserQuota, error)
+	Upsert(quota *model.UserQuota) error
+	DeleteByEmail(email string) (int64, error)
+	Lock(emails ...string) error
+	WithTx(tx *gorm.DB) UserQuotaRepository
+	WithTenant(tenantID string) UserQuotaRepository
+}
+
+func NewUserQuotaRepository(db *gorm.DB) UserQuotaRepository {
+	return &userQuotaRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the quotas of the tenant
+func (r *userQuotaRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
+}
+
+// GetByEmail support query the quota override of the email, it returns gorm.ErrRecordNotFound when the email has none
+func (r *userQuotaRepository) GetByEmail(email string) (*model.UserQuota, error) {
+	var quota model.UserQuota
+	if err := r.scoped().Where("email = ?", email).First(&quota).Error; err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// Upsert create the quota override of the email or replace every cap of the existing one
+func (r *userQuotaRepository) Upsert(quota *model.UserQuota) error {
+	quota.TenantID = r.tenantID
+	quota.CreatedAt = time.Now()
+	quota.UpdatedAt = time.Now()
+
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_friends", "max_subscriptions", "max_blocks", "max_new_per_day", "updated_by", "updated_at"}),
+	}).Create(quota).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteByEmail delete the quota override of the email so the defaults apply again
+func (r *userQuotaRepository) DeleteByEmail(email string) (int64, error) {
+	result := r.scoped().Where("email = ?", email).Delete(&model.UserQuota{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// Lock take the transaction lock of the quotas of the emails, the lock is released at the end of the transaction.
+// The emails are locked in a stable order so two transactions locking the same emails never wait on each other.
+func (r *userQuotaRepository) Lock(emails ...string) error {
+	sorted := append([]string(nil), emails...)
+	sort.Strings(sorted)
+	for _, email := range sorted {
+		if err := r.db.Exec("SELECT pg_advisory_xact_lock(hashtext(?), hashtext(?))", r.tenantID, email).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *userQuotaRepository) WithTx(tx *gorm.DB) UserQuotaRepository {
+	return &userQuotaRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that only read and write the quotas of the tenant
+func (r *userQuotaRepository) WithTenant(tenantID string) UserQuotaRepository {
+	return &userQuotaRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,133 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserQuotaGetByEmail(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserQuotaRepository(db).WithTenant("tenant-b")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_quota" WHERE tenant_id = $1 AND email = $2`)).
+		WithArgs("tenant-b", "alice@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "email", "max_friends", "max_new_per_day"}).
+			AddRow(1, "tenant-b", "alice@example.com", 10000, nil))
+
+	quota, err := repo.GetByEmail("alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, int64(10000), *quota.MaxFriends)
+	require.Nil(t, quota.MaxNewPerDay)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserQuotaGetByEmail_NotFound(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserQuotaRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_quota" WHERE tenant_id = $1 AND email = $2`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "alice@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.GetByEmail("alice@example.com")
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
+
+func TestUserQuotaUpsert(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserQuotaRepository(db).WithTenant("tenant-b")
+	maxFriends := int64(10000)
+	quota := &model.UserQuota{Email: "alice@example.com", MaxFriends: &maxFriends, UpdatedBy: "ops@example.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_quota" ("tenant_id","email","max_friends","max_subscriptions","max_blocks","max_new_per_day","updated_by","created_at","updated_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) ON CONFLICT ("tenant_id","email") DO UPDATE SET "max_friends"="excluded"."max_friends","max_subscriptions"="excluded"."max_subscriptions","max_blocks"="excluded"."max_blocks","max_new_per_day"="excluded"."max_new_per_day","updated_by"="excluded"."updated_by","updated_at"="excluded"."updated_at" RETURNING "id"`)).
+		WithArgs("tenant-b", "alice@example.com", maxFriends, nil, nil, nil, "ops@example.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Upsert(quota))
+	require.Equal(t, "tenant-b", quota.TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserQuotaDeleteByEmail(t *testing.T) {
+	tcs := map[string]struct {
+		rows    int64
+		err     error
+		deleted int64
+	}{
+		"Deleted": {
+			rows:    1,
+			deleted: 1,
+		},
+		"NoOverride": {
+			rows:    0,
+			deleted: 0,
+		},
+		"FailDatabase": {
+			err: sql.ErrConnDone,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+
+			repo := repository.NewUserQuotaRepository(db)
+
+			mock.ExpectBegin()
+			exec := mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_quota" WHERE tenant_id = $1 AND email = $2`)).
+				WithArgs(constant.DEFAULT_TENANT_ID, "alice@example.com")
+			if tc.err != nil {
+				exec.WillReturnError(tc.err)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tc.rows))
+				mock.ExpectCommit()
+			}
+
+			deleted, err := repo.DeleteByEmail("alice@example.com")
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.deleted, deleted)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserQuotaLock(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserQuotaRepository(db).WithTenant("tenant-b")
+
+	//The emails are locked in order whatever the order of the call
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`)).
+		WithArgs("tenant-b", "alice@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`)).
+		WithArgs("tenant-b", "bob@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.Lock("bob@example.com", "alice@example.com"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
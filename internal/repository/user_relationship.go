@@ -1,38 +1,77 @@
 package repository
 
 import (
+	"strings"
 	"time"
 
 	"github.com/quanluong166/friends_management/internal/constant"
 	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/pkg/utils"
 	"gorm.io/gorm"
 )
 
 type userRelationshipRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	tenantID string
+}
+
+// RelationshipFilter filter the relationships listed by admins, empty fields are not applied
+type RelationshipFilter struct {
+	//Email match the requestor or the target
+	Email  string     `json:"email,omitempty"`
+	Type   string     `json:"type,omitempty"`
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
 }
 
 // UserRelationshipController all the functions to support operate and manage user relationships
 type UserRelationshipRepository interface {
 	CreateFriendRelationship(email1, email2 string) error
-	UpdateToFriendship(email1, email2 string) error
 	GetListSubscriberEmail(target string) ([]string, error)
+	GetListSubscriberEmailWithPagination(target string, limit, offset int) ([]string, int64, error)
+	GetListBlockedEmailWithPagination(requestor string, limit, offset int) ([]string, int64, error)
 	GetListFriendshipEmail(requestor string) ([]string, error)
+	GetListFriendshipEmailAsOf(requestor string, asOf time.Time) ([]string, error)
+	GetListSubscriberEmailAsOfWithPagination(target string, asOf time.Time, limit, offset int) ([]string, int64, error)
+	GetListBlockedEmailAsOfWithPagination(requestor string, asOf time.Time, limit, offset int) ([]string, int64, error)
 	AddSubscriber(requestor, target string) error
 	CreateBlockRelationship(requestor, target string) error
 	CheckTwoUsersBlockedEachOther(email1, email2 string) (bool, error)
 	CheckTwoUsersAreFriends(email1, email2 string) (bool, error)
 	CheckIfTheRequestorAlreadySubscribe(email1, email2 string) (bool, error)
 	DeleteRelationship(email1, email2 string) error
+	GetRelationshipsBetween(email1, email2 string) ([]model.UserRelationship, error)
+	DeleteRelationshipByType(requestor, target, relationshipType string) (int64, error)
+	GetTargetEmailsByRequestors(requestors []string, relationshipType string) (map[string][]string, error)
+	GetRequestorEmailsByTargets(targets []string, relationshipType string) (map[string][]string, error)
+	GetBlockConnectionEmailsByEmails(emails []string) (map[string][]string, error)
+	GetContactEmails(email string, emails []string) ([]string, error)
+	GetKnownEmails(emails []string) ([]string, error)
+	ListRelationships(filter RelationshipFilter) ([]model.UserRelationship, int64, error)
+	GetRelationshipByID(id uint) (*model.UserRelationship, error)
+	DeleteRelationshipByID(id uint) (int64, error)
+	CountRelationshipsByType(requestor, relationshipType string) (int64, error)
+	CountFriendsAndSubscribers(email string) (int64, error)
+	WithTx(tx *gorm.DB) UserRelationshipRepository
+	WithTenant(tenantID string) UserRelationshipRepository
 }
 
+// NewUserRelationshipRepository create repository of the default tenant, use WithTenant to operate on another tenant
 func NewUserRelationshipRepository(db *gorm.DB) UserRelationshipRepository {
-	return &userRelationshipRepository{db}
+	return &userRelationshipRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the rows of the tenant, every query must start from it
+func (r *userRelationshipRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
 }
 
 // CreateFriendRelationship support create friend connection
 func (r *userRelationshipRepository) CreateFriendRelationship(email1, email2 string) error {
 	fristRelationship := &model.UserRelationship{
+		TenantID:       r.tenantID,
 		RequestorEmail: email1,
 		TargetEmail:    email2,
 		Type:           constant.FRIEND_RELATIONSHIP_TYPE,
@@ -40,16 +79,13 @@ func (r *userRelationshipRepository) CreateFriendRelationship(email1, email2 str
 		UpdatedAt:      time.Now(),
 	}
 
-	if err := r.db.Create(fristRelationship).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.create(fristRelationship)
 }
 
 // GetListSubscriberEmail support query all the subscriber connection of the target email
 func (r *userRelationshipRepository) GetListSubscriberEmail(target string) ([]string, error) {
 	var relationships []model.UserRelationship
-	err := r.db.Where("target_email = ? AND type = ?", target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).Find(&relationships).Error
+	err := r.scoped().Where("target_email = ? AND type = ?", target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).Find(&relationships).Error
 	if err != nil {
 		return nil, err
 	}
@@ -62,10 +98,61 @@ func (r *userRelationshipRepository) GetListSubscriberEmail(target string) ([]st
 	return subscriberEmails, nil
 }
 
+// GetListSubscriberEmailWithPagination support query one page of the subscriber connection of the target email and the total count
+func (r *userRelationshipRepository) GetListSubscriberEmailWithPagination(target string, limit, offset int) ([]string, int64, error) {
+	var total int64
+	if err := r.scoped().Model(&model.UserRelationship{}).
+		Where("target_email = ? AND type = ?", target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var relationships []model.UserRelationship
+	if err := r.scoped().
+		Where("target_email = ? AND type = ?", target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		Order("id").Limit(limit).Offset(offset).
+		Find(&relationships).Error; err != nil {
+		return nil, 0, err
+	}
+
+	subscriberEmails := make([]string, 0, len(relationships))
+	for _, relationship := range relationships {
+		subscriberEmails = append(subscriberEmails, relationship.RequestorEmail)
+	}
+
+	return subscriberEmails, total, nil
+}
+
+// GetListBlockedEmailWithPagination support query one page of the emails blocked by the requestor and the total count
+func (r *userRelationshipRepository) GetListBlockedEmailWithPagination(requestor string, limit, offset int) ([]string, int64, error) {
+	//Only the requestor side is queried so a user never learns who blocked them
+	var total int64
+	if err := r.scoped().Model(&model.UserRelationship{}).
+		Where("requestor_email = ? AND type = ?", requestor, constant.BLOCK_RELATIONSHIP_TYPE).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var relationships []model.UserRelationship
+	if err := r.scoped().
+		Where("requestor_email = ? AND type = ?", requestor, constant.BLOCK_RELATIONSHIP_TYPE).
+		Order("id").Limit(limit).Offset(offset).
+		Find(&relationships).Error; err != nil {
+		return nil, 0, err
+	}
+
+	blockedEmails := make([]string, 0, len(relationships))
+	for _, relationship := range relationships {
+		blockedEmails = append(blockedEmails, relationship.TargetEmail)
+	}
+
+	return blockedEmails, total, nil
+}
+
 // GetListFriendshipEmail support query all the friend connection of the requestor email
 func (r *userRelationshipRepository) GetListFriendshipEmail(requestor string) ([]string, error) {
 	var relationships []model.UserRelationship
-	err := r.db.Where("requestor_email = ? AND type = ?", requestor, constant.FRIEND_RELATIONSHIP_TYPE).Find(&relationships).Error
+	err := r.scoped().Where("requestor_email = ? AND type = ?", requestor, constant.FRIEND_RELATIONSHIP_TYPE).Find(&relationships).Error
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +168,7 @@ func (r *userRelationshipRepository) GetListFriendshipEmail(requestor string) ([
 // CheckTwoUsersBlockedEachOther support to check whether two email block the other
 func (r *userRelationshipRepository) CheckTwoUsersBlockedEachOther(email1, email2 string) (bool, error) {
 	var relationships []model.UserRelationship
-	err := r.db.Where(`
+	err := r.scoped().Where(`
     (requestor_email = ? AND target_email = ? AND type = ?) OR
     (requestor_email = ? AND target_email = ? AND type = ?)
 `, email1, email2, constant.BLOCK_RELATIONSHIP_TYPE, email2, email1, constant.BLOCK_RELATIONSHIP_TYPE).Find(&relationships).Error
@@ -100,7 +187,7 @@ func (r *userRelationshipRepository) CheckTwoUsersBlockedEachOther(email1, email
 func (r *userRelationshipRepository) CheckTwoUsersAreFriends(email1, email2 string) (bool, error) {
 	//Since the relationship is bi-directional, we only need to check one direction
 	var relationships []model.UserRelationship
-	err := r.db.Where("requestor_email = ? AND target_email = ? AND type = ?", email1, email2, constant.FRIEND_RELATIONSHIP_TYPE).Find(&relationships).Error
+	err := r.scoped().Where("requestor_email = ? AND target_email = ? AND type = ?", email1, email2, constant.FRIEND_RELATIONSHIP_TYPE).Find(&relationships).Error
 	if err != nil {
 		return false, err
 	}
@@ -111,23 +198,10 @@ func (r *userRelationshipRepository) CheckTwoUsersAreFriends(email1, email2 stri
 	return false, nil
 }
 
-// CheckTwoUsersAreFriends support to update subscriber to friend connection
-func (r *userRelationshipRepository) UpdateToFriendship(email1, email2 string) error {
-	err := r.db.Model(&model.UserRelationship{}).
-		Where("requestor_email = ? AND target_email = ? AND type = ?", email1, email2, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
-		Updates(map[string]interface{}{
-			"type":       constant.FRIEND_RELATIONSHIP_TYPE,
-			"updated_at": time.Now(),
-		}).Error
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 // AddSubscriber create subscriber connection
 func (r *userRelationshipRepository) AddSubscriber(requestor, target string) error {
 	subscription := &model.UserRelationship{
+		TenantID:       r.tenantID,
 		RequestorEmail: requestor,
 		TargetEmail:    target,
 		Type:           constant.SUBSCRIBER_RELATIONSHIOP_TYPE,
@@ -135,15 +209,13 @@ func (r *userRelationshipRepository) AddSubscriber(requestor, target string) err
 		UpdatedAt:      time.Now(),
 	}
 
-	if err := r.db.Create(subscription).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.create(subscription)
 }
 
 // CreateBlockRelationship create block connection
 func (r *userRelationshipRepository) CreateBlockRelationship(requestor, target string) error {
 	block := &model.UserRelationship{
+		TenantID:       r.tenantID,
 		RequestorEmail: requestor,
 		TargetEmail:    target,
 		Type:           constant.BLOCK_RELATIONSHIP_TYPE,
@@ -151,16 +223,13 @@ func (r *userRelationshipRepository) CreateBlockRelationship(requestor, target s
 		UpdatedAt:      time.Now(),
 	}
 
-	if err := r.db.Create(block).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.create(block)
 }
 
 // CheckIfTheRequestorAlreadySubscribe support to check if the requestor email already a subscriber of the target email
 func (r *userRelationshipRepository) CheckIfTheRequestorAlreadySubscribe(requestor, target string) (bool, error) {
 	var relationship *model.UserRelationship
-	err := r.db.Model(&relationship).
+	err := r.scoped().Model(&relationship).
 		Where("requestor_email = ? AND target_email = ? AND type = ?", requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).First(&relationship).Error
 	if err != nil {
 		return false, err
@@ -175,9 +244,344 @@ func (r *userRelationshipRepository) CheckIfTheRequestorAlreadySubscribe(request
 
 // DeleteRelationship delete the connection between the two emails
 func (r *userRelationshipRepository) DeleteRelationship(email1, email2 string) error {
-	err := r.db.Where("requestor_email = ? AND target_email = ?", email1, email2).Delete(&model.UserRelationship{}).Error
+	return r.transaction(func(tx *gorm.DB) error {
+		if err := r.closeHistory(tx, time.Now(), "requestor_email = ? AND target_email = ?", email1, email2); err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ?", r.tenantID).Where("requestor_email = ? AND target_email = ?", email1, email2).Delete(&model.UserRelationship{}).Error
+	})
+}
+
+// GetRelationshipsBetween support query every connection between the two emails in both directions
+func (r *userRelationshipRepository) GetRelationshipsBetween(email1, email2 string) ([]model.UserRelationship, error) {
+	var relationships []model.UserRelationship
+	err := r.scoped().Where("(requestor_email = ? AND target_email = ?) OR (requestor_email = ? AND target_email = ?)", email1, email2, email2, email1).
+		Order("id").Find(&relationships).Error
+	if err != nil {
+		return nil, err
+	}
+	return relationships, nil
+}
+
+// DeleteRelationshipByType delete one type of connection from the requestor to the target and return the number of deleted rows
+func (r *userRelationshipRepository) DeleteRelationshipByType(requestor, target, relationshipType string) (int64, error) {
+	var deleted int64
+	err := r.transaction(func(tx *gorm.DB) error {
+		if err := r.closeHistory(tx, time.Now(), "requestor_email = ? AND target_email = ? AND type = ?", requestor, target, relationshipType); err != nil {
+			return err
+		}
+		result := tx.Where("tenant_id = ?", r.tenantID).Where("requestor_email = ? AND target_email = ? AND type = ?", requestor, target, relationshipType).Delete(&model.UserRelationship{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// GetTargetEmailsByRequestors support query the target emails of one connection type for many requestors in a single query
+func (r *userRelationshipRepository) GetTargetEmailsByRequestors(requestors []string, relationshipType string) (map[string][]string, error) {
+	var relationships []model.UserRelationship
+	err := r.scoped().Where("requestor_email IN ? AND type = ?", requestors, relationshipType).Order("id").Find(&relationships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	targetEmails := make(map[string][]string, len(requestors))
+	for _, relationship := range relationships {
+		targetEmails[relationship.RequestorEmail] = append(targetEmails[relationship.RequestorEmail], relationship.TargetEmail)
+	}
+
+	return targetEmails, nil
+}
+
+// GetRequestorEmailsByTargets support query the requestor emails of one connection type for many targets in a single query
+func (r *userRelationshipRepository) GetRequestorEmailsByTargets(targets []string, relationshipType string) (map[string][]string, error) {
+	var relationships []model.UserRelationship
+	err := r.scoped().Where("target_email IN ? AND type = ?", targets, relationshipType).Order("id").Find(&relationships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	requestorEmails := make(map[string][]string, len(targets))
+	for _, relationship := range relationships {
+		requestorEmails[relationship.TargetEmail] = append(requestorEmails[relationship.TargetEmail], relationship.RequestorEmail)
+	}
+
+	return requestorEmails, nil
+}
+
+// GetBlockConnectionEmailsByEmails support query, for many emails in a single query, the emails that block them or are blocked by them
+func (r *userRelationshipRepository) GetBlockConnectionEmailsByEmails(emails []string) (map[string][]string, error) {
+	var relationships []model.UserRelationship
+	err := r.scoped().Where("(requestor_email IN ? OR target_email IN ?) AND type = ?", emails, emails, constant.BLOCK_RELATIONSHIP_TYPE).
+		Order("id").Find(&relationships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		requested[email] = true
+	}
+
+	blockEmails := make(map[string][]string, len(emails))
+	for _, relationship := range relationships {
+		if requested[relationship.RequestorEmail] {
+			blockEmails[relationship.RequestorEmail] = append(blockEmails[relationship.RequestorEmail], relationship.TargetEmail)
+		}
+		if requested[relationship.TargetEmail] {
+			blockEmails[relationship.TargetEmail] = append(blockEmails[relationship.TargetEmail], relationship.RequestorEmail)
+		}
+	}
+
+	return blockEmails, nil
+}
+
+// GetContactEmails support query, among the emails, the friends of the email and the emails subscribed to it.
+// The emails are compared case-insensitively and returned as given.
+func (r *userRelationshipRepository) GetContactEmails(email string, emails []string) ([]string, error) {
+	var relationships []model.UserRelationship
+	err := r.scoped().Where("LOWER(target_email) = LOWER(?) AND LOWER(requestor_email) IN ? AND type IN ?", email, utils.ToLower(emails),
+		[]string{constant.FRIEND_RELATIONSHIP_TYPE, constant.SUBSCRIBER_RELATIONSHIOP_TYPE}).Order("id").Find(&relationships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make(map[string]bool, len(relationships))
+	for _, relationship := range relationships {
+		contacts[strings.ToLower(relationship.RequestorEmail)] = true
+	}
+
+	var contactEmails []string
+	for _, email := range emails {
+		if contacts[strings.ToLower(email)] {
+			contactEmails = append(contactEmails, email)
+		}
+	}
+
+	return contactEmails, nil
+}
+
+// GetKnownEmails support query, among the emails, the ones that are part of at least one relationship.
+// The emails are compared case-insensitively and returned as given.
+func (r *userRelationshipRepository) GetKnownEmails(emails []string) ([]string, error) {
+	lowerEmails := utils.ToLower(emails)
+	var requestorEmails, targetEmails []string
+	if err := r.scoped().Model(&model.UserRelationship{}).Where("LOWER(requestor_email) IN ?", lowerEmails).Distinct().Pluck("requestor_email", &requestorEmails).Error; err != nil {
+		return nil, err
+	}
+	if err := r.scoped().Model(&model.UserRelationship{}).Where("LOWER(target_email) IN ?", lowerEmails).Distinct().Pluck("target_email", &targetEmails).Error; err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(requestorEmails)+len(targetEmails))
+	for _, email := range requestorEmails {
+		known[strings.ToLower(email)] = true
+	}
+	for _, email := range targetEmails {
+		known[strings.ToLower(email)] = true
+	}
+
+	var knownEmails []string
+	for _, email := range emails {
+		if known[strings.ToLower(email)] {
+			knownEmails = append(knownEmails, email)
+		}
+	}
+
+	return knownEmails, nil
+}
+
+// ListRelationships support query one page of relationships matching the filter and the total count
+func (r *userRelationshipRepository) ListRelationships(filter RelationshipFilter) ([]model.UserRelationship, int64, error) {
+	query := r.scoped().Model(&model.UserRelationship{})
+	if len(filter.Email) > 0 {
+		query = query.Where("requestor_email = ? OR target_email = ?", filter.Email, filter.Email)
+	}
+	if len(filter.Type) > 0 {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var relationships []model.UserRelationship
+	if err := query.Session(&gorm.Session{}).Order("id").Limit(filter.Limit).Offset(filter.Offset).Find(&relationships).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return relationships, total, nil
+}
+
+// GetRelationshipByID support query one relationship, it returns gorm.ErrRecordNotFound when the id does not exist
+func (r *userRelationshipRepository) GetRelationshipByID(id uint) (*model.UserRelationship, error) {
+	var relationship model.UserRelationship
+	if err := r.scoped().First(&relationship, id).Error; err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
+// DeleteRelationshipByID delete one relationship and return the number of deleted rows
+func (r *userRelationshipRepository) DeleteRelationshipByID(id uint) (int64, error) {
+	var deleted int64
+	err := r.transaction(func(tx *gorm.DB) error {
+		//The history is closed before the delete because the relationship row is needed to find it
+		relationship := tx.Model(&model.UserRelationship{}).Select("requestor_email, target_email, type").Where("tenant_id = ? AND id = ?", r.tenantID, id)
+		if err := r.closeHistory(tx, time.Now(), "(requestor_email, target_email, type) IN (?)", relationship); err != nil {
+			return err
+		}
+		result := tx.Where("tenant_id = ?", r.tenantID).Delete(&model.UserRelationship{}, id)
+		deleted = result.RowsAffected
+		return result.Error
+	})
 	if err != nil {
-		return err
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// CountRelationshipsByType support count one type of connection created by the requestor
+func (r *userRelationshipRepository) CountRelationshipsByType(requestor, relationshipType string) (int64, error) {
+	var total int64
+	if err := r.scoped().Model(&model.UserRelationship{}).
+		Where("requestor_email = ? AND type = ?", requestor, relationshipType).
+		Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// CountFriendsAndSubscribers support count the emails that receive the updates of the email without being mentioned
+func (r *userRelationshipRepository) CountFriendsAndSubscribers(email string) (int64, error) {
+	var total int64
+	if err := r.scoped().Model(&model.UserRelationship{}).
+		Where("(requestor_email = ? AND type = ?) OR (target_email = ? AND type = ?)", email, constant.FRIEND_RELATIONSHIP_TYPE, email, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		Count(&total).Error; err != nil {
+		return 0, err
 	}
-	return nil
+	return total, nil
+}
+
+// GetListFriendshipEmailAsOf support query all the friend connection the requestor email had at the time
+func (r *userRelationshipRepository) GetListFriendshipEmailAsOf(requestor string, asOf time.Time) ([]string, error) {
+	var histories []model.RelationshipHistory
+	err := r.historyAsOf(asOf).Where("requestor_email = ? AND type = ?", requestor, constant.FRIEND_RELATIONSHIP_TYPE).
+		Order("id").Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	friendshipEmails := make([]string, 0, len(histories))
+	for _, history := range histories {
+		friendshipEmails = append(friendshipEmails, history.TargetEmail)
+	}
+
+	return friendshipEmails, nil
+}
+
+// GetListSubscriberEmailAsOfWithPagination support query one page of the subscriber connection the target email had at the time and the total count
+func (r *userRelationshipRepository) GetListSubscriberEmailAsOfWithPagination(target string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	query := r.historyAsOf(asOf).Where("target_email = ? AND type = ?", target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var histories []model.RelationshipHistory
+	if err := query.Session(&gorm.Session{}).Order("id").Limit(limit).Offset(offset).Find(&histories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	subscriberEmails := make([]string, 0, len(histories))
+	for _, history := range histories {
+		subscriberEmails = append(subscriberEmails, history.RequestorEmail)
+	}
+
+	return subscriberEmails, total, nil
+}
+
+// GetListBlockedEmailAsOfWithPagination support query one page of the emails blocked by the requestor at the time and the total count
+func (r *userRelationshipRepository) GetListBlockedEmailAsOfWithPagination(requestor string, asOf time.Time, limit, offset int) ([]string, int64, error) {
+	query := r.historyAsOf(asOf).Where("requestor_email = ? AND type = ?", requestor, constant.BLOCK_RELATIONSHIP_TYPE)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var histories []model.RelationshipHistory
+	if err := query.Session(&gorm.Session{}).Order("id").Limit(limit).Offset(offset).Find(&histories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	blockedEmails := make([]string, 0, len(histories))
+	for _, history := range histories {
+		blockedEmails = append(blockedEmails, history.TargetEmail)
+	}
+
+	return blockedEmails, total, nil
+}
+
+// historyAsOf return the history rows of the tenant that were valid at the time
+func (r *userRelationshipRepository) historyAsOf(asOf time.Time) *gorm.DB {
+	return r.db.Model(&model.RelationshipHistory{}).Where("tenant_id = ?", r.tenantID).
+		Where("valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", asOf, asOf)
+}
+
+// create insert the relationship and open its history in one transaction
+func (r *userRelationshipRepository) create(relationship *model.UserRelationship) error {
+	return r.transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(relationship).Error; err != nil {
+			return err
+		}
+		return r.openHistory(tx, relationship.RequestorEmail, relationship.TargetEmail, relationship.Type, relationship.CreatedAt)
+	})
+}
+
+// openHistory record that the relationship is valid from the time
+func (r *userRelationshipRepository) openHistory(tx *gorm.DB, requestor, target, relationshipType string, validFrom time.Time) error {
+	return tx.Create(&model.RelationshipHistory{
+		TenantID:       r.tenantID,
+		RequestorEmail: requestor,
+		TargetEmail:    target,
+		Type:           relationshipType,
+		ValidFrom:      validFrom,
+	}).Error
+}
+
+// closeHistory end at the time the open history rows of the tenant matching the condition
+func (r *userRelationshipRepository) closeHistory(tx *gorm.DB, validTo time.Time, query string, args ...interface{}) error {
+	return tx.Model(&model.RelationshipHistory{}).Where("tenant_id = ?", r.tenantID).
+		Where(query, args...).Where("valid_to IS NULL").
+		Update("valid_to", validTo).Error
+}
+
+// transaction run fn in the transaction of the repository, or in a new one when the repository is not bound to a transaction
+func (r *userRelationshipRepository) transaction(fn func(tx *gorm.DB) error) error {
+	if _, ok := r.db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return fn(r.db)
+	}
+	return r.db.Transaction(fn)
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *userRelationshipRepository) WithTx(tx *gorm.DB) UserRelationshipRepository {
+	return &userRelationshipRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that only read and write the rows of the tenant
+func (r *userRelationshipRepository) WithTenant(tenantID string) UserRelationshipRepository {
+	return &userRelationshipRepository{db: r.db, tenantID: tenantID}
 }
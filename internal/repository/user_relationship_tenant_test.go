@@ -0,0 +1,351 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	tenantA = "tenant-a"
+	tenantB = "tenant-b"
+)
+
+// statementRecorder is a gorm logger that keep every executed statement with its values inlined
+type statementRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *statementRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+func setupTenantMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, *statementRecorder) {
+	//Statements are checked through the recorder, the mock only has to answer them in order
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(string, string) error { return nil })))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	recorder := &statementRecorder{Interface: logger.Default.LogMode(logger.Silent)}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{Logger: recorder})
+	require.NoError(t, err)
+	return gdb, mock, recorder
+}
+
+func relationshipRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "tenant_id", "requestor_email", "target_email", "type"}).
+		AddRow(1, tenantB, "alice@example.com", "bob@example.com", constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+}
+
+func expectSelect(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("").WillReturnRows(relationshipRows())
+}
+
+func expectCount(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+}
+
+func expectInsert(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+func expectWrite(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectInsertWithHistory answer the insert of a relationship and of its open history
+func expectInsertWithHistory(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+// expectDeleteWithHistory answer the close of the history and the delete of the relationship
+func expectDeleteWithHistory(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// insertTenantPattern match an insert whose first column is tenant_id and capture the inserted tenant
+var insertTenantPattern = regexp.MustCompile(`^INSERT INTO "(user_relationships|relationship_histories)" \("tenant_id",.*\) VALUES \('([^']*)',`)
+
+// TestUserRelationshipRepository_TenantIsolation run every repository method as tenant-b and check no statement can read or write
+// the rows of another tenant: reads, updates and deletes are filtered by tenant_id and inserts are written in tenant-b.
+func TestUserRelationshipRepository_TenantIsolation(t *testing.T) {
+	emails := []string{"alice@example.com", "bob@example.com"}
+
+	tcs := map[string]struct {
+		expect func(mock sqlmock.Sqlmock)
+		call   func(repo repository.UserRelationshipRepository) error
+	}{
+		"CreateFriendRelationship": {
+			expect: expectInsertWithHistory,
+			call: func(repo repository.UserRelationshipRepository) error {
+				return repo.CreateFriendRelationship(emails[0], emails[1])
+			},
+		},
+		"GetListSubscriberEmail": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetListSubscriberEmail(emails[1])
+				return err
+			},
+		},
+		"GetListSubscriberEmailWithPagination": {
+			expect: func(mock sqlmock.Sqlmock) { expectCount(mock); expectSelect(mock) },
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, _, err := repo.GetListSubscriberEmailWithPagination(emails[1], 10, 0)
+				return err
+			},
+		},
+		"GetListBlockedEmailWithPagination": {
+			expect: func(mock sqlmock.Sqlmock) { expectCount(mock); expectSelect(mock) },
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, _, err := repo.GetListBlockedEmailWithPagination(emails[0], 10, 0)
+				return err
+			},
+		},
+		"GetListFriendshipEmail": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetListFriendshipEmail(emails[0])
+				return err
+			},
+		},
+		"AddSubscriber": {
+			expect: expectInsertWithHistory,
+			call: func(repo repository.UserRelationshipRepository) error {
+				return repo.AddSubscriber(emails[0], emails[1])
+			},
+		},
+		"CreateBlockRelationship": {
+			expect: expectInsertWithHistory,
+			call: func(repo repository.UserRelationshipRepository) error {
+				return repo.CreateBlockRelationship(emails[0], emails[1])
+			},
+		},
+		"GetListFriendshipEmailAsOf": {
+			//The OR of the validity interval must not escape the tenant condition
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetListFriendshipEmailAsOf(emails[0], time.Now().Add(-24*time.Hour))
+				return err
+			},
+		},
+		"GetListSubscriberEmailAsOfWithPagination": {
+			expect: func(mock sqlmock.Sqlmock) { expectCount(mock); expectSelect(mock) },
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, _, err := repo.GetListSubscriberEmailAsOfWithPagination(emails[1], time.Now().Add(-24*time.Hour), 10, 0)
+				return err
+			},
+		},
+		"GetListBlockedEmailAsOfWithPagination": {
+			expect: func(mock sqlmock.Sqlmock) { expectCount(mock); expectSelect(mock) },
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, _, err := repo.GetListBlockedEmailAsOfWithPagination(emails[0], time.Now().Add(-24*time.Hour), 10, 0)
+				return err
+			},
+		},
+		"CheckTwoUsersBlockedEachOther": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.CheckTwoUsersBlockedEachOther(emails[0], emails[1])
+				return err
+			},
+		},
+		"CheckTwoUsersAreFriends": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.CheckTwoUsersAreFriends(emails[0], emails[1])
+				return err
+			},
+		},
+		"CheckIfTheRequestorAlreadySubscribe": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.CheckIfTheRequestorAlreadySubscribe(emails[0], emails[1])
+				return err
+			},
+		},
+		"DeleteRelationship": {
+			expect: expectDeleteWithHistory,
+			call: func(repo repository.UserRelationshipRepository) error {
+				return repo.DeleteRelationship(emails[0], emails[1])
+			},
+		},
+		"DeleteRelationshipByType": {
+			expect: expectDeleteWithHistory,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.DeleteRelationshipByType(emails[0], emails[1], constant.BLOCK_RELATIONSHIP_TYPE)
+				return err
+			},
+		},
+		"GetTargetEmailsByRequestors": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetTargetEmailsByRequestors(emails, constant.FRIEND_RELATIONSHIP_TYPE)
+				return err
+			},
+		},
+		"GetRequestorEmailsByTargets": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetRequestorEmailsByTargets(emails, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+				return err
+			},
+		},
+		"GetBlockConnectionEmailsByEmails": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetBlockConnectionEmailsByEmails(emails)
+				return err
+			},
+		},
+		"ListRelationships_NoFilter": {
+			expect: func(mock sqlmock.Sqlmock) { expectCount(mock); expectSelect(mock) },
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, _, err := repo.ListRelationships(repository.RelationshipFilter{Limit: 20})
+				return err
+			},
+		},
+		"ListRelationships_EmailFilter": {
+			//The OR of the email filter must not escape the tenant condition
+			expect: func(mock sqlmock.Sqlmock) { expectCount(mock); expectSelect(mock) },
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, _, err := repo.ListRelationships(repository.RelationshipFilter{Email: emails[0], Limit: 20})
+				return err
+			},
+		},
+		"GetRelationshipByID": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetRelationshipByID(1)
+				return err
+			},
+		},
+		"GetRelationshipsBetween": {
+			//The OR of the two directions must not escape the tenant condition
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetRelationshipsBetween(emails[0], emails[1])
+				return err
+			},
+		},
+		"CountRelationshipsByType": {
+			expect: expectCount,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.CountRelationshipsByType(emails[0], constant.FRIEND_RELATIONSHIP_TYPE)
+				return err
+			},
+		},
+		"CountFriendsAndSubscribers": {
+			//The OR of the two types must not escape the tenant condition
+			expect: expectCount,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.CountFriendsAndSubscribers(emails[0])
+				return err
+			},
+		},
+		"GetContactEmails": {
+			expect: expectSelect,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetContactEmails(emails[1], emails)
+				return err
+			},
+		},
+		"GetKnownEmails": {
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"requestor_email"}).AddRow("alice@example.com"))
+				mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"target_email"}).AddRow("bob@example.com"))
+			},
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.GetKnownEmails(emails)
+				return err
+			},
+		},
+		"DeleteRelationshipByID": {
+			expect: expectDeleteWithHistory,
+			call: func(repo repository.UserRelationshipRepository) error {
+				_, err := repo.DeleteRelationshipByID(1)
+				return err
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			db, mock, recorder := setupTenantMockDB(t)
+			tc.expect(mock)
+
+			repo := repository.NewUserRelationshipRepository(db).WithTenant(tenantA).WithTenant(tenantB)
+			require.NoError(t, tc.call(repo))
+			require.NoError(t, mock.ExpectationsWereMet())
+
+			require.NotEmpty(t, recorder.statements)
+			for _, statement := range recorder.statements {
+				require.NotContains(t, statement, tenantA)
+				require.NotContains(t, statement, "'"+constant.DEFAULT_TENANT_ID+"'")
+				if strings.HasPrefix(statement, "INSERT") {
+					match := insertTenantPattern.FindStringSubmatch(statement)
+					require.NotNil(t, match, statement)
+					require.Equal(t, tenantB, match[2])
+					continue
+				}
+				tenantCondition := "WHERE tenant_id = '" + tenantB + "'"
+				require.Contains(t, statement, tenantCondition, statement)
+				//Every other condition is grouped after the tenant so an OR can not widen the query
+				where := statement[strings.Index(statement, tenantCondition)+len(tenantCondition):]
+				if strings.Contains(where, " OR ") {
+					require.True(t, strings.HasPrefix(where, " AND ("), statement)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRelationshipRepository_WithTxKeepsTenant(t *testing.T) {
+	db, mock, recorder := setupTenantMockDB(t)
+	expectDeleteWithHistory(mock)
+
+	repo := repository.NewUserRelationshipRepository(db).WithTenant(tenantB)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := repo.WithTx(tx).DeleteRelationshipByType("alice@example.com", "bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE)
+		return err
+	})
+	require.NoError(t, err)
+	//The history is written in the same transaction, without a nested savepoint
+	require.Len(t, recorder.statements, 2)
+	for _, statement := range recorder.statements {
+		require.Contains(t, statement, "WHERE tenant_id = '"+tenantB+"' AND ")
+	}
+}
+
+func TestUserRelationshipRepository_DefaultTenant(t *testing.T) {
+	db, mock, recorder := setupTenantMockDB(t)
+	expectSelect(mock)
+
+	base := repository.NewUserRelationshipRepository(db)
+	_ = base.WithTenant(tenantB)
+	_, err := base.GetListFriendshipEmail("alice@example.com")
+	require.NoError(t, err)
+	require.Contains(t, recorder.statements[0], "WHERE tenant_id = '"+constant.DEFAULT_TENANT_ID+"' AND ")
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/quanluong166/friends_management/internal/constant"
@@ -34,6 +35,26 @@ func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, func()) {
 	return gdb, mock, cleanup
 }
 
+// expectOpenHistory expect the insert of an open history row of the relationship
+func expectOpenHistory(mock sqlmock.Sqlmock, requestor, target, relationshipType string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "relationship_histories" ("tenant_id","requestor_email","target_email","type","valid_from","valid_to") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target, relationshipType, sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+// expectCloseHistory expect the open history rows of the pair to be closed, the type is only matched when it is not empty
+func expectCloseHistory(mock sqlmock.Sqlmock, requestor, target, relationshipType string) {
+	if len(relationshipType) == 0 {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "relationship_histories" SET "valid_to"=$1 WHERE tenant_id = $2 AND (requestor_email = $3 AND target_email = $4) AND valid_to IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), constant.DEFAULT_TENANT_ID, requestor, target).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		return
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "relationship_histories" SET "valid_to"=$1 WHERE tenant_id = $2 AND (requestor_email = $3 AND target_email = $4 AND type = $5) AND valid_to IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), constant.DEFAULT_TENANT_ID, requestor, target, relationshipType).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
 func TestCreateFriendRelationship_Success(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
@@ -45,8 +66,9 @@ func TestCreateFriendRelationship_Success(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(email1, email2, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, email1, email2, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectOpenHistory(mock, email1, email2, constant.FRIEND_RELATIONSHIP_TYPE)
 
 	mock.ExpectCommit()
 	err := repo.CreateFriendRelationship(email1, email2)
@@ -65,11 +87,11 @@ func TestCreateFriendRelationship_FailCreateFirstRelation(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(email1, email2, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, email1, email2, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(sql.ErrConnDone)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(email2, email1, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, email2, email1, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
 	mock.ExpectRollback()
 
@@ -94,11 +116,11 @@ func TestCreateFriendRelationship_FailCreateSecondRelation(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(email1, email2, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, email1, email2, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(email2, email1, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, email2, email1, constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(sql.ErrConnDone)
 	mock.ExpectRollback()
 
@@ -124,7 +146,7 @@ func TestGetListSubscriberEmail(t *testing.T) {
 		AddRow("john@example.com", targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
 
 	mock.ExpectQuery(`SELECT \* FROM "user_relationships"`).
-		WithArgs(targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WithArgs(constant.DEFAULT_TENANT_ID, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
 		WillReturnRows(rows)
 
 	result, err := repo.GetListSubscriberEmail(targetEmail)
@@ -143,7 +165,7 @@ func TestGetListSubscriberEmail_FailDatabase(t *testing.T) {
 	targetEmail := "bob@example.com"
 
 	mock.ExpectQuery(`SELECT \* FROM "user_relationships"`).
-		WithArgs(targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WithArgs(constant.DEFAULT_TENANT_ID, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
 		WillReturnError(sql.ErrConnDone)
 
 	result, err := repo.GetListSubscriberEmail(targetEmail)
@@ -151,6 +173,79 @@ func TestGetListSubscriberEmail_FailDatabase(t *testing.T) {
 	require.Nil(t, nil, result)
 }
 
+func TestGetListSubscriberEmailWithPagination(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	targetEmail := "bob@example.com"
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_relationships"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	rows := sqlmock.NewRows([]string{"requestor_email", "target_email", "type"}).
+		AddRow("alice@example.com", targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		AddRow("john@example.com", targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+
+	mock.ExpectQuery(`SELECT \* FROM "user_relationships" WHERE .* ORDER BY id LIMIT \$4`).
+		WithArgs(constant.DEFAULT_TENANT_ID, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, 2).
+		WillReturnRows(rows)
+
+	result, total, err := repo.GetListSubscriberEmailWithPagination(targetEmail, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice@example.com", "john@example.com"}, result)
+	require.Equal(t, int64(3), total)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetListSubscriberEmailWithPagination_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	targetEmail := "bob@example.com"
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_relationships"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WillReturnError(sql.ErrConnDone)
+
+	result, total, err := repo.GetListSubscriberEmailWithPagination(targetEmail, 2, 0)
+	require.Error(t, err)
+	require.Nil(t, result)
+	require.Equal(t, int64(0), total)
+}
+
+func TestGetListBlockedEmailWithPagination(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	requestorEmail := "alice@example.com"
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_relationships"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestorEmail, constant.BLOCK_RELATIONSHIP_TYPE).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	rows := sqlmock.NewRows([]string{"requestor_email", "target_email", "type"}).
+		AddRow(requestorEmail, "bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE)
+
+	mock.ExpectQuery(`SELECT \* FROM "user_relationships" WHERE .* ORDER BY id LIMIT \$4 OFFSET \$5`).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestorEmail, constant.BLOCK_RELATIONSHIP_TYPE, 1, 1).
+		WillReturnRows(rows)
+
+	result, total, err := repo.GetListBlockedEmailWithPagination(requestorEmail, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob@example.com"}, result)
+	require.Equal(t, int64(2), total)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetListFriendshipEmail(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
@@ -164,7 +259,7 @@ func TestGetListFriendshipEmail(t *testing.T) {
 		AddRow(requestorEmail, "test3@example.com", constant.FRIEND_RELATIONSHIP_TYPE)
 
 	mock.ExpectQuery(`SELECT \* FROM "user_relationships"`).
-		WithArgs(requestorEmail, constant.FRIEND_RELATIONSHIP_TYPE).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestorEmail, constant.FRIEND_RELATIONSHIP_TYPE).
 		WillReturnRows(rows)
 
 	result, err := repo.GetListFriendshipEmail(requestorEmail)
@@ -174,6 +269,72 @@ func TestGetListFriendshipEmail(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetListFriendshipEmailAsOf(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	requestorEmail := "alice@example.com"
+	asOf := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"requestor_email", "target_email", "type", "valid_from", "valid_to"}).
+		AddRow(requestorEmail, "bob@example.com", constant.FRIEND_RELATIONSHIP_TYPE, asOf.AddDate(0, -1, 0), asOf.AddDate(0, 1, 0))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "relationship_histories" WHERE tenant_id = $1 AND (valid_from <= $2 AND (valid_to IS NULL OR valid_to > $3)) AND (requestor_email = $4 AND type = $5) ORDER BY id`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, asOf, asOf, requestorEmail, constant.FRIEND_RELATIONSHIP_TYPE).
+		WillReturnRows(rows)
+
+	result, err := repo.GetListFriendshipEmailAsOf(requestorEmail, asOf)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob@example.com"}, result)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetListSubscriberEmailAsOfWithPagination(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	targetEmail := "alice@example.com"
+	asOf := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "relationship_histories" WHERE tenant_id = $1 AND (valid_from <= $2 AND (valid_to IS NULL OR valid_to > $3)) AND (target_email = $4 AND type = $5)`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, asOf, asOf, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	rows := sqlmock.NewRows([]string{"requestor_email", "target_email", "type"}).
+		AddRow("bob@example.com", targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+
+	mock.ExpectQuery(`SELECT \* FROM "relationship_histories" WHERE .* ORDER BY id LIMIT \$6 OFFSET \$7`).
+		WithArgs(constant.DEFAULT_TENANT_ID, asOf, asOf, targetEmail, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, 1, 1).
+		WillReturnRows(rows)
+
+	result, total, err := repo.GetListSubscriberEmailAsOfWithPagination(targetEmail, asOf, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob@example.com"}, result)
+	require.Equal(t, int64(2), total)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetListBlockedEmailAsOfWithPagination_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "relationship_histories"`)).
+		WillReturnError(sql.ErrConnDone)
+
+	result, total, err := repo.GetListBlockedEmailAsOfWithPagination("alice@example.com", time.Now(), 10, 0)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Nil(t, result)
+	require.Equal(t, int64(0), total)
+}
+
 func TestCheckTwoUsersAreFriends(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
@@ -187,7 +348,7 @@ func TestCheckTwoUsersAreFriends(t *testing.T) {
 		AddRow(email2, email1, constant.FRIEND_RELATIONSHIP_TYPE)
 
 	mock.ExpectQuery(`SELECT \* FROM "user_relationships"`).
-		WithArgs(email1, email2, constant.FRIEND_RELATIONSHIP_TYPE).
+		WithArgs(constant.DEFAULT_TENANT_ID, email1, email2, constant.FRIEND_RELATIONSHIP_TYPE).
 		WillReturnRows(rows)
 
 	isFriend, err := repo.CheckTwoUsersAreFriends(email1, email2)
@@ -209,7 +370,7 @@ func TestCheckTwoUsersBlockedEachOther(t *testing.T) {
 		AddRow(email2, email1, constant.BLOCK_RELATIONSHIP_TYPE)
 
 	mock.ExpectQuery(`SELECT \* FROM "user_relationships"`).
-		WithArgs(email1, email2, constant.BLOCK_RELATIONSHIP_TYPE, email2, email1, constant.BLOCK_RELATIONSHIP_TYPE).
+		WithArgs(constant.DEFAULT_TENANT_ID, email1, email2, constant.BLOCK_RELATIONSHIP_TYPE, email2, email1, constant.BLOCK_RELATIONSHIP_TYPE).
 		WillReturnRows(rows)
 
 	isBlock, err := repo.CheckTwoUsersBlockedEachOther(email1, email2)
@@ -228,12 +389,14 @@ func TestAddSubscriber(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectOpenHistory(mock, requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
 	mock.ExpectCommit()
 
 	err := repo.AddSubscriber(requestor, target)
 	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreateBlockRelationship(t *testing.T) {
@@ -247,12 +410,14 @@ func TestCreateBlockRelationship(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_relationships"`)).
-		WithArgs(requestor, target, constant.BLOCK_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target, constant.BLOCK_RELATIONSHIP_TYPE, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectOpenHistory(mock, requestor, target, constant.BLOCK_RELATIONSHIP_TYPE)
 	mock.ExpectCommit()
 
 	err := repo.CreateBlockRelationship(requestor, target)
 	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCheckIfTheRequestorAlreadySubscribe(t *testing.T) {
@@ -268,7 +433,7 @@ func TestCheckIfTheRequestorAlreadySubscribe(t *testing.T) {
 		AddRow(requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships"`)).
-		WithArgs(requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, sqlmock.AnyArg()).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target, constant.SUBSCRIBER_RELATIONSHIOP_TYPE, sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
 	isSubscriber, err := repo.CheckIfTheRequestorAlreadySubscribe(requestor, target)
@@ -286,8 +451,9 @@ func TestDeleteRelationship(t *testing.T) {
 	target := "bob@example.com"
 
 	mock.ExpectBegin()
+	expectCloseHistory(mock, requestor, target, "")
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
-		WithArgs(requestor, target).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
@@ -307,11 +473,11 @@ func TestDeleteRelationship_FailDeleteFirstRelationship(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
-		WithArgs(target, requestor).
+		WithArgs(constant.DEFAULT_TENANT_ID, target, requestor).
 		WillReturnError(sql.ErrTxDone)
 
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
-		WithArgs(requestor, target).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectRollback()
 
@@ -331,11 +497,11 @@ func TestDeleteRelationship_FailDeleteSecondRelationship(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
-		WithArgs(target, requestor).
+		WithArgs(constant.DEFAULT_TENANT_ID, target, requestor).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
-		WithArgs(requestor, target).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target).
 		WillReturnError(sql.ErrTxDone)
 	mock.ExpectRollback()
 
@@ -348,20 +514,292 @@ func TestDeleteRelationship_FailDeleteSecondRelationship(t *testing.T) {
 	require.Error(t, mock.ExpectationsWereMet())
 }
 
-func TestUpdateToFriendShip_Success(t *testing.T) {
+func TestDeleteRelationshipByType(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
 
 	repo := repository.NewUserRelationshipRepository(db)
 
-	email1 := "alice@example.com"
-	email2 := "bob@example.com"
+	requestor := "alice@example.com"
+	target := "bob@example.com"
+
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user_relationships"`)).
-		WithArgs(constant.FRIEND_RELATIONSHIP_TYPE, sqlmock.AnyArg(), email1, email2, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+	expectCloseHistory(mock, requestor, target, constant.BLOCK_RELATIONSHIP_TYPE)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, requestor, target, constant.BLOCK_RELATIONSHIP_TYPE).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
-	err := repo.UpdateToFriendship(email1, email2)
+
+	deleted, err := repo.DeleteRelationshipByType(requestor, target, constant.BLOCK_RELATIONSHIP_TYPE)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteRelationshipByType_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectBegin()
+	expectCloseHistory(mock, "alice@example.com", "bob@example.com", constant.FRIEND_RELATIONSHIP_TYPE)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships"`)).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	deleted, err := repo.DeleteRelationshipByType("alice@example.com", "bob@example.com", constant.FRIEND_RELATIONSHIP_TYPE)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Equal(t, int64(0), deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTargetEmailsByRequestors(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "requestor_email", "target_email", "type"}).
+		AddRow(1, "alice@example.com", "bob@example.com", constant.FRIEND_RELATIONSHIP_TYPE).
+		AddRow(2, "carol@example.com", "bob@example.com", constant.FRIEND_RELATIONSHIP_TYPE).
+		AddRow(3, "alice@example.com", "carol@example.com", constant.FRIEND_RELATIONSHIP_TYPE)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 AND (requestor_email IN ($2,$3) AND type = $4) ORDER BY id`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "alice@example.com", "carol@example.com", constant.FRIEND_RELATIONSHIP_TYPE).
+		WillReturnRows(rows)
+
+	emails, err := repo.GetTargetEmailsByRequestors([]string{"alice@example.com", "carol@example.com"}, constant.FRIEND_RELATIONSHIP_TYPE)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"alice@example.com": {"bob@example.com", "carol@example.com"},
+		"carol@example.com": {"bob@example.com"},
+	}, emails)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRequestorEmailsByTargets(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "requestor_email", "target_email", "type"}).
+		AddRow(1, "bob@example.com", "alice@example.com", constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 AND (target_email IN ($2) AND type = $3) ORDER BY id`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "alice@example.com", constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WillReturnRows(rows)
+
+	emails, err := repo.GetRequestorEmailsByTargets([]string{"alice@example.com"}, constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"alice@example.com": {"bob@example.com"}}, emails)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBlockConnectionEmailsByEmails(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "requestor_email", "target_email", "type"}).
+		AddRow(1, "alice@example.com", "bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE).
+		AddRow(2, "carol@example.com", "alice@example.com", constant.BLOCK_RELATIONSHIP_TYPE)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 AND ((requestor_email IN ($2) OR target_email IN ($3)) AND type = $4) ORDER BY id`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "alice@example.com", "alice@example.com", constant.BLOCK_RELATIONSHIP_TYPE).
+		WillReturnRows(rows)
+
+	emails, err := repo.GetBlockConnectionEmailsByEmails([]string{"alice@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"alice@example.com": {"bob@example.com", "carol@example.com"}}, emails)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetContactEmails(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "requestor_email", "target_email", "type"}).
+		AddRow(1, "Bob@Example.com", "alice@example.com", constant.FRIEND_RELATIONSHIP_TYPE).
+		AddRow(2, "bob@example.com", "alice@example.com", constant.SUBSCRIBER_RELATIONSHIOP_TYPE)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 AND (LOWER(target_email) = LOWER($2) AND LOWER(requestor_email) IN ($3,$4) AND type IN ($5,$6)) ORDER BY id`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "Alice@example.com", "bob@example.com", "carol@example.com", constant.FRIEND_RELATIONSHIP_TYPE, constant.SUBSCRIBER_RELATIONSHIOP_TYPE).
+		WillReturnRows(rows)
+
+	//The emails are returned as given whatever their case in the relationships, the contacts of alice@example.com are found for Alice@example.com
+	emails, err := repo.GetContactEmails("Alice@example.com", []string{"bob@example.com", "Carol@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob@example.com"}, emails)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetKnownEmails(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT "requestor_email" FROM "user_relationships" WHERE tenant_id = $1 AND LOWER(requestor_email) IN ($2,$3,$4)`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "bob@example.com", "carol@example.com", "dave@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"requestor_email"}).AddRow("Dave@Example.com"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT "target_email" FROM "user_relationships" WHERE tenant_id = $1 AND LOWER(target_email) IN ($2,$3,$4)`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, "bob@example.com", "carol@example.com", "dave@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"target_email"}).AddRow("bob@example.com"))
+
+	emails, err := repo.GetKnownEmails([]string{"bob@example.com", "carol@example.com", "dave@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob@example.com", "dave@example.com"}, emails)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetKnownEmails_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(`SELECT DISTINCT "requestor_email" FROM "user_relationships"`).
+		WillReturnError(sql.ErrConnDone)
+
+	emails, err := repo.GetKnownEmails([]string{"bob@example.com"})
+	require.Error(t, err)
+	require.Nil(t, emails)
+}
+
+func TestGetTargetEmailsByRequestors_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "user_relationships"`).
+		WillReturnError(sql.ErrConnDone)
+
+	emails, err := repo.GetTargetEmailsByRequestors([]string{"alice@example.com"}, constant.FRIEND_RELATIONSHIP_TYPE)
+	require.Error(t, err)
+	require.Nil(t, emails)
+}
+
+func TestListRelationships(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	filter := repository.RelationshipFilter{
+		Email:  "alice@example.com",
+		Type:   constant.BLOCK_RELATIONSHIP_TYPE,
+		From:   &from,
+		To:     &to,
+		Limit:  10,
+		Offset: 20,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_relationships" WHERE tenant_id = $1 AND (requestor_email = $2 OR target_email = $3) AND type = $4 AND created_at >= $5 AND created_at <= $6`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, filter.Email, filter.Email, filter.Type, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 AND (requestor_email = $2 OR target_email = $3) AND type = $4 AND created_at >= $5 AND created_at <= $6 ORDER BY id LIMIT $7 OFFSET $8`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, filter.Email, filter.Email, filter.Type, from, to, 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requestor_email", "target_email", "type"}).
+			AddRow(21, "alice@example.com", "bob@example.com", constant.BLOCK_RELATIONSHIP_TYPE))
+
+	relationships, total, err := repo.ListRelationships(filter)
+	require.NoError(t, err)
+	require.Equal(t, int64(21), total)
+	require.Len(t, relationships, 1)
+	require.Equal(t, uint(21), relationships[0].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListRelationships_NoFilter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_relationships" WHERE tenant_id = $1`) + `$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 ORDER BY id LIMIT $2`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	relationships, total, err := repo.ListRelationships(repository.RelationshipFilter{Limit: 20})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), total)
+	require.Empty(t, relationships)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListRelationships_FailDatabase(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_relationships"`)).
+		WillReturnError(sql.ErrConnDone)
+
+	relationships, total, err := repo.ListRelationships(repository.RelationshipFilter{Limit: 20})
+	require.Error(t, err)
+	require.Nil(t, relationships)
+	require.Equal(t, int64(0), total)
+}
+
+func TestGetRelationshipByID(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships" WHERE tenant_id = $1 AND "user_relationships"."id" = $2`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, 7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requestor_email", "target_email", "type"}).
+			AddRow(7, "alice@example.com", "bob@example.com", constant.FRIEND_RELATIONSHIP_TYPE))
+
+	relationship, err := repo.GetRelationshipByID(7)
+	require.NoError(t, err)
+	require.Equal(t, "bob@example.com", relationship.TargetEmail)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRelationshipByID_NotFound(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_relationships"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	relationship, err := repo.GetRelationshipByID(7)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.Nil(t, relationship)
+}
+
+func TestDeleteRelationshipByID(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewUserRelationshipRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "relationship_histories" SET "valid_to"=$1 WHERE tenant_id = $2 AND (requestor_email, target_email, type) IN (SELECT requestor_email, target_email, type FROM "user_relationships" WHERE tenant_id = $3 AND id = $4) AND valid_to IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), constant.DEFAULT_TENANT_ID, constant.DEFAULT_TENANT_ID, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_relationships" WHERE tenant_id = $1 AND "user_relationships"."id" = $2`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := repo.DeleteRelationshipByID(7)
 	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
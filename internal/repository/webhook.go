@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+)
+
+type webhookRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// WebhookRepository all the functions to manage the webhooks of a tenant and their dead letters
+type WebhookRepository interface {
+	Create(webhook *model.Webhook) error
+	List() ([]model.Webhook, error)
+	DeleteByID(id uint) (int64, error)
+	ListDeadLetters(webhookID uint, limit, offset int) ([]model.WebhookDeadLetter, int64, error)
+	GetDeadLetter(webhookID, id uint) (*model.WebhookDeadLetter, error)
+	DeleteDeadLetter(id uint) error
+	WithTx(tx *gorm.DB) WebhookRepository
+	WithTenant(tenantID string) WebhookRepository
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// scoped return the db restricted to the rows of the tenant
+func (r *webhookRepository) scoped() *gorm.DB {
+	return r.db.Where("tenant_id = ?", r.tenantID)
+}
+
+// Create support register a webhook in the tenant
+func (r *webhookRepository) Create(webhook *model.Webhook) error {
+	webhook.TenantID = r.tenantID
+	return r.db.Create(webhook).Error
+}
+
+// List support query every webhook of the tenant, oldest first
+func (r *webhookRepository) List() ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.scoped().Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteByID support delete a webhook, its deliveries and dead letters are deleted by the foreign keys
+func (r *webhookRepository) DeleteByID(id uint) (int64, error) {
+	result := r.scoped().Where("id = ?", id).Delete(&model.Webhook{})
+	return result.RowsAffected, result.Error
+}
+
+// ListDeadLetters support query one page of the dead letters of a webhook, oldest first, and the total count
+func (r *webhookRepository) ListDeadLetters(webhookID uint, limit, offset int) ([]model.WebhookDeadLetter, int64, error) {
+	query := r.scoped().Model(&model.WebhookDeadLetter{}).Where("webhook_id = ?", webhookID)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deadLetters []model.WebhookDeadLetter
+	if err := query.Session(&gorm.Session{}).Order("id").Limit(limit).Offset(offset).Find(&deadLetters).Error; err != nil {
+		return nil, 0, err
+	}
+	return deadLetters, total, nil
+}
+
+// GetDeadLetter support query one dead letter of a webhook, it returns gorm.ErrRecordNotFound when there is none
+func (r *webhookRepository) GetDeadLetter(webhookID, id uint) (*model.WebhookDeadLetter, error) {
+	var deadLetter model.WebhookDeadLetter
+	if err := r.scoped().Where("webhook_id = ? AND id = ?", webhookID, id).First(&deadLetter).Error; err != nil {
+		return nil, err
+	}
+	return &deadLetter, nil
+}
+
+// DeleteDeadLetter support delete a dead letter once it is queued again
+func (r *webhookRepository) DeleteDeadLetter(id uint) error {
+	return r.scoped().Where("id = ?", id).Delete(&model.WebhookDeadLetter{}).Error
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *webhookRepository) WithTx(tx *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that only read and write the webhooks of the tenant
+func (r *webhookRepository) WithTenant(tenantID string) WebhookRepository {
+	return &webhookRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookDeliveryRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+// WebhookDeliveryRepository all the functions to queue and deliver webhook events, the delivery functions work on every tenant
+type WebhookDeliveryRepository interface {
+	Enqueue(delivery *model.WebhookDelivery) error
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+	MarkDelivered(id uint, at time.Time) error
+	MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MoveToDeadLetter(delivery *model.WebhookDelivery, lastError string, at time.Time) error
+	WithTx(tx *gorm.DB) WebhookDeliveryRepository
+	WithTenant(tenantID string) WebhookDeliveryRepository
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db, tenantID: constant.DEFAULT_TENANT_ID}
+}
+
+// Enqueue support queue an event for a webhook in the tenant, an event already queued for the webhook is ignored
+func (r *webhookDeliveryRepository) Enqueue(delivery *model.WebhookDelivery) error {
+	delivery.TenantID = r.tenantID
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery).Error
+}
+
+// ClaimDue support claim the oldest undelivered events of every tenant that can be attempted now, with their webhook.
+// The claimed deliveries are not due again before now plus lease, so the other instances skip them while they are sent
+// and the deliveries of an instance that stopped are attempted again after the lease.
+// It must run in a transaction, the rows claimed by a concurrent transaction are skipped.
+func (r *webhookDeliveryRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	var ids []uint
+	err := r.db.Model(&model.WebhookDelivery{}).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("delivered_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	if err := r.db.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error; err != nil {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	if err := r.db.Preload("Webhook").Where("id IN ?", ids).Order("id").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// MarkDelivered support record the event was accepted by the webhook
+func (r *webhookDeliveryRepository) MarkDelivered(id uint, at time.Time) error {
+	return r.db.Model(&model.WebhookDelivery{}).Where("id = ?", id).Update("delivered_at", at).Error
+}
+
+// MarkFailed support record a failed delivery and when it can be retried
+func (r *webhookDeliveryRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.db.Model(&model.WebhookDelivery{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+// MoveToDeadLetter support park a delivery that failed every attempt, it must run in a transaction
+func (r *webhookDeliveryRepository) MoveToDeadLetter(delivery *model.WebhookDelivery, lastError string, at time.Time) error {
+	err := r.db.Create(&model.WebhookDeadLetter{
+		TenantID:  delivery.TenantID,
+		WebhookID: delivery.WebhookID,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+		Attempts:  delivery.Attempts,
+		LastError: lastError,
+		CreatedAt: at,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Where("id = ?", delivery.ID).Delete(&model.WebhookDelivery{}).Error
+}
+
+// WithTx return a repository that run its queries in the transaction
+func (r *webhookDeliveryRepository) WithTx(tx *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: tx, tenantID: r.tenantID}
+}
+
+// WithTenant return a repository that queue the events of the tenant
+func (r *webhookDeliveryRepository) WithTenant(tenantID string) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: r.db, tenantID: tenantID}
+}
@@ -0,0 +1,152 @@
+package repository_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCreate(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := repository.NewWebhookRepository(db).WithTenant("tenant-b")
+	webhook := &model.Webhook{
+		URL:        "https://partner.example.com/hook",
+		EventTypes: []string{constant.DOMAIN_EVENT_BLOCKED},
+		Secret:     "whsec_abc",
+		CreatedBy:  "ops",
+		CreatedAt:  time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "webhooks"`)).
+		WithArgs("tenant-b", webhook.URL, `["Blocked"]`, webhook.Secret, webhook.CreatedBy, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Create(webhook))
+	require.Equal(t, uint(3), webhook.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeleteByID(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhooks" WHERE tenant_id = $1 AND id = $2`)).
+		WithArgs("tenant-b", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := repository.NewWebhookRepository(db).WithTenant("tenant-b").DeleteByID(3)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookListDeadLetters(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "webhook_dead_letters" WHERE tenant_id = $1 AND webhook_id = $2`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "webhook_dead_letters" WHERE tenant_id = $1 AND webhook_id = $2 ORDER BY id LIMIT $3 OFFSET $4`)).
+		WithArgs(constant.DEFAULT_TENANT_ID, 3, 5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event_id"}).AddRow(9, 3, 42))
+
+	deadLetters, total, err := repository.NewWebhookRepository(db).ListDeadLetters(3, 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), total)
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, uint(42), deadLetters[0].EventID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryEnqueue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	delivery := &model.WebhookDelivery{WebhookID: 3, EventID: 42, EventType: constant.DOMAIN_EVENT_BLOCKED, Payload: `{"id":42}`, NextAttemptAt: now, CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "webhook_deliveries"`)+`.*ON CONFLICT DO NOTHING`).
+		WithArgs("tenant-b", 3, 42, delivery.EventType, delivery.Payload, 0, now, "", nil, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewWebhookDeliveryRepository(db).WithTenant("tenant-b").Enqueue(delivery))
+	require.Equal(t, "tenant-b", delivery.TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryClaimDue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "webhook_deliveries" WHERE delivered_at IS NULL AND next_attempt_at <= $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(now, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "next_attempt_at"=$1 WHERE id IN ($2)`)).
+		WithArgs(now.Add(10*time.Minute), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "webhook_deliveries" WHERE id IN ($1) ORDER BY id`)).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "webhook_id"}).AddRow(7, "tenant-b", 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "webhooks" WHERE "webhooks"."id" = $1`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret"}).AddRow(3, "https://partner.example.com/hook", "whsec_abc"))
+
+	deliveries, err := repository.NewWebhookDeliveryRepository(db).ClaimDue(now, 10*time.Minute, 50)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, "whsec_abc", deliveries[0].Webhook.Secret)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryClaimDue_NothingDue(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "webhook_deliveries"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	deliveries, err := repository.NewWebhookDeliveryRepository(db).ClaimDue(time.Now(), 10*time.Minute, 50)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryMoveToDeadLetter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	at := time.Now()
+	delivery := &model.WebhookDelivery{ID: 7, TenantID: "tenant-b", WebhookID: 3, EventID: 42, EventType: constant.DOMAIN_EVENT_BLOCKED, Payload: `{"id":42}`, Attempts: 8}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "webhook_dead_letters"`)).
+		WithArgs("tenant-b", 3, 42, delivery.EventType, delivery.Payload, 8, "WEBHOOK_STATUS: 500", at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhook_deliveries" WHERE id = $1`)).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repository.NewWebhookDeliveryRepository(db).MoveToDeadLetter(delivery, "WEBHOOK_STATUS: 500", at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/middleware"
+)
+
+// RegisterAdminRoutes register the moderation api, only callers with the admin scope can use it
+func RegisterAdminRoutes(e *echo.Echo, adminService api.Admin, authentication echo.MiddlewareFunc) {
+	g := e.Group("/admin", authentication, middleware.RequireScope(auth.SCOPE_ADMIN))
+	g.GET("/relationships", adminService.ListRelationships)
+	g.DELETE("/relationships/:id", adminService.ForceRemoveRelationship)
+	g.DELETE("/blocks/:email/:other", adminService.ForceUnblock)
+	g.GET("/quotas/:email", adminService.GetQuota)
+	g.PUT("/quotas/:email", adminService.SetQuota)
+	g.DELETE("/quotas/:email", adminService.ResetQuota)
+	g.GET("/relationship-events", adminService.ListRelationshipEvents)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterGraphQLRoutes register the graphql endpoint
+func RegisterGraphQLRoutes(e *echo.Echo, graphQLHandler http.Handler, authentication echo.MiddlewareFunc) {
+	e.POST("/graphql", echo.WrapHandler(graphQLHandler), authentication)
+}
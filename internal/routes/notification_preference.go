@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+)
+
+// RegisterNotificationPreferenceRoutes register the notification preference api of a user
+func RegisterNotificationPreferenceRoutes(e *echo.Echo, notificationPreferenceService api.NotificationPreference, authentication echo.MiddlewareFunc) {
+	g := e.Group("/api/v2/users/:email", authentication)
+	g.GET("/notification-preferences", notificationPreferenceService.GetPreference)
+	g.PUT("/notification-preferences", notificationPreferenceService.SetPreference)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterOpenAPIRoutes serve the OpenAPI document of the api
+func RegisterOpenAPIRoutes(e *echo.Echo, doc *openapi3.T) {
+	e.GET("/openapi.json", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, doc)
+	})
+}
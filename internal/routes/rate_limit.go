@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/middleware"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+)
+
+// RateLimitBudgets is the budget of each kind of route
+type RateLimitBudgets struct {
+	//Routes that create or remove a relationship, they are the ones scripted to mass follow users
+	Write ratelimit.Budget
+	//Routes that compute the recipients of an update, they fan out over every relationship of the sender
+	Recipients ratelimit.Budget
+	//Every other route
+	Read ratelimit.Budget
+}
+
+// v1WriteRoutes are the mutating v1 routes, v1 use POST for reads too
+var v1WriteRoutes = map[string]bool{
+	"/api/user/relationship/friend":     true,
+	"/api/user/relationship/subscriber": true,
+	"/api/user/relationship/block":      true,
+}
+
+// RateLimitBudget classify the matched route of the request, the admin api is not limited
+func RateLimitBudget(budgets RateLimitBudgets) middleware.RateLimitBudget {
+	return func(c echo.Context) (ratelimit.Budget, bool) {
+		path, method := c.Path(), c.Request().Method
+		switch {
+		case strings.HasPrefix(path, "/admin/"):
+			return ratelimit.Budget{}, false
+		//Posting a status update resolves the recipients like the recipients api and fans out to their feeds
+		case strings.HasSuffix(path, "/recipients") || (method == http.MethodPost && strings.HasSuffix(path, "/updates")):
+			return budgets.Recipients, true
+		case method == http.MethodPut || method == http.MethodDelete || (method == http.MethodPost && v1WriteRoutes[path]):
+			return budgets.Write, true
+		}
+		return budgets.Read, true
+	}
+}
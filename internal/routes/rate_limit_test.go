@@ -0,0 +1,55 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/ratelimit"
+	"github.com/quanluong166/friends_management/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitBudget(t *testing.T) {
+	budgets := routes.RateLimitBudgets{
+		Write:      ratelimit.Budget{Name: "write", Limit: 30, Period: time.Minute},
+		Recipients: ratelimit.Budget{Name: "recipients", Limit: 60, Period: time.Minute},
+		Read:       ratelimit.Budget{Name: "read", Limit: 300, Period: time.Minute},
+	}
+
+	tcs := map[string]struct {
+		method  string
+		path    string
+		budget  string
+		limited bool
+	}{
+		"V1 add friend":       {method: http.MethodPost, path: "/api/user/relationship/friend", budget: "write", limited: true},
+		"V1 add subscriber":   {method: http.MethodPost, path: "/api/user/relationship/subscriber", budget: "write", limited: true},
+		"V1 add block":        {method: http.MethodPost, path: "/api/user/relationship/block", budget: "write", limited: true},
+		"V1 list friends":     {method: http.MethodPost, path: "/api/user/relationship/list", budget: "read", limited: true},
+		"V1 recipients":       {method: http.MethodPost, path: "/api/user/relationship/recipients", budget: "recipients", limited: true},
+		"V2 put subscription": {method: http.MethodPut, path: "/api/v2/users/:email/subscriptions/:other", budget: "write", limited: true},
+		"V2 delete friend":    {method: http.MethodDelete, path: "/api/v2/users/:email/friends/:other", budget: "write", limited: true},
+		"V2 list subscribers": {method: http.MethodGet, path: "/api/v2/users/:email/subscribers", budget: "read", limited: true},
+		"V2 recipients":       {method: http.MethodGet, path: "/api/v2/users/:email/recipients", budget: "recipients", limited: true},
+		"V2 post update":      {method: http.MethodPost, path: "/api/v2/users/:email/updates", budget: "recipients", limited: true},
+		"V2 feed":             {method: http.MethodGet, path: "/api/v2/users/:email/feed", budget: "read", limited: true},
+		"GraphQL":             {method: http.MethodPost, path: "/graphql", budget: "read", limited: true},
+		"Admin force unblock": {method: http.MethodDelete, path: "/admin/blocks/:email/:other", limited: false},
+		"Admin relationships": {method: http.MethodGet, path: "/admin/relationships", limited: false},
+	}
+
+	budgetOf := routes.RateLimitBudget(budgets)
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(tc.method, "/", nil), httptest.NewRecorder())
+			c.SetPath(tc.path)
+
+			budget, limited := budgetOf(c)
+			assert.Equal(t, tc.limited, limited)
+			assert.Equal(t, tc.budget, budget.Name)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+)
+
+// RegisterStatusUpdateRoutes register the status update api, idempotency is applied to posting since it is not idempotent
+func RegisterStatusUpdateRoutes(e *echo.Echo, statusUpdateService api.StatusUpdate, authentication, idempotency echo.MiddlewareFunc) {
+	g := e.Group("/api/v2/users/:email", authentication)
+	g.POST("/updates", statusUpdateService.PostUpdate, idempotency)
+	g.GET("/feed", statusUpdateService.ListFeed)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/middleware"
+)
+
+// RegisterStreamRoutes register the event stream api, browsers can not set headers on an EventSource or a websocket
+// so the token is also accepted in the access_token query parameter
+func RegisterStreamRoutes(e *echo.Echo, streamService api.Stream, authentication echo.MiddlewareFunc) {
+	g := e.Group("/api/v2/users/:email", middleware.QueryToken(), authentication)
+	g.GET("/events", streamService.Events)
+	g.GET("/events/ws", streamService.EventsWebSocket)
+}
@@ -3,13 +3,34 @@ package routes
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/middleware"
 )
 
-func RegisterUserRelationshipRoutes(e *echo.Echo, userRelationshipService api.UserRelationship) {
-	e.POST("/api/user/relationship/friend", userRelationshipService.AddFriend)
-	e.POST("/api/user/relationship/subscriber", userRelationshipService.AddSubscriber)
-	e.POST("/api/user/relationship/block", userRelationshipService.AddBlock)
-	e.POST("/api/user/relationship/list", userRelationshipService.ListFriend)
-	e.POST("/api/user/relationship/common-friends", userRelationshipService.ListCommonFriends)
-	e.POST("/api/user/relationship/recipients", userRelationshipService.GetListEmailCanReceiveUpdate)
+// RegisterUserRelationshipRoutes register the deprecated v1 user relationship api, idempotency is applied to the mutating routes
+func RegisterUserRelationshipRoutes(e *echo.Echo, userRelationshipService api.UserRelationship, authentication, idempotency echo.MiddlewareFunc) {
+	g := e.Group("/api/user/relationship", middleware.Deprecated("/api/v2"), authentication)
+	g.POST("/friend", userRelationshipService.AddFriend, idempotency)
+	g.POST("/subscriber", userRelationshipService.AddSubscriber, idempotency)
+	g.POST("/block", userRelationshipService.AddBlock, idempotency)
+	g.POST("/list", userRelationshipService.ListFriend)
+	g.POST("/common-friends", userRelationshipService.ListCommonFriends)
+	g.POST("/recipients", userRelationshipService.GetListEmailCanReceiveUpdate)
+	g.POST("/subscribers", userRelationshipService.ListSubscribers)
+	g.POST("/blocks", userRelationshipService.ListBlocks)
+}
+
+// RegisterUserRelationshipV2Routes register the resource oriented v2 user relationship api
+func RegisterUserRelationshipV2Routes(e *echo.Echo, userRelationshipService api.UserRelationshipV2, authentication echo.MiddlewareFunc) {
+	g := e.Group("/api/v2/users/:email", authentication)
+	g.GET("/friends", userRelationshipService.ListFriends)
+	g.PUT("/friends/:other", userRelationshipService.PutFriend)
+	g.DELETE("/friends/:other", userRelationshipService.DeleteFriend)
+	g.GET("/common-friends/:other", userRelationshipService.ListCommonFriends)
+	g.GET("/subscribers", userRelationshipService.ListSubscribers)
+	g.PUT("/subscriptions/:other", userRelationshipService.PutSubscription)
+	g.DELETE("/subscriptions/:other", userRelationshipService.DeleteSubscription)
+	g.GET("/blocks", userRelationshipService.ListBlocks)
+	g.PUT("/blocks/:other", userRelationshipService.PutBlock)
+	g.DELETE("/blocks/:other", userRelationshipService.DeleteBlock)
+	g.GET("/recipients", userRelationshipService.ListRecipients)
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/handler/api"
+	"github.com/quanluong166/friends_management/internal/middleware"
+)
+
+// RegisterWebhookRoutes register the webhook api, only callers with the admin scope can use it
+func RegisterWebhookRoutes(e *echo.Echo, webhookService api.Webhook, authentication echo.MiddlewareFunc) {
+	g := e.Group("/admin/webhooks", authentication, middleware.RequireScope(auth.SCOPE_ADMIN))
+	g.POST("", webhookService.CreateWebhook)
+	g.GET("", webhookService.ListWebhooks)
+	g.DELETE("/:id", webhookService.DeleteWebhook)
+	g.GET("/:id/dead-letters", webhookService.ListDeadLetters)
+	g.POST("/:id/dead-letters/:letter/replay", webhookService.ReplayDeadLetter)
+}
@@ -0,0 +1,109 @@
+package stream
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/quanluong166/friends_management/internal/outbox"
+)
+
+// Hub fan out the domain events to the clients of this instance, a client receives the events where its email is the
+// requestor or the target. It is an outbox sink fed with the changes of every instance by the notify listener.
+type Hub struct {
+	bufferSize int
+
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	closed      bool
+}
+
+// Subscription receive the events of one email until it is closed, C is closed when the client is too slow to keep up
+// and must reconnect to resume from the last event it received
+type Subscription struct {
+	C <-chan outbox.Message
+
+	hub  *Hub
+	key  string
+	c    chan outbox.Message
+	once sync.Once
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{bufferSize: bufferSize, subscribers: map[string]map[*Subscription]struct{}{}}
+}
+
+// subscriberKey identify the clients of one email, emails are case insensitive
+func subscriberKey(tenantID, email string) string {
+	return tenantID + "\x00" + strings.ToLower(email)
+}
+
+// Subscribe register a client for the events of email in the tenant
+func (h *Hub) Subscribe(tenantID, email string) *Subscription {
+	c := make(chan outbox.Message, h.bufferSize)
+	sub := &Subscription{C: c, hub: h, key: subscriberKey(tenantID, email), c: c}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(c)
+		return sub
+	}
+	if h.subscribers[sub.key] == nil {
+		h.subscribers[sub.key] = map[*Subscription]struct{}{}
+	}
+	h.subscribers[sub.key][sub] = struct{}{}
+	return sub
+}
+
+// Publish send the message to the clients of the requestor and the target, it never blocks the relay
+func (h *Hub) Publish(ctx context.Context, message outbox.Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := []string{subscriberKey(message.Tenant, message.Requestor)}
+	if target := subscriberKey(message.Tenant, message.Target); target != keys[0] {
+		keys = append(keys, target)
+	}
+	for _, key := range keys {
+		for sub := range h.subscribers[key] {
+			select {
+			case sub.c <- message:
+			default:
+				h.remove(sub)
+			}
+		}
+	}
+	return nil
+}
+
+// Close disconnect every client, it is called when the server shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Close stop the subscription, it is safe to call more than once
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove unregister the subscription and close its channel, the caller must hold the lock
+func (h *Hub) remove(sub *Subscription) {
+	sub.once.Do(func() {
+		delete(h.subscribers[sub.key], sub)
+		if len(h.subscribers[sub.key]) == 0 {
+			delete(h.subscribers, sub.key)
+		}
+		close(sub.c)
+	})
+}
@@ -0,0 +1,75 @@
+package stream_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func message(id uint, tenant, requestor, target string) outbox.Message {
+	return outbox.Message{ID: id, Tenant: tenant, Type: constant.DOMAIN_EVENT_BLOCKED, Requestor: requestor, Target: target}
+}
+
+func received(sub *stream.Subscription) []uint {
+	var ids []uint
+	for {
+		select {
+		case m, ok := <-sub.C:
+			if !ok {
+				return ids
+			}
+			ids = append(ids, m.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := stream.NewHub(10)
+	alice := hub.Subscribe("tenant-b", "Alice@example.com")
+	bob := hub.Subscribe("tenant-b", "bob@example.com")
+	otherTenant := hub.Subscribe("tenant-c", "alice@example.com")
+
+	require.NoError(t, hub.Publish(context.Background(), message(1, "tenant-b", "alice@example.com", "bob@example.com")))
+	require.NoError(t, hub.Publish(context.Background(), message(2, "tenant-b", "carol@example.com", "alice@example.com")))
+	require.NoError(t, hub.Publish(context.Background(), message(3, "tenant-b", "alice@example.com", "alice@example.com")))
+
+	require.Equal(t, []uint{1, 2, 3}, received(alice))
+	require.Equal(t, []uint{1}, received(bob))
+	require.Empty(t, received(otherTenant))
+}
+
+func TestHub_SlowSubscriberIsDisconnected(t *testing.T) {
+	hub := stream.NewHub(1)
+	slow := hub.Subscribe("tenant-b", "alice@example.com")
+
+	require.NoError(t, hub.Publish(context.Background(), message(1, "tenant-b", "alice@example.com", "bob@example.com")))
+	require.NoError(t, hub.Publish(context.Background(), message(2, "tenant-b", "alice@example.com", "bob@example.com")))
+
+	m, ok := <-slow.C
+	require.True(t, ok)
+	require.Equal(t, uint(1), m.ID)
+	_, ok = <-slow.C
+	require.False(t, ok)
+	slow.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := stream.NewHub(1)
+	sub := hub.Subscribe("tenant-b", "alice@example.com")
+	sub.Close()
+	_, ok := <-sub.C
+	require.False(t, ok)
+
+	other := hub.Subscribe("tenant-b", "bob@example.com")
+	hub.Close()
+	_, ok = <-other.C
+	require.False(t, ok)
+	_, ok = <-hub.Subscribe("tenant-b", "bob@example.com").C
+	require.False(t, ok)
+}
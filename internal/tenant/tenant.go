@@ -0,0 +1,50 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type tenantKey struct{}
+
+// NewContext store the tenant of the request in the context
+func NewContext(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext get the tenant of the request, the default tenant when none was resolved
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok && len(tenantID) > 0 {
+		return tenantID
+	}
+	return constant.DEFAULT_TENANT_ID
+}
+
+// Resolve choose the tenant of a request. Credentials bound to a tenant always use it and the requested tenant must match.
+// Only admins and services can request another tenant than the default one, other callers always use the default tenant.
+func Resolve(principal *auth.Principal, requested string) (string, error) {
+	if len(requested) > 0 && !idPattern.MatchString(requested) {
+		return "", apperror.InvalidInput("INVALID_TENANT")
+	}
+
+	if principal != nil && len(principal.Tenant) > 0 {
+		if len(requested) > 0 && requested != principal.Tenant {
+			return "", apperror.Forbidden("TENANT_MISMATCH")
+		}
+		return principal.Tenant, nil
+	}
+
+	if len(requested) == 0 || requested == constant.DEFAULT_TENANT_ID {
+		return constant.DEFAULT_TENANT_ID, nil
+	}
+	if principal == nil || !(principal.HasScope(auth.SCOPE_ADMIN) || principal.HasScope(auth.SCOPE_SERVICE)) {
+		return "", apperror.Forbidden("TENANT_MISMATCH")
+	}
+	return requested, nil
+}
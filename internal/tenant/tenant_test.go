@@ -0,0 +1,91 @@
+package tenant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quanluong166/friends_management/internal/apperror"
+	"github.com/quanluong166/friends_management/internal/auth"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	tcs := map[string]struct {
+		principal *auth.Principal
+		requested string
+		tenant    string
+		code      string
+	}{
+		"Default tenant": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			tenant:    constant.DEFAULT_TENANT_ID,
+		},
+		"Requested tenant": {
+			principal: &auth.Principal{Subject: "billing-service", Scopes: []string{auth.SCOPE_SERVICE}},
+			requested: "acme",
+			tenant:    "acme",
+		},
+		"Bound tenant": {
+			principal: &auth.Principal{Subject: "andy@example.com", Tenant: "acme"},
+			tenant:    "acme",
+		},
+		"Bound tenant requested again": {
+			principal: &auth.Principal{Subject: "andy@example.com", Tenant: "acme"},
+			requested: "acme",
+			tenant:    "acme",
+		},
+		"Bound tenant can not switch": {
+			principal: &auth.Principal{Subject: "andy@example.com", Tenant: "acme"},
+			requested: "globex",
+			code:      apperror.CODE_FORBIDDEN,
+		},
+		"Admin bound to a tenant can not switch": {
+			principal: &auth.Principal{Subject: "ops@example.com", Scopes: []string{auth.SCOPE_ADMIN}, Tenant: "acme"},
+			requested: "globex",
+			code:      apperror.CODE_FORBIDDEN,
+		},
+		"Admin requested tenant": {
+			principal: &auth.Principal{Subject: "ops@example.com", Scopes: []string{auth.SCOPE_ADMIN}},
+			requested: "acme",
+			tenant:    "acme",
+		},
+		"User can not request a tenant": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			requested: "acme",
+			code:      apperror.CODE_FORBIDDEN,
+		},
+		"Anonymous can not request a tenant": {
+			requested: "acme",
+			code:      apperror.CODE_FORBIDDEN,
+		},
+		"User requested the default tenant": {
+			principal: &auth.Principal{Subject: "andy@example.com"},
+			requested: constant.DEFAULT_TENANT_ID,
+			tenant:    constant.DEFAULT_TENANT_ID,
+		},
+		"Invalid tenant": {
+			principal: &auth.Principal{Subject: "billing-service", Scopes: []string{auth.SCOPE_SERVICE}},
+			requested: "acme'; --",
+			code:      apperror.CODE_INVALID_INPUT,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			tenantID, err := tenant.Resolve(tc.principal, tc.requested)
+			if len(tc.code) > 0 {
+				assert.Equal(t, tc.code, apperror.As(err).Code)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.tenant, tenantID)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, constant.DEFAULT_TENANT_ID, tenant.FromContext(context.Background()))
+	assert.Equal(t, "acme", tenant.FromContext(tenant.NewContext(context.Background(), "acme")))
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/repository"
+)
+
+// Dispatcher is the outbox sink that queue every domain event for the webhooks of its tenant subscribed to it.
+// A message published again is not queued twice for the same webhook.
+type Dispatcher struct {
+	webhookRepo         repository.WebhookRepository
+	webhookDeliveryRepo repository.WebhookDeliveryRepository
+}
+
+func NewDispatcher(webhookRepo repository.WebhookRepository, webhookDeliveryRepo repository.WebhookDeliveryRepository) *Dispatcher {
+	return &Dispatcher{webhookRepo: webhookRepo, webhookDeliveryRepo: webhookDeliveryRepo}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, message outbox.Message) error {
+	webhooks, err := d.webhookRepo.WithTenant(message.Tenant).List()
+	if err != nil {
+		return fmt.Errorf("LIST_WEBHOOKS_FAIL: %w", err)
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("ENCODE_WEBHOOK_PAYLOAD_FAIL: %w", err)
+	}
+
+	deliveryRepo := d.webhookDeliveryRepo.WithTenant(message.Tenant)
+	now := time.Now()
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(message.Type) {
+			continue
+		}
+
+		err := deliveryRepo.Enqueue(&model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       message.ID,
+			EventType:     message.Type,
+			Payload:       string(payload),
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("ENQUEUE_WEBHOOK_DELIVERY_FAIL: %w", err)
+		}
+	}
+	return nil
+}
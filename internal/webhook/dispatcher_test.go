@@ -0,0 +1,81 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/webhook"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher_Publish(t *testing.T) {
+	message := outbox.Message{
+		ID:         42,
+		Tenant:     "tenant-b",
+		Type:       constant.DOMAIN_EVENT_BLOCKED,
+		Requestor:  "alice@example.com",
+		Target:     "bob@example.com",
+		OccurredAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	payload := `{"id":42,"tenant":"tenant-b","type":"Blocked","requestor":"alice@example.com","target":"bob@example.com","occurred_at":"2025-01-02T03:04:05Z"}`
+
+	testCases := map[string]struct {
+		webhooks    []model.Webhook
+		listErr     error
+		enqueued    []uint
+		enqueueErr  error
+		expectedErr string
+	}{
+		"QueueSubscribedWebhooks": {
+			webhooks: []model.Webhook{
+				{ID: 1},
+				{ID: 2, EventTypes: []string{constant.DOMAIN_EVENT_SUBSCRIBED}},
+				{ID: 3, EventTypes: []string{constant.DOMAIN_EVENT_SUBSCRIBED, constant.DOMAIN_EVENT_BLOCKED}},
+			},
+			enqueued: []uint{1, 3},
+		},
+		"NoWebhook": {},
+		"ListFail": {
+			listErr:     errors.New("DATABASE_ERROR"),
+			expectedErr: "LIST_WEBHOOKS_FAIL: DATABASE_ERROR",
+		},
+		"EnqueueFail": {
+			webhooks:    []model.Webhook{{ID: 1}},
+			enqueued:    []uint{1},
+			enqueueErr:  errors.New("DATABASE_ERROR"),
+			expectedErr: "ENQUEUE_WEBHOOK_DELIVERY_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			webhookRepo := new(controller.MockWebhookRepository)
+			webhookRepo.On("List").Return(tc.webhooks, tc.listErr).Once()
+			deliveryRepo := new(controller.MockWebhookDeliveryRepository)
+			for _, id := range tc.enqueued {
+				webhookID := id
+				deliveryRepo.On("Enqueue", mock.MatchedBy(func(delivery *model.WebhookDelivery) bool {
+					return delivery.WebhookID == webhookID && delivery.EventID == 42 && delivery.EventType == constant.DOMAIN_EVENT_BLOCKED &&
+						delivery.Payload == payload && !delivery.NextAttemptAt.IsZero()
+				})).Return(tc.enqueueErr).Once()
+			}
+
+			err := webhook.NewDispatcher(webhookRepo, deliveryRepo).Publish(context.Background(), message)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, "tenant-b", webhookRepo.Tenant)
+			webhookRepo.AssertExpectations(t)
+			deliveryRepo.AssertExpectations(t)
+		})
+	}
+}
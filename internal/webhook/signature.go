@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/constant"
+)
+
+// NewSecret generate the signing secret of a new webhook
+func NewSecret() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return constant.WEBHOOK_SECRET_PREFIX + hex.EncodeToString(data), nil
+}
+
+// Sign compute the signature header of a payload, "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<payload>">".
+// The time is signed so receivers can reject old deliveries that are replayed by a third party.
+func Sign(secret string, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, digest(secret, timestamp, payload))
+}
+
+// Verify check a signature header was computed with the secret for the payload, receivers written in go can use it
+func Verify(secret, header string, payload []byte) bool {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(digest(secret, timestamp, payload)))
+}
+
+func digest(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,45 @@
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/quanluong166/friends_management/internal/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":1}`)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte(`1700000000.{"id":1}`))
+	assert.Equal(t, "t=1700000000,v1="+hex.EncodeToString(mac.Sum(nil)), webhook.Sign("whsec_test", at, payload))
+}
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"id":1}`)
+	header := webhook.Sign("whsec_test", time.Now(), payload)
+
+	tcs := map[string]struct {
+		secret  string
+		header  string
+		payload []byte
+		valid   bool
+	}{
+		"Valid":           {secret: "whsec_test", header: header, payload: payload, valid: true},
+		"WrongSecret":     {secret: "whsec_other", header: header, payload: payload},
+		"TamperedPayload": {secret: "whsec_test", header: header, payload: []byte(`{"id":2}`)},
+		"MissingTime":     {secret: "whsec_test", header: header[len("t=1700000000,"):], payload: payload},
+		"Empty":           {secret: "whsec_test", payload: payload},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, webhook.Verify(tc.secret, tc.header, tc.payload))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/outbox"
+	"github.com/quanluong166/friends_management/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	retryBaseDelay = 10 * time.Second
+	retryMaxDelay  = time.Hour
+	//claimLease is how long the claimed deliveries are kept from the other instances, the deliveries not sent before it
+	//ends are left to the next claim
+	claimLease = 10 * time.Minute
+	//maxConcurrentWebhooks is how many webhooks of a batch are called at the same time
+	maxConcurrentWebhooks = 8
+)
+
+// Worker deliver the queued events to the webhooks, a failed delivery is retried with a backoff and parked in the
+// dead letters after maxAttempts
+type Worker struct {
+	db          *gorm.DB
+	repo        repository.WebhookDeliveryRepository
+	client      *http.Client
+	batchSize   int
+	maxAttempts int
+}
+
+func NewWorker(db *gorm.DB, repo repository.WebhookDeliveryRepository, client *http.Client, batchSize, maxAttempts int) *Worker {
+	return &Worker{db: db, repo: repo, client: client, batchSize: batchSize, maxAttempts: maxAttempts}
+}
+
+// RunOnce attempt one batch of due deliveries and return how many were delivered.
+// The batch is claimed in a short transaction and the deliveries are sent outside of it, so instances deliver different
+// events at the same time and each delivery is marked on its own. The webhooks of the batch are called concurrently,
+// a slow endpoint only delays its own deliveries.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	claimedAt := time.Now()
+	var deliveries []model.WebhookDelivery
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deliveries, err = w.repo.WithTx(tx).ClaimDue(claimedAt, claimLease, w.batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("CLAIM_WEBHOOK_DELIVERIES_FAIL: %w", err)
+	}
+
+	//The deliveries of a webhook are sent one after the other, in the order of the events
+	var webhookIDs []uint
+	byWebhook := make(map[uint][]*model.WebhookDelivery)
+	for i := range deliveries {
+		id := deliveries[i].WebhookID
+		if _, ok := byWebhook[id]; !ok {
+			webhookIDs = append(webhookIDs, id)
+		}
+		byWebhook[id] = append(byWebhook[id], &deliveries[i])
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		delivered int
+		errs      []error
+	)
+	slots := make(chan struct{}, maxConcurrentWebhooks)
+	for _, id := range webhookIDs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(deliveries []*model.WebhookDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			for _, delivery := range deliveries {
+				//Another instance can claim the deliveries again once the lease ended
+				if time.Since(claimedAt) >= claimLease {
+					return
+				}
+				ok, err := w.deliver(ctx, delivery)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				}
+				if ok {
+					delivered++
+				}
+				mu.Unlock()
+			}
+		}(byWebhook[id])
+	}
+	wg.Wait()
+	return delivered, errors.Join(errs...)
+}
+
+// deliver send one claimed delivery and record the outcome, it reports whether the event was delivered
+func (w *Worker) deliver(ctx context.Context, delivery *model.WebhookDelivery) (bool, error) {
+	sendErr := w.send(ctx, delivery)
+	now := time.Now()
+	if sendErr == nil {
+		if err := w.repo.MarkDelivered(delivery.ID, now); err != nil {
+			return true, fmt.Errorf("MARK_WEBHOOK_DELIVERED_FAIL: %w", err)
+		}
+		return true, nil
+	}
+
+	delivery.Attempts++
+	if delivery.Attempts >= w.maxAttempts {
+		err := w.db.Transaction(func(tx *gorm.DB) error {
+			return w.repo.WithTx(tx).MoveToDeadLetter(delivery, sendErr.Error(), now)
+		})
+		if err != nil {
+			return false, fmt.Errorf("MOVE_WEBHOOK_DEAD_LETTER_FAIL: %w", err)
+		}
+		return false, nil
+	}
+
+	nextAttemptAt := now.Add(outbox.Backoff(delivery.Attempts, retryBaseDelay, retryMaxDelay))
+	if err := w.repo.MarkFailed(delivery.ID, delivery.Attempts, nextAttemptAt, sendErr.Error()); err != nil {
+		return false, fmt.Errorf("MARK_WEBHOOK_DELIVERY_FAILED_FAIL: %w", err)
+	}
+	return false, nil
+}
+
+// Run deliver the due events every interval until the process exits
+func (w *Worker) Run(interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := w.RunOnce(context.Background()); err != nil {
+			logger.Error(fmt.Errorf("DELIVER_WEBHOOKS_FAIL: %w", err))
+		}
+	}
+}
+
+// send post the signed payload to the webhook, any status other than 2xx is a failed delivery
+func (w *Worker) send(ctx context.Context, delivery *model.WebhookDelivery) error {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Webhook-ID", strconv.FormatUint(uint64(delivery.WebhookID), 10))
+	req.Header.Set("X-Event-ID", strconv.FormatUint(uint64(delivery.EventID), 10))
+	req.Header.Set("X-Event-Type", delivery.EventType)
+	req.Header.Set(constant.WEBHOOK_SIGNATURE_HEADER, Sign(delivery.Webhook.Secret, time.Now(), payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("WEBHOOK_STATUS: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/quanluong166/friends_management/internal/constant"
+	"github.com/quanluong166/friends_management/internal/controller"
+	"github.com/quanluong166/friends_management/internal/model"
+	"github.com/quanluong166/friends_management/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const secret = "whsec_test"
+
+// receiver is a partner app answering with status and checking every delivery is signed
+func receiver(t *testing.T, status int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.True(t, webhook.Verify(secret, r.Header.Get(constant.WEBHOOK_SIGNATURE_HEADER), body))
+		assert.Equal(t, "42", r.Header.Get("X-Event-ID"))
+		assert.Equal(t, constant.DOMAIN_EVENT_SUBSCRIBED, r.Header.Get("X-Event-Type"))
+		assert.Equal(t, `{"id":42}`, string(body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func delivery(url string, attempts int) model.WebhookDelivery {
+	return model.WebhookDelivery{
+		ID:        7,
+		WebhookID: 3,
+		Webhook:   model.Webhook{ID: 3, URL: url, Secret: secret},
+		EventID:   42,
+		EventType: constant.DOMAIN_EVENT_SUBSCRIBED,
+		Payload:   `{"id":42}`,
+		Attempts:  attempts,
+	}
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	ok, down := receiver(t, http.StatusOK), receiver(t, http.StatusInternalServerError)
+
+	testCases := map[string]struct {
+		deliveries  []model.WebhookDelivery
+		claimErr    error
+		deadLetter  bool
+		setup       func(repo *controller.MockWebhookDeliveryRepository)
+		count       int
+		expectedErr string
+	}{
+		"Delivered": {
+			deliveries: []model.WebhookDelivery{delivery(ok.URL, 0)},
+			setup: func(repo *controller.MockWebhookDeliveryRepository) {
+				repo.On("MarkDelivered", uint(7), mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+			count: 1,
+		},
+		"FailedIsRetriedWithBackoff": {
+			deliveries: []model.WebhookDelivery{delivery(down.URL, 2)},
+			setup: func(repo *controller.MockWebhookDeliveryRepository) {
+				before := time.Now()
+				repo.On("MarkFailed", uint(7), 3, mock.MatchedBy(func(next time.Time) bool {
+					//third failure waits 4 times the base delay
+					return !next.Before(before.Add(40*time.Second)) && next.Before(time.Now().Add(41*time.Second))
+				}), "WEBHOOK_STATUS: 500").Return(nil).Once()
+			},
+		},
+		"LastAttemptMovedToDeadLetter": {
+			deliveries: []model.WebhookDelivery{delivery(down.URL, 4)},
+			deadLetter: true,
+			setup: func(repo *controller.MockWebhookDeliveryRepository) {
+				repo.On("MoveToDeadLetter", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+					return d.ID == 7 && d.Attempts == 5
+				}), "WEBHOOK_STATUS: 500", mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+		},
+		"MarkFailureDoesNotStopTheBatch": {
+			deliveries: []model.WebhookDelivery{delivery(ok.URL, 0), otherDelivery(ok.URL)},
+			setup: func(repo *controller.MockWebhookDeliveryRepository) {
+				repo.On("MarkDelivered", uint(7), mock.AnythingOfType("time.Time")).Return(errors.New("DATABASE_ERROR")).Once()
+				repo.On("MarkDelivered", uint(8), mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+			count:       2,
+			expectedErr: "MARK_WEBHOOK_DELIVERED_FAIL: DATABASE_ERROR",
+		},
+		"NothingDue": {},
+		"ClaimFail": {
+			claimErr:    errors.New("DATABASE_ERROR"),
+			expectedErr: "CLAIM_WEBHOOK_DELIVERIES_FAIL: DATABASE_ERROR",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db, sqlMock := setupMockTxDB(t)
+			sqlMock.ExpectBegin()
+			if tc.claimErr == nil {
+				sqlMock.ExpectCommit()
+			} else {
+				sqlMock.ExpectRollback()
+			}
+			if tc.deadLetter {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectCommit()
+			}
+
+			repo := new(controller.MockWebhookDeliveryRepository)
+			repo.On("ClaimDue", mock.AnythingOfType("time.Time"), 10*time.Minute, 100).Return(tc.deliveries, tc.claimErr).Once()
+			if tc.setup != nil {
+				tc.setup(repo)
+			}
+
+			count, err := webhook.NewWorker(db, repo, http.DefaultClient, 100, 5).RunOnce(context.Background())
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.count, count)
+			repo.AssertExpectations(t)
+			require.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWorker_RunOnceSlowWebhook(t *testing.T) {
+	//The slow webhook only answers once the other webhook of the batch got its event
+	released := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-released:
+			w.WriteHeader(http.StatusOK)
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	t.Cleanup(slow.Close)
+	ok := receiver(t, http.StatusOK)
+
+	db, sqlMock := setupMockTxDB(t)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	first := otherDelivery(slow.URL)
+	first.ID = 6
+	repo := new(controller.MockWebhookDeliveryRepository)
+	repo.On("ClaimDue", mock.AnythingOfType("time.Time"), 10*time.Minute, 100).
+		Return([]model.WebhookDelivery{first, delivery(ok.URL, 0)}, nil).Once()
+	repo.On("MarkDelivered", uint(7), mock.AnythingOfType("time.Time")).Return(nil).Once().
+		Run(func(mock.Arguments) { close(released) })
+	repo.On("MarkDelivered", uint(6), mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+	count, err := webhook.NewWorker(db, repo, http.DefaultClient, 100, 5).RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	repo.AssertExpectations(t)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// otherDelivery is an event queued for another webhook
+func otherDelivery(url string) model.WebhookDelivery {
+	return model.WebhookDelivery{
+		ID:        8,
+		WebhookID: 4,
+		Webhook:   model.Webhook{ID: 4, URL: url, Secret: secret},
+		EventID:   42,
+		EventType: constant.DOMAIN_EVENT_SUBSCRIBED,
+		Payload:   `{"id":42}`,
+	}
+}
+
+func setupMockTxDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return db, mock
+}
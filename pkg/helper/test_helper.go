@@ -23,7 +23,7 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to connect to PostgreSQL: %v", err)
 	}
 
-	if err := db.AutoMigrate(&model.UserRelationship{}); err != nil {
+	if err := db.AutoMigrate(&model.UserRelationship{}, &model.IdempotencyKey{}, &model.AdminAuditLog{}, &model.RateLimitBucket{}, &model.UserQuota{}, &model.RelationshipEvent{}, &model.RelationshipHistory{}, &model.OutboxEvent{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.WebhookDeadLetter{}, &model.StatusUpdate{}, &model.FeedEntry{}, &model.EmailDelivery{}, &model.DigestEntry{}, &model.NotificationPreference{}); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 	return db
@@ -1,5 +1,7 @@
 package utils
 
+import "strings"
+
 // FindCommon find common elements between two arrays
 func FindCommon(arr1, arr2 []string) []string {
 	lookup := make(map[string]bool)
@@ -44,6 +46,32 @@ func RemoveSameElementsFromSecond(arr1, arr2 []string) []string {
 	return result
 }
 
+// RemoveSameEmailsFromSecond remove email in arr2 that exist in arr1, emails are compared case-insensitively
+func RemoveSameEmailsFromSecond(arr1, arr2 []string) []string {
+	lookup := make(map[string]bool)
+	for _, val := range arr1 {
+		lookup[strings.ToLower(val)] = true
+	}
+
+	var result []string
+	for _, val := range arr2 {
+		if !lookup[strings.ToLower(val)] {
+			result = append(result, val)
+		}
+	}
+
+	return result
+}
+
+// ToLower lower case every element of the array
+func ToLower(arr []string) []string {
+	result := make([]string, len(arr))
+	for i, val := range arr {
+		result[i] = strings.ToLower(val)
+	}
+	return result
+}
+
 // Append all the input array into one array
 func Combine(arr ...[]string) []string {
 	totalSize := len(arr)